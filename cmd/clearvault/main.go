@@ -1,17 +1,24 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"crypto/rand"
+	"encoding/base64"
 	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"clearvault/internal/api"
 	"clearvault/internal/config"
+	"clearvault/internal/crypto"
 	"clearvault/internal/metadata"
 	"clearvault/internal/proxy"
 	"clearvault/internal/remote"
@@ -52,13 +59,57 @@ func main() {
 	serverUIPath := serverCmd.String("ui", "", "UI 静态资源目录路径（用于管理面板）")
 	serverHelp := serverCmd.Bool("help", false, "显示帮助信息")
 
-	// 5. 检查是否有命令参数
+	// 5. repack 子命令参数（pack 空间回收）
+	repackCmd := flag.NewFlagSet("repack", flag.ExitOnError)
+	repackConfigPath := repackCmd.String("config", "config.yaml", "配置文件路径")
+	repackMinWaste := repackCmd.Float64("min-waste", 0.3, "失效数据占比达到该值的 pack 才会被重写")
+	repackHelp := repackCmd.Bool("help", false, "显示帮助信息")
+
+	// 6. resync 子命令参数（镜像副本修复）
+	resyncCmd := flag.NewFlagSet("resync", flag.ExitOnError)
+	resyncConfigPath := resyncCmd.String("config", "config.yaml", "配置文件路径")
+	resyncHelp := resyncCmd.Bool("help", false, "显示帮助信息")
+
+	// 7. repair 子命令参数（条带分片修复）
+	repairCmd := flag.NewFlagSet("repair", flag.ExitOnError)
+	repairConfigPath := repairCmd.String("config", "config.yaml", "配置文件路径")
+	repairHelp := repairCmd.Bool("help", false, "显示帮助信息")
+
+	// 8. migrate-layout 子命令参数（远端目录布局迁移）
+	migrateLayoutCmd := flag.NewFlagSet("migrate-layout", flag.ExitOnError)
+	migrateLayoutConfigPath := migrateLayoutCmd.String("config", "config.yaml", "配置文件路径")
+	migrateLayoutDryRun := migrateLayoutCmd.Bool("dry-run", false, "只统计需要移动的对象，不实际移动")
+	migrateLayoutHelp := migrateLayoutCmd.Bool("help", false, "显示帮助信息")
+
+	// 9. migrate 子命令参数（远端间迁移）
+	migrateCmd := flag.NewFlagSet("migrate", flag.ExitOnError)
+	migrateConfigPath := migrateCmd.String("config", "config.yaml", "配置文件路径")
+	migrateTo := migrateCmd.String("to", "", "新远端的配置文件（完整配置或只有 remote 部分）")
+	migrateWorkers := migrateCmd.Int("workers", 4, "并发复制数")
+	migrateNoSwitch := migrateCmd.Bool("no-switch", false, "复制完成后不修改配置文件中的 remote")
+	migrateForce := migrateCmd.Bool("force", false, "即使有对象在当前远端不存在也切换 remote")
+	migrateHelp := migrateCmd.Bool("help", false, "显示帮助信息")
+
+	// 10. recovery-keygen 子命令参数（生成恢复密钥对）
+	recoveryKeygenCmd := flag.NewFlagSet("recovery-keygen", flag.ExitOnError)
+	recoveryKeygenOut := recoveryKeygenCmd.String("out", "", "恢复私钥的保存路径（请离线保管）")
+	recoveryKeygenHelp := recoveryKeygenCmd.Bool("help", false, "显示帮助信息")
+
+	// 11. recover-keys 子命令参数（用恢复私钥更换主密钥）
+	recoverKeysCmd := flag.NewFlagSet("recover-keys", flag.ExitOnError)
+	recoverKeysConfigPath := recoverKeysCmd.String("config", "config.yaml", "配置文件路径")
+	recoverKeysKey := recoverKeysCmd.String("recovery-key", "", "恢复私钥文件路径")
+	recoverKeysNewKey := recoverKeysCmd.String("new-master-key", "", "新的主密钥（base64，不指定则自动生成）")
+	recoverKeysForce := recoverKeysCmd.Bool("force", false, "即使部分文件无法取回也换用新主密钥")
+	recoverKeysHelp := recoverKeysCmd.Bool("help", false, "显示帮助信息")
+
+	// 12. 检查是否有命令参数
 	if len(os.Args) < 2 {
 		printUsage()
 		return
 	}
 
-	// 6. 根据命令类型分发
+	// 13. 根据命令类型分发
 	if cmdFunc, ok := commands[os.Args[1]]; ok {
 		cmdFunc(os.Args[2:])
 		return
@@ -128,6 +179,105 @@ func main() {
 			log.Fatalf("Unknown config subcommand: %s", rest[0])
 		}
 
+	case "key":
+		configPath, rest := extractConfigPath(os.Args[2:])
+		if len(rest) < 1 || rest[0] == "--help" || rest[0] == "-help" {
+			printKeyUsage()
+			return
+		}
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		switch rest[0] {
+		case "backup":
+			handleKeyBackup(cfg, rest[1:])
+		case "restore":
+			handleKeyRestore(cfg, configPath, rest[1:])
+		default:
+			log.Fatalf("Unknown key subcommand: %s", rest[0])
+		}
+
+	case "repack":
+		repackCmd.Parse(os.Args[2:])
+		if *repackHelp {
+			printRepackUsage()
+			return
+		}
+		cfg, err := config.LoadConfig(*repackConfigPath)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		handleRepack(cfg, *repackMinWaste)
+
+	case "resync":
+		resyncCmd.Parse(os.Args[2:])
+		if *resyncHelp {
+			printResyncUsage()
+			return
+		}
+		cfg, err := config.LoadConfig(*resyncConfigPath)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		handleResync(cfg)
+
+	case "repair":
+		repairCmd.Parse(os.Args[2:])
+		if *repairHelp {
+			printRepairUsage()
+			return
+		}
+		cfg, err := config.LoadConfig(*repairConfigPath)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		handleRepair(cfg)
+
+	case "migrate-layout":
+		migrateLayoutCmd.Parse(os.Args[2:])
+		if *migrateLayoutHelp {
+			printMigrateLayoutUsage()
+			return
+		}
+		cfg, err := config.LoadConfig(*migrateLayoutConfigPath)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		handleMigrateLayout(cfg, *migrateLayoutDryRun)
+
+	case "migrate":
+		migrateCmd.Parse(os.Args[2:])
+		if *migrateHelp {
+			printMigrateUsage()
+			return
+		}
+		cfg, err := config.LoadConfig(*migrateConfigPath)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		handleMigrate(cfg, *migrateConfigPath, *migrateTo, *migrateWorkers, *migrateNoSwitch, *migrateForce)
+
+	case "recovery-keygen":
+		recoveryKeygenCmd.Parse(os.Args[2:])
+		if *recoveryKeygenHelp {
+			printRecoveryKeygenUsage()
+			return
+		}
+		handleRecoveryKeygen(*recoveryKeygenOut)
+
+	case "recover-keys":
+		recoverKeysCmd.Parse(os.Args[2:])
+		if *recoverKeysHelp {
+			printRecoverKeysUsage()
+			return
+		}
+		cfg, err := config.LoadConfig(*recoverKeysConfigPath)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		handleRecoverKeys(cfg, *recoverKeysConfigPath, *recoverKeysKey, *recoverKeysNewKey, *recoverKeysForce)
+
 	case "server":
 		serverCmd.Parse(os.Args[2:])
 		if *serverHelp {
@@ -171,7 +321,15 @@ func printUsage() {
 	log.Println("  encrypt   Encrypt local files/directories (offline)")
 	log.Println("  export    Export metadata to encrypted share package")
 	log.Println("  import    Import metadata from encrypted share package")
+	log.Println("  key       Back up the master key as Shamir shares, or restore it")
+	log.Println("  migrate   Copy all objects to a new remote and switch to it")
+	log.Println("  migrate-layout  Move flat remote objects into the configured directory layout")
 	log.Println("  mount     Mount encrypted storage via FUSE")
+	log.Println("  recover-keys     Re-encrypt file keys under a new master key using the recovery key")
+	log.Println("  recovery-keygen  Generate a recovery key pair for key escrow")
+	log.Println("  repack    Reclaim space in small-file pack objects")
+	log.Println("  repair    Rebuild lost shards of a striped remote")
+	log.Println("  resync    Copy missing objects between mirror replicas")
 	log.Println("  server    Start WebDAV server")
 	log.Println("")
 	log.Println("Examples:")
@@ -251,6 +409,391 @@ func printServerUsage() {
 	log.Println("  clearvault server --config config-s3.yaml")
 }
 
+func printRepackUsage() {
+	log.Println("Usage: clearvault repack [options]")
+	log.Println("")
+	log.Println("Rewrite small-file pack objects to reclaim space from deleted entries")
+	log.Println("")
+	log.Println("Options:")
+	log.Println("  --config string     配置文件路径 (default \"config.yaml\")")
+	log.Println("  --min-waste float   失效数据占比阈值 (default 0.3)")
+	log.Println("  --help              显示帮助信息")
+	log.Println("")
+	log.Println("Examples:")
+	log.Println("  clearvault repack --config config.yaml")
+	log.Println("  clearvault repack --min-waste 0.5")
+}
+
+func printResyncUsage() {
+	log.Println("Usage: clearvault resync [options]")
+	log.Println("")
+	log.Println("Compare all replicas of a mirror remote and copy objects missing on any replica")
+	log.Println("")
+	log.Println("Options:")
+	log.Println("  --config string     配置文件路径 (default \"config.yaml\")")
+	log.Println("  --help              显示帮助信息")
+	log.Println("")
+	log.Println("Examples:")
+	log.Println("  clearvault resync --config config.yaml")
+}
+
+func printRepairUsage() {
+	log.Println("Usage: clearvault repair [options]")
+	log.Println("")
+	log.Println("Scan all members of a striped remote and rebuild missing or damaged shards")
+	log.Println("")
+	log.Println("Options:")
+	log.Println("  --config string     配置文件路径 (default \"config.yaml\")")
+	log.Println("  --help              显示帮助信息")
+	log.Println("")
+	log.Println("Examples:")
+	log.Println("  clearvault repair --config config.yaml")
+}
+
+func printMigrateLayoutUsage() {
+	log.Println("Usage: clearvault migrate-layout [options]")
+	log.Println("")
+	log.Println("Move objects stored flat on the remote into the directory layout set by remote.layout")
+	log.Println("Objects not yet moved remain readable, so the server may keep running during migration")
+	log.Println("")
+	log.Println("Options:")
+	log.Println("  --config string     配置文件路径 (default \"config.yaml\")")
+	log.Println("  --dry-run           只统计需要移动的对象，不实际移动")
+	log.Println("  --help              显示帮助信息")
+	log.Println("")
+	log.Println("Examples:")
+	log.Println("  clearvault migrate-layout --config config.yaml --dry-run")
+	log.Println("  clearvault migrate-layout --config config.yaml")
+}
+
+func printMigrateUsage() {
+	log.Println("Usage: clearvault migrate --to <remote config> [options]")
+	log.Println("")
+	log.Println("Copy every object referenced by metadata from the current remote to a new one,")
+	log.Println("verify sizes, then switch remote in the config file. Objects are copied as-is")
+	log.Println("(no re-encryption); metadata is not changed. Run again to resume after interruption.")
+	log.Println("")
+	log.Println("If some referenced objects do not exist on the current remote (e.g. files still in")
+	log.Println("the local upload queue), the config is not switched unless --force is given.")
+	log.Println("")
+	log.Println("Options:")
+	log.Println("  --config string     配置文件路径 (default \"config.yaml\")")
+	log.Println("  --to string         新远端的配置文件（完整配置或只有 remote 部分）")
+	log.Println("  --workers int       并发复制数 (default 4)")
+	log.Println("  --no-switch         复制完成后不修改配置文件中的 remote")
+	log.Println("  --force             即使有对象在当前远端不存在也切换 remote")
+	log.Println("  --help              显示帮助信息")
+	log.Println("")
+	log.Println("Examples:")
+	log.Println("  clearvault migrate --config config.yaml --to r2.yaml")
+	log.Println("  clearvault migrate --to r2.yaml --workers 8 --no-switch")
+}
+
+func printKeyUsage() {
+	log.Println("Usage: clearvault key [--config <path>] <backup|restore> [options]")
+	log.Println("")
+	log.Println("backup   Split the master key into N Shamir shares, any K of which restore it.")
+	log.Println("         Shares are printable text (uppercase, QR alphanumeric) with a checksum.")
+	log.Println("restore  Read shares (one per line) from --in or stdin, recombine them, verify")
+	log.Println("         the key against the vault descriptor and write it into the config file.")
+	log.Println("")
+	log.Println("Options (backup):")
+	log.Println("  --shares int        分享总数 N (default 5)")
+	log.Println("  --threshold int     恢复所需的分享数 K (default 3)")
+	log.Println("  --out string        每份分享写入该目录下的单独文件（可选，默认只打印）")
+	log.Println("")
+	log.Println("Options (restore):")
+	log.Println("  --in string         包含分享的文本文件（可选，默认从标准输入读取）")
+	log.Println("  --force             覆盖配置中已有的不同主密钥")
+	log.Println("")
+	log.Println("Examples:")
+	log.Println("  clearvault key backup --shares 5 --threshold 3")
+	log.Println("  clearvault key --config config.yaml restore --in shares.txt")
+}
+
+func printRecoveryKeygenUsage() {
+	log.Println("Usage: clearvault recovery-keygen --out <file>")
+	log.Println("")
+	log.Println("Generate an X25519 recovery key pair. The private key is written to --out;")
+	log.Println("put the printed public key into security.recovery_public_key. Files written")
+	log.Println("afterwards keep a copy of their file key sealed to the recovery public key.")
+	log.Println("")
+	log.Println("Options:")
+	log.Println("  --out string        恢复私钥的保存路径（请离线保管）")
+	log.Println("  --help              显示帮助信息")
+	log.Println("")
+	log.Println("Examples:")
+	log.Println("  clearvault recovery-keygen --out /media/usb/clearvault-recovery.key")
+}
+
+func printRecoverKeysUsage() {
+	log.Println("Usage: clearvault recover-keys --recovery-key <file> [options]")
+	log.Println("")
+	log.Println("Recover access after the master key is lost: unseal every file key with the")
+	log.Println("recovery private key, re-encrypt it under a new master key, update the vault")
+	log.Println("descriptor and write the new master key to the config file. Offline operation")
+	log.Println("on metadata only; remote objects are not changed. Run again to resume.")
+	log.Println("")
+	log.Println("Files written before the recovery key was configured are re-encrypted with the")
+	log.Println("master key still in the config, if it works. If any file can be unwrapped by")
+	log.Println("neither key, nothing is changed unless --force is given.")
+	log.Println("")
+	log.Println("Options:")
+	log.Println("  --config string          配置文件路径 (default \"config.yaml\")")
+	log.Println("  --recovery-key string    恢复私钥文件路径")
+	log.Println("  --new-master-key string  新的主密钥（base64，不指定则自动生成）")
+	log.Println("  --force                  即使部分文件无法取回也换用新主密钥")
+	log.Println("  --help                   显示帮助信息")
+	log.Println("")
+	log.Println("Examples:")
+	log.Println("  clearvault recover-keys --config config.yaml --recovery-key /media/usb/clearvault-recovery.key")
+}
+
+// cacheDir 返回缓存目录（写回暂存、pack 暂存、块缓存）
+func cacheDir(cfg *config.Config) string {
+	if cfg.Storage.CacheDir == "" {
+		return "storage/cache"
+	}
+	return cfg.Storage.CacheDir
+}
+
+// configureProxy 根据配置启用代理的可选功能
+func configureProxy(p *proxy.Proxy, cfg *config.Config) error {
+	if err := p.SetRecoveryKey(cfg.Security.RecoveryPublicKey); err != nil {
+		return err
+	}
+	if err := p.SetCompression(cfg.Storage.Compression); err != nil {
+		return err
+	}
+	p.SetVerifyUploads(cfg.Storage.VerifyUploads)
+	if rules := cfg.Remote.S3.StorageClasses; len(rules) > 0 {
+		classes := make([]proxy.StorageClassRule, len(rules))
+		for i, r := range rules {
+			classes[i] = proxy.StorageClassRule{Prefix: r.Path, Class: r.Class}
+		}
+		p.SetStorageClasses(classes)
+	}
+	cacheDir := cacheDir(cfg)
+	if cfg.Storage.BlockCache.Enabled {
+		err := p.EnableBlockCache(proxy.BlockCacheOptions{
+			Dir:     filepath.Join(cacheDir, "blocks"),
+			MaxSize: cfg.Storage.BlockCache.MaxSize << 20,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to enable block cache: %w", err)
+		}
+	}
+	if cfg.Storage.WriteBack.Enabled {
+		err := p.EnableWriteBack(proxy.WriteBackOptions{
+			StagingDir: filepath.Join(cacheDir, "staging"),
+			Workers:    cfg.Storage.WriteBack.Workers,
+			RetryDelay: time.Duration(cfg.Storage.WriteBack.RetryDelay) * time.Second,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to enable write-back: %w", err)
+		}
+	}
+	if cfg.Storage.ReadAhead.Enabled {
+		p.EnableReadAhead(proxy.ReadAheadOptions{
+			Window:   cfg.Storage.ReadAhead.Window << 20,
+			Segment:  cfg.Storage.ReadAhead.Segment << 10,
+			Parallel: cfg.Storage.ReadAhead.Parallel,
+		})
+	}
+	if cfg.Storage.Pack.Enabled {
+		err := p.EnablePacking(proxy.PackOptions{
+			Threshold:     cfg.Storage.Pack.Threshold,
+			TargetSize:    cfg.Storage.Pack.TargetSize,
+			FlushInterval: time.Duration(cfg.Storage.Pack.FlushInterval) * time.Second,
+			StagingDir:    filepath.Join(cacheDir, "packs"),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to enable packing: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkVault 用保险库描述校验主密钥，不匹配时退出，避免读取失败或用错误的密钥写入新文件
+func checkVault(p *proxy.Proxy) {
+	d, err := p.CheckVault(context.Background())
+	if err != nil {
+		log.Fatalf("Vault check failed: %v", err)
+	}
+	log.Printf("Vault %s: master key verified", d.ID)
+}
+
+// handleRepack - pack 空间回收
+func handleRepack(cfg *config.Config, minWaste float64) {
+	if !cfg.Storage.Pack.Enabled {
+		log.Fatalf("Error: storage.pack.enabled is false, nothing to repack")
+	}
+
+	meta, err := metadata.NewLocalStorage(cfg.Storage.MetadataPath)
+	if err != nil {
+		log.Fatalf("Failed to initialize metadata storage: %v", err)
+	}
+	defer meta.Close()
+
+	remoteStorage, err := remote.NewRemoteStorage(cfg.Remote)
+	if err != nil {
+		log.Fatalf("Failed to create remote storage: %v", err)
+	}
+	defer remoteStorage.Close()
+
+	p, err := proxy.NewProxy(meta, remoteStorage, cfg.Security.MasterKey)
+	if err != nil {
+		log.Fatalf("Failed to initialize proxy: %v", err)
+	}
+	checkVault(p)
+	// 只做回收：暂存目录可能属于正在运行的服务，不启用写回、块缓存、预读，也不上传暂存的 pack
+	p.SetVerifyUploads(cfg.Storage.VerifyUploads)
+	if err := p.EnablePackCompaction(filepath.Join(cacheDir(cfg), "packs")); err != nil {
+		log.Fatalf("Failed to enable pack compaction: %v", err)
+	}
+	defer p.Close()
+
+	stats, err := p.CompactPacks(context.Background(), minWaste)
+	if err != nil {
+		log.Fatalf("Repack failed: %v", err)
+	}
+	log.Printf("✅ Repack completed: scanned=%d rewritten=%d deleted=%d reclaimed=%d bytes",
+		stats.PacksScanned, stats.PacksRewritten, stats.PacksDeleted, stats.BytesReclaimed)
+}
+
+// handleResync - 镜像副本修复
+func handleResync(cfg *config.Config) {
+	remoteStorage, err := remote.NewRemoteStorage(cfg.Remote)
+	if err != nil {
+		log.Fatalf("Failed to create remote storage: %v", err)
+	}
+	defer remoteStorage.Close()
+
+	m, ok := remote.Unwrap(remoteStorage).(*remote.MirrorStorage)
+	if !ok {
+		log.Fatalf("Error: remote.type is not mirror, nothing to resync")
+	}
+	stats, err := m.Resync(context.Background())
+	if err != nil {
+		log.Fatalf("Resync failed: %v", err)
+	}
+	log.Printf("✅ Resync completed: copied=%d deleted=%d failed=%d", stats.Copied, stats.Deleted, stats.Failed)
+}
+
+// handleRepair - 条带分片修复
+func handleRepair(cfg *config.Config) {
+	remoteStorage, err := remote.NewRemoteStorage(cfg.Remote)
+	if err != nil {
+		log.Fatalf("Failed to create remote storage: %v", err)
+	}
+	defer remoteStorage.Close()
+
+	s, ok := remote.Unwrap(remoteStorage).(*remote.StripedStorage)
+	if !ok {
+		log.Fatalf("Error: remote.type is not striped, nothing to repair")
+	}
+	stats, err := s.Repair(context.Background())
+	if err != nil {
+		log.Fatalf("Repair failed: %v", err)
+	}
+	log.Printf("✅ Repair completed: objects=%d repaired=%d lost=%d failed=%d",
+		stats.Objects, stats.Repaired, stats.Lost, stats.Failed)
+}
+
+// handleMigrateLayout - 远端目录布局迁移
+func handleMigrateLayout(cfg *config.Config, dryRun bool) {
+	remoteStorage, err := remote.NewRemoteStorage(cfg.Remote)
+	if err != nil {
+		log.Fatalf("Failed to create remote storage: %v", err)
+	}
+	defer remoteStorage.Close()
+
+	l, ok := remoteStorage.(*remote.LayoutStorage)
+	if !ok {
+		log.Fatalf("Error: remote.layout.levels is 0, nothing to migrate")
+	}
+	stats, err := l.Migrate(context.Background(), dryRun)
+	if err != nil {
+		log.Fatalf("Layout migration failed: %v", err)
+	}
+	log.Printf("✅ Layout migration completed: scanned=%d moved=%d failed=%d", stats.Scanned, stats.Moved, stats.Failed)
+}
+
+// handleMigrate - 远端间迁移
+func handleMigrate(cfg *config.Config, configPath, to string, workers int, noSwitch, force bool) {
+	if to == "" {
+		log.Fatalf("Error: --to is required")
+	}
+	target, err := config.LoadRemoteConfig(to)
+	if err != nil {
+		log.Fatalf("Failed to load target remote config: %v", err)
+	}
+
+	meta, err := metadata.NewLocalStorage(cfg.Storage.MetadataPath)
+	if err != nil {
+		log.Fatalf("Failed to initialize metadata storage: %v", err)
+	}
+	defer meta.Close()
+	names, err := metadata.RemoteObjects(meta)
+	if err != nil {
+		log.Fatalf("Failed to read metadata: %v", err)
+	}
+	names = append(names, proxy.VaultObjectName)
+
+	src, err := remote.NewRemoteStorage(cfg.Remote)
+	if err != nil {
+		log.Fatalf("Failed to create remote storage: %v", err)
+	}
+	defer src.Close()
+	dst, err := remote.NewRemoteStorage(target)
+	if err != nil {
+		log.Fatalf("Failed to create target remote storage: %v", err)
+	}
+	defer dst.Close()
+
+	log.Printf("Migrating %d objects to the new remote...", len(names))
+	stats, err := remote.Copy(context.Background(), src, dst, names, remote.CopyOptions{Workers: workers})
+	if err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+	log.Printf("Migration finished: objects=%d copied=%d skipped=%d missing=%d failed=%d bytes=%d",
+		stats.Objects, stats.Copied, stats.Skipped, stats.Missing, stats.Failed, stats.Bytes)
+	if stats.Failed > 0 {
+		log.Fatalf("Error: %d objects failed to copy, run the command again to resume", stats.Failed)
+	}
+	if stats.Missing > 0 {
+		// 写回队列或未封存 pack 中的文件只在本地，切换后会上传到新远端；其余缺失的对象在两边都不存在
+		log.Printf("Warning: %d objects referenced by metadata do not exist on the current remote. "+
+			"Files still waiting in the local upload queue (write-back staging, unsealed packs) exist only "+
+			"locally and will be uploaded to whichever remote is configured when the server next runs; "+
+			"anything else is lost on both remotes", stats.Missing)
+	}
+	if noSwitch {
+		log.Printf("✅ Migration completed, %s not changed (--no-switch)", configPath)
+		return
+	}
+	if stats.Missing > 0 && !force {
+		log.Fatalf("Error: %s not switched because of missing objects. Let the server drain its upload queue "+
+			"and run the command again, or use --force to switch anyway", configPath)
+	}
+
+	// 切换前备份原配置文件；重新读取以免把环境变量覆盖的值写入文件
+	if data, err := os.ReadFile(configPath); err == nil {
+		if err := os.WriteFile(configPath+".bak", data, 0600); err != nil {
+			log.Fatalf("Failed to back up config: %v", err)
+		}
+	}
+	fresh, err := config.LoadConfig(configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	fresh.Remote = target
+	if err := config.SaveConfig(configPath, fresh); err != nil {
+		log.Fatalf("Failed to save config: %v", err)
+	}
+	log.Printf("✅ Migration completed, remote in %s switched to the new remote (previous config saved to %s.bak)", configPath, configPath)
+}
+
 // handleEncrypt - 本地文件加密
 func handleEncrypt(cmd *flag.FlagSet, cfg *config.Config, meta metadata.Storage, encryptInput, encryptOutput *string) {
 	// 验证必需参数
@@ -266,6 +809,13 @@ func handleEncrypt(cmd *flag.FlagSet, cfg *config.Config, meta metadata.Storage,
 	if err != nil {
 		log.Fatalf("Failed to initialize proxy: %v", err)
 	}
+	checkVault(p)
+	if err := p.SetRecoveryKey(cfg.Security.RecoveryPublicKey); err != nil {
+		log.Fatalf("Failed to configure recovery key: %v", err)
+	}
+	if err := p.SetCompression(cfg.Storage.Compression); err != nil {
+		log.Fatalf("Failed to configure compression: %v", err)
+	}
 
 	// 调用 ExportLocal 进行本地文件加密
 	if err := p.ExportLocal(*encryptInput, *encryptOutput); err != nil {
@@ -293,6 +843,7 @@ func handleExport(cmd *flag.FlagSet, cfg *config.Config, meta metadata.Storage,
 	if err != nil {
 		log.Fatalf("Failed to initialize proxy: %v", err)
 	}
+	checkVault(p)
 
 	// 生成随机密码（如果未指定）
 	shareKey := *exportShareKey
@@ -325,6 +876,10 @@ func handleImport(cmd *flag.FlagSet, cfg *config.Config, meta metadata.Storage,
 	if err != nil {
 		log.Fatalf("Failed to initialize proxy: %v", err)
 	}
+	checkVault(p)
+	if err := p.SetRecoveryKey(cfg.Security.RecoveryPublicKey); err != nil {
+		log.Fatalf("Failed to configure recovery key: %v", err)
+	}
 
 	// 接收分享包
 	err = p.ReceiveSharePackage(*importInput, *importShareKey)
@@ -335,6 +890,259 @@ func handleImport(cmd *flag.FlagSet, cfg *config.Config, meta metadata.Storage,
 	log.Printf("✅ Share package imported successfully")
 }
 
+// handleRecoveryKeygen - 生成恢复密钥对
+func handleRecoveryKeygen(out string) {
+	if out == "" {
+		log.Fatalf("Error: --out parameter is required")
+	}
+	pub, priv, err := crypto.GenerateRecoveryKey()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	// O_EXCL：不覆盖已有的私钥文件
+	f, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		log.Fatalf("Failed to create recovery key file: %v", err)
+	}
+	if _, err := fmt.Fprintln(f, base64.StdEncoding.EncodeToString(priv)); err != nil {
+		f.Close()
+		log.Fatalf("Failed to write recovery key file: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("Failed to write recovery key file: %v", err)
+	}
+	log.Printf("✅ Recovery private key saved to %s", out)
+	log.Printf("🔑 Recovery public key: %s", base64.StdEncoding.EncodeToString(pub))
+	log.Println("Add the public key to security.recovery_public_key in your config.")
+	log.Println("⚠️  Keep the private key offline: anyone holding it can decrypt every file written after it is configured.")
+}
+
+// handleRecoverKeys - 用恢复私钥更换主密钥
+func handleRecoverKeys(cfg *config.Config, configPath, keyPath, newKey string, force bool) {
+	if keyPath == "" {
+		log.Fatalf("Error: --recovery-key parameter is required")
+	}
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		log.Fatalf("Failed to read recovery key: %v", err)
+	}
+	priv, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		log.Fatalf("Failed to decode recovery key: %v", err)
+	}
+	pub, err := crypto.RecoveryPublicKey(priv)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if cfg.Security.RecoveryPublicKey != "" && cfg.Security.RecoveryPublicKey != base64.StdEncoding.EncodeToString(pub) {
+		log.Fatalf("Error: the recovery key does not match security.recovery_public_key")
+	}
+
+	if newKey == "" {
+		key, err := crypto.GenerateRandomBytes(32)
+		if err != nil {
+			log.Fatalf("Failed to generate master key: %v", err)
+		}
+		newKey = base64.StdEncoding.EncodeToString(key)
+	} else if key, err := base64.StdEncoding.DecodeString(newKey); err != nil || len(key) != 32 {
+		log.Fatalf("Error: --new-master-key must be a base64 encoded 32-byte key")
+	}
+
+	meta, err := metadata.NewLocalStorage(cfg.Storage.MetadataPath)
+	if err != nil {
+		log.Fatalf("Failed to initialize metadata storage: %v", err)
+	}
+	defer meta.Close()
+	remoteStorage, err := remote.NewRemoteStorage(cfg.Remote)
+	if err != nil {
+		log.Fatalf("Failed to create remote storage: %v", err)
+	}
+	defer remoteStorage.Close()
+
+	p, err := proxy.NewProxy(meta, remoteStorage, newKey)
+	if err != nil {
+		log.Fatalf("Failed to initialize proxy: %v", err)
+	}
+	// 配置中的原主密钥（若仍可用）用于解开配置恢复公钥之前写入的文件
+	opts := proxy.RecoverOptions{OldMasterKey: cfg.Security.MasterKey, DryRun: true}
+	stats, err := p.RecoverKeys(priv, opts)
+	if err != nil {
+		log.Fatalf("Key recovery check failed: %v", err)
+	}
+	if stats.Missing > 0 {
+		if !force {
+			log.Fatalf("Error: %d files have no recovery key and the configured master key cannot unwrap them; they would become unreadable under the new key. Restore the old master key (e.g. with `key restore`) and run again, or use --force to switch anyway", stats.Missing)
+		}
+		log.Printf("Warning: %d files cannot be recovered and will be unreadable under the new key (--force)", stats.Missing)
+	}
+
+	opts.DryRun = false
+	if stats, err = p.RecoverKeys(priv, opts); err != nil {
+		log.Fatalf("Key recovery failed: %v (run the command again with --new-master-key %s to resume)", err, newKey)
+	}
+	d, err := p.RekeyVault(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to update vault descriptor: %v (run the command again with --new-master-key %s to resume)", err, newKey)
+	}
+	log.Printf("Key recovery finished: vault=%s files=%d recovered=%d rewrapped=%d missing=%d", d.ID, stats.Files, stats.Recovered, stats.Rewrapped, stats.Missing)
+
+	// 与 migrate 相同：先备份原配置，重新读取以免把环境变量覆盖的值写入文件
+	if data, err := os.ReadFile(configPath); err == nil {
+		if err := os.WriteFile(configPath+".bak", data, 0600); err != nil {
+			log.Fatalf("Failed to back up config: %v", err)
+		}
+	}
+	fresh, err := config.LoadConfig(configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	fresh.Security.MasterKey = newKey
+	if err := config.SaveConfig(configPath, fresh); err != nil {
+		log.Printf("🔑 New Master Key: %s", newKey)
+		log.Fatalf("Failed to save config, set security.master_key to the key above manually: %v", err)
+	}
+	log.Printf("✅ Master key replaced in %s (previous config saved to %s.bak)", configPath, configPath)
+	log.Printf("🔑 New Master Key: %s", newKey)
+	log.Printf("⚠️  IMPORTANT: Please backup this key!")
+}
+
+// handleKeyBackup - 主密钥 Shamir 分享备份
+func handleKeyBackup(cfg *config.Config, args []string) {
+	cmd := flag.NewFlagSet("key backup", flag.ExitOnError)
+	n := cmd.Int("shares", 5, "分享总数 N")
+	threshold := cmd.Int("threshold", 3, "恢复所需的分享数 K")
+	out := cmd.String("out", "", "每份分享写入该目录下的单独文件（可选，默认只打印）")
+	cmd.Parse(args)
+
+	if cfg.Security.MasterKey == "" || cfg.Security.MasterKey == "CHANGE-THIS-TO-A-SECURE-32BYTE-KEY" {
+		log.Fatalf("Error: master key is not configured")
+	}
+	key, err := base64.StdEncoding.DecodeString(cfg.Security.MasterKey)
+	if err != nil {
+		log.Fatalf("Failed to decode master key: %v", err)
+	}
+
+	// 备份前确认配置中的密钥就是保险库使用的密钥
+	meta, err := metadata.NewLocalStorage(cfg.Storage.MetadataPath)
+	if err != nil {
+		log.Fatalf("Failed to initialize metadata storage: %v", err)
+	}
+	defer meta.Close()
+	p, err := proxy.NewProxy(meta, nil, cfg.Security.MasterKey)
+	if err != nil {
+		log.Fatalf("Failed to initialize proxy: %v", err)
+	}
+	checkVault(p)
+
+	shares, err := crypto.SplitKey(key, *n, *threshold)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	// 自检：用前 K 份恢复
+	if got, err := crypto.CombineKey(shares[:*threshold]); err != nil || !bytes.Equal(got, key) {
+		log.Fatalf("Share self-check failed: %v", err)
+	}
+
+	if *out != "" {
+		if err := os.MkdirAll(*out, 0700); err != nil {
+			log.Fatalf("Failed to create output directory: %v", err)
+		}
+	}
+	log.Printf("✅ Master key split into %d shares, any %d of them restore it (backup ID %s)", *n, *threshold, shares[0].Check)
+	for _, s := range shares {
+		if *out == "" {
+			fmt.Println(s.String())
+			continue
+		}
+		name := filepath.Join(*out, fmt.Sprintf("clearvault-key-share-%d.txt", s.Index))
+		if err := os.WriteFile(name, []byte(s.String()+"\n"), 0600); err != nil {
+			log.Fatalf("Failed to write share: %v", err)
+		}
+		log.Printf("Share %d written to %s", s.Index, name)
+	}
+	log.Println("⚠️  Store each share in a different place; fewer than the threshold reveal nothing about the key.")
+}
+
+// handleKeyRestore - 从 Shamir 分享恢复主密钥
+func handleKeyRestore(cfg *config.Config, configPath string, args []string) {
+	cmd := flag.NewFlagSet("key restore", flag.ExitOnError)
+	in := cmd.String("in", "", "包含分享的文本文件（可选，默认从标准输入读取）")
+	force := cmd.Bool("force", false, "覆盖配置中已有的不同主密钥")
+	cmd.Parse(args)
+
+	r := os.Stdin
+	if *in != "" {
+		f, err := os.Open(*in)
+		if err != nil {
+			log.Fatalf("Failed to open shares: %v", err)
+		}
+		defer f.Close()
+		r = f
+	} else {
+		log.Println("Enter key shares, one per line (end with an empty line or EOF):")
+	}
+
+	var shares []*crypto.KeyShare
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			if *in == "" && line == "" && len(shares) > 0 {
+				break
+			}
+			continue
+		}
+		s, err := crypto.ParseKeyShare(line)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		shares = append(shares, s)
+		if *in == "" && len(shares) >= s.Threshold {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("Failed to read shares: %v", err)
+	}
+	key, err := crypto.CombineKey(shares)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(key)
+
+	// 写入配置前用保险库描述校验恢复出的密钥
+	meta, err := metadata.NewLocalStorage(cfg.Storage.MetadataPath)
+	if err != nil {
+		log.Fatalf("Failed to initialize metadata storage: %v", err)
+	}
+	defer meta.Close()
+	remoteStorage, err := remote.NewRemoteStorage(cfg.Remote)
+	if err != nil {
+		log.Printf("Warning: remote storage unavailable, verifying against metadata only: %v", err)
+		remoteStorage = nil
+	} else {
+		defer remoteStorage.Close()
+	}
+	p, err := proxy.NewProxy(meta, remoteStorage, encoded)
+	if err != nil {
+		log.Fatalf("Failed to initialize proxy: %v", err)
+	}
+	checkVault(p)
+
+	current := cfg.Security.MasterKey
+	switch {
+	case current == encoded:
+		log.Printf("✅ Master key verified, %s already contains it", configPath)
+		return
+	case current != "" && current != "CHANGE-THIS-TO-A-SECURE-32BYTE-KEY" && !*force:
+		log.Fatalf("Error: %s already contains a different master key, use --force to replace it", configPath)
+	}
+	if err := config.SaveMasterKey(configPath, encoded); err != nil {
+		log.Fatalf("Failed to save master key: %v", err)
+	}
+	log.Printf("✅ Master key restored from %d shares and saved to %s", len(shares), configPath)
+}
+
 func extractConfigPath(args []string) (string, []string) {
 	configPath := "config.yaml"
 	rest := make([]string, 0, len(args))
@@ -430,6 +1238,11 @@ func handleServer(cfg *config.Config, configPath string, uiPath string) {
 		if err != nil {
 			log.Fatalf("Failed to initialize proxy: %v", err)
 		}
+		checkVault(p)
+		if err := configureProxy(p, cfg); err != nil {
+			log.Fatalf("%v", err)
+		}
+		defer p.Close()
 	}
 
 	// 即使未初始化，也启动 HTTP 服务以便进行 Setup
@@ -449,6 +1262,7 @@ func handleServer(cfg *config.Config, configPath string, uiPath string) {
 
 	// API Handler 需要感知初始化状态
 	apiHandler := api.NewAPIHandler(configPath) // 内部不再自动生成 Key
+	apiHandler.SetProxy(p)
 
 	// 注册 API 路由
 	http.HandleFunc("/api/v1/status", apiHandler.AuthMiddleware(apiHandler.HandleStatus))
@@ -460,6 +1274,9 @@ func handleServer(cfg *config.Config, configPath string, uiPath string) {
 	http.HandleFunc("/api/v1/tools/encrypt", apiHandler.AuthMiddleware(apiHandler.HandleToolEncrypt))
 	http.HandleFunc("/api/v1/tools/export", apiHandler.AuthMiddleware(apiHandler.HandleToolExport))
 	http.HandleFunc("/api/v1/tools/import", apiHandler.AuthMiddleware(apiHandler.HandleToolImport))
+	http.HandleFunc("/api/v1/cache", apiHandler.AuthMiddleware(apiHandler.HandleCache))
+	http.HandleFunc("/api/v1/writeback", apiHandler.AuthMiddleware(apiHandler.HandleWriteBack))
+	http.HandleFunc("/api/v1/bandwidth", apiHandler.AuthMiddleware(apiHandler.HandleBandwidth))
 
 	if strings.TrimSpace(uiPath) != "" {
 		absUI, err := filepath.Abs(uiPath)
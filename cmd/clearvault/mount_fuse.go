@@ -74,6 +74,11 @@ func handleMount(args []string) {
 	if err != nil {
 		log.Fatalf("Failed to initialize proxy: %v", err)
 	}
+	checkVault(p)
+	if err := configureProxy(p, cfg); err != nil {
+		log.Fatalf("%v", err)
+	}
+	defer p.Close()
 
 	// 创建 FUSE 文件系统
 	// NewClearVaultFS 内部会读取 FUSE_UID/FUSE_GID 环境变量
@@ -4,6 +4,8 @@ import (
 	"clearvault/internal/config"
 	"clearvault/internal/metadata"
 	"clearvault/internal/proxy"
+	"clearvault/internal/remote"
+	"context"
 	"crypto/rand"
 	"encoding/json"
 	"errors"
@@ -26,6 +28,7 @@ type APIHandler struct {
 	mountMu    sync.Mutex
 	startTime  time.Time
 	token      string
+	proxy      *proxy.Proxy // 服务端代理，未初始化时为 nil
 }
 
 type ToolResponse struct {
@@ -77,6 +80,106 @@ func NewAPIHandler(configPath string) *APIHandler {
 	}
 }
 
+// SetProxy 设置服务端代理，供缓存统计等运行时接口使用
+func (h *APIHandler) SetProxy(p *proxy.Proxy) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.proxy = p
+}
+
+// HandleCache 返回本地块缓存的命中统计
+func (h *APIHandler) HandleCache(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.mu.RLock()
+	p := h.proxy
+	h.mu.RUnlock()
+
+	resp := map[string]interface{}{"enabled": false}
+	if p != nil {
+		if stats, ok := p.CacheStats(); ok {
+			resp["enabled"] = true
+			resp["stats"] = stats
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HandleWriteBack 返回写回模式的上传队列及进度
+func (h *APIHandler) HandleWriteBack(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.mu.RLock()
+	p := h.proxy
+	h.mu.RUnlock()
+
+	resp := map[string]interface{}{"enabled": false}
+	if p != nil {
+		if queue, ok := p.WriteBackQueue(); ok {
+			var pending, uploaded int64
+			for _, u := range queue {
+				pending += u.Size
+				uploaded += u.Uploaded
+			}
+			resp["enabled"] = true
+			resp["queue"] = queue
+			resp["pending_files"] = len(queue)
+			resp["pending_bytes"] = pending
+			resp["uploaded_bytes"] = uploaded
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HandleBandwidth 查询（GET）或调整（PUT）远端带宽限制，调整立即生效但不写入配置文件
+func (h *APIHandler) HandleBandwidth(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	p := h.proxy
+	h.mu.RUnlock()
+
+	var t *remote.ThrottleStorage
+	if p != nil {
+		t = p.Throttle()
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		if t == nil {
+			http.Error(w, "Bandwidth limiting is not available", http.StatusServiceUnavailable)
+			return
+		}
+		var req config.BandwidthConfig
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := t.SetBandwidth(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp := map[string]interface{}{"enabled": false}
+	if t != nil {
+		resp["enabled"] = true
+		resp["limits"] = t.Bandwidth()
+		resp["active"] = t.Active()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
 type StatusResponse struct {
 	Status    string    `json:"status"`
 	Uptime    string    `json:"uptime"`
@@ -100,12 +203,23 @@ func (h *APIHandler) HandleStatus(w http.ResponseWriter, r *http.Request) {
 
 	initialized := h.IsInitialized()
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	h.mu.RLock()
+	p := h.proxy
+	h.mu.RUnlock()
+
+	resp := map[string]interface{}{
 		"status":      "running",
 		"uptime":      time.Since(h.startTime).String(),
 		"initialized": initialized,
-	})
+	}
+	// 启用离线检测时附带远端健康状态
+	if p != nil {
+		if health, ok := p.RemoteHealth(); ok {
+			resp["remote"] = health
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
 }
 
 func (h *APIHandler) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
@@ -558,6 +672,12 @@ func (h *APIHandler) newLocalProxy() (*proxy.Proxy, metadata.Storage, *config.Co
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	if _, err := p.CheckVault(context.Background()); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := p.SetRecoveryKey(cfg.Security.RecoveryPublicKey); err != nil {
+		return nil, nil, nil, err
+	}
 	return p, meta, cfg, nil
 }
 
@@ -11,6 +11,10 @@ import (
 	"testing"
 
 	"clearvault/internal/config"
+	"clearvault/internal/metadata"
+	"clearvault/internal/proxy"
+	"clearvault/internal/remote"
+	"clearvault/internal/remote/local"
 )
 
 // setupTestAPI creates a test API handler with temporary config
@@ -117,6 +121,128 @@ func TestAPIHandler_HandleStatus_MethodNotAllowed(t *testing.T) {
 	}
 }
 
+// TestAPIHandler_HandleCache tests the block cache stats endpoint
+func TestAPIHandler_HandleCache(t *testing.T) {
+	handler, _, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	get := func() map[string]interface{} {
+		rec := httptest.NewRecorder()
+		handler.HandleCache(rec, httptest.NewRequest(http.MethodGet, "/api/v1/cache", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		var response map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		return response
+	}
+
+	if resp := get(); resp["enabled"] != false {
+		t.Errorf("Expected cache disabled without proxy, got %v", resp)
+	}
+
+	meta, err := metadata.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := local.NewClient(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := proxy.NewProxy(meta, rs, "dGhpcy1pcy1hLTMyLWJ5dGUtbG9uZy1tYXN0ZXJrZXk=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.EnableBlockCache(proxy.BlockCacheOptions{Dir: t.TempDir(), MaxSize: 1 << 20}); err != nil {
+		t.Fatal(err)
+	}
+	handler.SetProxy(p)
+
+	resp := get()
+	stats, ok := resp["stats"].(map[string]interface{})
+	if resp["enabled"] != true || !ok || stats["max_size"] != float64(1<<20) {
+		t.Errorf("Unexpected response: %v", resp)
+	}
+	if _, ok := stats["hits"]; !ok {
+		t.Error("Expected hit counter in stats")
+	}
+}
+
+// TestAPIHandler_HandleWriteBack tests the write-back queue endpoint
+func TestAPIHandler_HandleWriteBack(t *testing.T) {
+	handler, _, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	rec := httptest.NewRecorder()
+	handler.HandleWriteBack(rec, httptest.NewRequest(http.MethodGet, "/api/v1/writeback", nil))
+	var response map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response["enabled"] != false {
+		t.Errorf("Expected write-back disabled without proxy, got %v", response)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleWriteBack(rec, httptest.NewRequest(http.MethodPost, "/api/v1/writeback", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+}
+
+func TestAPIHandler_HandleBandwidth(t *testing.T) {
+	handler, _, cleanup := setupTestAPI(t)
+	defer cleanup()
+
+	do := func(method, body string) (int, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		handler.HandleBandwidth(rec, httptest.NewRequest(method, "/api/v1/bandwidth", strings.NewReader(body)))
+		var response map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec.Code, response
+	}
+
+	if code, resp := do(http.MethodGet, ""); code != http.StatusOK || resp["enabled"] != false {
+		t.Errorf("Expected bandwidth limiting disabled without proxy, got %d %v", code, resp)
+	}
+	if code, _ := do(http.MethodPut, `{"upload":100}`); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, code)
+	}
+
+	meta, err := metadata.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := remote.NewRemoteStorage(config.RemoteConfig{Type: "local", LocalPath: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	p, err := proxy.NewProxy(meta, rs, "dGhpcy1pcy1hLTMyLWJ5dGUtbG9uZy1tYXN0ZXJrZXk=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler.SetProxy(p)
+
+	code, resp := do(http.MethodPut, `{"upload":2048,"schedule":[{"start":"00:00","end":"00:00","download":512}]}`)
+	active, _ := resp["active"].(map[string]interface{})
+	if code != http.StatusOK || resp["enabled"] != true || active["download"] != float64(512) || active["upload"] != float64(0) {
+		t.Errorf("Unexpected response %d %v", code, resp)
+	}
+	if p.Throttle().Bandwidth().Upload != 2048 {
+		t.Error("Expected limits to be applied to the remote")
+	}
+
+	if code, _ := do(http.MethodPut, `{"schedule":[{"start":"bad"}]}`); code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, code)
+	}
+	if code, _ := do(http.MethodDelete, ""); code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, code)
+	}
+}
+
 // TestAPIHandler_AuthMiddleware tests authentication middleware
 func TestAPIHandler_AuthMiddleware(t *testing.T) {
 	handler, _, cleanup := setupTestAPI(t)
@@ -36,7 +36,7 @@ type Auth struct {
 
 type RemoteConfig struct {
 	// 通用字段
-	Type string `yaml:"type" json:"type"` // "webdav" 或 "s3"
+	Type string `yaml:"type" json:"type"` // "webdav"、"s3"、"azblob"、"gcs"、"local"、"sftp"、"mirror" 或 "striped"
 
 	// WebDAV 字段
 	URL  string `yaml:"url" json:"url"`
@@ -51,17 +51,191 @@ type RemoteConfig struct {
 	SecretKey string `yaml:"secret_key" json:"secret_key"`
 	UseSSL    bool   `yaml:"use_ssl" json:"use_ssl"`
 
+	// S3 高级选项
+	S3 S3Options `yaml:"s3" json:"s3"`
+
 	// Local Filesystem 字段
-	LocalPath string `yaml:"local_path" json:"local_path"`
+	LocalPath     string `yaml:"local_path" json:"local_path"`
+	LocalReadOnly bool   `yaml:"local_read_only" json:"local_read_only"` // 只读打开已有目录，写操作返回错误
+
+	// SFTP 字段（用户名/密码复用 user/pass）
+	Host          string `yaml:"host" json:"host"`                     // host 或 host:port，默认端口 22
+	KeyFile       string `yaml:"key_file" json:"key_file"`             // 私钥文件路径
+	KeyPassphrase string `yaml:"key_passphrase" json:"key_passphrase"` // 私钥密码
+	KnownHosts    string `yaml:"known_hosts" json:"known_hosts"`       // known_hosts 文件路径，默认 ~/.ssh/known_hosts
+	RootPath      string `yaml:"root_path" json:"root_path"`           // 远端存放目录
+
+	// Azure Blob 字段（endpoint 复用上面的字段，留空时使用官方地址，Azurite 等模拟器需填写）
+	AccountName string `yaml:"account_name" json:"account_name"`
+	AccountKey  string `yaml:"account_key" json:"account_key"`
+	Container   string `yaml:"container" json:"container"`
+
+	// GCS 字段（bucket/endpoint 复用上面的字段）
+	CredentialsFile string `yaml:"credentials_file" json:"credentials_file"` // 服务账号 JSON 密钥，留空时使用默认凭据
+
+	// 超时设置（所有类型通用）
+	Timeouts TimeoutConfig `yaml:"timeouts" json:"timeouts"`
+
+	// 临时错误重试（所有类型通用）
+	Retry RetryConfig `yaml:"retry" json:"retry"`
+
+	// 可续传分块上传（s3、webdav）
+	Resumable ResumableConfig `yaml:"resumable" json:"resumable"`
+
+	// 并行分块上传（s3、webdav），不能与 resumable 同时启用
+	ParallelUpload ParallelUploadConfig `yaml:"parallel_upload" json:"parallel_upload"`
+
+	// 容量上限（GiB，所有类型通用），用于 S3 等无法查询剩余空间的远端，0 表示不限制
+	Quota int64 `yaml:"quota" json:"quota"`
+
+	// 对象目录布局（仅顶层配置生效，镜像/条带的子远端沿用顶层布局）
+	Layout LayoutConfig `yaml:"layout" json:"layout"`
+
+	// 离线检测（仅顶层配置生效）
+	Health HealthConfig `yaml:"health" json:"health"`
+
+	// 带宽限制（仅顶层配置生效，可通过管理接口运行时调整）
+	Bandwidth BandwidthConfig `yaml:"bandwidth" json:"bandwidth"`
+
+	// 镜像字段（type: mirror）：每个子远端使用各自完整的配置
+	Mirrors        []RemoteConfig `yaml:"mirrors" json:"mirrors"`
+	WriteQuorum    int            `yaml:"write_quorum" json:"write_quorum"`       // 写入成功所需的副本/分片数，默认全部
+	ResyncInterval int            `yaml:"resync_interval" json:"resync_interval"` // 后台修复落后副本的间隔（秒），默认 300
+	MirrorState    string         `yaml:"mirror_state" json:"mirror_state"`       // 待修复对象记录文件，默认 storage/cache/mirror-state.json
+
+	// 条带字段（type: striped）：对象编码为 data_shards 个数据分片和其余的校验分片，依次存放在 shards 中
+	Shards     []RemoteConfig `yaml:"shards" json:"shards"`
+	DataShards int            `yaml:"data_shards" json:"data_shards"` // 数据分片数 k，任意 k 个分片即可恢复对象
+}
+
+// TimeoutConfig 远端操作超时（秒，0 表示不限制）
+type TimeoutConfig struct {
+	Operation int `yaml:"operation" json:"operation"` // Stat/Delete/Rename 及下载首字节超时
+	Idle      int `yaml:"idle" json:"idle"`           // 传输中无数据进展的最长时间
+}
+
+// RetryConfig 远端临时错误（5xx、429、连接重置等）重试设置
+type RetryConfig struct {
+	MaxAttempts int `yaml:"max_attempts" json:"max_attempts"` // 最大尝试次数（含首次），默认 3，设为 1 关闭重试
+	BaseDelay   int `yaml:"base_delay" json:"base_delay"`     // 首次重试等待（毫秒），之后指数增长，默认 500
+	MaxDelay    int `yaml:"max_delay" json:"max_delay"`       // 单次等待上限（毫秒），默认 30000
+}
+
+// ResumableConfig 可续传分块上传：大文件按块上传，连接中断只重传当前块；
+// 进程重启后再次以相同对象名上传（写回暂存、pack、resync 等）时从日志记录的分块继续
+// S3 使用 multipart upload，WebDAV 使用 Nextcloud chunking v2（url 须为 .../remote.php/dav/files/<user>/）
+type ResumableConfig struct {
+	Enabled    bool   `yaml:"enabled" json:"enabled"`
+	ChunkSize  int    `yaml:"chunk_size" json:"chunk_size"`   // 分块大小（MiB），默认 16，最小 5
+	JournalDir string `yaml:"journal_dir" json:"journal_dir"` // 上传日志目录，默认 storage/cache/uploads
+	MaxAge     int    `yaml:"max_age" json:"max_age"`         // 未完成上传的保留时间（小时），超时后放弃，默认 24
+}
+
+// ParallelUploadConfig 并行分块上传：从加密流依次读出分块并同时上传多个分块
+type ParallelUploadConfig struct {
+	Enabled  bool `yaml:"enabled" json:"enabled"`
+	PartSize int  `yaml:"part_size" json:"part_size"` // 分块大小（MiB），默认 16，最小 5
+	Parallel int  `yaml:"parallel" json:"parallel"`   // 同时上传的分块数，默认 4
+}
+
+// HealthConfig 远端离线检测：连续失败达到阈值后熔断，请求直接返回离线错误，后台探测到恢复后自动切回
+type HealthConfig struct {
+	Enabled          bool `yaml:"enabled" json:"enabled"`
+	FailureThreshold int  `yaml:"failure_threshold" json:"failure_threshold"` // 判定离线的连续失败次数，默认 3
+	ProbeInterval    int  `yaml:"probe_interval" json:"probe_interval"`       // 离线期间探测间隔（秒），默认 15
+}
+
+// BandwidthConfig 远端带宽限制（KiB/s，0 表示不限制），schedule 中匹配当前时间的第一条规则代替默认值
+type BandwidthConfig struct {
+	Upload   int64           `yaml:"upload" json:"upload"`
+	Download int64           `yaml:"download" json:"download"`
+	Total    int64           `yaml:"total" json:"total"` // 上传与下载合计
+	Schedule []BandwidthRule `yaml:"schedule" json:"schedule"`
+}
+
+// BandwidthRule 每日时间段内的限速，end 不晚于 start 时跨越午夜（如 22:00-07:00）
+type BandwidthRule struct {
+	Start    string `yaml:"start" json:"start"` // HH:MM，本地时间
+	End      string `yaml:"end" json:"end"`
+	Upload   int64  `yaml:"upload" json:"upload"`
+	Download int64  `yaml:"download" json:"download"`
+	Total    int64  `yaml:"total" json:"total"`
+}
+
+// S3Options S3 高级选项：寻址方式、自定义 CA、存储类别、服务端加密、对象锁定与版本化存储桶
+type S3Options struct {
+	Addressing     string             `yaml:"addressing" json:"addressing"`           // auto（默认）、path 或 virtual
+	CAFile         string             `yaml:"ca_file" json:"ca_file"`                 // 自定义 CA 证书（PEM），用于私有部署的自签名证书
+	StorageClass   string             `yaml:"storage_class" json:"storage_class"`     // 默认存储类别，如 STANDARD_IA
+	StorageClasses []StorageClassRule `yaml:"storage_classes" json:"storage_classes"` // 按虚拟路径前缀指定存储类别（仅顶层配置生效）
+	SSE            string             `yaml:"sse" json:"sse"`                         // 服务端加密：s3、kms 或 c，空表示不指定
+	SSEKMSKeyID    string             `yaml:"sse_kms_key_id" json:"sse_kms_key_id"`   // SSE-KMS 密钥 ID，空表示使用默认密钥
+	SSECKey        string             `yaml:"sse_c_key" json:"sse_c_key"`             // SSE-C 密钥（base64 编码的 32 字节）
+	ObjectLock     ObjectLockConfig   `yaml:"object_lock" json:"object_lock"`
+	DeleteVersions bool               `yaml:"delete_versions" json:"delete_versions"` // 删除时移除对象的所有版本（版本化存储桶）
+}
+
+// StorageClassRule 虚拟路径前缀对应的存储类别，多条匹配时取最长的前缀
+type StorageClassRule struct {
+	Path  string `yaml:"path" json:"path"`
+	Class string `yaml:"class" json:"class"`
+}
+
+// ObjectLockConfig 对象锁定：新对象在 days 天内不可删除或覆盖（存储桶须已启用对象锁定）
+type ObjectLockConfig struct {
+	Mode string `yaml:"mode" json:"mode"` // GOVERNANCE 或 COMPLIANCE，空表示不锁定
+	Days int    `yaml:"days" json:"days"`
+}
+
+// LayoutConfig 远端对象目录布局，例如 levels: 2, width: 2 时对象存放为 ab/cd/abcd…
+type LayoutConfig struct {
+	Levels int `yaml:"levels" json:"levels"` // 目录层数，0（默认）表示所有对象平铺在同一目录
+	Width  int `yaml:"width" json:"width"`   // 每层目录名长度，默认 2
 }
 
 type SecurityConfig struct {
-	MasterKey string `yaml:"master_key" json:"master_key"`
+	MasterKey         string `yaml:"master_key" json:"master_key"`
+	RecoveryPublicKey string `yaml:"recovery_public_key" json:"recovery_public_key"` // 恢复公钥（base64 X25519），为空不托管 FEK
 }
 
 type StorageConfig struct {
-	MetadataPath string `yaml:"metadata_path" json:"metadata_path"` // JSON metadata directory
-	CacheDir     string `yaml:"cache_dir" json:"cache_dir"`
+	MetadataPath  string           `yaml:"metadata_path" json:"metadata_path"` // JSON metadata directory
+	CacheDir      string           `yaml:"cache_dir" json:"cache_dir"`
+	Pack          PackConfig       `yaml:"pack" json:"pack"`
+	Compression   string           `yaml:"compression" json:"compression"` // 块级压缩算法：none（默认）或 zstd
+	BlockCache    BlockCacheConfig `yaml:"block_cache" json:"block_cache"`
+	WriteBack     WriteBackConfig  `yaml:"write_back" json:"write_back"`
+	ReadAhead     ReadAheadConfig  `yaml:"read_ahead" json:"read_ahead"`
+	VerifyUploads bool             `yaml:"verify_uploads" json:"verify_uploads"` // 上传后校验远端大小及 MD5，不一致时不保存元数据
+}
+
+// ReadAheadConfig 预读：检测到顺序读取后并行预取后续分段，FUSE 与 WebDAV 共用
+type ReadAheadConfig struct {
+	Enabled  bool  `yaml:"enabled" json:"enabled"`
+	Window   int64 `yaml:"window" json:"window"`     // 最大预读窗口（MiB），默认 16
+	Segment  int64 `yaml:"segment" json:"segment"`   // 每个范围请求的大小（KiB），默认 1024
+	Parallel int   `yaml:"parallel" json:"parallel"` // 同时进行的预读请求数，默认 4
+}
+
+// WriteBackConfig 写回模式：写入先加密落到 cache_dir/staging 并立即可见，后台队列上传到远端
+type WriteBackConfig struct {
+	Enabled    bool `yaml:"enabled" json:"enabled"`
+	Workers    int  `yaml:"workers" json:"workers"`         // 并发上传数，默认 2
+	RetryDelay int  `yaml:"retry_delay" json:"retry_delay"` // 上传失败后首次重试等待（秒），之后指数增长，默认 10
+}
+
+// BlockCacheConfig 本地密文块缓存：范围读取的密文块缓存在 cache_dir/blocks，按 LRU 淘汰
+type BlockCacheConfig struct {
+	Enabled bool  `yaml:"enabled" json:"enabled"`
+	MaxSize int64 `yaml:"max_size" json:"max_size"` // 缓存总大小上限（MiB），默认 1024
+}
+
+// PackConfig 小文件打包配置
+type PackConfig struct {
+	Enabled       bool  `yaml:"enabled" json:"enabled"`
+	Threshold     int64 `yaml:"threshold" json:"threshold"`           // 不超过该大小（字节）的文件写入 pack，默认 256KiB
+	TargetSize    int64 `yaml:"target_size" json:"target_size"`       // 单个 pack 目标大小（字节），默认 8MiB
+	FlushInterval int   `yaml:"flush_interval" json:"flush_interval"` // 未写满 pack 的最长等待时间（秒），默认 30
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -83,6 +257,28 @@ func LoadConfig(path string) (*Config, error) {
 	return &cfg, nil
 }
 
+// LoadRemoteConfig 读取单独的远端配置文件，内容可以是完整配置（取其中的 remote）或只有 remote 部分
+func LoadRemoteConfig(path string) (RemoteConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return RemoteConfig{}, err
+	}
+	var full struct {
+		Remote *RemoteConfig `yaml:"remote"`
+	}
+	if err := yaml.Unmarshal(data, &full); err != nil {
+		return RemoteConfig{}, err
+	}
+	if full.Remote != nil {
+		return *full.Remote, nil
+	}
+	var rc RemoteConfig
+	if err := yaml.Unmarshal(data, &rc); err != nil {
+		return RemoteConfig{}, err
+	}
+	return rc, nil
+}
+
 // GenerateMasterKey checks if master key is set, if not generates it and saves to file
 func GenerateMasterKey(configPath string, cfg *Config) error {
 	// Validate or Generate Master Key
@@ -147,9 +343,36 @@ func processEnvOverrides(cfg *Config) {
 	if v := os.Getenv("REMOTE_LOCAL_PATH"); v != "" {
 		cfg.Remote.LocalPath = v
 	}
+	if v := os.Getenv("REMOTE_HOST"); v != "" {
+		cfg.Remote.Host = v
+	}
+	if v := os.Getenv("REMOTE_KEY_FILE"); v != "" {
+		cfg.Remote.KeyFile = v
+	}
+	if v := os.Getenv("REMOTE_KNOWN_HOSTS"); v != "" {
+		cfg.Remote.KnownHosts = v
+	}
+	if v := os.Getenv("REMOTE_ROOT_PATH"); v != "" {
+		cfg.Remote.RootPath = v
+	}
+	if v := os.Getenv("REMOTE_ACCOUNT_NAME"); v != "" {
+		cfg.Remote.AccountName = v
+	}
+	if v := os.Getenv("REMOTE_ACCOUNT_KEY"); v != "" {
+		cfg.Remote.AccountKey = v
+	}
+	if v := os.Getenv("REMOTE_CONTAINER"); v != "" {
+		cfg.Remote.Container = v
+	}
+	if v := os.Getenv("REMOTE_CREDENTIALS_FILE"); v != "" {
+		cfg.Remote.CredentialsFile = v
+	}
 	if v := os.Getenv("MASTER_KEY"); v != "" {
 		cfg.Security.MasterKey = v
 	}
+	if v := os.Getenv("RECOVERY_PUBLIC_KEY"); v != "" {
+		cfg.Security.RecoveryPublicKey = v
+	}
 	if v := os.Getenv("ACCESS_TOKEN"); v != "" {
 		cfg.Access.Token = v
 	}
@@ -169,6 +392,7 @@ func generateAndSaveMasterKey(configPath string, cfg *Config) error {
 	log.Printf("⚠️  Auto-generated master key. Saving to config file...")
 	log.Printf("🔑 Master Key: %s", encodedKey)
 	log.Printf("⚠️  IMPORTANT: Please backup this key! Data cannot be recovered if lost!")
+	log.Printf("💡 Run 'clearvault key backup --shares 5 --threshold 3' to split it into Shamir shares for safekeeping")
 
 	// Read original file to preserve formatting and comments
 	originalData, err := os.ReadFile(configPath)
@@ -176,27 +400,13 @@ func generateAndSaveMasterKey(configPath string, cfg *Config) error {
 		return fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	// Replace master_key line
-	lines := strings.Split(string(originalData), "\n")
-	found := false
-	for i, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if strings.HasPrefix(trimmed, "master_key:") {
-			// Preserve indentation
-			indent := strings.Repeat(" ", len(line)-len(strings.TrimLeft(line, " ")))
-			lines[i] = fmt.Sprintf("%smaster_key: \"%s\"", indent, encodedKey)
-			found = true
-			break
-		}
-	}
-
+	newData, found := replaceMasterKey(string(originalData), encodedKey)
 	if !found {
 		// Log warning and return if not found (don't want to corrupt file structure)
 		log.Printf("Warning: Could not find 'master_key:' line in config file to update.")
 	}
 
 	// Write back to file
-	newData := strings.Join(lines, "\n")
 	if err := os.WriteFile(configPath, []byte(newData), 0600); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
@@ -205,6 +415,43 @@ func generateAndSaveMasterKey(configPath string, cfg *Config) error {
 	return nil
 }
 
+// SaveMasterKey 将主密钥写回配置文件，只替换 master_key 一行以保留注释与格式
+// 文件不存在或没有 master_key 行时按完整配置重新写入
+func SaveMasterKey(configPath, encodedKey string) error {
+	data, err := os.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	newData, found := replaceMasterKey(string(data), encodedKey)
+	if !found {
+		cfg, err := LoadConfig(configPath)
+		if err != nil {
+			return err
+		}
+		cfg.Security.MasterKey = encodedKey
+		return SaveConfig(configPath, cfg)
+	}
+	if err := os.WriteFile(configPath, []byte(newData), 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// replaceMasterKey 替换配置文本中的 master_key 行（保留缩进），返回是否找到
+func replaceMasterKey(data, encodedKey string) (string, bool) {
+	lines := strings.Split(data, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "master_key:") {
+			// Preserve indentation
+			indent := strings.Repeat(" ", len(line)-len(strings.TrimLeft(line, " ")))
+			lines[i] = fmt.Sprintf("%smaster_key: \"%s\"", indent, encodedKey)
+			return strings.Join(lines, "\n"), true
+		}
+	}
+	return data, false
+}
+
 // SaveConfig saves the configuration struct to the specified file path
 func SaveConfig(path string, cfg *Config) error {
 	data, err := yaml.Marshal(cfg)
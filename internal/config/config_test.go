@@ -105,3 +105,58 @@ func TestLoadConfigNoFileNoKey(t *testing.T) {
 		t.Fatalf("Expected Security.MasterKey to be empty when no file and no env var, got %q", cfg.Security.MasterKey)
 	}
 }
+
+func TestLoadRemoteConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	full := filepath.Join(dir, "full.yaml")
+	os.WriteFile(full, []byte("remote:\n  type: local\n  local_path: /data/a\nserver:\n  listen: \":8080\"\n"), 0600)
+	rc, err := LoadRemoteConfig(full)
+	if err != nil {
+		t.Fatalf("LoadRemoteConfig failed: %v", err)
+	}
+	if rc.Type != "local" || rc.LocalPath != "/data/a" {
+		t.Errorf("Unexpected remote config from full file: %+v", rc)
+	}
+
+	bare := filepath.Join(dir, "bare.yaml")
+	os.WriteFile(bare, []byte("type: local\nlocal_path: /data/b\n"), 0600)
+	rc, err = LoadRemoteConfig(bare)
+	if err != nil {
+		t.Fatalf("LoadRemoteConfig failed: %v", err)
+	}
+	if rc.Type != "local" || rc.LocalPath != "/data/b" {
+		t.Errorf("Unexpected remote config from bare file: %+v", rc)
+	}
+
+	if _, err := LoadRemoteConfig(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestSaveMasterKey(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	content := "# 安全配置\nsecurity:\n  # 主加密密钥\n  master_key: \"old\"\n"
+	if err := os.WriteFile(configPath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveMasterKey(configPath, "bmV3LWtleQ=="); err != nil {
+		t.Fatalf("SaveMasterKey failed: %v", err)
+	}
+	data, _ := os.ReadFile(configPath)
+	want := "# 安全配置\nsecurity:\n  # 主加密密钥\n  master_key: \"bmV3LWtleQ==\"\n"
+	if string(data) != want {
+		t.Errorf("Expected comments to be preserved, got:\n%s", data)
+	}
+
+	// 没有 master_key 行时写入完整配置
+	missing := filepath.Join(dir, "missing.yaml")
+	if err := SaveMasterKey(missing, "bmV3LWtleQ=="); err != nil {
+		t.Fatalf("SaveMasterKey failed: %v", err)
+	}
+	cfg, err := LoadConfig(missing)
+	if err != nil || cfg.Security.MasterKey != "bmV3LWtleQ==" {
+		t.Errorf("Expected key in new config, got %+v, %v", cfg, err)
+	}
+}
@@ -0,0 +1,287 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// 压缩算法名称（记录在 FileMeta.Compression 中）
+const (
+	CompressionNone = ""
+	CompressionZstd = "zstd"
+)
+
+// 压缩格式中每个块在加密前带 1 字节标志，标明块内容是否被压缩
+const (
+	chunkFlagRaw  byte = 0
+	chunkFlagZstd byte = 1
+)
+
+// 自适应旁路：连续多个块不可压缩时，跳过后续若干块的压缩尝试
+const (
+	bypassAfter = 4
+	bypassSkip  = 16
+)
+
+var ErrInvalidChunkIndex = errors.New("invalid chunk index")
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+func zstdCodec() (*zstd.Encoder, *zstd.Decoder, error) {
+	zstdOnce.Do(func() {
+		zstdEncoder, zstdErr = zstd.NewWriter(nil,
+			zstd.WithEncoderLevel(zstd.SpeedDefault),
+			zstd.WithEncoderConcurrency(1))
+		if zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil,
+			zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderMaxMemory(ChunkSize))
+	})
+	return zstdEncoder, zstdDecoder, zstdErr
+}
+
+// EncryptStreamCompressed 按 64KiB 明文块压缩后加密，返回每个密文块的长度。
+// 压缩收益不足的块以原文存储，明文块边界保持不变，因此仍可按块随机读取。
+func (e *Engine) EncryptStreamCompressed(r io.Reader, w io.Writer, baseNonce []byte) ([]uint32, error) {
+	enc, _, err := zstdCodec()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(e.key)
+	if err != nil {
+		return nil, err
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, ChunkSize)
+	plainBuf := make([]byte, 0, ChunkSize+1)
+	outBuf := make([]byte, 0, ChunkSize+1+aesgcm.Overhead())
+
+	var lens []uint32
+	var chunkIndex uint64
+	misses, skip := 0, 0
+
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			plain := plainBuf[:0]
+			compressed := false
+			if skip > 0 {
+				skip--
+			} else {
+				plain = append(plain, chunkFlagZstd)
+				plain = enc.EncodeAll(buf[:n], plain)
+				// 至少节省 1/32 才值得解压开销
+				if len(plain)-1 < n-n/32 {
+					compressed = true
+					misses = 0
+				} else if misses++; misses >= bypassAfter {
+					misses = 0
+					skip = bypassSkip
+				}
+			}
+			if !compressed {
+				plain = append(plainBuf[:0], chunkFlagRaw)
+				plain = append(plain, buf[:n]...)
+			}
+
+			nonce := deriveNonce(baseNonce, chunkIndex)
+			ciphertext := aesgcm.Seal(outBuf[:0], nonce, plain, nil)
+			if _, err := w.Write(ciphertext); err != nil {
+				return nil, err
+			}
+			lens = append(lens, uint32(len(ciphertext)))
+			chunkIndex++
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return lens, nil
+}
+
+// DecryptStreamCompressed 解密并解压从 startChunkIndex 开始的压缩格式密文块，
+// index 为整个文件的密文块索引。
+func (e *Engine) DecryptStreamCompressed(r io.Reader, w io.Writer, baseNonce []byte, startChunkIndex uint64, index *ChunkIndex) error {
+	_, dec, err := zstdCodec()
+	if err != nil {
+		return err
+	}
+	block, err := aes.NewCipher(e.key)
+	if err != nil {
+		return err
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, ChunkSize+1+aesgcm.Overhead())
+	outBuf := make([]byte, 0, ChunkSize)
+
+	for i := startChunkIndex; i < uint64(index.Chunks()); i++ {
+		size := index.chunkLen(i)
+		if size > int64(len(buf)) {
+			return ErrInvalidChunkIndex
+		}
+		n, err := io.ReadFull(r, buf[:size])
+		if err == io.EOF {
+			// 范围读取只覆盖部分块
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		nonce := deriveNonce(baseNonce, i)
+		plain, err := aesgcm.Open(buf[:0], nonce, buf[:n], nil)
+		if err != nil || len(plain) == 0 {
+			return ErrDecryptFail
+		}
+
+		switch plain[0] {
+		case chunkFlagRaw:
+			_, err = w.Write(plain[1:])
+		case chunkFlagZstd:
+			var out []byte
+			out, err = dec.DecodeAll(plain[1:], outBuf[:0])
+			if err != nil {
+				return fmt.Errorf("decompress chunk %d: %w", i, err)
+			}
+			if len(out) > ChunkSize {
+				return ErrDecryptFail
+			}
+			_, err = w.Write(out)
+		default:
+			return fmt.Errorf("unknown chunk flag %d in chunk %d", plain[0], i)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncodeChunkIndex 将密文块长度编码为紧凑的大端字节序列
+func EncodeChunkIndex(lens []uint32) []byte {
+	b := make([]byte, 4*len(lens))
+	for i, l := range lens {
+		binary.BigEndian.PutUint32(b[4*i:], l)
+	}
+	return b
+}
+
+// DecodeChunkIndex 解析 EncodeChunkIndex 的输出
+func DecodeChunkIndex(b []byte) ([]uint32, error) {
+	if len(b)%4 != 0 {
+		return nil, ErrInvalidChunkIndex
+	}
+	lens := make([]uint32, len(b)/4)
+	for i := range lens {
+		lens[i] = binary.BigEndian.Uint32(b[4*i:])
+	}
+	return lens, nil
+}
+
+// ChunkIndex 压缩格式的密文块索引，保存各块在密文中的累计偏移，按块号直接查询
+type ChunkIndex struct {
+	offsets []int64 // offsets[i] 为第 i 块的起始偏移，最后一项为密文总长度
+}
+
+// NewChunkIndex 由各密文块长度构造索引
+func NewChunkIndex(lens []uint32) *ChunkIndex {
+	offsets := make([]int64, len(lens)+1)
+	for i, l := range lens {
+		offsets[i+1] = offsets[i] + int64(l)
+	}
+	return &ChunkIndex{offsets: offsets}
+}
+
+// Chunks 返回块数
+func (x *ChunkIndex) Chunks() int {
+	return len(x.offsets) - 1
+}
+
+// Size 返回所有密文块的总长度
+func (x *ChunkIndex) Size() int64 {
+	return x.offsets[len(x.offsets)-1]
+}
+
+// Range 返回第 startChunk 到 endChunk（含）块在密文中的偏移和长度，超出部分忽略
+func (x *ChunkIndex) Range(startChunk, endChunk uint64) (int64, int64) {
+	n := uint64(x.Chunks())
+	if startChunk >= n || endChunk < startChunk {
+		return x.offsets[min(startChunk, n)], 0
+	}
+	if endChunk >= n {
+		endChunk = n - 1
+	}
+	return x.offsets[startChunk], x.offsets[endChunk+1] - x.offsets[startChunk]
+}
+
+func (x *ChunkIndex) chunkLen(i uint64) int64 {
+	return x.offsets[i+1] - x.offsets[i]
+}
+
+// SealedChunkIndexSize 返回 chunks 个块的加密索引长度
+func SealedChunkIndexSize(chunks int) int64 {
+	return int64(4*chunks) + TagSize
+}
+
+// SealChunkIndex 加密块索引，供追加在压缩格式密文之后。
+// 使用块号 len(lens) 对应的 nonce，与数据块的 nonce 不重复。
+func (e *Engine) SealChunkIndex(lens []uint32, baseNonce []byte) ([]byte, error) {
+	block, err := aes.NewCipher(e.key)
+	if err != nil {
+		return nil, err
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return aesgcm.Seal(nil, deriveNonce(baseNonce, uint64(len(lens))), EncodeChunkIndex(lens), nil), nil
+}
+
+// OpenChunkIndex 解密 SealChunkIndex 的输出，chunks 为文件的块数
+func (e *Engine) OpenChunkIndex(sealed, baseNonce []byte, chunks int) (*ChunkIndex, error) {
+	if int64(len(sealed)) != SealedChunkIndexSize(chunks) {
+		return nil, ErrInvalidChunkIndex
+	}
+	block, err := aes.NewCipher(e.key)
+	if err != nil {
+		return nil, err
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aesgcm.Open(nil, deriveNonce(baseNonce, uint64(chunks)), sealed, nil)
+	if err != nil {
+		return nil, ErrDecryptFail
+	}
+	lens, err := DecodeChunkIndex(plain)
+	if err != nil {
+		return nil, err
+	}
+	return NewChunkIndex(lens), nil
+}
@@ -0,0 +1,133 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEngine_CompressedRoundTrip(t *testing.T) {
+	key, _ := GenerateRandomBytes(32)
+	baseNonce, _ := GenerateRandomBytes(12)
+	engine, _ := NewEngine(key)
+
+	// 前 3 块可压缩，随后 2 块为随机数据，最后一块不满
+	text := bytes.Repeat([]byte("clearvault compressible log line\n"), 3*ChunkSize/33+1)[:3*ChunkSize]
+	random, _ := GenerateRandomBytes(2*ChunkSize + 1000)
+	data := append(text, random...)
+
+	cipherBuf := &bytes.Buffer{}
+	lens, err := engine.EncryptStreamCompressed(bytes.NewReader(data), cipherBuf, baseNonce)
+	if err != nil {
+		t.Fatalf("EncryptStreamCompressed failed: %v", err)
+	}
+	if len(lens) != 6 {
+		t.Fatalf("Expected 6 chunks, got %d", len(lens))
+	}
+	if lens[0] >= CipherChunkSize/2 {
+		t.Errorf("Compressible chunk not compressed: %d bytes", lens[0])
+	}
+	if lens[3] != CipherChunkSize+1 {
+		t.Errorf("Random chunk should be stored raw, got %d bytes", lens[3])
+	}
+
+	out := &bytes.Buffer{}
+	index := NewChunkIndex(lens)
+	if err := engine.DecryptStreamCompressed(bytes.NewReader(cipherBuf.Bytes()), out, baseNonce, 0, index); err != nil {
+		t.Fatalf("DecryptStreamCompressed failed: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Fatalf("Round trip mismatch")
+	}
+
+	// 从第 2 块开始只读取 2 块
+	offset, length := index.Range(2, 3)
+	part := cipherBuf.Bytes()[offset : offset+length]
+	out.Reset()
+	if err := engine.DecryptStreamCompressed(bytes.NewReader(part), out, baseNonce, 2, index); err != nil {
+		t.Fatalf("Ranged DecryptStreamCompressed failed: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), data[2*ChunkSize:4*ChunkSize]) {
+		t.Errorf("Ranged decrypt mismatch")
+	}
+}
+
+func TestEngine_CompressedTamper(t *testing.T) {
+	key, _ := GenerateRandomBytes(32)
+	baseNonce, _ := GenerateRandomBytes(12)
+	engine, _ := NewEngine(key)
+
+	cipherBuf := &bytes.Buffer{}
+	lens, err := engine.EncryptStreamCompressed(bytes.NewReader(bytes.Repeat([]byte("a"), 1000)), cipherBuf, baseNonce)
+	if err != nil {
+		t.Fatalf("EncryptStreamCompressed failed: %v", err)
+	}
+	ciphertext := cipherBuf.Bytes()
+	ciphertext[0] ^= 0xFF
+	err = engine.DecryptStreamCompressed(bytes.NewReader(ciphertext), &bytes.Buffer{}, baseNonce, 0, NewChunkIndex(lens))
+	if err != ErrDecryptFail {
+		t.Errorf("Expected ErrDecryptFail, got %v", err)
+	}
+}
+
+func TestChunkIndexEncoding(t *testing.T) {
+	lens := []uint32{17, CipherChunkSize + 1, 0x01020304}
+	got, err := DecodeChunkIndex(EncodeChunkIndex(lens))
+	if err != nil {
+		t.Fatalf("DecodeChunkIndex failed: %v", err)
+	}
+	if len(got) != len(lens) {
+		t.Fatalf("Expected %d entries, got %d", len(lens), len(got))
+	}
+	for i := range lens {
+		if got[i] != lens[i] {
+			t.Errorf("Entry %d: got %d, want %d", i, got[i], lens[i])
+		}
+	}
+	if _, err := DecodeChunkIndex([]byte{1, 2, 3}); err != ErrInvalidChunkIndex {
+		t.Errorf("Expected ErrInvalidChunkIndex, got %v", err)
+	}
+
+	index := NewChunkIndex(lens)
+	if offset, length := index.Range(1, 1); offset != 17 || length != CipherChunkSize+1 {
+		t.Errorf("Range(1,1) = %d,%d", offset, length)
+	}
+	if offset, length := index.Range(1, 10); offset != 17 || length != CipherChunkSize+1+0x01020304 {
+		t.Errorf("Range(1,10) = %d,%d", offset, length)
+	}
+	if index.Size() != 17+CipherChunkSize+1+0x01020304 {
+		t.Errorf("Size = %d", index.Size())
+	}
+	if offset, length := NewChunkIndex(nil).Range(0, 0); offset != 0 || length != 0 {
+		t.Errorf("Empty Range = %d,%d", offset, length)
+	}
+}
+
+func TestSealedChunkIndex(t *testing.T) {
+	key, _ := GenerateRandomBytes(32)
+	baseNonce, _ := GenerateRandomBytes(12)
+	engine, _ := NewEngine(key)
+
+	lens := []uint32{100, 200, 300}
+	sealed, err := engine.SealChunkIndex(lens, baseNonce)
+	if err != nil {
+		t.Fatalf("SealChunkIndex failed: %v", err)
+	}
+	if int64(len(sealed)) != SealedChunkIndexSize(len(lens)) {
+		t.Fatalf("Sealed size %d, want %d", len(sealed), SealedChunkIndexSize(len(lens)))
+	}
+	index, err := engine.OpenChunkIndex(sealed, baseNonce, len(lens))
+	if err != nil {
+		t.Fatalf("OpenChunkIndex failed: %v", err)
+	}
+	if offset, length := index.Range(1, 2); offset != 100 || length != 500 {
+		t.Errorf("Range(1,2) = %d,%d", offset, length)
+	}
+
+	if _, err := engine.OpenChunkIndex(sealed, baseNonce, 2); err != ErrInvalidChunkIndex {
+		t.Errorf("Expected ErrInvalidChunkIndex for wrong chunk count, got %v", err)
+	}
+	sealed[0] ^= 0xFF
+	if _, err := engine.OpenChunkIndex(sealed, baseNonce, len(lens)); err != ErrDecryptFail {
+		t.Errorf("Expected ErrDecryptFail for tampered index, got %v", err)
+	}
+}
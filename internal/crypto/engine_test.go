@@ -79,3 +79,18 @@ func TestEngine_DecryptRange(t *testing.T) {
 		}
 	}
 }
+
+func TestKeyCheckValue(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	kcv := KeyCheckValue(key, "vault-a")
+	if len(kcv) != 32 || kcv != KeyCheckValue(key, "vault-a") {
+		t.Fatalf("Expected stable 16-byte hex value, got %q", kcv)
+	}
+	if kcv == KeyCheckValue(key, "vault-b") {
+		t.Error("Expected check value to depend on the vault ID")
+	}
+	other := bytes.Repeat([]byte{2}, 32)
+	if kcv == KeyCheckValue(other, "vault-a") {
+		t.Error("Expected check value to depend on the key")
+	}
+}
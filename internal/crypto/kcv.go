@@ -0,0 +1,18 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// kcvLabel 密钥校验值的域分隔前缀，避免与主密钥的其他用途混淆
+const kcvLabel = "clearvault key check value v1:"
+
+// KeyCheckValue 计算主密钥的校验值：HMAC-SHA256(key, label || vaultID) 的前 16 字节（十六进制）
+// 校验值绑定保险库 ID，不同保险库使用同一密钥时校验值也不同，且无法据此恢复密钥
+func KeyCheckValue(key []byte, vaultID string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(kcvLabel + vaultID))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
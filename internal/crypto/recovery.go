@@ -0,0 +1,107 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// recoveryInfo HKDF 的 info 前缀，区分用途与版本
+const recoveryInfo = "clearvault recovery key v1"
+
+// ErrInvalidSealedKey 密封数据格式错误或与恢复私钥不匹配
+var ErrInvalidSealedKey = errors.New("invalid sealed key or wrong recovery key")
+
+// GenerateRecoveryKey 生成 X25519 恢复密钥对（各 32 字节）
+func GenerateRecoveryKey() (publicKey, privateKey []byte, err error) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate recovery key: %w", err)
+	}
+	return priv.PublicKey().Bytes(), priv.Bytes(), nil
+}
+
+// RecoveryPublicKey 由恢复私钥计算对应的公钥
+func RecoveryPublicKey(privateKey []byte) ([]byte, error) {
+	priv, err := ecdh.X25519().NewPrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recovery private key: %w", err)
+	}
+	return priv.PublicKey().Bytes(), nil
+}
+
+// SealKey 用恢复公钥封装对称密钥（临时 X25519 密钥协商 + HKDF-SHA256 + AES-256-GCM）
+// 输出格式：临时公钥（32 字节）|| 密文与认证标签
+func SealKey(publicKey, key []byte) ([]byte, error) {
+	pub, err := ecdh.X25519().NewPublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recovery public key: %w", err)
+	}
+	eph, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := eph.ECDH(pub)
+	if err != nil {
+		return nil, err
+	}
+	ephPub := eph.PublicKey().Bytes()
+	aead, err := recoveryAEAD(shared, ephPub, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	// 每次封装使用新的临时密钥，派生出的密钥只用一次，可以使用固定的零 nonce
+	nonce := make([]byte, aead.NonceSize())
+	return aead.Seal(ephPub, nonce, key, nil), nil
+}
+
+// OpenKey 用恢复私钥解开 SealKey 封装的对称密钥
+func OpenKey(privateKey, sealed []byte) ([]byte, error) {
+	priv, err := ecdh.X25519().NewPrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recovery private key: %w", err)
+	}
+	if len(sealed) < 32+TagSize {
+		return nil, ErrInvalidSealedKey
+	}
+	ephPub, ciphertext := sealed[:32], sealed[32:]
+	eph, err := ecdh.X25519().NewPublicKey(ephPub)
+	if err != nil {
+		return nil, ErrInvalidSealedKey
+	}
+	shared, err := priv.ECDH(eph)
+	if err != nil {
+		return nil, ErrInvalidSealedKey
+	}
+	aead, err := recoveryAEAD(shared, ephPub, priv.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	key, err := aead.Open(nil, make([]byte, aead.NonceSize()), ciphertext, nil)
+	if err != nil {
+		return nil, ErrInvalidSealedKey
+	}
+	return key, nil
+}
+
+// recoveryAEAD 由共享密钥派生 AES-256-GCM，info 中包含双方公钥
+func recoveryAEAD(shared, ephPub, recipientPub []byte) (cipher.AEAD, error) {
+	info := make([]byte, 0, len(recoveryInfo)+64)
+	info = append(info, recoveryInfo...)
+	info = append(info, ephPub...)
+	info = append(info, recipientPub...)
+	key, err := hkdf.Key(sha256.New, shared, nil, string(info), 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,52 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRecoveryKey(t *testing.T) {
+	pub, priv, err := GenerateRecoveryKey()
+	if err != nil {
+		t.Fatalf("GenerateRecoveryKey failed: %v", err)
+	}
+	derived, err := RecoveryPublicKey(priv)
+	if err != nil || !bytes.Equal(derived, pub) {
+		t.Fatalf("RecoveryPublicKey mismatch: %x vs %x (%v)", derived, pub, err)
+	}
+
+	fek, _ := GenerateRandomBytes(32)
+	sealed, err := SealKey(pub, fek)
+	if err != nil {
+		t.Fatalf("SealKey failed: %v", err)
+	}
+	if len(sealed) != 32+len(fek)+TagSize {
+		t.Errorf("Unexpected sealed length %d", len(sealed))
+	}
+	again, _ := SealKey(pub, fek)
+	if bytes.Equal(sealed, again) {
+		t.Error("Expected a fresh ephemeral key for each seal")
+	}
+
+	opened, err := OpenKey(priv, sealed)
+	if err != nil || !bytes.Equal(opened, fek) {
+		t.Fatalf("OpenKey failed: %v", err)
+	}
+
+	_, otherPriv, _ := GenerateRecoveryKey()
+	if _, err := OpenKey(otherPriv, sealed); !errors.Is(err, ErrInvalidSealedKey) {
+		t.Errorf("Expected ErrInvalidSealedKey with another key, got %v", err)
+	}
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := OpenKey(priv, tampered); !errors.Is(err, ErrInvalidSealedKey) {
+		t.Errorf("Expected ErrInvalidSealedKey for tampered data, got %v", err)
+	}
+	if _, err := OpenKey(priv, sealed[:20]); !errors.Is(err, ErrInvalidSealedKey) {
+		t.Errorf("Expected ErrInvalidSealedKey for short data, got %v", err)
+	}
+	if _, err := SealKey([]byte("short"), fek); err == nil {
+		t.Error("Expected error for invalid public key")
+	}
+}
@@ -0,0 +1,219 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strconv"
+	"strings"
+)
+
+// sharePrefix 分享文本的格式标识与版本
+const sharePrefix = "CVK1"
+
+// shareGroup 分享数据每组字符数（打印与抄写时按组分隔）
+const shareGroup = 4
+
+var (
+	// ErrInvalidShare 分享文本格式错误或校验和不符（抄写错误）
+	ErrInvalidShare = errors.New("invalid key share")
+	// ErrShareMismatch 分享来自不同的备份，或组合后的密钥与校验值不符
+	ErrShareMismatch = errors.New("key shares do not belong together")
+)
+
+// shareEncoding 大写 Base32 无填充，字符集属于二维码字母数字模式
+var shareEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// KeyShare 主密钥的一份 Shamir 分享
+type KeyShare struct {
+	Threshold int    // 恢复所需的分享数
+	Index     byte   // 分享序号（多项式的 x 坐标，1..255）
+	Check     string // 备份标识（同一次备份的所有分享相同，随机生成，与密钥无关）
+	Data      []byte // 多项式在 Index 处的取值，与密钥等长
+}
+
+// String 编码为可打印文本：CVK1-<门限>-<序号>-<校验值>-<Base32 数据与 CRC32>
+// 只含大写字母、数字、连字符与空格，可直接生成二维码或手工抄写
+func (s *KeyShare) String() string {
+	header := s.header()
+	payload := binary.BigEndian.AppendUint32(append([]byte(nil), s.Data...), crc32.ChecksumIEEE(append([]byte(header), s.Data...)))
+	encoded := shareEncoding.EncodeToString(payload)
+	groups := make([]string, 0, len(encoded)/shareGroup+1)
+	for len(encoded) > shareGroup {
+		groups = append(groups, encoded[:shareGroup])
+		encoded = encoded[shareGroup:]
+	}
+	groups = append(groups, encoded)
+	return header + strings.Join(groups, " ")
+}
+
+func (s *KeyShare) header() string {
+	return fmt.Sprintf("%s-%d-%d-%s-", sharePrefix, s.Threshold, s.Index, s.Check)
+}
+
+// ParseKeyShare 解析 String 生成的分享文本，忽略空白与大小写
+func ParseKeyShare(text string) (*KeyShare, error) {
+	text = strings.ToUpper(strings.Join(strings.Fields(text), ""))
+	parts := strings.Split(text, "-")
+	if len(parts) != 5 || parts[0] != sharePrefix {
+		return nil, fmt.Errorf("%w: expected %s-<threshold>-<index>-<check>-<data>", ErrInvalidShare, sharePrefix)
+	}
+	threshold, err := strconv.Atoi(parts[1])
+	if err != nil || threshold < 2 || threshold > 255 {
+		return nil, fmt.Errorf("%w: bad threshold %q", ErrInvalidShare, parts[1])
+	}
+	index, err := strconv.Atoi(parts[2])
+	if err != nil || index < 1 || index > 255 {
+		return nil, fmt.Errorf("%w: bad index %q", ErrInvalidShare, parts[2])
+	}
+	payload, err := shareEncoding.DecodeString(parts[4])
+	if err != nil || len(payload) <= 4 {
+		return nil, fmt.Errorf("%w: bad data", ErrInvalidShare)
+	}
+	s := &KeyShare{
+		Threshold: threshold,
+		Index:     byte(index),
+		Check:     parts[3],
+		Data:      payload[:len(payload)-4],
+	}
+	sum := binary.BigEndian.Uint32(payload[len(payload)-4:])
+	if crc32.ChecksumIEEE(append([]byte(s.header()), s.Data...)) != sum {
+		return nil, fmt.Errorf("%w: checksum mismatch (typo?)", ErrInvalidShare)
+	}
+	return s, nil
+}
+
+// shareSetID 生成一次备份的标识（8 位十六进制），用于确认各分享属于同一次备份
+// 不从密钥派生：少于门限的分享加上标识仍不包含密钥的任何信息，组合结果由调用方用保险库校验值确认
+func shareSetID() (string, error) {
+	b, err := GenerateRandomBytes(4)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(b)), nil
+}
+
+// SplitKey 将密钥拆分为 n 份 Shamir 分享，任意 threshold 份可以恢复，少于 threshold 份不泄露任何信息
+func SplitKey(key []byte, n, threshold int) ([]*KeyShare, error) {
+	if threshold < 2 || threshold > n || n > 255 {
+		return nil, fmt.Errorf("invalid shares %d / threshold %d: need 2 <= threshold <= shares <= 255", n, threshold)
+	}
+	if len(key) == 0 {
+		return nil, errors.New("empty key")
+	}
+	shares := make([]*KeyShare, n)
+	check, err := shareSetID()
+	if err != nil {
+		return nil, err
+	}
+	for i := range shares {
+		shares[i] = &KeyShare{Threshold: threshold, Index: byte(i + 1), Check: check, Data: make([]byte, len(key))}
+	}
+	// 每个字节独立使用一个 threshold-1 次随机多项式，常数项为密钥字节
+	coeffs := make([]byte, threshold)
+	for j, b := range key {
+		if _, err := rand.Read(coeffs[1:]); err != nil {
+			return nil, err
+		}
+		coeffs[0] = b
+		for _, s := range shares {
+			s.Data[j] = polyEval(coeffs, s.Index)
+		}
+	}
+	return shares, nil
+}
+
+// CombineKey 用至少 threshold 份分享恢复密钥；多于 threshold 份时检查其余分享与结果一致
+func CombineKey(shares []*KeyShare) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("no key shares")
+	}
+	first := shares[0]
+	seen := make(map[byte]bool)
+	var used []*KeyShare
+	for _, s := range shares {
+		if s.Threshold != first.Threshold || s.Check != first.Check || len(s.Data) != len(first.Data) {
+			return nil, fmt.Errorf("%w: share %d is from a different backup", ErrShareMismatch, s.Index)
+		}
+		if !seen[s.Index] {
+			seen[s.Index] = true
+			used = append(used, s)
+		}
+	}
+	if len(used) < first.Threshold {
+		return nil, fmt.Errorf("need %d distinct key shares, got %d", first.Threshold, len(used))
+	}
+	used, extra := used[:first.Threshold], used[first.Threshold:]
+	for _, s := range extra {
+		if !bytes.Equal(interpolate(used, s.Index), s.Data) {
+			return nil, fmt.Errorf("%w: share %d does not match the others", ErrShareMismatch, s.Index)
+		}
+	}
+	return interpolate(used, 0), nil
+}
+
+// interpolate 拉格朗日插值求多项式在 x 处的值
+func interpolate(shares []*KeyShare, x byte) []byte {
+	out := make([]byte, len(shares[0].Data))
+	for i, si := range shares {
+		basis := byte(1)
+		for k, sk := range shares {
+			if k != i {
+				// l_i(x) = Π (x - x_k) / (x_i - x_k)，GF(256) 中减法即异或
+				basis = gfMul(basis, gfDiv(x^sk.Index, si.Index^sk.Index))
+			}
+		}
+		for j := range out {
+			out[j] ^= gfMul(basis, si.Data[j])
+		}
+	}
+	return out
+}
+
+// polyEval 按霍纳法则在 GF(256) 中计算多项式在 x 处的值
+func polyEval(coeffs []byte, x byte) byte {
+	var y byte
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y = gfMul(y, x) ^ coeffs[i]
+	}
+	return y
+}
+
+// GF(2^8) 运算，约化多项式 x^8+x^4+x^3+x+1（0x11b），生成元 3
+var gfExp, gfLog = gfTables()
+
+func gfTables() (exp [510]byte, log [256]byte) {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		exp[i] = x
+		exp[i+255] = x
+		log[x] = byte(i)
+		// x *= 3
+		hi := x & 0x80
+		x2 := x << 1
+		if hi != 0 {
+			x2 ^= 0x1b
+		}
+		x ^= x2
+	}
+	return exp, log
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
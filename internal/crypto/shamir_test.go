@@ -0,0 +1,108 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSplitCombineKey(t *testing.T) {
+	key, _ := GenerateRandomBytes(32)
+	shares, err := SplitKey(key, 5, 3)
+	if err != nil {
+		t.Fatalf("SplitKey failed: %v", err)
+	}
+	if len(shares) != 5 {
+		t.Fatalf("Expected 5 shares, got %d", len(shares))
+	}
+
+	// 任意 3 份都能恢复
+	for a := 0; a < 5; a++ {
+		for b := a + 1; b < 5; b++ {
+			for c := b + 1; c < 5; c++ {
+				got, err := CombineKey([]*KeyShare{shares[c], shares[a], shares[b]})
+				if err != nil || !bytes.Equal(got, key) {
+					t.Fatalf("CombineKey(%d,%d,%d) failed: %v", a, b, c, err)
+				}
+			}
+		}
+	}
+
+	// 份数不足（重复的分享不计入）
+	if _, err := CombineKey([]*KeyShare{shares[0], shares[1], shares[1]}); err == nil {
+		t.Error("Expected error with too few distinct shares")
+	}
+
+	// 混入另一次备份的分享
+	other, _ := SplitKey(key, 5, 3)
+	other2, _ := GenerateRandomBytes(32)
+	foreign, _ := SplitKey(other2, 5, 3)
+	if _, err := CombineKey([]*KeyShare{shares[0], shares[1], foreign[2]}); !errors.Is(err, ErrShareMismatch) {
+		t.Errorf("Expected ErrShareMismatch, got %v", err)
+	}
+	// 同一密钥的两次备份标识不同（标识与密钥无关）
+	if other[0].Check == shares[0].Check {
+		t.Error("Expected a fresh backup identifier for each backup")
+	}
+	if _, err := CombineKey([]*KeyShare{shares[0], shares[1], other[2]}); !errors.Is(err, ErrShareMismatch) {
+		t.Errorf("Expected ErrShareMismatch for shares of different backups, got %v", err)
+	}
+	// 多于门限的分享必须与组合结果一致
+	if got, err := CombineKey(shares); err != nil || !bytes.Equal(got, key) {
+		t.Errorf("CombineKey with all shares failed: %v", err)
+	}
+	bad := *shares[4]
+	bad.Data = append([]byte(nil), bad.Data...)
+	bad.Data[0] ^= 1
+	if _, err := CombineKey([]*KeyShare{shares[0], shares[1], shares[2], &bad}); !errors.Is(err, ErrShareMismatch) {
+		t.Errorf("Expected ErrShareMismatch for an inconsistent extra share, got %v", err)
+	}
+
+	for _, tc := range [][2]int{{3, 1}, {2, 3}, {256, 2}} {
+		if _, err := SplitKey(key, tc[0], tc[1]); err == nil {
+			t.Errorf("Expected error for shares=%d threshold=%d", tc[0], tc[1])
+		}
+	}
+}
+
+func TestKeyShareText(t *testing.T) {
+	key, _ := GenerateRandomBytes(32)
+	shares, _ := SplitKey(key, 3, 2)
+	text := shares[1].String()
+	if !strings.HasPrefix(text, "CVK1-2-2-") {
+		t.Errorf("Unexpected share text %q", text)
+	}
+	for _, r := range text {
+		if !strings.ContainsRune("ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789- ", r) {
+			t.Fatalf("Share text contains %q, not QR alphanumeric", r)
+		}
+	}
+
+	// 解析忽略空白、换行与大小写
+	parsed, err := ParseKeyShare(" " + strings.ToLower(strings.ReplaceAll(text, " ", "\n")) + " ")
+	if err != nil {
+		t.Fatalf("ParseKeyShare failed: %v", err)
+	}
+	if parsed.Threshold != 2 || parsed.Index != 2 || parsed.Check != shares[1].Check || !bytes.Equal(parsed.Data, shares[1].Data) {
+		t.Errorf("Parsed share mismatch: %+v", parsed)
+	}
+	p0, _ := ParseKeyShare(shares[0].String())
+	if got, err := CombineKey([]*KeyShare{p0, parsed}); err != nil || !bytes.Equal(got, key) {
+		t.Errorf("CombineKey from text failed: %v", err)
+	}
+
+	// 抄写错误由 CRC 发现
+	i := strings.LastIndex(text, "-") + 3
+	typo := []byte(text)
+	if typo[i] == 'A' {
+		typo[i] = 'B'
+	} else {
+		typo[i] = 'A'
+	}
+	for _, bad := range []string{string(typo), "CVK1-2-2", "CVK2" + text[4:], strings.Replace(text, "CVK1-2-2-", "CVK1-2-3-", 1), strings.Replace(text, "CVK1-2-", "CVK1-1-", 1)} {
+		if _, err := ParseKeyShare(bad); !errors.Is(err, ErrInvalidShare) {
+			t.Errorf("Expected ErrInvalidShare for %q, got %v", bad, err)
+		}
+	}
+}
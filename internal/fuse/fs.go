@@ -4,6 +4,8 @@ package fuse
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"log"
 	"os"
@@ -14,6 +16,7 @@ import (
 	"time"
 
 	"clearvault/internal/proxy"
+	"clearvault/internal/remote"
 
 	"github.com/winfsp/cgofuse/fuse"
 )
@@ -37,9 +40,14 @@ type ClearVaultFS struct {
 	uid   uint32
 	gid   uint32
 
+	// 所有远端操作的父 ctx，卸载时取消以中止进行中的传输
+	ctx    context.Context
+	cancel context.CancelFunc
+
 	mu      sync.Mutex
 	nextFH  uint64
 	writers map[uint64]*writeHandle
+	readers map[uint64]*proxy.FileReader // 启用预读时每个只读打开的文件一个
 }
 
 func NewClearVaultFS(p *proxy.Proxy) *ClearVaultFS {
@@ -55,12 +63,16 @@ func NewClearVaultFS(p *proxy.Proxy) *ClearVaultFS {
 			gid = uint32(n)
 		}
 	}
+	ctx, cancel := context.WithCancel(context.Background())
 	return &ClearVaultFS{
+		ctx:     ctx,
+		cancel:  cancel,
 		proxy:   p,
 		uid:     uid,
 		gid:     gid,
 		nextFH:  1,
 		writers: make(map[uint64]*writeHandle),
+		readers: make(map[uint64]*proxy.FileReader),
 	}
 }
 
@@ -90,6 +102,11 @@ func decodeOpenFlags(flags int) string {
 	return strings.Join(parts, "|")
 }
 
+// Destroy is called when the file system is unmounted
+func (fs *ClearVaultFS) Destroy() {
+	fs.cancel()
+}
+
 // Getattr gets file attributes
 func (fs *ClearVaultFS) Getattr(path string, stat *fuse.Stat_t, fh uint64) int {
 	if path == "/" {
@@ -208,7 +225,7 @@ func (fs *ClearVaultFS) Open(path string, flags int) (int, uint64) {
 			return -fuse.EOPNOTSUPP, 0
 		}
 		log.Printf("FUSE Open write path=%q flags=0x%x(%s) placeholder=%v", path, flags, decodeOpenFlags(flags), placeholder)
-		_ = fs.proxy.RemoveAll(path)
+		_ = fs.proxy.RemoveAll(fs.ctx, path)
 		fh := fs.newWriteHandle(path)
 		return 0, fh
 	}
@@ -216,14 +233,50 @@ func (fs *ClearVaultFS) Open(path string, flags int) (int, uint64) {
 		log.Printf("FUSE Open read placeholder path=%q flags=0x%x(%s)", path, flags, decodeOpenFlags(flags))
 		return 0, 0
 	}
+	if meta.Size > 0 {
+		r, err := fs.proxy.OpenReader(fs.ctx, path)
+		if err != nil {
+			log.Printf("FUSE Open read error path=%q err=%v", path, err)
+			return -fuse.EIO, 0
+		}
+		if r != nil {
+			return 0, fs.newReadHandle(r)
+		}
+	}
 	return 0, 0
 }
 
 // Read reads data
 func (fs *ClearVaultFS) Read(path string, buff []byte, ofst int64, fh uint64) int {
-	rc, err := fs.proxy.DownloadRange(path, ofst, int64(len(buff)))
+	if r := fs.getReadHandle(fh); r != nil {
+		// 预读读取器：顺序读取时后续分段已在后台并行预取
+		n, err := r.ReadAt(buff, ofst)
+		if err != nil && err != io.EOF {
+			log.Printf("FUSE Read error: path=%q offset=%d n=%d err=%v", path, ofst, n, err)
+			if n > 0 {
+				return n
+			}
+			if errors.Is(err, remote.ErrRemoteOffline) {
+				return -fuse.ENETUNREACH
+			}
+			if isTemporaryError(err) {
+				return -fuse.EAGAIN
+			}
+			return -fuse.EIO
+		}
+		return n
+	}
+
+	ctx, cancel := context.WithCancel(fs.ctx)
+	defer cancel()
+
+	rc, err := fs.proxy.DownloadRange(ctx, path, ofst, int64(len(buff)))
 	if err != nil {
 		log.Printf("FUSE Read error: path=%q offset=%d len=%d err=%v", path, ofst, len(buff), err)
+		if errors.Is(err, remote.ErrRemoteOffline) {
+			// 远端离线且内容未缓存，立即失败而不是让应用长时间等待
+			return -fuse.ENETUNREACH
+		}
 		return -fuse.EIO
 	}
 	defer rc.Close()
@@ -242,6 +295,9 @@ func (fs *ClearVaultFS) Read(path string, buff []byte, ofst int64, fh uint64) in
 			return -fuse.EAGAIN
 		}
 		log.Printf("FUSE Read error: path=%q offset=%d n=%d err=%v", path, ofst, n, err)
+		if errors.Is(err, remote.ErrRemoteOffline) {
+			return -fuse.ENETUNREACH
+		}
 		return -fuse.EIO
 	}
 
@@ -304,7 +360,7 @@ func toLower(s string) string {
 // Create creates a file
 func (fs *ClearVaultFS) Create(path string, flags int, mode uint32) (int, uint64) {
 	log.Printf("FUSE Create path=%q flags=0x%x(%s) mode=0%o", path, flags, decodeOpenFlags(flags), mode)
-	_ = fs.proxy.RemoveAll(path)
+	_ = fs.proxy.RemoveAll(fs.ctx, path)
 	fh := fs.newWriteHandle(path)
 	log.Printf("FUSE Create ok path=%q fh=%d", path, fh)
 	return 0, fh
@@ -316,7 +372,7 @@ func (fs *ClearVaultFS) Truncate(path string, size int64, fh uint64) int {
 		if fh != 0 {
 			return -fuse.EOPNOTSUPP
 		}
-		err := fs.proxy.UploadFile(path, bytes.NewReader(nil), 0)
+		err := fs.proxy.UploadFile(fs.ctx, path, bytes.NewReader(nil), 0)
 		if err != nil {
 			return -fuse.EIO
 		}
@@ -353,7 +409,9 @@ func (fs *ClearVaultFS) Write(path string, buff []byte, ofst int64, fh uint64) i
 		h.uploadPath = h.path
 		log.Printf("FUSE Write start upload path=%q fh=%d", h.uploadPath, fh)
 		go func(p string) {
-			err := fs.proxy.UploadFile(p, pr, -1)
+			err := fs.proxy.UploadFile(fs.ctx, p, pr, -1)
+			// 上传失败后让后续 Write 立即返回错误，而不是阻塞在管道上
+			pr.CloseWithError(err)
 			h.uploadCh <- err
 		}(h.uploadPath)
 	}
@@ -385,6 +443,10 @@ func (fs *ClearVaultFS) Flush(path string, fh uint64) int {
 }
 
 func (fs *ClearVaultFS) Release(path string, fh uint64) int {
+	if r := fs.removeReadHandle(fh); r != nil {
+		r.Close()
+		return 0
+	}
 	h := fs.removeWriteHandle(fh)
 	if h == nil {
 		return 0
@@ -403,7 +465,7 @@ func (fs *ClearVaultFS) Release(path string, fh uint64) int {
 			return 0
 		}
 		log.Printf("FUSE Release upload empty path=%q fh=%d", h.path, fh)
-		if err := fs.proxy.UploadFile(h.path, bytes.NewReader(nil), 0); err != nil {
+		if err := fs.proxy.UploadFile(fs.ctx, h.path, bytes.NewReader(nil), 0); err != nil {
 			return -fuse.EIO
 		}
 		return 0
@@ -448,7 +510,7 @@ func (fs *ClearVaultFS) Mkdir(path string, mode uint32) int {
 
 // Unlink deletes file
 func (fs *ClearVaultFS) Unlink(path string) int {
-	err := fs.proxy.RemoveAll(path)
+	err := fs.proxy.RemoveAll(fs.ctx, path)
 	if err != nil {
 		return -fuse.EIO
 	}
@@ -473,14 +535,23 @@ func (fs *ClearVaultFS) Rename(oldpath string, newpath string) int {
 	return 0
 }
 
+// Statfs 按远端报告的空间换算块数，远端不支持查询或剩余空间未知时返回固定值
 func (fs *ClearVaultFS) Statfs(path string, stat *fuse.Statfs_t) int {
-	stat.Bsize = 4096
-	stat.Frsize = 4096
+	const bsize = 4096
+	stat.Bsize = bsize
+	stat.Frsize = bsize
 	stat.Blocks = 1024 * 1024
 	stat.Bfree = 1024 * 1024
 	stat.Bavail = 1024 * 1024
 	stat.Files = 1024 * 1024
 	stat.Ffree = 1024 * 1024
+	used, available, err := fs.proxy.Quota(fs.ctx)
+	if err != nil || available < 0 {
+		return 0
+	}
+	stat.Bfree = uint64(available) / bsize
+	stat.Bavail = stat.Bfree
+	stat.Blocks = stat.Bfree + uint64(max(used, 0))/bsize
 	return 0
 }
 
@@ -533,6 +604,29 @@ func (fs *ClearVaultFS) removeWriteHandle(fh uint64) *writeHandle {
 	return h
 }
 
+func (fs *ClearVaultFS) newReadHandle(r *proxy.FileReader) uint64 {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fh := fs.nextFH
+	fs.nextFH++
+	fs.readers[fh] = r
+	return fh
+}
+
+func (fs *ClearVaultFS) getReadHandle(fh uint64) *proxy.FileReader {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.readers[fh]
+}
+
+func (fs *ClearVaultFS) removeReadHandle(fh uint64) *proxy.FileReader {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	r := fs.readers[fh]
+	delete(fs.readers, fh)
+	return r
+}
+
 func (fs *ClearVaultFS) pendingSize(path string) (int64, bool) {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
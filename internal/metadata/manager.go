@@ -13,6 +13,29 @@ type FileMeta struct {
 	FEK        []byte    `json:"fek"`            // 加密的文件加密密钥
 	Salt       []byte    `json:"salt"`           // 加密 Salt/Nonce
 	UpdatedAt  time.Time `json:"updated_at"`     // 更新时间
+
+	// 小文件打包存储（PackName 为空表示独立远端对象）
+	PackName   string `json:"pack_name,omitempty"`   // 所在 pack 对象名
+	PackOffset int64  `json:"pack_offset,omitempty"` // 在 pack 中的密文偏移
+	PackLength int64  `json:"pack_length,omitempty"` // 在 pack 中的密文长度
+
+	// 块级压缩（Compression 为空表示未压缩的定长块格式）
+	Compression string `json:"compression,omitempty"`  // 压缩算法，如 "zstd"
+	ChunkIndex  []byte `json:"chunk_index,omitempty"`  // 各密文块长度（大端 uint32 序列），块数较多时为空
+	IndexOffset int64  `json:"index_offset,omitempty"` // 加密块索引在密文中的偏移（0 表示内联在 ChunkIndex 中）
+
+	// 密钥托管（为空表示写入时未配置恢复公钥）
+	RecoveryFEK []byte `json:"recovery_fek,omitempty"` // 用恢复公钥封装的文件加密密钥
+}
+
+// IsCompressed 判断文件是否使用块级压缩格式
+func (m *FileMeta) IsCompressed() bool {
+	return m.Compression != ""
+}
+
+// IsPacked 判断文件是否存储在共享 pack 对象中
+func (m *FileMeta) IsPacked() bool {
+	return m.PackName != ""
 }
 
 type Storage interface {
@@ -0,0 +1,43 @@
+package metadata
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+)
+
+// VaultStore 可选接口：可保存保险库描述（proxy.VaultDescriptor 的 JSON）的元数据存储
+type VaultStore interface {
+	// LoadVault 返回保存的描述，尚未保存时返回 nil
+	LoadVault() ([]byte, error)
+	SaveVault(data []byte) error
+}
+
+// LoadVault 描述保存在标记文件 .clearvault 中，旧版本写入的纯文本标记视为尚未保存
+func (s *LocalStorage) LoadVault() ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(s.baseDir, ".clearvault"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return nil, nil
+	}
+	return data, nil
+}
+
+// SaveVault 先写临时文件再重命名，避免写入中断损坏标记文件
+func (s *LocalStorage) SaveVault(data []byte) error {
+	marker := filepath.Join(s.baseDir, ".clearvault")
+	tmp := marker + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, marker); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
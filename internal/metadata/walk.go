@@ -0,0 +1,55 @@
+package metadata
+
+import (
+	"path"
+	"sort"
+)
+
+// WalkFunc 遍历元数据时对每个文件（非目录）调用的回调
+type WalkFunc func(p string, meta *FileMeta) error
+
+// Walk 递归遍历 root 下的所有文件元数据
+func Walk(s Storage, root string, fn WalkFunc) error {
+	children, err := s.ReadDir(root)
+	if err != nil {
+		return err
+	}
+	for i := range children {
+		child := &children[i]
+		childPath := path.Join(root, child.Name)
+		if child.IsDir {
+			if err := Walk(s, childPath, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(childPath, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoteObjects 返回元数据引用的所有远端对象名（独立对象与 pack 对象，去重并排序）
+func RemoteObjects(s Storage) ([]string, error) {
+	seen := make(map[string]bool)
+	err := Walk(s, "/", func(p string, m *FileMeta) error {
+		name := m.RemoteName
+		if m.IsPacked() {
+			name = m.PackName
+		}
+		if name != "" {
+			seen[name] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
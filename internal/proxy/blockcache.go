@@ -0,0 +1,457 @@
+package proxy
+
+import (
+	"clearvault/internal/crypto"
+	"clearvault/internal/remote"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BlockCacheOptions 本地密文块缓存配置
+type BlockCacheOptions struct {
+	Dir     string // 缓存目录
+	MaxSize int64  // 缓存总大小上限（字节）
+}
+
+const (
+	defaultBlockCacheMaxSize = 1 << 30
+
+	// blockSize 缓存块大小，与一个密文分块对齐
+	blockSize = crypto.CipherChunkSize
+)
+
+// BlockCacheStats 缓存统计
+type BlockCacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Entries   int    `json:"entries"`
+	Size      int64  `json:"size"`
+	MaxSize   int64  `json:"max_size"`
+}
+
+// blockCache 把远端对象按 blockSize 切块缓存在本地磁盘，按 LRU 淘汰
+// 缓存内容是远端原样的密文，文件名是远端对象名的哈希，本地不落明文
+type blockCache struct {
+	remote.RemoteStorage
+	opts BlockCacheOptions
+
+	mu      sync.Mutex
+	lru     *list.List                         // 最近使用的在前
+	objects map[string]map[int64]*list.Element // 对象哈希 -> 块号 -> LRU 节点
+	size    int64
+
+	hits, misses, evictions atomic.Uint64
+}
+
+type cachedBlock struct {
+	key   string
+	index int64
+	size  int64
+}
+
+// EnableBlockCache 启用本地密文块缓存，范围读取（FUSE 读、WebDAV Range）优先命中本地块
+// 上传、删除、重命名远端对象时对应的块随之失效；启用时加载目录中已有的块
+func (p *Proxy) EnableBlockCache(opts BlockCacheOptions) error {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = defaultBlockCacheMaxSize
+	}
+	if opts.Dir == "" {
+		return fmt.Errorf("block cache directory is required")
+	}
+	if err := os.MkdirAll(opts.Dir, 0700); err != nil {
+		return fmt.Errorf("failed to create block cache directory: %w", err)
+	}
+	c := &blockCache{
+		RemoteStorage: p.remote,
+		opts:          opts,
+		lru:           list.New(),
+		objects:       make(map[string]map[int64]*list.Element),
+	}
+	if err := c.load(); err != nil {
+		return err
+	}
+	p.remote = c
+	p.cache = c
+	return nil
+}
+
+// CacheStats 返回块缓存统计，未启用缓存时 ok 为 false
+func (p *Proxy) CacheStats() (stats BlockCacheStats, ok bool) {
+	if p.cache == nil {
+		return BlockCacheStats{}, false
+	}
+	return p.cache.stats(), true
+}
+
+func (c *blockCache) stats() BlockCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return BlockCacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Entries:   c.lru.Len(),
+		Size:      c.size,
+		MaxSize:   c.opts.MaxSize,
+	}
+}
+
+func cacheKey(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *blockCache) blockPath(key string, index int64) string {
+	return filepath.Join(c.opts.Dir, key[:2], key+"-"+strconv.FormatInt(index, 10))
+}
+
+// load 扫描缓存目录重建索引，按修改时间恢复 LRU 顺序
+func (c *blockCache) load() error {
+	type found struct {
+		block cachedBlock
+		mtime time.Time
+	}
+	var blocks []found
+	err := filepath.WalkDir(c.opts.Dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if strings.HasSuffix(d.Name(), ".tmp") {
+			os.Remove(p)
+			return nil
+		}
+		key, idx, ok := strings.Cut(d.Name(), "-")
+		index, perr := strconv.ParseInt(idx, 10, 64)
+		if !ok || len(key) != sha256.Size*2 || perr != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		blocks = append(blocks, found{cachedBlock{key, index, info.Size()}, info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load block cache: %w", err)
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].mtime.After(blocks[j].mtime) })
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, b := range blocks {
+		c.insertLocked(b.block)
+	}
+	c.evictLocked()
+	if len(blocks) > 0 {
+		log.Printf("Proxy: Loaded %d cached blocks (%d bytes)", c.lru.Len(), c.size)
+	}
+	return nil
+}
+
+// insertLocked 加入索引（放在 LRU 末尾），调用方持有 c.mu
+func (c *blockCache) insertLocked(b cachedBlock) {
+	blocks := c.objects[b.key]
+	if blocks == nil {
+		blocks = make(map[int64]*list.Element)
+		c.objects[b.key] = blocks
+	}
+	blocks[b.index] = c.lru.PushBack(&b)
+	c.size += b.size
+}
+
+func (c *blockCache) removeLocked(e *list.Element) {
+	b := c.lru.Remove(e).(*cachedBlock)
+	c.size -= b.size
+	delete(c.objects[b.key], b.index)
+	if len(c.objects[b.key]) == 0 {
+		delete(c.objects, b.key)
+	}
+	os.Remove(c.blockPath(b.key, b.index))
+}
+
+// evictLocked 淘汰最久未使用的块直到不超过 MaxSize
+func (c *blockCache) evictLocked() {
+	for c.size > c.opts.MaxSize && c.lru.Len() > 0 {
+		c.removeLocked(c.lru.Back())
+		c.evictions.Add(1)
+	}
+}
+
+func (c *blockCache) cached(key string, index int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.objects[key][index]
+	return ok
+}
+
+// covers 判断 [start, start+length) 涉及的块是否都已缓存
+func (c *blockCache) covers(name string, start, length int64) bool {
+	if length <= 0 {
+		return false
+	}
+	key := cacheKey(name)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	blocks := c.objects[key]
+	for i := start / blockSize; i <= (start+length-1)/blockSize; i++ {
+		if _, ok := blocks[i]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// get 读取缓存块，未命中返回 nil
+func (c *blockCache) get(key string, index int64) []byte {
+	c.mu.Lock()
+	e, ok := c.objects[key][index]
+	if ok {
+		c.lru.MoveToFront(e)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	data, err := os.ReadFile(c.blockPath(key, index))
+	if err != nil {
+		// 文件被外部删除或刚被淘汰，按未命中处理
+		c.mu.Lock()
+		if e, ok := c.objects[key][index]; ok {
+			c.removeLocked(e)
+		}
+		c.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	os.Chtimes(c.blockPath(key, index), now, now)
+	return data
+}
+
+// put 写入缓存块（先写临时文件再重命名），失败只记录日志
+func (c *blockCache) put(key string, index int64, data []byte) {
+	p := c.blockPath(key, index)
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		log.Printf("Proxy: Failed to write cache block: %v", err)
+		return
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		log.Printf("Proxy: Failed to write cache block: %v", err)
+		os.Remove(tmp)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.objects[key][index]; ok {
+		c.removeLocked(e)
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		log.Printf("Proxy: Failed to write cache block: %v", err)
+		os.Remove(tmp)
+		return
+	}
+	c.insertLocked(cachedBlock{key, index, int64(len(data))})
+	c.lru.MoveToFront(c.objects[key][index])
+	c.evictLocked()
+}
+
+// invalidate 删除对象的所有缓存块
+func (c *blockCache) invalidate(name string) {
+	key := cacheKey(name)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.objects[key] {
+		c.removeLocked(e)
+	}
+}
+
+func (c *blockCache) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	c.invalidate(name)
+	err := c.RemoteStorage.Upload(ctx, name, data, size)
+	// 上传期间的读取可能缓存了旧内容
+	c.invalidate(name)
+	return err
+}
+
+func (c *blockCache) Delete(ctx context.Context, name string) error {
+	c.invalidate(name)
+	return c.RemoteStorage.Delete(ctx, name)
+}
+
+func (c *blockCache) Rename(ctx context.Context, oldName, newName string) error {
+	c.invalidate(oldName)
+	c.invalidate(newName)
+	return c.RemoteStorage.Rename(ctx, oldName, newName)
+}
+
+// List 透传到底层存储
+func (c *blockCache) List(ctx context.Context, prefix string, fn func(name string, info os.FileInfo) error) error {
+	return remote.List(ctx, c.RemoteStorage, prefix, fn)
+}
+
+func (c *blockCache) Quota(ctx context.Context) (int64, int64, error) {
+	return remote.Quota(ctx, c.RemoteStorage)
+}
+
+// DownloadRange 按块读取：命中的块从本地读取，连续未命中的块合并为一次远端范围请求并写入缓存
+// 读到末尾（length 为 0）或超过缓存容量 1/8 且未完全缓存的大范围读取直接透传，避免冲掉整个缓存
+func (c *blockCache) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	if length <= 0 || (length > c.opts.MaxSize/8 && !c.covers(name, start, length)) {
+		return c.RemoteStorage.DownloadRange(ctx, name, start, length)
+	}
+	return &blockReader{c: c, ctx: ctx, name: name, key: cacheKey(name), pos: start, end: start + length, size: -1}, nil
+}
+
+// blockReader 顺序读取 [pos, end) 范围内的块
+type blockReader struct {
+	c    *blockCache
+	ctx  context.Context
+	name string
+	key  string
+	pos  int64
+	end  int64
+
+	cur    []byte        // 当前块中尚未返回的数据
+	run    io.ReadCloser // 连续未命中块的远端流
+	runIdx int64         // run 下一个块的块号
+	runEnd int64         // run 覆盖的块号上限（不含）
+	eof    bool
+	size   int64 // 对象大小，-1 表示尚未查询
+}
+
+func (r *blockReader) Read(p []byte) (int, error) {
+	for len(r.cur) == 0 {
+		if r.eof || r.pos >= r.end {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.cur)
+	r.cur = r.cur[n:]
+	r.pos += int64(n)
+	return n, nil
+}
+
+// next 加载 pos 所在的块
+func (r *blockReader) next() error {
+	index := r.pos / blockSize
+	var data []byte
+	if r.run != nil && r.runIdx == index {
+		var err error
+		if data, err = r.readRun(); err != nil {
+			return err
+		}
+	} else if data = r.c.get(r.key, index); data != nil {
+		r.c.hits.Add(1)
+	} else {
+		if err := r.openRun(index); err != nil {
+			return err
+		}
+		var err error
+		if data, err = r.readRun(); err != nil {
+			return err
+		}
+	}
+
+	// 短块是对象的最后一块
+	r.eof = len(data) < blockSize
+	off := r.pos - index*blockSize
+	if off >= int64(len(data)) {
+		return nil
+	}
+	data = data[off:]
+	if rest := r.end - r.pos; int64(len(data)) > rest {
+		data = data[:rest]
+	}
+	r.cur = data
+	return nil
+}
+
+// openRun 从 index 开始，为连续未命中的块打开一次远端范围请求
+func (r *blockReader) openRun(index int64) error {
+	r.closeRun()
+	last := (r.end - 1) / blockSize
+	end := index + 1
+	for end <= last && !r.c.cached(r.key, end) {
+		end++
+	}
+	rc, err := r.c.RemoteStorage.DownloadRange(r.ctx, r.name, index*blockSize, (end-index)*blockSize)
+	if err != nil {
+		return err
+	}
+	r.run, r.runIdx, r.runEnd = rc, index, end
+	return nil
+}
+
+// readRun 从远端流读取一个完整块并写入缓存
+func (r *blockReader) readRun() ([]byte, error) {
+	buf := make([]byte, blockSize)
+	n, err := io.ReadFull(r.run, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		r.closeRun()
+		return nil, err
+	}
+	r.c.misses.Add(1)
+	buf = buf[:n]
+	// 只有对象的最后一块可以是短块，否则是连接中断等导致的截断，不能缓存
+	if n < blockSize {
+		if err := r.checkLast(r.runIdx, int64(n)); err != nil {
+			r.closeRun()
+			return nil, err
+		}
+	}
+	if n > 0 {
+		r.c.put(r.key, r.runIdx, buf)
+	}
+	r.runIdx++
+	if n < blockSize || r.runIdx >= r.runEnd {
+		r.closeRun()
+	}
+	return buf, nil
+}
+
+// checkLast 确认长度为 n 的第 index 块正好结束于对象末尾
+func (r *blockReader) checkLast(index, n int64) error {
+	if r.size < 0 {
+		info, err := r.c.RemoteStorage.Stat(r.ctx, r.name)
+		if err != nil {
+			return fmt.Errorf("short read of block %d of '%s': %w", index, r.name, err)
+		}
+		r.size = info.Size()
+	}
+	if index*blockSize+n != r.size {
+		return fmt.Errorf("short read of block %d of '%s' (%d bytes, object size %d): %w", index, r.name, n, r.size, io.ErrUnexpectedEOF)
+	}
+	return nil
+}
+
+func (r *blockReader) closeRun() {
+	if r.run != nil {
+		r.run.Close()
+		r.run = nil
+	}
+}
+
+func (r *blockReader) Close() error {
+	r.closeRun()
+	return nil
+}
@@ -0,0 +1,229 @@
+package proxy
+
+import (
+	"bytes"
+	"clearvault/internal/crypto"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// countingRemote 统计远端范围请求次数
+type countingRemote struct {
+	*mockRemoteStorage
+	ranges int
+}
+
+func (m *countingRemote) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	m.ranges++
+	return m.mockRemoteStorage.DownloadRange(ctx, name, start, length)
+}
+
+func newCacheTestProxy(t *testing.T, dir string, maxSize int64) (*Proxy, *countingRemote) {
+	t.Helper()
+	remote := &countingRemote{mockRemoteStorage: newMockRemoteStorage()}
+	p, _ := newPackTestProxy(t, remote.mockRemoteStorage, t.TempDir())
+	p.packer = nil
+	p.remote = remote
+	if err := p.EnableBlockCache(BlockCacheOptions{Dir: dir, MaxSize: maxSize}); err != nil {
+		t.Fatalf("EnableBlockCache failed: %v", err)
+	}
+	return p, remote
+}
+
+func readRange(t *testing.T, p *Proxy, name string, offset, length int64) []byte {
+	t.Helper()
+	rc, err := p.DownloadRange(context.Background(), name, offset, length)
+	if err != nil {
+		t.Fatalf("DownloadRange(%d, %d) failed: %v", offset, length, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("Read range failed: %v", err)
+	}
+	return data
+}
+
+func TestBlockCacheRange(t *testing.T) {
+	p, remote := newCacheTestProxy(t, t.TempDir(), 64<<20)
+	content := make([]byte, 5*64*1024+123)
+	rand.New(rand.NewSource(1)).Read(content)
+	if err := p.UploadFile(context.Background(), "/f.bin", bytes.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+
+	ranges := [][2]int64{{0, 10}, {70000, 100000}, {5 * 64 * 1024, 123}, {100, 0}, {64 * 1024, 2 * 64 * 1024}}
+	for _, r := range ranges {
+		// 返回结果从 offset 所在的明文分块开头开始
+		got := readRange(t, p, "/f.bin", r[0], r[1])
+		skip := r[0] % crypto.ChunkSize
+		want := r[1]
+		if want <= 0 {
+			want = 1
+		}
+		if int64(len(got)) < skip+want || !bytes.Equal(got[skip:skip+want], content[r[0]:r[0]+want]) {
+			t.Fatalf("Range %v mismatch", r)
+		}
+	}
+	misses := p.cache.stats().Misses
+	// 用到的分块为 0、1、2、5，每块只从远端读取一次
+	if misses != 4 {
+		t.Errorf("Expected 4 block misses, got %d", misses)
+	}
+
+	// 再次读取全部命中本地
+	remote.ranges = 0
+	for _, r := range ranges {
+		readRange(t, p, "/f.bin", r[0], r[1])
+	}
+	stats, _ := p.CacheStats()
+	if remote.ranges != 0 || stats.Misses != misses || stats.Hits == 0 {
+		t.Errorf("Expected cache hits only, got %d remote requests, stats %+v", remote.ranges, stats)
+	}
+}
+
+func TestBlockCacheInvalidation(t *testing.T) {
+	dir := t.TempDir()
+	p, _ := newCacheTestProxy(t, dir, 64<<20)
+	ctx := context.Background()
+	c := p.cache
+
+	payload := bytes.Repeat([]byte("a"), 3*blockSize)
+	p.remote.Upload(ctx, "obj", bytes.NewReader(payload), int64(len(payload)))
+	read := func() []byte {
+		rc, err := p.remote.DownloadRange(ctx, "obj", blockSize+10, blockSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rc.Close()
+		data, _ := io.ReadAll(rc)
+		return data
+	}
+	read()
+	if st := c.stats(); st.Entries != 2 {
+		t.Fatalf("Expected 2 cached blocks, got %d", st.Entries)
+	}
+
+	// 缓存在重启后仍然可用
+	p2, remote2 := newCacheTestProxy(t, dir, 64<<20)
+	if st := p2.cache.stats(); st.Entries != 2 || st.Size != 2*blockSize {
+		t.Errorf("Expected reloaded cache with 2 blocks, got %+v", st)
+	}
+	remote2.files["obj"] = payload
+	p2.remote.DownloadRange(ctx, "obj", blockSize, 10)
+	if remote2.ranges != 0 {
+		t.Error("Expected reloaded block to be served locally")
+	}
+
+	// 覆盖后读取新内容
+	payload = bytes.Repeat([]byte("b"), 3*blockSize)
+	p.remote.Upload(ctx, "obj", bytes.NewReader(payload), int64(len(payload)))
+	if st := c.stats(); st.Entries != 0 {
+		t.Errorf("Expected blocks to be invalidated on upload, got %d", st.Entries)
+	}
+	if got := read(); !bytes.Equal(got, payload[:blockSize]) {
+		t.Error("Expected new content after overwrite")
+	}
+
+	p.remote.Rename(ctx, "obj", "obj2")
+	if st := c.stats(); st.Entries != 0 {
+		t.Errorf("Expected blocks to be invalidated on rename, got %d", st.Entries)
+	}
+	p.remote.Upload(ctx, "obj", bytes.NewReader(payload), int64(len(payload)))
+	read()
+	p.remote.Delete(ctx, "obj")
+	if st := c.stats(); st.Entries != 0 || st.Size != 0 {
+		t.Errorf("Expected blocks to be invalidated on delete, got %+v", st)
+	}
+}
+
+// truncatingRemote 模拟连接中途断开：范围请求最多返回 limit 字节
+type truncatingRemote struct {
+	*mockRemoteStorage
+	limit int64
+}
+
+func (m *truncatingRemote) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	rc, err := m.mockRemoteStorage.DownloadRange(ctx, name, start, length)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(rc, m.limit), rc}, nil
+}
+
+func TestBlockCacheTruncatedRead(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newCacheTestProxy(t, t.TempDir(), 64<<20)
+	c := p.cache
+	remote := &truncatingRemote{mockRemoteStorage: newMockRemoteStorage(), limit: blockSize + 100}
+	c.RemoteStorage = remote
+
+	payload := bytes.Repeat([]byte("a"), 3*blockSize)
+	remote.files["obj"] = payload
+	rc, err := c.DownloadRange(ctx, "obj", 0, 3*blockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadAll(rc)
+	rc.Close()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("Expected ErrUnexpectedEOF for truncated block, got %v", err)
+	}
+	if c.cached(cacheKey("obj"), 1) {
+		t.Error("Truncated block should not be cached")
+	}
+
+	// 对象真正的最后一块可以是短块
+	remote.limit = 1 << 30
+	remote.files["short"] = payload[:blockSize+100]
+	rc, err = c.DownloadRange(ctx, "short", 0, 2*blockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || len(data) != blockSize+100 {
+		t.Fatalf("Expected %d bytes, got %d (%v)", blockSize+100, len(data), err)
+	}
+	if !c.cached(cacheKey("short"), 1) {
+		t.Error("Last short block should be cached")
+	}
+}
+
+func TestBlockCacheEviction(t *testing.T) {
+	p, _ := newCacheTestProxy(t, t.TempDir(), 3*blockSize)
+	ctx := context.Background()
+	payload := make([]byte, 5*blockSize)
+	p.remote.Upload(ctx, "obj", bytes.NewReader(payload), int64(len(payload)))
+
+	for i := int64(0); i < 5; i++ {
+		rc, err := p.remote.DownloadRange(ctx, "obj", i*blockSize, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, rc)
+		rc.Close()
+	}
+	st := p.cache.stats()
+	if st.Entries != 3 || st.Evictions != 2 || st.Size > st.MaxSize {
+		t.Errorf("Unexpected stats after eviction: %+v", st)
+	}
+	// 最早读取的块已被淘汰
+	if p.cache.cached(cacheKey("obj"), 0) || !p.cache.cached(cacheKey("obj"), 4) {
+		t.Error("Expected least recently used block to be evicted")
+	}
+
+	// 超过容量 1/8 的范围读取不进入缓存
+	rc, _ := p.remote.DownloadRange(ctx, "obj", 0, 2*blockSize)
+	io.Copy(io.Discard, rc)
+	rc.Close()
+	if p.cache.cached(cacheKey("obj"), 0) {
+		t.Error("Expected large range to bypass the cache")
+	}
+}
@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"clearvault/internal/crypto"
+	"clearvault/internal/metadata"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// 块数不超过该值时块索引内联在元数据中（约 16MiB 明文、1KiB 索引），
+// 更大的文件把加密后的索引追加在远端对象末尾，避免元数据 JSON 随文件大小膨胀
+const maxInlineChunks = 256
+
+// 内存中缓存的远端块索引数量
+const maxCachedIndexes = 16
+
+// compressInfo encryptStream 产生的压缩格式元数据
+type compressInfo struct {
+	compression string
+	chunkIndex  []byte
+	indexOffset int64
+}
+
+func (ci compressInfo) apply(m *metadata.FileMeta) {
+	m.Compression = ci.compression
+	m.ChunkIndex = ci.chunkIndex
+	m.IndexOffset = ci.indexOffset
+}
+
+// encryptStream 按当前压缩设置加密，返回写入 FileMeta 的压缩算法和块索引
+func (p *Proxy) encryptStream(engine *crypto.Engine, r io.Reader, w io.Writer, salt []byte) (compressInfo, error) {
+	if p.compression == crypto.CompressionNone {
+		return compressInfo{}, engine.EncryptStream(r, w, salt)
+	}
+	lens, err := engine.EncryptStreamCompressed(r, w, salt)
+	if err != nil {
+		return compressInfo{}, err
+	}
+	ci := compressInfo{compression: p.compression}
+	if len(lens) <= maxInlineChunks {
+		ci.chunkIndex = crypto.EncodeChunkIndex(lens)
+		return ci, nil
+	}
+	sealed, err := engine.SealChunkIndex(lens, salt)
+	if err != nil {
+		return compressInfo{}, err
+	}
+	if _, err := w.Write(sealed); err != nil {
+		return compressInfo{}, err
+	}
+	ci.indexOffset = crypto.NewChunkIndex(lens).Size()
+	return ci, nil
+}
+
+// decryptStream 从 startChunk 开始解密，index 为 nil 表示未压缩的定长块格式
+func decryptStream(engine *crypto.Engine, meta *metadata.FileMeta, index *crypto.ChunkIndex, r io.Reader, w io.Writer, startChunk uint64) error {
+	if index == nil {
+		return engine.DecryptStreamFrom(r, w, meta.Salt, startChunk)
+	}
+	return engine.DecryptStreamCompressed(r, w, meta.Salt, startChunk, index)
+}
+
+// chunkIndex 返回压缩文件的块索引，未压缩文件返回 nil；索引在远端对象末尾时读取后缓存
+func (p *Proxy) chunkIndex(ctx context.Context, engine *crypto.Engine, meta *metadata.FileMeta) (*crypto.ChunkIndex, error) {
+	if !meta.IsCompressed() {
+		return nil, nil
+	}
+	if meta.Compression != crypto.CompressionZstd {
+		return nil, fmt.Errorf("unsupported compression: %s", meta.Compression)
+	}
+	if meta.IndexOffset == 0 {
+		lens, err := crypto.DecodeChunkIndex(meta.ChunkIndex)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.NewChunkIndex(lens), nil
+	}
+	// 远端对象名随内容变化（覆盖写入生成新名称），按名称缓存无需失效
+	if index := p.indexes.get(meta.RemoteName); index != nil {
+		return index, nil
+	}
+	chunks := chunkCount(meta.Size)
+	rc, err := p.openCipher(ctx, meta, meta.IndexOffset, crypto.SealedChunkIndexSize(chunks))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk index: %w", err)
+	}
+	defer rc.Close()
+	sealed, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk index: %w", err)
+	}
+	index, err := engine.OpenChunkIndex(sealed, meta.Salt, chunks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open chunk index: %w", err)
+	}
+	p.indexes.put(meta.RemoteName, index)
+	return index, nil
+}
+
+// cipherSize 返回未打包文件的密文大小（含末尾的块索引）
+func cipherSize(meta *metadata.FileMeta) (int64, error) {
+	if !meta.IsCompressed() {
+		return crypto.CalculateEncryptedSize(meta.Size), nil
+	}
+	if meta.IndexOffset > 0 {
+		return meta.IndexOffset + crypto.SealedChunkIndexSize(chunkCount(meta.Size)), nil
+	}
+	lens, err := crypto.DecodeChunkIndex(meta.ChunkIndex)
+	if err != nil {
+		return 0, err
+	}
+	return crypto.NewChunkIndex(lens).Size(), nil
+}
+
+func chunkCount(size int64) int {
+	return int((size + crypto.ChunkSize - 1) / crypto.ChunkSize)
+}
+
+// indexCache 按远端对象名缓存块索引，超出容量时淘汰最早加入的
+type indexCache struct {
+	mu    sync.Mutex
+	items map[string]*crypto.ChunkIndex
+	order []string
+}
+
+func (c *indexCache) get(name string) *crypto.ChunkIndex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.items[name]
+}
+
+func (c *indexCache) put(name string, index *crypto.ChunkIndex) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.items == nil {
+		c.items = make(map[string]*crypto.ChunkIndex)
+	}
+	if _, ok := c.items[name]; ok {
+		return
+	}
+	if len(c.order) >= maxCachedIndexes {
+		delete(c.items, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.items[name] = index
+	c.order = append(c.order, name)
+}
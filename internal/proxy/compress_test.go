@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"bytes"
+	"clearvault/internal/crypto"
+	"clearvault/internal/metadata"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+// TestCompressedUploadAndRange tests compressed uploads with full and ranged reads
+func TestCompressedUploadAndRange(t *testing.T) {
+	meta, err := metadata.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to init metadata: %v", err)
+	}
+	mockRemote := newMockRemoteStorage()
+	p, err := NewProxy(meta, mockRemote, "dGhpcy1pcy1hLTMyLWJ5dGUtbG9uZy1tYXN0ZXJrZXk=")
+	if err != nil {
+		t.Fatalf("NewProxy failed: %v", err)
+	}
+	if err := p.SetCompression("zstd"); err != nil {
+		t.Fatalf("SetCompression failed: %v", err)
+	}
+	if err := p.SetCompression("lz4"); err == nil {
+		t.Errorf("Expected error for unsupported compression")
+	}
+
+	// 可压缩文本后接随机数据，覆盖压缩块与原文块
+	text := bytes.Repeat([]byte("0123456789 clearvault\n"), 20000)
+	random, _ := crypto.GenerateRandomBytes(150000)
+	data := append(text, random...)
+
+	if err := p.UploadFile(context.Background(), "/mixed.log", bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+
+	m, _ := meta.Get("/mixed.log")
+	if m == nil || !m.IsCompressed() {
+		t.Fatalf("Expected compressed metadata, got %+v", m)
+	}
+	if stored := int64(len(mockRemote.files[m.RemoteName])); stored >= crypto.CalculateEncryptedSize(int64(len(data))) {
+		t.Errorf("Compressed object not smaller: %d bytes", stored)
+	}
+
+	rc, err := p.DownloadFile(context.Background(), "/mixed.log")
+	if err != nil {
+		t.Fatalf("DownloadFile failed: %v", err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if !bytes.Equal(got, data) {
+		t.Fatalf("Downloaded content mismatch: got %d bytes, want %d", len(got), len(data))
+	}
+
+	// DownloadRange 返回从起始块边界开始的明文
+	offsets := []int64{0, 100, crypto.ChunkSize + 5, int64(len(text)) + 10, int64(len(data)) - 1}
+	for _, off := range offsets {
+		rc, err := p.DownloadRange(context.Background(), "/mixed.log", off, 4096)
+		if err != nil {
+			t.Fatalf("DownloadRange(%d) failed: %v", off, err)
+		}
+		got, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("DownloadRange(%d) read failed: %v", off, err)
+		}
+		chunkStart := off / crypto.ChunkSize * crypto.ChunkSize
+		skip := off - chunkStart
+		if int64(len(got)) < skip+1 || !bytes.Equal(got[skip:], data[off:off+int64(len(got))-skip]) {
+			t.Errorf("DownloadRange(%d) content mismatch", off)
+		}
+	}
+}
+
+// TestCompressedLargeFileIndex 块数较多的文件把块索引放在远端对象末尾，元数据中不再内联
+func TestCompressedLargeFileIndex(t *testing.T) {
+	ctx := context.Background()
+	meta, err := metadata.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to init metadata: %v", err)
+	}
+	mockRemote := newMockRemoteStorage()
+	masterKey := "dGhpcy1pcy1hLTMyLWJ5dGUtbG9uZy1tYXN0ZXJrZXk="
+	p, err := NewProxy(meta, mockRemote, masterKey)
+	if err != nil {
+		t.Fatalf("NewProxy failed: %v", err)
+	}
+	p.SetCompression("zstd")
+
+	data := bytes.Repeat([]byte("0123456789 clearvault\n"), (maxInlineChunks+10)*crypto.ChunkSize/22)
+	if err := p.UploadFile(ctx, "/big.log", bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	m, _ := meta.Get("/big.log")
+	if m == nil || len(m.ChunkIndex) != 0 || m.IndexOffset == 0 {
+		t.Fatalf("Expected chunk index stored in the object, got %d inline bytes, offset %d", len(m.ChunkIndex), m.IndexOffset)
+	}
+	if n, _ := cipherSize(m); n != int64(len(mockRemote.files[m.RemoteName])) {
+		t.Errorf("cipherSize = %d, object has %d bytes", n, len(mockRemote.files[m.RemoteName]))
+	}
+
+	// 新实例没有缓存，范围读取时从远端读取索引
+	p2, _ := NewProxy(meta, mockRemote, masterKey)
+	for _, p := range []*Proxy{p2, p} {
+		off := int64(200*crypto.ChunkSize + 7)
+		rc, err := p.DownloadRange(ctx, "/big.log", off, 100)
+		if err != nil {
+			t.Fatalf("DownloadRange failed: %v", err)
+		}
+		got, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil || len(got) < 107 || !bytes.Equal(got[7:107], data[off:off+100]) {
+			t.Fatalf("DownloadRange content mismatch (%v)", err)
+		}
+	}
+	rc, err := p2.DownloadFile(ctx, "/big.log")
+	if err != nil {
+		t.Fatalf("DownloadFile failed: %v", err)
+	}
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("DownloadFile mismatch: got %d bytes, want %d (%v)", len(got), len(data), err)
+	}
+
+	// 篡改对象末尾的索引
+	obj := mockRemote.files[m.RemoteName]
+	obj[len(obj)-1] ^= 0xFF
+	p3, _ := NewProxy(meta, mockRemote, masterKey)
+	if _, err := p3.DownloadRange(ctx, "/big.log", 0, 10); !errors.Is(err, crypto.ErrDecryptFail) {
+		t.Errorf("Expected ErrDecryptFail for tampered index, got %v", err)
+	}
+}
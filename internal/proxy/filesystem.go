@@ -4,11 +4,14 @@ import (
 	"clearvault/internal/crypto"
 	"clearvault/internal/metadata"
 	"context"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
 	"path"
+	"strconv"
 	"time"
 
 	"golang.org/x/net/webdav"
@@ -37,6 +40,11 @@ func (fs *FileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode)
 	return fs.p.meta.Save(meta, name)
 }
 
+// CheckAvailable 远端离线时检查文件能否从本地读取
+func (fs *FileSystem) CheckAvailable(name string) error {
+	return fs.p.CheckAvailable(name)
+}
+
 func (fs *FileSystem) SetPendingSize(name string, size int64) {
 	fs.p.SetPendingSize(name, size)
 }
@@ -47,6 +55,7 @@ func (fs *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm
 	if name == "/" || name == "" || name == "." || name == "\\" {
 		return &ProxyFile{
 			fs:    fs,
+			ctx:   ctx,
 			name:  "/",
 			isDir: true,
 		}, nil
@@ -63,6 +72,7 @@ func (fs *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm
 
 		return &ProxyFile{
 			fs:    fs,
+			ctx:   ctx,
 			name:  name,
 			isNew: true,
 			size:  size,
@@ -74,6 +84,7 @@ func (fs *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm
 		log.Printf("FS OpenFile: returning empty file for memory placeholder '%s'", name)
 		return &ProxyFile{
 			fs:   fs,
+			ctx:  ctx,
 			name: name,
 			meta: &metadata.FileMeta{
 				Name:       path.Base(name),
@@ -94,6 +105,7 @@ func (fs *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm
 	if meta.IsDir {
 		return &ProxyFile{
 			fs:    fs,
+			ctx:   ctx,
 			name:  name,
 			isDir: true,
 		}, nil
@@ -101,13 +113,14 @@ func (fs *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm
 
 	return &ProxyFile{
 		fs:   fs,
+		ctx:  ctx,
 		name: name,
 		meta: meta,
 	}, nil
 }
 
 func (fs *FileSystem) RemoveAll(ctx context.Context, name string) error {
-	return fs.p.RemoveAll(name)
+	return fs.p.RemoveAll(ctx, name)
 }
 
 func (fs *FileSystem) Rename(ctx context.Context, oldName, newName string) error {
@@ -149,6 +162,7 @@ func (fs *FileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error
 
 type ProxyFile struct {
 	fs    *FileSystem
+	ctx   context.Context // 打开文件的请求上下文，请求结束后传输随之取消
 	name  string
 	meta  *metadata.FileMeta
 	isDir bool
@@ -163,9 +177,17 @@ type ProxyFile struct {
 
 	// Download fields
 	reader io.ReadCloser
+	ra     *FileReader // 启用预读时代替 reader
 	offset int64
 }
 
+func (f *ProxyFile) context() context.Context {
+	if f.ctx == nil {
+		return context.Background()
+	}
+	return f.ctx
+}
+
 func (f *ProxyFile) Close() error {
 	if f.isNew {
 		if !f.written {
@@ -181,6 +203,10 @@ func (f *ProxyFile) Close() error {
 		}
 		return nil
 	}
+	if f.ra != nil {
+		f.ra.Close()
+		f.ra = nil
+	}
 	if f.reader != nil {
 		err := f.reader.Close()
 		f.reader = nil
@@ -197,6 +223,22 @@ func (f *ProxyFile) Read(p []byte) (n int, err error) {
 		return 0, io.EOF
 	}
 
+	if f.ra == nil && f.reader == nil && f.meta != nil && f.meta.Size > 0 {
+		f.ra, err = f.fs.p.OpenReader(f.context(), f.name)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if f.ra != nil {
+		// 预读读取器按偏移读取，Seek 后已读取的分段可以复用
+		n, err = f.ra.ReadAt(p, f.offset)
+		f.offset += int64(n)
+		if err == io.EOF && n > 0 {
+			err = nil
+		}
+		return n, err
+	}
+
 	if f.reader == nil {
 		// Calculate length needed to read rest of file (or until EOF)
 		length := int64(0)
@@ -211,7 +253,7 @@ func (f *ProxyFile) Read(p []byte) (n int, err error) {
 			return 0, io.EOF
 		}
 
-		f.reader, err = f.fs.p.DownloadRange(f.name, f.offset, length)
+		f.reader, err = f.fs.p.DownloadRange(f.context(), f.name, f.offset, length)
 		if err != nil {
 			return 0, err
 		}
@@ -321,7 +363,39 @@ func (f *ProxyFile) Stat() (os.FileInfo, error) {
 			modTime: f.meta.UpdatedAt,
 		}, nil
 	}
-	return f.fs.Stat(context.Background(), f.name)
+	return f.fs.Stat(f.context(), f.name)
+}
+
+// DeadProps 目录附带 RFC 4331 空间属性（quota-available-bytes、quota-used-bytes），Windows 据此显示剩余空间
+// 远端不支持查询或值未知时不返回对应属性
+func (f *ProxyFile) DeadProps() (map[xml.Name]webdav.Property, error) {
+	if !f.isDir {
+		return nil, nil
+	}
+	used, available, err := f.fs.p.Quota(f.context())
+	if err != nil {
+		return nil, nil
+	}
+	props := make(map[xml.Name]webdav.Property, 2)
+	for local, v := range map[string]int64{"quota-available-bytes": available, "quota-used-bytes": used} {
+		if v < 0 {
+			continue
+		}
+		name := xml.Name{Space: "DAV:", Local: local}
+		props[name] = webdav.Property{XMLName: name, InnerXML: []byte(strconv.FormatInt(v, 10))}
+	}
+	return props, nil
+}
+
+// Patch 不保存自定义属性，与未实现 webdav.DeadPropsHolder 时一样全部拒绝
+func (f *ProxyFile) Patch(patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	pstat := webdav.Propstat{Status: http.StatusForbidden}
+	for _, patch := range patches {
+		for _, p := range patch.Props {
+			pstat.Props = append(pstat.Props, webdav.Property{XMLName: p.XMLName})
+		}
+	}
+	return []webdav.Propstat{pstat}, nil
 }
 
 func (f *ProxyFile) Write(p []byte) (n int, err error) {
@@ -336,8 +410,9 @@ func (f *ProxyFile) Write(p []byte) (n int, err error) {
 			f.written = true
 
 			go func() {
-				// UploadFile closes pr when done reading
-				err := f.fs.p.UploadFile(f.name, pr, f.size)
+				err := f.fs.p.UploadFile(f.context(), f.name, pr, f.size)
+				// 上传失败（如请求被取消）后让后续 Write 立即返回错误，而不是阻塞在管道上
+				pr.CloseWithError(err)
 				f.uploadErr <- err
 			}()
 		}
@@ -0,0 +1,145 @@
+package proxy
+
+import (
+	"bytes"
+	"clearvault/internal/remote"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// offlineRemote 可切换为不可达的内存远端
+type offlineRemote struct {
+	*queueRemote
+	down bool
+}
+
+func (m *offlineRemote) setDown(down bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.down = down
+}
+
+func (m *offlineRemote) isDown() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.down
+}
+
+func (m *offlineRemote) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	if m.isDown() {
+		return syscall.ECONNREFUSED
+	}
+	return m.queueRemote.Upload(ctx, name, data, size)
+}
+
+func (m *offlineRemote) Download(ctx context.Context, name string) (io.ReadCloser, error) {
+	return m.DownloadRange(ctx, name, 0, 0)
+}
+
+func (m *offlineRemote) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.down {
+		return nil, syscall.ECONNREFUSED
+	}
+	return m.mockRemoteStorage.DownloadRange(ctx, name, start, length)
+}
+
+func (m *offlineRemote) Stat(ctx context.Context, path string) (os.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.down {
+		return nil, syscall.ECONNREFUSED
+	}
+	return m.mockRemoteStorage.Stat(ctx, path)
+}
+
+func TestOfflineMode(t *testing.T) {
+	rs := &offlineRemote{queueRemote: &queueRemote{mockRemoteStorage: newMockRemoteStorage()}}
+	health := remote.WithHealth(rs, remote.Health{FailureThreshold: 1, ProbeInterval: 5 * time.Millisecond})
+	meta := newTestMeta(t)
+	p, err := NewProxy(meta, health, "dGhpcy1pcy1hLTMyLWJ5dGUtbG9uZy1tYXN0ZXJrZXk=")
+	if err != nil {
+		t.Fatalf("NewProxy failed: %v", err)
+	}
+	if err := p.EnableBlockCache(BlockCacheOptions{Dir: t.TempDir(), MaxSize: 64 << 20}); err != nil {
+		t.Fatalf("EnableBlockCache failed: %v", err)
+	}
+	if err := p.EnableWriteBack(WriteBackOptions{StagingDir: t.TempDir(), RetryDelay: 10 * time.Millisecond}); err != nil {
+		t.Fatalf("EnableWriteBack failed: %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+	ctx := context.Background()
+
+	content := make([]byte, 200*1024+7)
+	rand.New(rand.NewSource(3)).Read(content)
+	for _, name := range []string{"/cached.bin", "/cold.bin"} {
+		if err := p.UploadFile(ctx, name, bytes.NewReader(content), int64(len(content))); err != nil {
+			t.Fatalf("UploadFile failed: %v", err)
+		}
+	}
+	waitQueueEmpty(t, p)
+	readAllFile(t, p, "/cached.bin")
+
+	rs.setDown(true)
+	p.remote.Stat(ctx, "probe")
+	if st, ok := p.RemoteHealth(); !ok || st.Online {
+		t.Fatalf("Expected remote to be offline, got %+v", st)
+	}
+
+	// 元数据操作与已缓存的文件不受影响
+	if entries, err := p.ReadDir("/"); err != nil || len(entries) != 2 {
+		t.Errorf("ReadDir while offline failed: %d entries, %v", len(entries), err)
+	}
+	if err := p.CheckAvailable("/cached.bin"); err != nil {
+		t.Errorf("Expected cached file to be available, got %v", err)
+	}
+	if got := readAllFile(t, p, "/cached.bin"); !bytes.Equal(got, content) {
+		t.Error("Cached content mismatch while offline")
+	}
+
+	// 未缓存的文件立即失败
+	if err := p.CheckAvailable("/cold.bin"); !errors.Is(err, remote.ErrRemoteOffline) {
+		t.Errorf("Expected ErrRemoteOffline, got %v", err)
+	}
+	rc, err := p.DownloadFile(ctx, "/cold.bin")
+	if err == nil {
+		_, err = io.ReadAll(rc)
+		rc.Close()
+	}
+	if !errors.Is(err, remote.ErrRemoteOffline) {
+		t.Errorf("Expected ErrRemoteOffline reading uncached file, got %v", err)
+	}
+
+	// 离线期间的写入进入队列且可读，不消耗重试次数
+	if err := p.UploadFile(ctx, "/new.txt", bytes.NewReader([]byte("queued")), 6); err != nil {
+		t.Fatalf("UploadFile while offline failed: %v", err)
+	}
+	if err := p.CheckAvailable("/new.txt"); err != nil {
+		t.Errorf("Expected staged file to be available, got %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if q, _ := p.WriteBackQueue(); len(q) != 1 || q[0].Attempts != 0 {
+		t.Fatalf("Expected queued upload without attempts, got %+v", q)
+	}
+
+	// 远端恢复后自动切回在线并上传队列
+	rs.setDown(false)
+	waitQueueEmpty(t, p)
+	fm, _ := meta.Get("/new.txt")
+	if !rs.has(fm.RemoteName) {
+		t.Fatal("Expected queued file to be uploaded after recovery")
+	}
+	if err := p.CheckAvailable("/cold.bin"); err != nil {
+		t.Errorf("Expected no error when online, got %v", err)
+	}
+	if got := readAllFile(t, p, "/cold.bin"); !bytes.Equal(got, content) {
+		t.Error("Content mismatch after recovery")
+	}
+}
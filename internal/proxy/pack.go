@@ -0,0 +1,621 @@
+package proxy
+
+import (
+	"bytes"
+	"clearvault/internal/crypto"
+	"clearvault/internal/metadata"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PackOptions 小文件打包配置
+type PackOptions struct {
+	Threshold     int64         // 明文大小不超过该值的文件写入 pack
+	TargetSize    int64         // pack 达到该大小后封存并上传
+	FlushInterval time.Duration // 未写满的 pack 最长等待时间
+	StagingDir    string        // 本地暂存目录（未上传完成的 pack）
+}
+
+const (
+	defaultPackThreshold     = 256 * 1024
+	defaultPackTargetSize    = 8 * 1024 * 1024
+	defaultPackFlushInterval = 30 * time.Second
+
+	packFileSuffix = ".pack"
+	packIndexFile  = "index.json"
+)
+
+// packWriter 将多个小文件的密文顺序追加到共享 pack 对象中
+// 每个条目使用各自的 FEK/Salt 独立加密，因此 pack 内条目可直接按偏移做范围读取
+type packWriter struct {
+	p    *Proxy
+	opts PackOptions
+
+	mu      sync.Mutex
+	current string              // 当前写入中的 pack 名
+	file    *os.File            // 当前 pack 的暂存文件
+	size    int64               // 当前 pack 已写入字节数
+	sealed  map[string]struct{} // 已封存但尚未上传成功的 pack
+	known   map[string]struct{} // 所有已上传的 pack（用于回收完全失效的 pack）
+	timer   *time.Timer
+	closed  bool
+	flushMu sync.Mutex
+}
+
+// EnablePacking 启用小文件打包
+// 启用时会把暂存目录中遗留的 pack（例如进程崩溃前未上传的）重新加入上传队列
+func (p *Proxy) EnablePacking(opts PackOptions) error {
+	if opts.Threshold <= 0 {
+		opts.Threshold = defaultPackThreshold
+	}
+	if opts.TargetSize <= 0 {
+		opts.TargetSize = defaultPackTargetSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = defaultPackFlushInterval
+	}
+	if opts.StagingDir == "" {
+		return fmt.Errorf("pack staging directory is required")
+	}
+	if err := os.MkdirAll(opts.StagingDir, 0755); err != nil {
+		return fmt.Errorf("failed to create pack staging directory: %w", err)
+	}
+
+	w := &packWriter{
+		p:      p,
+		opts:   opts,
+		sealed: make(map[string]struct{}),
+		known:  make(map[string]struct{}),
+	}
+	if err := w.loadIndex(); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(opts.StagingDir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), packFileSuffix) {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), packFileSuffix)
+		log.Printf("Proxy: Found staged pack '%s', scheduling upload", name)
+		w.sealed[name] = struct{}{}
+	}
+
+	p.packer = w
+	if len(w.sealed) > 0 {
+		go w.uploadSealed()
+	}
+	return nil
+}
+
+// EnablePackCompaction 只为 CompactPacks 准备 pack 索引，供独立运行的 repack 使用
+// 暂存目录可能属于正在运行的服务：不扫描、不上传其中的 pack（可能仍在追加），也不接受新的写入
+func (p *Proxy) EnablePackCompaction(stagingDir string) error {
+	if stagingDir == "" {
+		return fmt.Errorf("pack staging directory is required")
+	}
+	w := &packWriter{
+		p:      p,
+		opts:   PackOptions{StagingDir: stagingDir},
+		sealed: make(map[string]struct{}),
+		known:  make(map[string]struct{}),
+		closed: true,
+	}
+	if err := w.loadIndex(); err != nil {
+		return err
+	}
+	p.packer = w
+	return nil
+}
+
+func (w *packWriter) stagingPath(name string) string {
+	return filepath.Join(w.opts.StagingDir, name+packFileSuffix)
+}
+
+// Append 追加一个条目的密文，返回所在 pack 名与偏移
+func (w *packWriter) Append(ciphertext []byte) (string, int64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return "", 0, fmt.Errorf("pack writer closed")
+	}
+
+	if w.file == nil {
+		name := w.p.generateRemoteName()
+		f, err := os.OpenFile(w.stagingPath(name), os.O_CREATE|os.O_RDWR|os.O_EXCL, 0600)
+		if err != nil {
+			return "", 0, err
+		}
+		w.current = name
+		w.file = f
+		w.size = 0
+		w.timer = time.AfterFunc(w.opts.FlushInterval, func() {
+			if err := w.Flush(); err != nil {
+				log.Printf("Proxy: Pack flush failed: %v", err)
+			}
+		})
+	}
+
+	offset := w.size
+	if _, err := w.file.Write(ciphertext); err != nil {
+		return "", 0, err
+	}
+	// 元数据会在 Append 返回后保存，这里先落盘保证重启后能恢复
+	if err := w.file.Sync(); err != nil {
+		return "", 0, err
+	}
+	w.size += int64(len(ciphertext))
+	name := w.current
+
+	if w.size >= w.opts.TargetSize {
+		if err := w.sealLocked(); err != nil {
+			return "", 0, err
+		}
+		go w.uploadSealed()
+	}
+	return name, offset, nil
+}
+
+// sealLocked 封存当前 pack，调用者需持有 w.mu
+func (w *packWriter) sealLocked() error {
+	if w.file == nil {
+		return nil
+	}
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	err := w.file.Close()
+	w.sealed[w.current] = struct{}{}
+	log.Printf("Proxy: Sealed pack '%s' (%d bytes)", w.current, w.size)
+	w.file = nil
+	w.current = ""
+	w.size = 0
+	return err
+}
+
+// Flush 封存当前 pack 并上传所有待上传的 pack
+func (w *packWriter) Flush() error {
+	w.mu.Lock()
+	err := w.sealLocked()
+	w.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return w.uploadSealed()
+}
+
+// uploadSealed 上传所有已封存的 pack，失败的会保留在队列中等待下次重试
+func (w *packWriter) uploadSealed() error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	names := make([]string, 0, len(w.sealed))
+	for name := range w.sealed {
+		names = append(names, name)
+	}
+	w.mu.Unlock()
+
+	var firstErr error
+	for _, name := range names {
+		if err := w.uploadPack(name); err != nil {
+			log.Printf("Proxy: Failed to upload pack '%s': %v", name, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+	}
+	if firstErr != nil {
+		w.scheduleRetry()
+	}
+	return firstErr
+}
+
+func (w *packWriter) scheduleRetry() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	time.AfterFunc(w.opts.FlushInterval, func() {
+		_ = w.uploadSealed()
+	})
+}
+
+func (w *packWriter) uploadPack(name string) error {
+	local := w.stagingPath(name)
+	f, err := os.Open(local)
+	if err != nil {
+		return err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	log.Printf("Proxy: Uploading pack '%s' (%d bytes)", name, st.Size())
+	err = w.p.remote.Upload(context.Background(), name, f, st.Size())
+	f.Close()
+	if err == nil {
+		err = w.p.verifyUpload(context.Background(), name, st.Size(), nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	delete(w.sealed, name)
+	w.known[name] = struct{}{}
+	err = w.saveIndexLocked()
+	w.mu.Unlock()
+	if err != nil {
+		log.Printf("Proxy: Failed to save pack index: %v", err)
+	}
+
+	if err := os.Remove(local); err != nil {
+		log.Printf("Proxy: Failed to remove staged pack '%s': %v", local, err)
+	}
+	return nil
+}
+
+// staged 判断 pack 是否仍在本地暂存（尚未上传）
+func (w *packWriter) staged(name string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stagedLocked(name)
+}
+
+func (w *packWriter) stagedLocked(name string) bool {
+	_, sealed := w.sealed[name]
+	return sealed || name == w.current
+}
+
+// openRange 读取 pack 中的一段密文，尚未上传的 pack 从本地暂存文件读取
+func (w *packWriter) openRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	w.mu.Lock()
+	staged := w.stagedLocked(name)
+	var f *os.File
+	var err error
+	if staged {
+		f, err = os.Open(w.stagingPath(name))
+	}
+	w.mu.Unlock()
+
+	if !staged || errors.Is(err, os.ErrNotExist) {
+		// 暂存文件可能刚刚上传完成并被删除
+		return w.p.remote.DownloadRange(ctx, name, start, length)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &readCloser{
+		Reader: io.NewSectionReader(f, start, length),
+		Closer: f,
+	}, nil
+}
+
+// Close 上传所有未完成的 pack 并停止后台任务
+func (w *packWriter) Close() error {
+	err := w.Flush()
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+	return err
+}
+
+func (w *packWriter) loadIndex() error {
+	data, err := os.ReadFile(filepath.Join(w.opts.StagingDir, packIndexFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		log.Printf("Proxy: Ignoring corrupted pack index: %v", err)
+		return nil
+	}
+	for _, name := range names {
+		w.known[name] = struct{}{}
+	}
+	return nil
+}
+
+func (w *packWriter) saveIndexLocked() error {
+	names := make([]string, 0, len(w.known))
+	for name := range w.known {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	data, err := json.Marshal(names)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(w.opts.StagingDir, packIndexFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(w.opts.StagingDir, packIndexFile))
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// readSmall 尝试读取最多 limit 字节，返回数据以及数据流是否已经结束
+func readSmall(r io.Reader, limit int64) ([]byte, bool, error) {
+	buf := &bytes.Buffer{}
+	n, err := io.CopyN(buf, r, limit+1)
+	if err != nil && err != io.EOF {
+		return nil, false, err
+	}
+	return buf.Bytes(), n <= limit, nil
+}
+
+// uploadPacked 将小文件加密后追加到 pack 中并保存元数据
+func (p *Proxy) uploadPacked(pname string, plaintext []byte) error {
+	fek, err := crypto.GenerateRandomBytes(32)
+	if err != nil {
+		return err
+	}
+	salt, err := crypto.GenerateRandomBytes(12)
+	if err != nil {
+		return err
+	}
+	encryptedFEK, recoveryFEK, err := p.wrapFEK(fek)
+	if err != nil {
+		return err
+	}
+	engine, err := crypto.NewEngine(fek)
+	if err != nil {
+		return err
+	}
+
+	cipherBuf := &bytes.Buffer{}
+	ci, err := p.encryptStream(engine, bytes.NewReader(plaintext), cipherBuf, salt)
+	if err != nil {
+		return fmt.Errorf("encryption failed: %w", err)
+	}
+
+	packName, offset, err := p.packer.Append(cipherBuf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	meta := &metadata.FileMeta{
+		Name:       path.Base(pname),
+		RemoteName: p.generateRemoteName(),
+		Size:       int64(len(plaintext)),
+		IsDir:      false,
+		FEK:        encryptedFEK,
+		Salt:       salt,
+		UpdatedAt:  time.Now(),
+		PackName:   packName,
+		PackOffset: offset,
+		PackLength: int64(cipherBuf.Len()),
+
+		RecoveryFEK: recoveryFEK,
+	}
+	ci.apply(meta)
+	err = p.meta.Save(meta, pname)
+	log.Printf("Proxy: UploadFile packed '%s' into '%s' at %d (size: %d, err: %v)", pname, packName, offset, len(plaintext), err)
+	return err
+}
+
+// CompactStats 压缩整理结果
+type CompactStats struct {
+	PacksScanned   int   `json:"packs_scanned"`
+	PacksRewritten int   `json:"packs_rewritten"`
+	PacksDeleted   int   `json:"packs_deleted"`
+	BytesReclaimed int64 `json:"bytes_reclaimed"`
+}
+
+type packEntry struct {
+	path string
+	meta metadata.FileMeta
+}
+
+// CompactPacks 回收 pack 中已删除条目占用的空间
+// 失效字节占比不低于 minWaste 的 pack 会被重写为只包含存活条目的新 pack，
+// 不再被任何元数据引用的 pack 会被直接删除
+func (p *Proxy) CompactPacks(ctx context.Context, minWaste float64) (*CompactStats, error) {
+	if p.packer == nil {
+		return nil, fmt.Errorf("packing is not enabled")
+	}
+	w := p.packer
+	if err := w.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to flush packs before compaction: %w", err)
+	}
+
+	live := make(map[string][]packEntry)
+	err := metadata.Walk(p.meta, "/", func(fp string, m *metadata.FileMeta) error {
+		if m.IsPacked() {
+			live[m.PackName] = append(live[m.PackName], packEntry{path: fp, meta: *m})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	names := make(map[string]struct{}, len(w.known)+len(live))
+	for name := range w.known {
+		names[name] = struct{}{}
+	}
+	w.mu.Unlock()
+	for name := range live {
+		names[name] = struct{}{}
+	}
+
+	stats := &CompactStats{}
+	for name := range names {
+		stats.PacksScanned++
+		entries := live[name]
+
+		info, err := p.remote.Stat(ctx, name)
+		if err != nil {
+			log.Printf("Proxy: Compact skipping pack '%s': %v", name, err)
+			continue
+		}
+
+		if len(entries) == 0 {
+			log.Printf("Proxy: Compact deleting unreferenced pack '%s'", name)
+			if err := p.remote.Delete(ctx, name); err != nil {
+				log.Printf("Proxy: Compact failed to delete pack '%s': %v", name, err)
+				continue
+			}
+			w.forget(name)
+			stats.PacksDeleted++
+			stats.BytesReclaimed += info.Size()
+			continue
+		}
+
+		var liveBytes int64
+		for _, e := range entries {
+			liveBytes += e.meta.PackLength
+		}
+		if info.Size() == 0 || float64(info.Size()-liveBytes)/float64(info.Size()) < minWaste {
+			continue
+		}
+
+		if err := p.rewritePack(ctx, name, entries); err != nil {
+			log.Printf("Proxy: Compact failed to rewrite pack '%s': %v", name, err)
+			continue
+		}
+		stats.PacksRewritten++
+		stats.BytesReclaimed += info.Size() - liveBytes
+	}
+
+	log.Printf("Proxy: Compact finished: %+v", *stats)
+	return stats, nil
+}
+
+// rewritePack 将存活条目复制到新 pack，更新元数据后删除旧 pack
+// 条目密文原样复制，无需重新加密
+func (p *Proxy) rewritePack(ctx context.Context, oldName string, entries []packEntry) error {
+	w := p.packer
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].meta.PackOffset < entries[j].meta.PackOffset
+	})
+
+	newName := p.generateRemoteName()
+	// 不使用 .pack 后缀，服务启动时不会把重写中的文件当作待上传的 pack
+	f, err := os.CreateTemp(w.opts.StagingDir, "repack-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	offsets := make([]int64, len(entries))
+	var size int64
+	for i, e := range entries {
+		rc, err := p.remote.DownloadRange(ctx, oldName, e.meta.PackOffset, e.meta.PackLength)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		n, err := io.Copy(f, rc)
+		rc.Close()
+		if err != nil {
+			f.Close()
+			return err
+		}
+		if n != e.meta.PackLength {
+			f.Close()
+			return fmt.Errorf("short read for '%s': got %d, want %d", e.path, n, e.meta.PackLength)
+		}
+		offsets[i] = size
+		size += n
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	err = p.remote.Upload(ctx, newName, f, size)
+	f.Close()
+	if err == nil {
+		if err = p.verifyUpload(ctx, newName, size, nil); err != nil {
+			p.discardUpload(ctx, newName)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	w.known[newName] = struct{}{}
+	if err := w.saveIndexLocked(); err != nil {
+		log.Printf("Proxy: Failed to save pack index: %v", err)
+	}
+	w.mu.Unlock()
+
+	// entries 是遍历元数据时的快照，repack 可能与运行中的服务同时执行：
+	// 保存前重新读取，条目已被删除、覆盖或重命名时跳过，不把旧元数据写回
+	skipped := 0
+	for i, e := range entries {
+		cur, err := p.meta.Get(e.path)
+		if err != nil || cur == nil || !samePackEntry(cur, &e.meta) {
+			skipped++
+			log.Printf("Proxy: Compact skipping '%s': changed during repack", e.path)
+			continue
+		}
+		cur.PackName = newName
+		cur.PackOffset = offsets[i]
+		if err := p.meta.Save(cur, e.path); err != nil {
+			// 旧 pack 仍然保留，已迁移的条目指向新 pack，数据不会丢失
+			return fmt.Errorf("failed to update metadata for '%s': %w", e.path, err)
+		}
+	}
+
+	// 被跳过的条目可能已重命名到其他路径但仍引用旧 pack，保留旧 pack，
+	// 下次 repack 时不再被引用的 pack 会被删除
+	if skipped > 0 {
+		log.Printf("Proxy: Rewrote pack '%s' -> '%s', kept old pack (%d entries changed during repack)", oldName, newName, skipped)
+		return nil
+	}
+	if err := p.remote.Delete(ctx, oldName); err != nil {
+		log.Printf("Proxy: Failed to delete old pack '%s': %v", oldName, err)
+		return nil
+	}
+	w.forget(oldName)
+	log.Printf("Proxy: Rewrote pack '%s' -> '%s' (%d entries, %d bytes)", oldName, newName, len(entries), size)
+	return nil
+}
+
+// samePackEntry 判断当前元数据是否仍指向快照中的同一个 pack 条目
+func samePackEntry(cur, snap *metadata.FileMeta) bool {
+	return cur.PackName == snap.PackName &&
+		cur.PackOffset == snap.PackOffset &&
+		cur.PackLength == snap.PackLength &&
+		cur.RemoteName == snap.RemoteName
+}
+
+func (w *packWriter) forget(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.known, name)
+	if err := w.saveIndexLocked(); err != nil {
+		log.Printf("Proxy: Failed to save pack index: %v", err)
+	}
+}
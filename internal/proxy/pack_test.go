@@ -0,0 +1,339 @@
+package proxy
+
+import (
+	"bytes"
+	"clearvault/internal/metadata"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newPackTestProxy(t *testing.T, mockRemote *mockRemoteStorage, stagingDir string) (*Proxy, metadata.Storage) {
+	t.Helper()
+	meta, err := metadata.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to init metadata: %v", err)
+	}
+	masterKey := "dGhpcy1pcy1hLTMyLWJ5dGUtbG9uZy1tYXN0ZXJrZXk="
+	p, err := NewProxy(meta, mockRemote, masterKey)
+	if err != nil {
+		t.Fatalf("NewProxy failed: %v", err)
+	}
+	err = p.EnablePacking(PackOptions{
+		Threshold:     1024,
+		TargetSize:    64 * 1024,
+		FlushInterval: time.Hour,
+		StagingDir:    stagingDir,
+	})
+	if err != nil {
+		t.Fatalf("EnablePacking failed: %v", err)
+	}
+	return p, meta
+}
+
+func readAllFile(t *testing.T, p *Proxy, name string) []byte {
+	t.Helper()
+	rc, err := p.DownloadFile(context.Background(), name)
+	if err != nil {
+		t.Fatalf("DownloadFile %s failed: %v", name, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("Read %s failed: %v", name, err)
+	}
+	return data
+}
+
+func TestPackSmallFiles(t *testing.T) {
+	mockRemote := newMockRemoteStorage()
+	p, meta := newPackTestProxy(t, mockRemote, t.TempDir())
+
+	files := map[string][]byte{
+		"/a.txt": []byte("alpha"),
+		"/b.txt": []byte("bravo bravo"),
+		"/c.txt": {},
+	}
+	for name, content := range files {
+		// 未知大小（FUSE 写入）也应被识别为小文件
+		if err := p.UploadFile(context.Background(), name, bytes.NewReader(content), -1); err != nil {
+			t.Fatalf("UploadFile %s failed: %v", name, err)
+		}
+	}
+
+	var packName string
+	for name := range files {
+		m, err := meta.Get(name)
+		if err != nil || m == nil {
+			t.Fatalf("meta for %s missing: %v", name, err)
+		}
+		if !m.IsPacked() {
+			t.Fatalf("%s should be packed", name)
+		}
+		if packName == "" {
+			packName = m.PackName
+		} else if m.PackName != packName {
+			t.Errorf("%s in pack %s, want %s", name, m.PackName, packName)
+		}
+	}
+
+	if len(mockRemote.files) != 0 {
+		t.Errorf("Expected no remote objects before flush, got %d", len(mockRemote.files))
+	}
+
+	// 上传前从本地暂存读取
+	for name, content := range files {
+		if got := readAllFile(t, p, name); !bytes.Equal(got, content) {
+			t.Errorf("%s before flush: got %q, want %q", name, got, content)
+		}
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, ok := mockRemote.files[packName]; !ok {
+		t.Fatalf("pack %s not uploaded", packName)
+	}
+
+	for name, content := range files {
+		if got := readAllFile(t, p, name); !bytes.Equal(got, content) {
+			t.Errorf("%s after flush: got %q, want %q", name, got, content)
+		}
+	}
+
+	rc, err := p.DownloadRange(context.Background(), "/b.txt", 6, 5)
+	if err != nil {
+		t.Fatalf("DownloadRange failed: %v", err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(got) != "bravo bravo" {
+		// DownloadRange 返回从块起始位置开始的数据
+		t.Errorf("DownloadRange got %q", got)
+	}
+}
+
+func TestPackLargeFileBypass(t *testing.T) {
+	mockRemote := newMockRemoteStorage()
+	p, meta := newPackTestProxy(t, mockRemote, t.TempDir())
+
+	content := bytes.Repeat([]byte("x"), 4096)
+	if err := p.UploadFile(context.Background(), "/large.bin", bytes.NewReader(content), -1); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	m, _ := meta.Get("/large.bin")
+	if m == nil || m.IsPacked() {
+		t.Fatalf("large file should not be packed: %+v", m)
+	}
+	if _, ok := mockRemote.files[m.RemoteName]; !ok {
+		t.Fatalf("remote object for large file missing")
+	}
+	if got := readAllFile(t, p, "/large.bin"); !bytes.Equal(got, content) {
+		t.Errorf("large file content mismatch")
+	}
+}
+
+func TestCompactPacks(t *testing.T) {
+	mockRemote := newMockRemoteStorage()
+	p, meta := newPackTestProxy(t, mockRemote, t.TempDir())
+
+	for i := 0; i < 6; i++ {
+		content := []byte(fmt.Sprintf("file number %d", i))
+		if err := p.UploadFile(context.Background(), fmt.Sprintf("/f%d.txt", i), bytes.NewReader(content), int64(len(content))); err != nil {
+			t.Fatalf("UploadFile failed: %v", err)
+		}
+	}
+	if err := p.packer.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	m0, _ := meta.Get("/f0.txt")
+	oldPack := m0.PackName
+
+	for i := 0; i < 4; i++ {
+		if err := p.RemoveAll(context.Background(), fmt.Sprintf("/f%d.txt", i)); err != nil {
+			t.Fatalf("RemoveAll failed: %v", err)
+		}
+	}
+	if _, ok := mockRemote.files[oldPack]; !ok {
+		t.Fatalf("pack should survive deletion of entries")
+	}
+
+	stats, err := p.CompactPacks(context.Background(), 0.5)
+	if err != nil {
+		t.Fatalf("CompactPacks failed: %v", err)
+	}
+	if stats.PacksRewritten != 1 {
+		t.Errorf("PacksRewritten = %d, want 1", stats.PacksRewritten)
+	}
+	if _, ok := mockRemote.files[oldPack]; ok {
+		t.Errorf("old pack should be deleted after compaction")
+	}
+
+	for i := 4; i < 6; i++ {
+		name := fmt.Sprintf("/f%d.txt", i)
+		m, _ := meta.Get(name)
+		if m.PackName == oldPack {
+			t.Errorf("%s still references old pack", name)
+		}
+		want := fmt.Sprintf("file number %d", i)
+		if got := readAllFile(t, p, name); string(got) != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+
+	// 删除剩余条目后，pack 完全失效，应被直接删除
+	p.RemoveAll(context.Background(), "/f4.txt")
+	p.RemoveAll(context.Background(), "/f5.txt")
+	stats, err = p.CompactPacks(context.Background(), 0.5)
+	if err != nil {
+		t.Fatalf("CompactPacks failed: %v", err)
+	}
+	if stats.PacksDeleted != 1 {
+		t.Errorf("PacksDeleted = %d, want 1", stats.PacksDeleted)
+	}
+	if len(mockRemote.files) != 0 {
+		t.Errorf("Expected all packs reclaimed, %d objects left", len(mockRemote.files))
+	}
+}
+
+func TestCompactPacks_ConcurrentChanges(t *testing.T) {
+	ctx := context.Background()
+	mockRemote := newMockRemoteStorage()
+	p, meta := newPackTestProxy(t, mockRemote, t.TempDir())
+
+	for i := 0; i < 4; i++ {
+		content := []byte(fmt.Sprintf("file number %d", i))
+		if err := p.UploadFile(ctx, fmt.Sprintf("/f%d.txt", i), bytes.NewReader(content), int64(len(content))); err != nil {
+			t.Fatalf("UploadFile failed: %v", err)
+		}
+	}
+	if err := p.packer.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	m0, _ := meta.Get("/f0.txt")
+	oldPack := m0.PackName
+
+	// repack 遍历元数据得到的快照
+	var entries []packEntry
+	metadata.Walk(meta, "/", func(fp string, m *metadata.FileMeta) error {
+		entries = append(entries, packEntry{path: fp, meta: *m})
+		return nil
+	})
+
+	// 快照之后服务端删除、重命名、覆盖了部分文件
+	if err := p.RemoveAll(ctx, "/f0.txt"); err != nil {
+		t.Fatalf("RemoveAll failed: %v", err)
+	}
+	if err := p.RenameFile("/f1.txt", "/g1.txt"); err != nil {
+		t.Fatalf("RenameFile failed: %v", err)
+	}
+	updated := []byte("overwritten")
+	if err := p.UploadFile(ctx, "/f2.txt", bytes.NewReader(updated), int64(len(updated))); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	if err := p.packer.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	if err := p.rewritePack(ctx, oldPack, entries); err != nil {
+		t.Fatalf("rewritePack failed: %v", err)
+	}
+	if m, _ := meta.Get("/f0.txt"); m != nil {
+		t.Errorf("Deleted file was restored: %+v", m)
+	}
+	if m, _ := meta.Get("/f1.txt"); m != nil {
+		t.Errorf("Renamed file was duplicated: %+v", m)
+	}
+	if got := readAllFile(t, p, "/g1.txt"); string(got) != "file number 1" {
+		t.Errorf("/g1.txt: got %q", got)
+	}
+	if got := readAllFile(t, p, "/f2.txt"); string(got) != string(updated) {
+		t.Errorf("Overwrite was reverted: got %q", got)
+	}
+	if m, _ := meta.Get("/f3.txt"); m == nil || m.PackName == oldPack {
+		t.Errorf("Unchanged entry should move to the new pack: %+v", m)
+	}
+	if got := readAllFile(t, p, "/f3.txt"); string(got) != "file number 3" {
+		t.Errorf("/f3.txt: got %q", got)
+	}
+	// 重命名后的条目仍引用旧 pack，旧 pack 必须保留
+	if _, ok := mockRemote.files[oldPack]; !ok {
+		t.Error("Old pack should be kept while entries changed during repack")
+	}
+}
+
+func TestPackRecoveryAfterRestart(t *testing.T) {
+	stagingDir := t.TempDir()
+	mockRemote := newMockRemoteStorage()
+	p, meta := newPackTestProxy(t, mockRemote, stagingDir)
+
+	if err := p.UploadFile(context.Background(), "/crash.txt", bytes.NewReader([]byte("survives")), 8); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	m, _ := meta.Get("/crash.txt")
+	if _, err := os.Stat(filepath.Join(stagingDir, m.PackName+packFileSuffix)); err != nil {
+		t.Fatalf("staged pack missing: %v", err)
+	}
+
+	// 模拟进程崩溃：不调用 Close，直接用新的 Proxy 接管暂存目录
+	p2, err := NewProxy(meta, mockRemote, "dGhpcy1pcy1hLTMyLWJ5dGUtbG9uZy1tYXN0ZXJrZXk=")
+	if err != nil {
+		t.Fatalf("NewProxy failed: %v", err)
+	}
+	if err := p2.EnablePacking(PackOptions{StagingDir: stagingDir, FlushInterval: time.Hour}); err != nil {
+		t.Fatalf("EnablePacking failed: %v", err)
+	}
+	if err := p2.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, ok := mockRemote.files[m.PackName]; !ok {
+		t.Fatalf("staged pack not uploaded after restart")
+	}
+	if got := readAllFile(t, p2, "/crash.txt"); string(got) != "survives" {
+		t.Errorf("got %q, want %q", got, "survives")
+	}
+}
+
+func TestPackCompactionLeavesStagedPacks(t *testing.T) {
+	stagingDir := t.TempDir()
+	mockRemote := newMockRemoteStorage()
+	p, meta := newPackTestProxy(t, mockRemote, stagingDir)
+
+	if err := p.UploadFile(context.Background(), "/open.txt", bytes.NewReader([]byte("appending")), 9); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	m, _ := meta.Get("/open.txt")
+	staged := filepath.Join(stagingDir, m.PackName+packFileSuffix)
+
+	// 独立的 repack 进程与运行中的服务共享暂存目录
+	r, err := NewProxy(meta, mockRemote, "dGhpcy1pcy1hLTMyLWJ5dGUtbG9uZy1tYXN0ZXJrZXk=")
+	if err != nil {
+		t.Fatalf("NewProxy failed: %v", err)
+	}
+	if err := r.EnablePackCompaction(stagingDir); err != nil {
+		t.Fatalf("EnablePackCompaction failed: %v", err)
+	}
+	if _, err := r.CompactPacks(context.Background(), 0.5); err != nil {
+		t.Fatalf("CompactPacks failed: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := os.Stat(staged); err != nil {
+		t.Errorf("Staged pack was touched by repack: %v", err)
+	}
+	if _, ok := mockRemote.files[m.PackName]; ok {
+		t.Error("Staged pack was uploaded by repack")
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if got := readAllFile(t, p, "/open.txt"); string(got) != "appending" {
+		t.Errorf("got %q, want %q", got, "appending")
+	}
+}
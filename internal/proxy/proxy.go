@@ -5,6 +5,8 @@ import (
 	"clearvault/internal/crypto"
 	"clearvault/internal/metadata"
 	"clearvault/internal/remote"
+	"context"
+	"crypto/md5"
 	sysrand "crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
@@ -12,6 +14,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"os"
 	"path"
 	"path/filepath"
@@ -110,6 +113,18 @@ type Proxy struct {
 	masterKey    []byte
 	pendingSizes sync.Map // path -> int64 (for tracking file size during upload)
 	pendingCache *PendingFileCache
+	packer       *packWriter
+	cache        *blockCache
+	writeBack    *writeBack
+	health       *remote.HealthStorage   // 未启用离线检测时为 nil
+	throttle     *remote.ThrottleStorage // 远端不是由 remote.NewRemoteStorage 创建时为 nil
+	readAhead    *ReadAheadOptions       // 未启用预读时为 nil
+	verify       bool                    // 上传后校验远端对象
+	classes      []StorageClassRule      // 按虚拟路径选择的存储类别
+	quota        quotaCache              // 远端空间查询缓存
+	recoveryKey  []byte                  // 恢复公钥（X25519），未配置时为 nil
+	compression  string
+	indexes      indexCache // 从远端对象读取的块索引
 }
 
 func NewProxy(meta metadata.Storage, remoteStorage remote.RemoteStorage, masterKeyBase64 string) (*Proxy, error) {
@@ -122,9 +137,36 @@ func NewProxy(meta metadata.Storage, remoteStorage remote.RemoteStorage, masterK
 		remote:       remoteStorage,
 		masterKey:    key,
 		pendingCache: NewPendingFileCache(),
+		health:       remote.HealthOf(remoteStorage),
+		throttle:     remote.ThrottleOf(remoteStorage),
 	}, nil
 }
 
+// SetCompression 设置新写入文件的块级压缩算法（"" 或 "none" 表示不压缩）
+func (p *Proxy) SetCompression(algo string) error {
+	switch algo {
+	case "", "none":
+		p.compression = crypto.CompressionNone
+	case crypto.CompressionZstd:
+		p.compression = algo
+	default:
+		return fmt.Errorf("unsupported compression: %s", algo)
+	}
+	return nil
+}
+
+// Close 上传尚未落到远端的数据（如未封存的 pack）；写回队列中未上传的文件留在暂存目录，下次启动后继续
+func (p *Proxy) Close() error {
+	var err error
+	if p.packer != nil {
+		err = p.packer.Close()
+	}
+	if p.writeBack != nil {
+		p.writeBack.Close()
+	}
+	return err
+}
+
 func (p *Proxy) SetPendingSize(path string, size int64) {
 	path = p.normalizePath(path)
 	p.pendingSizes.Store(path, size)
@@ -163,7 +205,12 @@ func (p *Proxy) encryptFEK(fek []byte) ([]byte, error) {
 
 // decryptFEK decrypts the File Encryption Key with the Master Key.
 func (p *Proxy) decryptFEK(encryptedFEK []byte) ([]byte, error) {
-	block, err := crypto.NewEngine(p.masterKey)
+	return decryptFEKWith(p.masterKey, encryptedFEK)
+}
+
+// decryptFEKWith 用指定主密钥解密 FEK
+func decryptFEKWith(masterKey, encryptedFEK []byte) ([]byte, error) {
+	block, err := crypto.NewEngine(masterKey)
 	if err != nil {
 		return nil, err
 	}
@@ -217,7 +264,7 @@ func (p *Proxy) SavePlaceholder(pname string) error {
 	return nil
 }
 
-func (p *Proxy) UploadFile(pname string, r io.Reader, size int64) error {
+func (p *Proxy) UploadFile(ctx context.Context, pname string, r io.Reader, size int64) error {
 	pname = p.normalizePath(pname)
 	log.Printf("Proxy: UploadFile (Streaming) starting for '%s' (size: %d)", pname, size)
 
@@ -227,6 +274,18 @@ func (p *Proxy) UploadFile(pname string, r io.Reader, size int64) error {
 		p.pendingCache.Remove(pname)
 	}
 
+	// 小文件（或未知大小但实际很小的文件）写入共享 pack
+	if p.packer != nil && size <= p.packer.opts.Threshold {
+		data, small, err := readSmall(r, p.packer.opts.Threshold)
+		if err != nil {
+			return err
+		}
+		if small {
+			return p.uploadPacked(pname, data)
+		}
+		r = io.MultiReader(bytes.NewReader(data), r)
+	}
+
 	fek, err := crypto.GenerateRandomBytes(32)
 	if err != nil {
 		return err
@@ -236,7 +295,7 @@ func (p *Proxy) UploadFile(pname string, r io.Reader, size int64) error {
 		return err
 	}
 
-	encryptedFEK, err := p.encryptFEK(fek)
+	encryptedFEK, recoveryFEK, err := p.wrapFEK(fek)
 	if err != nil {
 		return err
 	}
@@ -254,48 +313,93 @@ func (p *Proxy) UploadFile(pname string, r io.Reader, size int64) error {
 
 	cr := &countReader{r: r}
 	errChan := make(chan error, 1)
+	var ci compressInfo
+
+	// 直接上传时统计写出的密文并计算 MD5，用于上传后校验
+	var out io.Writer = pw
+	var hw *hashWriter
+	if p.verify && p.writeBack == nil {
+		hw = &hashWriter{w: pw, h: md5.New()}
+		out = hw
+	}
 
 	go func() {
-		err := engine.EncryptStream(cr, pw, salt)
+		var err error
+		ci, err = p.encryptStream(engine, cr, out, salt)
 		pw.CloseWithError(err)
 		errChan <- err
 	}()
 
+	ctx = p.storageClassContext(ctx, pname)
 	var encSize int64
-	if size > 0 {
+	if p.writeBack != nil {
+		// 写回模式：密文先落到本地暂存目录，元数据保存后由后台队列上传
+		err = p.writeBack.stage(remoteName, pname, pr)
+		if err != nil {
+			pr.CloseWithError(err)
+			log.Printf("Proxy: Staging failed for '%s': %v", pname, err)
+			return err
+		}
+	} else if size > 0 && p.compression == crypto.CompressionNone {
+		// 压缩后的密文大小无法预知，按未知大小上传
 		encSize = crypto.CalculateEncryptedSize(size)
 
-		err = p.remote.Upload(remoteName, pr, encSize)
+		err = p.remote.Upload(ctx, remoteName, pr, encSize)
 		if err != nil {
+			pr.CloseWithError(err)
 			log.Printf("Proxy: Remote Upload failed for '%s': %v", pname, err)
+			p.abandonUpload(remoteName)
 			return err
 		}
 	} else {
 		// Stream without size, just pass through
-		err = p.remote.Upload(remoteName, pr, -1)
+		err = p.remote.Upload(ctx, remoteName, pr, -1)
 		if err != nil {
+			pr.CloseWithError(err)
 			log.Printf("Proxy: Remote Upload failed for '%s': %v", pname, err)
+			p.abandonUpload(remoteName)
 			return err
 		}
 	}
 
 	// Check encryption error
 	if encErr := <-errChan; encErr != nil {
+		if p.writeBack != nil {
+			p.writeBack.discard(remoteName)
+		}
 		return fmt.Errorf("encryption failed: %w", encErr)
 	}
 
+	// 校验失败时不保存元数据，已有的旧版本保持不变
+	if hw != nil {
+		if err := p.verifyUpload(ctx, remoteName, hw.n, hw.h.Sum(nil)); err != nil {
+			log.Printf("Proxy: Upload verification failed for '%s': %v", pname, err)
+			p.discardUpload(ctx, remoteName)
+			return err
+		}
+	}
+
 	// Update metadata with actual size
 
 	meta := &metadata.FileMeta{
-		Name:       path.Base(pname),
-		RemoteName: remoteName,
-		Size:       cr.n,
-		IsDir:      false,
-		FEK:        encryptedFEK,
-		Salt:       salt,
-		UpdatedAt:  time.Now(),
-	}
+		Name:        path.Base(pname),
+		RemoteName:  remoteName,
+		Size:        cr.n,
+		IsDir:       false,
+		FEK:         encryptedFEK,
+		Salt:        salt,
+		UpdatedAt:   time.Now(),
+		RecoveryFEK: recoveryFEK,
+	}
+	ci.apply(meta)
 	err = p.meta.Save(meta, pname)
+	if p.writeBack != nil {
+		if err != nil {
+			p.writeBack.discard(remoteName)
+		} else {
+			p.writeBack.commit(remoteName)
+		}
+	}
 	log.Printf("Proxy: UploadFile finished for '%s' (size: %d, err: %v)", pname, cr.n, err)
 	return err
 }
@@ -350,7 +454,7 @@ func (p *Proxy) ExportLocal(inputPath, outputDir string) error {
 		if err != nil {
 			return err
 		}
-		encryptedFEK, err := p.encryptFEK(fek)
+		encryptedFEK, recoveryFEK, err := p.wrapFEK(fek)
 		if err != nil {
 			return err
 		}
@@ -369,7 +473,7 @@ func (p *Proxy) ExportLocal(inputPath, outputDir string) error {
 			inFile.Close()
 			return err
 		}
-		err = engine.EncryptStream(inFile, outFile, salt)
+		ci, err := p.encryptStream(engine, inFile, outFile, salt)
 		closeErr := outFile.Close()
 		inFile.Close()
 		if err != nil {
@@ -379,14 +483,16 @@ func (p *Proxy) ExportLocal(inputPath, outputDir string) error {
 			return closeErr
 		}
 		meta := &metadata.FileMeta{
-			Name:       path.Base(metaPath),
-			RemoteName: remoteName,
-			Size:       fi.Size(),
-			IsDir:      false,
-			FEK:        encryptedFEK,
-			Salt:       salt,
-			UpdatedAt:  fi.ModTime(),
+			Name:        path.Base(metaPath),
+			RemoteName:  remoteName,
+			Size:        fi.Size(),
+			IsDir:       false,
+			FEK:         encryptedFEK,
+			Salt:        salt,
+			UpdatedAt:   fi.ModTime(),
+			RecoveryFEK: recoveryFEK,
 		}
+		ci.apply(meta)
 		return p.meta.Save(meta, metaPath)
 	})
 	return err
@@ -401,7 +507,7 @@ func (sw *sizeWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
-func (p *Proxy) RemoveAll(pname string) error {
+func (p *Proxy) RemoveAll(ctx context.Context, pname string) error {
 	pname = p.normalizePath(pname)
 	log.Printf("Proxy: RemoveAll '%s'", pname)
 
@@ -419,11 +525,7 @@ func (p *Proxy) RemoveAll(pname string) error {
 	}
 
 	if !meta.IsDir {
-		log.Printf("Proxy: Deleting single remote file '%s'", meta.RemoteName)
-		err = p.remote.Delete(meta.RemoteName)
-		if err != nil {
-			log.Printf("Proxy: Warning: Failed to delete remote file %s: %v", meta.RemoteName, err)
-		}
+		p.deleteRemote(ctx, meta)
 	} else {
 		// Recursive delete for directory.
 		// We need to delete ALL remote files belonging to this tree.
@@ -431,13 +533,13 @@ func (p *Proxy) RemoveAll(pname string) error {
 		// Wait, ReadDir only gives immediate children.
 		// For RemoveAll to be efficient, the Storage should maybe return all remote names in a tree?
 		// Or we just do a recursive walk here.
-		p.recursiveRemoteDelete(pname)
+		p.recursiveRemoteDelete(ctx, pname)
 	}
 
 	return p.meta.RemoveAll(pname)
 }
 
-func (p *Proxy) recursiveRemoteDelete(pname string) {
+func (p *Proxy) recursiveRemoteDelete(ctx context.Context, pname string) {
 	children, err := p.meta.ReadDir(pname)
 	if err != nil {
 		return
@@ -445,12 +547,110 @@ func (p *Proxy) recursiveRemoteDelete(pname string) {
 	for _, child := range children {
 		childPath := path.Join(pname, child.Name)
 		if child.IsDir {
-			p.recursiveRemoteDelete(childPath)
+			p.recursiveRemoteDelete(ctx, childPath)
 		} else {
-			log.Printf("Proxy: Deleting remote file '%s' for '%s'", child.RemoteName, childPath)
-			p.remote.Delete(child.RemoteName)
+			p.deleteRemote(ctx, &child)
+		}
+	}
+}
+
+// deleteRemote 删除文件对应的远端对象
+// pack 中的条目只删除元数据，空间由 CompactPacks 回收
+func (p *Proxy) deleteRemote(ctx context.Context, meta *metadata.FileMeta) {
+	if meta.IsPacked() {
+		log.Printf("Proxy: Leaving packed entry '%s' in pack '%s' for compaction", meta.Name, meta.PackName)
+		return
+	}
+	if p.writeBack != nil && p.writeBack.remove(meta.RemoteName) {
+		log.Printf("Proxy: Dropped staged upload '%s' for '%s'", meta.RemoteName, meta.Name)
+		return
+	}
+	log.Printf("Proxy: Deleting remote file '%s' for '%s'", meta.RemoteName, meta.Name)
+	if err := p.remote.Delete(ctx, meta.RemoteName); err != nil {
+		log.Printf("Proxy: Warning: Failed to delete remote file %s: %v", meta.RemoteName, err)
+	}
+}
+
+// openCipher 打开文件密文的指定范围（length 为 0 表示到末尾）
+func (p *Proxy) openCipher(ctx context.Context, meta *metadata.FileMeta, start, length int64) (io.ReadCloser, error) {
+	if meta.IsPacked() {
+		if length <= 0 || start+length > meta.PackLength {
+			length = meta.PackLength - start
+		}
+		if length <= 0 {
+			return io.NopCloser(bytes.NewReader(nil)), nil
+		}
+		if p.packer != nil {
+			return p.packer.openRange(ctx, meta.PackName, meta.PackOffset+start, length)
+		}
+		return p.remote.DownloadRange(ctx, meta.PackName, meta.PackOffset+start, length)
+	}
+	if p.writeBack != nil {
+		// 尚未上传的文件从本地暂存文件读取
+		f, err := p.writeBack.open(meta.RemoteName)
+		if err != nil {
+			return nil, err
+		}
+		if f != nil {
+			if length <= 0 {
+				length = math.MaxInt64 - start
+			}
+			return &readCloser{Reader: io.NewSectionReader(f, start, length), Closer: f}, nil
 		}
 	}
+	if start == 0 && length <= 0 && p.cache != nil {
+		// 整个文件的读取同样经过块缓存，离线时已缓存的文件仍可读取
+		if n, err := cipherSize(meta); err == nil && n > 0 {
+			length = n
+		}
+	}
+	if start == 0 && length <= 0 {
+		return p.remote.Download(ctx, meta.RemoteName)
+	}
+	return p.remote.DownloadRange(ctx, meta.RemoteName, start, length)
+}
+
+// RemoteHealth 返回远端健康状态，未启用离线检测时 ok 为 false
+func (p *Proxy) RemoteHealth() (status remote.HealthStatus, ok bool) {
+	if p.health == nil {
+		return remote.HealthStatus{}, false
+	}
+	return p.health.Status(), true
+}
+
+// Throttle 返回远端限速层，远端不是由 remote.NewRemoteStorage 创建时为 nil
+func (p *Proxy) Throttle() *remote.ThrottleStorage {
+	return p.throttle
+}
+
+// CheckAvailable 远端离线时检查文件能否完全从本地（写回暂存、pack 暂存、块缓存）读取，不能时返回 ErrRemoteOffline
+func (p *Proxy) CheckAvailable(pname string) error {
+	if p.health == nil || p.health.Online() {
+		return nil
+	}
+	pname = p.normalizePath(pname)
+	meta, err := p.meta.Get(pname)
+	if err != nil || meta == nil || meta.IsDir || meta.Size == 0 || p.availableLocally(meta) {
+		return nil
+	}
+	return fmt.Errorf("'%s' is not cached locally: %w", pname, remote.ErrRemoteOffline)
+}
+
+func (p *Proxy) availableLocally(meta *metadata.FileMeta) bool {
+	if meta.IsPacked() {
+		if p.packer != nil && p.packer.staged(meta.PackName) {
+			return true
+		}
+		return p.cache != nil && p.cache.covers(meta.PackName, meta.PackOffset, meta.PackLength)
+	}
+	if p.writeBack != nil && p.writeBack.staged(meta.RemoteName) {
+		return true
+	}
+	if p.cache == nil {
+		return false
+	}
+	n, err := cipherSize(meta)
+	return err == nil && p.cache.covers(meta.RemoteName, 0, n)
 }
 
 func (p *Proxy) RenameFile(oldPath, newPath string) error {
@@ -490,7 +690,7 @@ func (p *Proxy) ReadDir(path string) ([]metadata.FileMeta, error) {
 	return p.meta.ReadDir(path)
 }
 
-func (p *Proxy) DownloadFile(pname string) (io.ReadCloser, error) {
+func (p *Proxy) DownloadFile(ctx context.Context, pname string) (io.ReadCloser, error) {
 	pname = p.normalizePath(pname)
 
 	// Check if there's a memory placeholder (for Raidrive compatibility)
@@ -514,14 +714,19 @@ func (p *Proxy) DownloadFile(pname string) (io.ReadCloser, error) {
 		return nil, err
 	}
 
-	cipherRC, err := p.remote.Download(meta.RemoteName)
+	index, err := p.chunkIndex(ctx, engine, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherRC, err := p.openCipher(ctx, meta, 0, 0)
 	if err != nil {
 		return nil, err
 	}
 
 	pr, pw := io.Pipe()
 	go func() {
-		err := engine.DecryptStream(cipherRC, pw, meta.Salt)
+		err := decryptStream(engine, meta, index, cipherRC, pw, 0)
 		cipherRC.Close()
 		pw.CloseWithError(err)
 	}()
@@ -529,7 +734,7 @@ func (p *Proxy) DownloadFile(pname string) (io.ReadCloser, error) {
 	return pr, nil
 }
 
-func (p *Proxy) DownloadRange(pname string, offset, length int64) (io.ReadCloser, error) {
+func (p *Proxy) DownloadRange(ctx context.Context, pname string, offset, length int64) (io.ReadCloser, error) {
 	pname = p.normalizePath(pname)
 
 	// Check if there's a memory placeholder (for Raidrive compatibility)
@@ -617,17 +822,25 @@ func (p *Proxy) DownloadRange(pname string, offset, length int64) (io.ReadCloser
 		encLength += lastChunkSize + crypto.TagSize
 	}
 
+	index, err := p.chunkIndex(ctx, engine, meta)
+	if err != nil {
+		return nil, err
+	}
+	if index != nil {
+		encStart, encLength = index.Range(startChunk, endChunk)
+	}
+
 	log.Printf("Proxy: DownloadRange '%s' offset=%d length=%d startChunk=%d endChunk=%d encStart=%d encLength=%d",
 		pname, offset, length, startChunk, endChunk, encStart, encLength)
 
-	cipherRC, err := p.remote.DownloadRange(meta.RemoteName, encStart, encLength)
+	cipherRC, err := p.openCipher(ctx, meta, encStart, encLength)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download range: %w", err)
 	}
 
 	pr, pw := io.Pipe()
 	go func() {
-		err := engine.DecryptStreamFrom(cipherRC, pw, meta.Salt, startChunk)
+		err := decryptStream(engine, meta, index, cipherRC, pw, startChunk)
 		cipherRC.Close()
 		pw.CloseWithError(err)
 	}()
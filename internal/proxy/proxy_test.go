@@ -5,6 +5,7 @@ import (
 	"clearvault/internal/metadata"
 	"clearvault/internal/remote"
 	"clearvault/internal/webdav"
+	"context"
 	"io"
 	"os"
 	"testing"
@@ -85,7 +86,7 @@ func TestZeroByteFileUpload(t *testing.T) {
 	}
 
 	// 4. Upload actual content (simulating Raidrive Phase 2)
-	err = p.UploadFile("/test.txt", bytes.NewReader([]byte("test content")), 12)
+	err = p.UploadFile(context.Background(), "/test.txt", bytes.NewReader([]byte("test content")), 12)
 	if err != nil {
 		t.Fatalf("UploadFile failed: %v", err)
 	}
@@ -108,7 +109,7 @@ func TestZeroByteFileUpload(t *testing.T) {
 	}
 
 	// 7. Verify Download returns actual content
-	rc, err := p.DownloadFile("/test.txt")
+	rc, err := p.DownloadFile(context.Background(), "/test.txt")
 	if err != nil {
 		t.Fatalf("DownloadFile failed: %v", err)
 	}
@@ -212,7 +213,7 @@ func (m *mockRemoteStorage) ensureMap() {
 	}
 }
 
-func (m *mockRemoteStorage) Upload(name string, data io.Reader, size int64) error {
+func (m *mockRemoteStorage) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
 	m.ensureMap()
 	content, err := io.ReadAll(data)
 	if err != nil {
@@ -222,7 +223,7 @@ func (m *mockRemoteStorage) Upload(name string, data io.Reader, size int64) erro
 	return nil
 }
 
-func (m *mockRemoteStorage) Download(name string) (io.ReadCloser, error) {
+func (m *mockRemoteStorage) Download(ctx context.Context, name string) (io.ReadCloser, error) {
 	content, ok := m.files[name]
 	if !ok {
 		return nil, os.ErrNotExist
@@ -230,24 +231,24 @@ func (m *mockRemoteStorage) Download(name string) (io.ReadCloser, error) {
 	return io.NopCloser(bytes.NewReader(content)), nil
 }
 
-func (m *mockRemoteStorage) DownloadRange(name string, start, length int64) (io.ReadCloser, error) {
+func (m *mockRemoteStorage) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
 	content, ok := m.files[name]
 	if !ok {
 		return nil, os.ErrNotExist
 	}
 	end := start + length
-	if end > int64(len(content)) {
+	if length <= 0 || end > int64(len(content)) {
 		end = int64(len(content))
 	}
 	return io.NopCloser(bytes.NewReader(content[start:end])), nil
 }
 
-func (m *mockRemoteStorage) Delete(path string) error {
+func (m *mockRemoteStorage) Delete(ctx context.Context, path string) error {
 	delete(m.files, path)
 	return nil
 }
 
-func (m *mockRemoteStorage) Rename(oldPath, newPath string) error {
+func (m *mockRemoteStorage) Rename(ctx context.Context, oldPath, newPath string) error {
 	m.ensureMap()
 	content, ok := m.files[oldPath]
 	if !ok {
@@ -258,7 +259,7 @@ func (m *mockRemoteStorage) Rename(oldPath, newPath string) error {
 	return nil
 }
 
-func (m *mockRemoteStorage) Stat(path string) (os.FileInfo, error) {
+func (m *mockRemoteStorage) Stat(ctx context.Context, path string) (os.FileInfo, error) {
 	m.ensureMap()
 	content, ok := m.files[path]
 	if !ok {
@@ -314,13 +315,13 @@ func TestLargeFileUpload(t *testing.T) {
 	}
 
 	// Upload
-	err = p.UploadFile("/large.bin", bytes.NewReader(data), int64(fileSize))
+	err = p.UploadFile(context.Background(), "/large.bin", bytes.NewReader(data), int64(fileSize))
 	if err != nil {
 		t.Fatalf("UploadFile failed: %v", err)
 	}
 
 	// Download and verify
-	rc, err := p.DownloadFile("/large.bin")
+	rc, err := p.DownloadFile(context.Background(), "/large.bin")
 	if err != nil {
 		t.Fatalf("DownloadFile failed: %v", err)
 	}
@@ -373,14 +374,14 @@ func TestSpecialCharactersFilename(t *testing.T) {
 
 	for _, name := range specialNames {
 		content := []byte("content for " + name)
-		err := p.UploadFile(name, bytes.NewReader(content), int64(len(content)))
+		err := p.UploadFile(context.Background(), name, bytes.NewReader(content), int64(len(content)))
 		if err != nil {
 			t.Errorf("Failed to upload %s: %v", name, err)
 			continue
 		}
 
 		// Verify download
-		rc, err := p.DownloadFile(name)
+		rc, err := p.DownloadFile(context.Background(), name)
 		if err != nil {
 			t.Errorf("Failed to download %s: %v", name, err)
 			continue
@@ -421,7 +422,7 @@ func TestFileRename(t *testing.T) {
 
 	// Create original file
 	originalContent := []byte("original content")
-	err = p.UploadFile("/original.txt", bytes.NewReader(originalContent), int64(len(originalContent)))
+	err = p.UploadFile(context.Background(), "/original.txt", bytes.NewReader(originalContent), int64(len(originalContent)))
 	if err != nil {
 		t.Fatalf("UploadFile failed: %v", err)
 	}
@@ -433,13 +434,13 @@ func TestFileRename(t *testing.T) {
 	}
 
 	// Verify original doesn't exist
-	_, err = p.DownloadFile("/original.txt")
+	_, err = p.DownloadFile(context.Background(), "/original.txt")
 	if err == nil {
 		t.Error("Expected error when downloading original file after rename")
 	}
 
 	// Verify renamed file exists with correct content
-	rc, err := p.DownloadFile("/renamed.txt")
+	rc, err := p.DownloadFile(context.Background(), "/renamed.txt")
 	if err != nil {
 		t.Fatalf("Failed to download renamed file: %v", err)
 	}
@@ -477,25 +478,25 @@ func TestFileDelete(t *testing.T) {
 
 	// Create file
 	content := []byte("content to delete")
-	err = p.UploadFile("/delete-me.txt", bytes.NewReader(content), int64(len(content)))
+	err = p.UploadFile(context.Background(), "/delete-me.txt", bytes.NewReader(content), int64(len(content)))
 	if err != nil {
 		t.Fatalf("UploadFile failed: %v", err)
 	}
 
 	// Verify file exists
-	_, err = p.DownloadFile("/delete-me.txt")
+	_, err = p.DownloadFile(context.Background(), "/delete-me.txt")
 	if err != nil {
 		t.Fatalf("File should exist before deletion")
 	}
 
 	// Delete file
-	err = p.RemoveAll("/delete-me.txt")
+	err = p.RemoveAll(context.Background(), "/delete-me.txt")
 	if err != nil {
 		t.Fatalf("Delete failed: %v", err)
 	}
 
 	// Verify file is deleted
-	_, err = p.DownloadFile("/delete-me.txt")
+	_, err = p.DownloadFile(context.Background(), "/delete-me.txt")
 	if err == nil {
 		t.Error("Expected error when downloading deleted file")
 	}
@@ -522,13 +523,13 @@ func TestEmptyContentUpload(t *testing.T) {
 	}
 
 	// Upload empty content
-	err = p.UploadFile("/empty.txt", bytes.NewReader([]byte{}), 0)
+	err = p.UploadFile(context.Background(), "/empty.txt", bytes.NewReader([]byte{}), 0)
 	if err != nil {
 		t.Fatalf("UploadFile failed: %v", err)
 	}
 
 	// Download and verify
-	rc, err := p.DownloadFile("/empty.txt")
+	rc, err := p.DownloadFile(context.Background(), "/empty.txt")
 	if err != nil {
 		t.Fatalf("DownloadFile failed: %v", err)
 	}
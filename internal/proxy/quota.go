@@ -0,0 +1,46 @@
+package proxy
+
+import (
+	"clearvault/internal/remote"
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// quotaTTL 远端空间查询结果的缓存时间，文件管理器会频繁查询剩余空间
+	quotaTTL = time.Minute
+	// quotaTimeout 单次查询的超时，避免远端无响应时阻塞 statfs/PROPFIND
+	quotaTimeout = 10 * time.Second
+)
+
+// quotaCache 缓存最近一次查询结果（包括失败）
+type quotaCache struct {
+	mu        sync.Mutex
+	at        time.Time
+	used      int64
+	available int64
+	err       error
+}
+
+// Quota 返回远端的已用和剩余空间（字节，未知为 -1），结果缓存一分钟
+// 远端不支持查询时返回 remote.ErrNotSupported，调用方应回退到默认值
+func (p *Proxy) Quota(ctx context.Context) (used, available int64, err error) {
+	c := &p.quota
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.at.IsZero() && time.Since(c.at) < quotaTTL {
+		return c.used, c.available, c.err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, quotaTimeout)
+	defer cancel()
+	used, available, err = remote.Quota(ctx, p.remote)
+	if err != nil && !errors.Is(err, remote.ErrNotSupported) {
+		log.Printf("Proxy: Failed to query remote quota: %v", err)
+	}
+	c.at, c.used, c.available, c.err = time.Now(), used, available, err
+	return used, available, err
+}
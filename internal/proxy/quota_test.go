@@ -0,0 +1,73 @@
+package proxy
+
+import (
+	"clearvault/internal/remote"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/webdav"
+)
+
+// quotaRemote 报告固定空间并统计查询次数
+type quotaRemote struct {
+	*mockRemoteStorage
+	used, available int64
+	calls           int
+}
+
+func (m *quotaRemote) Quota(ctx context.Context) (int64, int64, error) {
+	m.calls++
+	return m.used, m.available, nil
+}
+
+func TestQuota(t *testing.T) {
+	rs := &quotaRemote{mockRemoteStorage: newMockRemoteStorage(), used: 1000, available: 4096}
+	p, err := NewProxy(newTestMeta(t), rs, "dGhpcy1pcy1hLTMyLWJ5dGUtbG9uZy1tYXN0ZXJrZXk=")
+	if err != nil {
+		t.Fatalf("NewProxy failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		used, available, err := p.Quota(context.Background())
+		if err != nil || used != 1000 || available != 4096 {
+			t.Fatalf("Expected 1000 used, 4096 available, got %d, %d, %v", used, available, err)
+		}
+	}
+	if rs.calls != 1 {
+		t.Errorf("Expected quota to be cached, remote queried %d times", rs.calls)
+	}
+
+	// 通过 WebDAV 服务端的 PROPFIND 报告 RFC 4331 属性
+	h := &webdav.Handler{FileSystem: NewFileSystem(p), LockSystem: webdav.NewMemLS()}
+	req := httptest.NewRequest("PROPFIND", "/", strings.NewReader(`<?xml version="1.0"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:quota-available-bytes/><d:quota-used-bytes/></d:prop></d:propfind>`))
+	req.Header.Set("Depth", "0")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	body := w.Body.String()
+	if w.Code != http.StatusMultiStatus || !strings.Contains(body, ">4096</D:quota-available-bytes>") || !strings.Contains(body, ">1000</D:quota-used-bytes>") {
+		t.Errorf("Expected quota properties in PROPFIND response, got %d: %s", w.Code, body)
+	}
+}
+
+func TestQuota_NotSupported(t *testing.T) {
+	p, err := NewProxy(newTestMeta(t), newMockRemoteStorage(), "dGhpcy1pcy1hLTMyLWJ5dGUtbG9uZy1tYXN0ZXJrZXk=")
+	if err != nil {
+		t.Fatalf("NewProxy failed: %v", err)
+	}
+	if _, _, err := p.Quota(context.Background()); !errors.Is(err, remote.ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+	f, err := NewFileSystem(p).OpenFile(context.Background(), "/", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	props, err := f.(webdav.DeadPropsHolder).DeadProps()
+	if err != nil || len(props) != 0 {
+		t.Errorf("Expected no quota properties, got %v, %v", props, err)
+	}
+}
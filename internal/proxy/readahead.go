@@ -0,0 +1,239 @@
+package proxy
+
+import (
+	"clearvault/internal/crypto"
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ReadAheadOptions 预读配置
+type ReadAheadOptions struct {
+	Window   int64 // 顺序读取时的最大预读窗口（字节）
+	Segment  int64 // 每个远端范围请求的大小，向上取整到明文分块大小
+	Parallel int   // 同时进行的预读请求数
+}
+
+const (
+	defaultReadAheadWindow   = 16 << 20
+	defaultReadAheadSegment  = 1 << 20
+	defaultReadAheadParallel = 4
+)
+
+// EnableReadAhead 启用预读：FUSE 与 WebDAV 的读取经过 FileReader，
+// 检测到顺序读取后并行预取后续分段
+func (p *Proxy) EnableReadAhead(opts ReadAheadOptions) {
+	if opts.Segment <= 0 {
+		opts.Segment = defaultReadAheadSegment
+	}
+	opts.Segment = (opts.Segment + crypto.ChunkSize - 1) / crypto.ChunkSize * crypto.ChunkSize
+	if opts.Window <= 0 {
+		opts.Window = defaultReadAheadWindow
+	}
+	if opts.Window < opts.Segment {
+		opts.Window = opts.Segment
+	}
+	if opts.Parallel <= 0 {
+		opts.Parallel = defaultReadAheadParallel
+	}
+	p.readAhead = &opts
+}
+
+// FileReader 带预读的随机读取器，每个打开的文件一个
+// 文件按 Segment 切分，每段一次远端范围请求；最近使用的分段保留在内存中，
+// 回跳（seek）到已读取的位置时直接复用，内存占用不超过 Window + Parallel*Segment
+type FileReader struct {
+	p      *Proxy
+	opts   ReadAheadOptions
+	name   string
+	size   int64
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	segments map[int64]*segment
+	lru      *list.List // 最近使用的在前
+	inflight int
+	next     int64 // 上次读取的结束位置
+	window   int64 // 当前预读窗口，顺序读取时倍增，随机读取时归零
+}
+
+type segment struct {
+	index int64
+	elem  *list.Element
+	done  chan struct{}
+	data  []byte
+	err   error
+}
+
+// OpenReader 打开带预读的读取器，未启用预读或文件为内存占位符时返回 nil
+// ctx 取消或调用 Close 后进行中的预读随之取消
+func (p *Proxy) OpenReader(ctx context.Context, pname string) (*FileReader, error) {
+	if p.readAhead == nil {
+		return nil, nil
+	}
+	pname = p.normalizePath(pname)
+	if p.pendingCache.Exists(pname) {
+		return nil, nil
+	}
+	meta, err := p.meta.Get(pname)
+	if err != nil || meta == nil {
+		return nil, fmt.Errorf("file not found: %s", pname)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &FileReader{
+		p:        p,
+		opts:     *p.readAhead,
+		name:     pname,
+		size:     meta.Size,
+		ctx:      ctx,
+		cancel:   cancel,
+		segments: make(map[int64]*segment),
+		lru:      list.New(),
+	}, nil
+}
+
+// Size 返回打开时的文件大小
+func (r *FileReader) Size() int64 {
+	return r.size
+}
+
+// ReadAt 实现 io.ReaderAt
+func (r *FileReader) ReadAt(b []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset")
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+	end := min(off+int64(len(b)), r.size)
+	first, last := off/r.opts.Segment, (end-1)/r.opts.Segment
+
+	r.mu.Lock()
+	r.adaptLocked(off, end)
+	segs := make([]*segment, 0, last-first+1)
+	for i := first; i <= last; i++ {
+		segs = append(segs, r.fetchLocked(i))
+	}
+	r.prefetchLocked(last+1, end)
+	r.evictLocked(first, last)
+	r.mu.Unlock()
+
+	n := 0
+	for _, s := range segs {
+		select {
+		case <-s.done:
+		case <-r.ctx.Done():
+			return n, r.ctx.Err()
+		}
+		if s.err != nil {
+			// 失败的分段不保留，下次读取时重新请求
+			r.drop(s)
+			return n, s.err
+		}
+		rel := off + int64(n) - s.index*r.opts.Segment
+		if rel >= int64(len(s.data)) {
+			// 远端数据比元数据记录的短
+			break
+		}
+		n += copy(b[n:end-off], s.data[rel:])
+	}
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// adaptLocked 根据访问模式调整预读窗口：紧接上次读取（允许一个分段内的乱序）视为顺序读取
+func (r *FileReader) adaptLocked(off, end int64) {
+	if off >= r.next-r.opts.Segment && off <= r.next+r.opts.Segment {
+		r.window = min(max(r.window*2, r.opts.Segment), r.opts.Window)
+	} else {
+		r.window = 0
+	}
+	r.next = end
+}
+
+// fetchLocked 返回分段，不存在时启动远端请求
+func (r *FileReader) fetchLocked(index int64) *segment {
+	if s, ok := r.segments[index]; ok {
+		r.lru.MoveToFront(s.elem)
+		return s
+	}
+	s := &segment{index: index, done: make(chan struct{})}
+	s.elem = r.lru.PushFront(s)
+	r.segments[index] = s
+	r.inflight++
+	go r.load(s)
+	return s
+}
+
+// prefetchLocked 预取 end 之后预读窗口内的分段，进行中的请求数不超过 Parallel
+func (r *FileReader) prefetchLocked(from, end int64) {
+	if r.window == 0 {
+		return
+	}
+	last := min(end+r.window-1, r.size-1) / r.opts.Segment
+	for i := from; i <= last && r.inflight < r.opts.Parallel; i++ {
+		if _, ok := r.segments[i]; !ok {
+			r.fetchLocked(i)
+		}
+	}
+}
+
+// evictLocked 按 LRU 淘汰已完成的分段，保留本次读取用到的 [first, last]
+func (r *FileReader) evictLocked(first, last int64) {
+	limit := int(r.opts.Window/r.opts.Segment) + r.opts.Parallel + int(last-first+1)
+	for e := r.lru.Back(); e != nil && len(r.segments) > limit; {
+		s := e.Value.(*segment)
+		e = e.Prev()
+		if s.index >= first && s.index <= last {
+			continue
+		}
+		select {
+		case <-s.done:
+			r.lru.Remove(s.elem)
+			delete(r.segments, s.index)
+		default:
+		}
+	}
+}
+
+func (r *FileReader) load(s *segment) {
+	start := s.index * r.opts.Segment
+	rc, err := r.p.DownloadRange(r.ctx, r.name, start, r.opts.Segment)
+	if err == nil {
+		// Segment 是分块大小的整数倍，返回的数据正好从分段开头开始
+		s.data, err = io.ReadAll(io.LimitReader(rc, r.opts.Segment))
+		rc.Close()
+	}
+	s.err = err
+	r.mu.Lock()
+	r.inflight--
+	r.mu.Unlock()
+	close(s.done)
+}
+
+func (r *FileReader) drop(s *segment) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.segments[s.index] == s {
+		r.lru.Remove(s.elem)
+		delete(r.segments, s.index)
+	}
+}
+
+// Close 取消进行中的预读并释放缓存的分段
+func (r *FileReader) Close() error {
+	r.cancel()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.segments = make(map[int64]*segment)
+	r.lru.Init()
+	return nil
+}
@@ -0,0 +1,170 @@
+package proxy
+
+import (
+	"bytes"
+	"clearvault/internal/crypto"
+	"context"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rangeRemote 并发安全地记录远端范围请求的起始位置
+type rangeRemote struct {
+	*mockRemoteStorage
+	mu     sync.Mutex
+	starts []int64
+}
+
+func (m *rangeRemote) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	m.mu.Lock()
+	m.starts = append(m.starts, start)
+	m.mu.Unlock()
+	return m.mockRemoteStorage.DownloadRange(ctx, name, start, length)
+}
+
+func (m *rangeRemote) requests() []int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]int64(nil), m.starts...)
+}
+
+const testSegment = 2 * crypto.ChunkSize
+
+func newReadAheadTestProxy(t *testing.T, size int) (*Proxy, *rangeRemote, []byte) {
+	t.Helper()
+	remote := &rangeRemote{mockRemoteStorage: newMockRemoteStorage()}
+	p, err := NewProxy(newTestMeta(t), remote, "dGhpcy1pcy1hLTMyLWJ5dGUtbG9uZy1tYXN0ZXJrZXk=")
+	if err != nil {
+		t.Fatalf("NewProxy failed: %v", err)
+	}
+	content := make([]byte, size)
+	rand.New(rand.NewSource(4)).Read(content)
+	if err := p.UploadFile(context.Background(), "/video.bin", bytes.NewReader(content), int64(size)); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	p.EnableReadAhead(ReadAheadOptions{Window: 4 * testSegment, Segment: testSegment, Parallel: 2})
+	return p, remote, content
+}
+
+func openTestReader(t *testing.T, p *Proxy) *FileReader {
+	t.Helper()
+	r, err := p.OpenReader(context.Background(), "/video.bin")
+	if err != nil || r == nil {
+		t.Fatalf("OpenReader failed: %v", err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+// waitRequests 等待后台预读发出至少 n 个请求
+func waitRequests(t *testing.T, remote *rangeRemote, n int) []int64 {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if reqs := remote.requests(); len(reqs) >= n {
+			return reqs
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Expected %d remote requests, got %v", n, remote.requests())
+	return nil
+}
+
+func TestReadAheadSequential(t *testing.T) {
+	p, remote, content := newReadAheadTestProxy(t, 20*testSegment+1234)
+	r := openTestReader(t, p)
+
+	buf := make([]byte, 32*1024)
+	r.ReadAt(buf, 0)
+	// 顺序读取开始后后续分段在后台预取
+	waitRequests(t, remote, 2)
+
+	var got []byte
+	for off := int64(0); ; off += int64(len(buf)) {
+		n, err := r.ReadAt(buf, off)
+		got = append(got, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("ReadAt(%d) failed: %v", off, err)
+		}
+	}
+	if !bytes.Equal(got, content) {
+		t.Fatal("Content mismatch")
+	}
+
+	// 每个分段只请求一次，且都按分段对齐（远端偏移为密文偏移）
+	seen := make(map[int64]bool)
+	for _, start := range remote.requests() {
+		if seen[start] || start%(2*crypto.CipherChunkSize) != 0 {
+			t.Errorf("Unexpected request at %d: %v", start, remote.requests())
+		}
+		seen[start] = true
+	}
+	if len(seen) != 21 {
+		t.Errorf("Expected 21 segment requests, got %d", len(seen))
+	}
+
+	// 缓存的分段数有上限
+	r.mu.Lock()
+	n := len(r.segments)
+	r.mu.Unlock()
+	if n > 4+2+1 {
+		t.Errorf("Expected at most 7 cached segments, got %d", n)
+	}
+}
+
+func TestReadAheadRandom(t *testing.T) {
+	p, remote, content := newReadAheadTestProxy(t, 20*testSegment)
+	r := openTestReader(t, p)
+
+	// 随机读取不预取
+	offsets := []int64{15 * testSegment, 3 * testSegment, 9*testSegment + 100}
+	buf := make([]byte, 1000)
+	for _, off := range offsets {
+		if _, err := r.ReadAt(buf, off); err != nil {
+			t.Fatalf("ReadAt(%d) failed: %v", off, err)
+		}
+		if !bytes.Equal(buf, content[off:off+1000]) {
+			t.Fatalf("Content mismatch at %d", off)
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	if reqs := remote.requests(); len(reqs) != len(offsets) {
+		t.Errorf("Expected %d remote requests, got %v", len(offsets), reqs)
+	}
+
+	// 回到已读取过的位置直接复用
+	if _, err := r.ReadAt(buf, 3*testSegment+5); err != nil || !bytes.Equal(buf, content[3*testSegment+5:][:1000]) {
+		t.Fatalf("Re-read failed: %v", err)
+	}
+	if reqs := remote.requests(); len(reqs) != len(offsets) {
+		t.Errorf("Expected re-read to be served from memory, got %v", reqs)
+	}
+}
+
+func TestReadAheadProxyFile(t *testing.T) {
+	p, _, content := newReadAheadTestProxy(t, 5*testSegment+77)
+	f, err := NewFileSystem(p).OpenFile(context.Background(), "/video.bin", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	defer f.Close()
+
+	got, err := io.ReadAll(f)
+	if err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("ReadAll mismatch: %v", err)
+	}
+	if _, err := f.Seek(testSegment+10, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 100)
+	if _, err := io.ReadFull(f, buf); err != nil || !bytes.Equal(buf, content[testSegment+10:][:100]) {
+		t.Fatalf("Read after seek mismatch: %v", err)
+	}
+}
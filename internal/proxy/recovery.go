@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"clearvault/internal/crypto"
+	"clearvault/internal/metadata"
+	"encoding/base64"
+	"fmt"
+	"log"
+)
+
+// SetRecoveryKey 设置恢复公钥（base64 编码的 X25519 公钥），空字符串表示不托管
+// 设置后新写入的文件除主密钥加密的 FEK 外，还保存一份用恢复公钥封装的 FEK
+func (p *Proxy) SetRecoveryKey(publicKeyBase64 string) error {
+	if publicKeyBase64 == "" {
+		p.recoveryKey = nil
+		return nil
+	}
+	key, err := base64.StdEncoding.DecodeString(publicKeyBase64)
+	if err != nil {
+		return fmt.Errorf("failed to decode recovery public key: %w", err)
+	}
+	// 提前校验公钥，避免每次上传时才报错
+	if _, err := crypto.SealKey(key, make([]byte, 32)); err != nil {
+		return err
+	}
+	p.recoveryKey = key
+	return nil
+}
+
+// wrapFEK 用主密钥加密 FEK，配置了恢复公钥时同时返回封装的 FEK
+func (p *Proxy) wrapFEK(fek []byte) (encrypted, recovery []byte, err error) {
+	encrypted, err = p.encryptFEK(fek)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.recoveryKey != nil {
+		if recovery, err = crypto.SealKey(p.recoveryKey, fek); err != nil {
+			return nil, nil, fmt.Errorf("failed to seal FEK for recovery: %w", err)
+		}
+	}
+	return encrypted, recovery, nil
+}
+
+// RecoverStats 密钥恢复统计
+type RecoverStats struct {
+	Files     int // 遍历到的文件数
+	Recovered int // 用恢复私钥取回并以新主密钥重新加密的文件数
+	Rewrapped int // 没有托管 FEK、用原主密钥解开（或已是新主密钥）的文件数
+	Missing   int // 两种方式都解不开的文件数，换用新主密钥后将无法读取
+}
+
+// RecoverOptions 密钥恢复选项
+type RecoverOptions struct {
+	OldMasterKey string // 原主密钥（base64），为空或已失效时没有托管 FEK 的文件计入 Missing
+	DryRun       bool   // 只检查并统计，不修改元数据
+}
+
+// RecoverKeys 用恢复私钥解开各文件托管的 FEK，并用当前主密钥（新密钥）重新加密后保存元数据
+// 没有托管 FEK 的文件尝试用原主密钥解开；只修改元数据，远端对象不变，中途失败可以重复执行
+func (p *Proxy) RecoverKeys(privateKey []byte, opts RecoverOptions) (*RecoverStats, error) {
+	var oldKey []byte
+	if opts.OldMasterKey != "" {
+		if key, err := base64.StdEncoding.DecodeString(opts.OldMasterKey); err == nil && len(key) == 32 {
+			oldKey = key
+		}
+	}
+	stats := &RecoverStats{}
+	err := metadata.Walk(p.meta, "/", func(path string, m *metadata.FileMeta) error {
+		if len(m.FEK) == 0 {
+			return nil
+		}
+		stats.Files++
+		var fek []byte
+		switch {
+		case len(m.RecoveryFEK) > 0:
+			var err error
+			if fek, err = crypto.OpenKey(privateKey, m.RecoveryFEK); err != nil {
+				return fmt.Errorf("failed to recover the file key of '%s': %w", path, err)
+			}
+			stats.Recovered++
+		case oldKey != nil:
+			if key, err := decryptFEKWith(oldKey, m.FEK); err == nil {
+				fek = key
+				stats.Rewrapped++
+			} else if _, err := p.decryptFEK(m.FEK); err == nil {
+				// 重复执行时已经改用新主密钥
+				stats.Rewrapped++
+				return nil
+			}
+		}
+		if fek == nil {
+			stats.Missing++
+			log.Printf("Proxy: No recovery key for '%s' and the old master key cannot unwrap it, skipped", path)
+			return nil
+		}
+		if opts.DryRun {
+			return nil
+		}
+		var err error
+		if m.FEK, err = p.encryptFEK(fek); err != nil {
+			return err
+		}
+		if err := p.meta.Save(m, path); err != nil {
+			return fmt.Errorf("failed to save metadata for '%s': %w", path, err)
+		}
+		return nil
+	})
+	return stats, err
+}
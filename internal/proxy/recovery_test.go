@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"bytes"
+	"clearvault/internal/crypto"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestRecoverKeys(t *testing.T) {
+	ctx := context.Background()
+	meta := newTestMeta(t)
+	rs := newMockRemoteStorage()
+	pub, priv, err := crypto.GenerateRecoveryKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := newVaultProxy(t, meta, rs, testMasterKey)
+	if _, err := p.CheckVault(ctx); err != nil {
+		t.Fatalf("CheckVault failed: %v", err)
+	}
+	// 配置恢复公钥之前写入的文件没有托管 FEK
+	old := []byte("written before escrow")
+	if err := p.UploadFile(ctx, "/old.txt", bytes.NewReader(old), int64(len(old))); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	if err := p.SetRecoveryKey(base64.StdEncoding.EncodeToString(pub)); err != nil {
+		t.Fatalf("SetRecoveryKey failed: %v", err)
+	}
+	data := []byte("escrowed file content")
+	if err := p.UploadFile(ctx, "/docs/a.txt", bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	m, err := meta.Get("/docs/a.txt")
+	if err != nil || len(m.RecoveryFEK) == 0 {
+		t.Fatalf("Expected recovery FEK in metadata, got %+v, %v", m, err)
+	}
+
+	// 主密钥丢失：先检查，没有托管 FEK 的文件无法取回，元数据不变
+	np := newVaultProxy(t, meta, rs, otherTestKey)
+	before, _ := meta.Get("/docs/a.txt")
+	stats, err := np.RecoverKeys(priv, RecoverOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("RecoverKeys failed: %v", err)
+	}
+	if stats.Files != 2 || stats.Recovered != 1 || stats.Missing != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if after, _ := meta.Get("/docs/a.txt"); !bytes.Equal(after.FEK, before.FEK) {
+		t.Error("Dry run modified metadata")
+	}
+
+	// 原主密钥仍可用时，没有托管 FEK 的文件用它解开后一并改用新主密钥
+	stats, err = np.RecoverKeys(priv, RecoverOptions{OldMasterKey: testMasterKey})
+	if err != nil {
+		t.Fatalf("RecoverKeys failed: %v", err)
+	}
+	if stats.Files != 2 || stats.Recovered != 1 || stats.Rewrapped != 1 || stats.Missing != 0 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	// 重复执行：已改用新主密钥的文件不计入 Missing
+	stats, err = np.RecoverKeys(priv, RecoverOptions{OldMasterKey: testMasterKey})
+	if err != nil || stats.Rewrapped != 1 || stats.Missing != 0 {
+		t.Errorf("Unexpected stats on resume %+v, %v", stats, err)
+	}
+	if _, err := np.CheckVault(ctx); !errors.Is(err, ErrWrongKey) {
+		t.Fatalf("Expected ErrWrongKey before rekey, got %v", err)
+	}
+	if _, err := np.RekeyVault(ctx); err != nil {
+		t.Fatalf("RekeyVault failed: %v", err)
+	}
+	if _, err := newVaultProxy(t, meta, rs, otherTestKey).CheckVault(ctx); err != nil {
+		t.Errorf("CheckVault with new key failed: %v", err)
+	}
+	if _, err := newVaultProxy(t, newTestMeta(t), rs, otherTestKey).CheckVault(ctx); err != nil {
+		t.Errorf("Remote descriptor not updated: %v", err)
+	}
+	if _, err := newVaultProxy(t, meta, rs, testMasterKey).CheckVault(ctx); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("Expected ErrWrongKey with the old key, got %v", err)
+	}
+
+	rc, err := np.DownloadFile(ctx, "/docs/a.txt")
+	if err != nil {
+		t.Fatalf("DownloadFile failed: %v", err)
+	}
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("Expected %q, got %q (%v)", data, got, err)
+	}
+
+	rc, err = np.DownloadFile(ctx, "/old.txt")
+	if err != nil {
+		t.Fatalf("DownloadFile failed: %v", err)
+	}
+	got, err = io.ReadAll(rc)
+	rc.Close()
+	if err != nil || !bytes.Equal(got, old) {
+		t.Errorf("Expected %q, got %q (%v)", old, got, err)
+	}
+
+	// 错误的恢复私钥在检查阶段就失败
+	_, wrong, _ := crypto.GenerateRecoveryKey()
+	if _, err := np.RecoverKeys(wrong, RecoverOptions{DryRun: true}); !errors.Is(err, crypto.ErrInvalidSealedKey) {
+		t.Errorf("Expected ErrInvalidSealedKey, got %v", err)
+	}
+}
+
+func TestSetRecoveryKey(t *testing.T) {
+	p := newVaultProxy(t, newTestMeta(t), newMockRemoteStorage(), testMasterKey)
+	if err := p.SetRecoveryKey("not base64!"); err == nil {
+		t.Error("Expected error for invalid encoding")
+	}
+	if err := p.SetRecoveryKey(base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Error("Expected error for invalid key length")
+	}
+	if err := p.SetRecoveryKey(""); err != nil || p.recoveryKey != nil {
+		t.Errorf("Expected escrow to be disabled, got %v", err)
+	}
+	if _, rec, err := p.wrapFEK(make([]byte, 32)); err != nil || rec != nil {
+		t.Errorf("Expected no recovery FEK, got %x, %v", rec, err)
+	}
+}
@@ -0,0 +1,41 @@
+package proxy
+
+import (
+	"clearvault/internal/remote"
+	"context"
+	"strings"
+)
+
+// StorageClassRule 虚拟路径前缀对应的远端存储类别
+type StorageClassRule struct {
+	Prefix string
+	Class  string
+}
+
+// SetStorageClasses 按虚拟路径为新上传的文件选择存储类别（如归档目录使用冷存储），只有 S3 远端生效
+// 多条规则匹配时取最长的前缀；pack 对象混合多个文件，使用远端默认的存储类别
+func (p *Proxy) SetStorageClasses(rules []StorageClassRule) {
+	p.classes = make([]StorageClassRule, 0, len(rules))
+	for _, r := range rules {
+		p.classes = append(p.classes, StorageClassRule{Prefix: p.normalizePath(r.Prefix), Class: r.Class})
+	}
+}
+
+// storageClassContext 在 ctx 中附带 pname 匹配的存储类别
+func (p *Proxy) storageClassContext(ctx context.Context, pname string) context.Context {
+	var best StorageClassRule
+	for _, r := range p.classes {
+		if len(r.Prefix) > len(best.Prefix) && pathHasPrefix(pname, r.Prefix) {
+			best = r
+		}
+	}
+	return remote.WithStorageClass(ctx, best.Class)
+}
+
+// pathHasPrefix 按路径段判断前缀：/archive 匹配 /archive/a，不匹配 /archived
+func pathHasPrefix(pname, prefix string) bool {
+	if prefix == "/" {
+		return true
+	}
+	return pname == prefix || strings.HasPrefix(pname, prefix+"/")
+}
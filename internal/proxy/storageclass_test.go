@@ -0,0 +1,49 @@
+package proxy
+
+import (
+	"bytes"
+	"clearvault/internal/remote/s3"
+	"context"
+	"io"
+	"testing"
+)
+
+// classRemote 记录每次上传附带的存储类别
+type classRemote struct {
+	*mockRemoteStorage
+	classes []string
+}
+
+func (m *classRemote) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	class, _ := s3.StorageClassFrom(ctx)
+	m.classes = append(m.classes, class)
+	return m.mockRemoteStorage.Upload(ctx, name, data, size)
+}
+
+func TestStorageClasses(t *testing.T) {
+	remote := &classRemote{mockRemoteStorage: newMockRemoteStorage()}
+	p, err := NewProxy(newTestMeta(t), remote, "dGhpcy1pcy1hLTMyLWJ5dGUtbG9uZy1tYXN0ZXJrZXk=")
+	if err != nil {
+		t.Fatalf("NewProxy failed: %v", err)
+	}
+	p.SetStorageClasses([]StorageClassRule{
+		{Prefix: "/archive", Class: "GLACIER_IR"},
+		{Prefix: "archive/photos/", Class: "DEEP_ARCHIVE"},
+	})
+
+	tests := []struct{ path, want string }{
+		{"/archive/a.bin", "GLACIER_IR"},
+		{"/archive/photos/2024/b.jpg", "DEEP_ARCHIVE"},
+		{"/archived/c.bin", ""},
+		{"/d.bin", ""},
+	}
+	for _, tt := range tests {
+		remote.classes = nil
+		if err := p.UploadFile(context.Background(), tt.path, bytes.NewReader([]byte("data")), 4); err != nil {
+			t.Fatalf("UploadFile(%s) failed: %v", tt.path, err)
+		}
+		if len(remote.classes) != 1 || remote.classes[0] != tt.want {
+			t.Errorf("%s: expected storage class %q, got %v", tt.path, tt.want, remote.classes)
+		}
+	}
+}
@@ -183,6 +183,8 @@ func (p *Proxy) addFileToTar(
 		}
 		metaCopy.FEK = rawFEK
 	}
+	// 恢复公钥属于本保险库，接收方按自己的配置重新封装
+	metaCopy.RecoveryFEK = nil
 
 	// 2. 序列化元数据
 	metaJSON, err := json.MarshalIndent(metaCopy, "", "  ")
@@ -372,14 +374,15 @@ func (p *Proxy) ExtractTarPackage(
 			return nil, fmt.Errorf("failed to unmarshal metadata %s: %w", metaFile, err)
 		}
 
-		// 使用主密钥重新加密 FEK
-		encryptedFEK, err := p.encryptFEK(meta.FEK)
+		// 使用主密钥重新加密 FEK（配置了恢复公钥时同时封装）
+		encryptedFEK, recoveryFEK, err := p.wrapFEK(meta.FEK)
 		if err != nil {
 			return nil, fmt.Errorf("failed to re-encrypt FEK for %s: %w", meta.Name, err)
 		}
 
 		// 更新元数据
 		meta.FEK = encryptedFEK
+		meta.RecoveryFEK = recoveryFEK
 
 		// 保存到本地存储（使用 path + name 构建虚拟路径）
 		virtualPath := filepath.Join(meta.Path, meta.Name)
@@ -0,0 +1,217 @@
+package proxy
+
+import (
+	"bytes"
+	"clearvault/internal/crypto"
+	"clearvault/internal/metadata"
+	"clearvault/internal/remote"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+)
+
+// VaultObjectName 远端保存保险库描述的对象名（不是十六进制名称，不受目录布局影响）
+const VaultObjectName = ".clearvault-vault"
+
+// vaultVersion 当前的保险库格式版本
+const vaultVersion = 1
+
+var (
+	// ErrWrongKey 主密钥与保险库描述中的校验值不符
+	ErrWrongKey = errors.New("master key does not match this vault")
+	// ErrVaultMismatch 元数据与远端的保险库描述属于不同的保险库
+	ErrVaultMismatch = errors.New("metadata and remote belong to different vaults")
+)
+
+// VaultDescriptor 保险库描述，同时保存在远端（VaultObjectName）与元数据目录中
+// 启动时用 KCV 校验主密钥，避免用错误的密钥读取失败或写入无法解密的新文件
+type VaultDescriptor struct {
+	ID        string    `json:"vault_id"`
+	Version   int       `json:"version"`
+	KCV       string    `json:"kcv"` // crypto.KeyCheckValue(masterKey, ID)
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// check 校验格式版本与主密钥
+func (d *VaultDescriptor) check(key []byte) error {
+	if d.Version > vaultVersion {
+		return fmt.Errorf("vault format version %d is newer than supported (%d), please upgrade", d.Version, vaultVersion)
+	}
+	if crypto.KeyCheckValue(key, d.ID) != d.KCV {
+		return fmt.Errorf("%w (vault %s)", ErrWrongKey, d.ID)
+	}
+	return nil
+}
+
+// CheckVault 校验主密钥，并保证元数据与远端都保存了保险库描述：
+//   - 两处都有描述时 ID 必须一致，校验值必须与主密钥匹配
+//   - 只有一处有描述时校验后补写到另一处
+//   - 都没有时（新保险库或旧版本创建的数据）先用主密钥解密一个已有文件的 FEK，成功后创建描述
+//
+// 远端不可用（未配置或读取失败）时只使用元数据中的描述，补写远端失败只记录日志
+func (p *Proxy) CheckVault(ctx context.Context) (*VaultDescriptor, error) {
+	store, _ := p.meta.(metadata.VaultStore)
+	local, err := p.loadLocalVault(store)
+	if err != nil {
+		return nil, err
+	}
+	stored, remoteOK := p.loadRemoteVault(ctx)
+
+	d := local
+	switch {
+	case local != nil && stored != nil && local.ID != stored.ID:
+		return nil, fmt.Errorf("%w: metadata has %s, remote has %s", ErrVaultMismatch, local.ID, stored.ID)
+	case d == nil:
+		d = stored
+	}
+	if d == nil {
+		if err := p.checkLegacyKey(); err != nil {
+			return nil, err
+		}
+		if d, err = p.newVault(); err != nil {
+			return nil, err
+		}
+		log.Printf("Proxy: Created vault %s", d.ID)
+	}
+	if err := d.check(p.masterKey); err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if local == nil && store != nil {
+		if err := store.SaveVault(data); err != nil {
+			return nil, fmt.Errorf("failed to save vault descriptor to metadata: %w", err)
+		}
+	}
+	if stored == nil && remoteOK {
+		if err := p.remote.Upload(ctx, VaultObjectName, bytes.NewReader(data), int64(len(data))); err != nil {
+			log.Printf("Proxy: Failed to upload vault descriptor: %v", err)
+		}
+	}
+	return d, nil
+}
+
+// RekeyVault 按当前主密钥重写保险库描述的校验值（保留 ID），用于通过恢复密钥更换主密钥之后
+// 与 CheckVault 不同，远端不可用或写入失败时返回错误，避免远端留下旧的校验值
+func (p *Proxy) RekeyVault(ctx context.Context) (*VaultDescriptor, error) {
+	store, _ := p.meta.(metadata.VaultStore)
+	local, err := p.loadLocalVault(store)
+	if err != nil {
+		return nil, err
+	}
+	stored, remoteOK := p.loadRemoteVault(ctx)
+	if p.remote != nil && !remoteOK {
+		return nil, errors.New("remote vault descriptor is unavailable")
+	}
+
+	d := local
+	switch {
+	case local != nil && stored != nil && local.ID != stored.ID:
+		return nil, fmt.Errorf("%w: metadata has %s, remote has %s", ErrVaultMismatch, local.ID, stored.ID)
+	case d == nil:
+		d = stored
+	}
+	if d == nil {
+		if d, err = p.newVault(); err != nil {
+			return nil, err
+		}
+	}
+	if d.Version > vaultVersion {
+		return nil, fmt.Errorf("vault format version %d is newer than supported (%d), please upgrade", d.Version, vaultVersion)
+	}
+	d.KCV = crypto.KeyCheckValue(p.masterKey, d.ID)
+
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if store != nil {
+		if err := store.SaveVault(data); err != nil {
+			return nil, fmt.Errorf("failed to save vault descriptor to metadata: %w", err)
+		}
+	}
+	if p.remote != nil {
+		if err := p.remote.Upload(ctx, VaultObjectName, bytes.NewReader(data), int64(len(data))); err != nil {
+			return nil, fmt.Errorf("failed to upload vault descriptor: %w", err)
+		}
+	}
+	return d, nil
+}
+
+func (p *Proxy) loadLocalVault(store metadata.VaultStore) (*VaultDescriptor, error) {
+	if store == nil {
+		return nil, nil
+	}
+	data, err := store.LoadVault()
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var d VaultDescriptor
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("invalid vault descriptor in metadata: %w", err)
+	}
+	return &d, nil
+}
+
+// loadRemoteVault 读取远端描述，第二个返回值表示远端可用（对象不存在也算可用）
+func (p *Proxy) loadRemoteVault(ctx context.Context) (*VaultDescriptor, bool) {
+	if p.remote == nil {
+		return nil, false
+	}
+	rc, err := p.remote.Download(ctx, VaultObjectName)
+	if err != nil {
+		if !remote.IsNotFound(err) {
+			log.Printf("Proxy: Failed to read vault descriptor from remote, using metadata only: %v", err)
+			return nil, false
+		}
+		return nil, true
+	}
+	defer rc.Close()
+	var d VaultDescriptor
+	if err := json.NewDecoder(io.LimitReader(rc, 64<<10)).Decode(&d); err != nil {
+		log.Printf("Proxy: Invalid vault descriptor on remote, ignoring: %v", err)
+		return nil, false
+	}
+	return &d, true
+}
+
+// checkLegacyKey 尚无描述时用主密钥解密第一个文件的 FEK，空保险库直接通过
+func (p *Proxy) checkLegacyKey() error {
+	errFound := errors.New("found")
+	var checkErr error
+	err := metadata.Walk(p.meta, "/", func(path string, m *metadata.FileMeta) error {
+		if len(m.FEK) == 0 {
+			return nil
+		}
+		if _, err := p.decryptFEK(m.FEK); err != nil {
+			checkErr = fmt.Errorf("%w: cannot decrypt the file key of '%s'", ErrWrongKey, path)
+		}
+		return errFound
+	})
+	if err != nil && err != errFound {
+		return fmt.Errorf("failed to read metadata: %w", err)
+	}
+	return checkErr
+}
+
+func (p *Proxy) newVault() (*VaultDescriptor, error) {
+	id, err := crypto.GenerateRandomBytes(16)
+	if err != nil {
+		return nil, err
+	}
+	d := &VaultDescriptor{
+		ID:        hex.EncodeToString(id),
+		Version:   vaultVersion,
+		CreatedAt: time.Now().UTC(),
+	}
+	d.KCV = crypto.KeyCheckValue(p.masterKey, d.ID)
+	return d, nil
+}
@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"bytes"
+	"clearvault/internal/metadata"
+	"clearvault/internal/remote"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const (
+	testMasterKey = "dGhpcy1pcy1hLTMyLWJ5dGUtbG9uZy1tYXN0ZXJrZXk="
+	otherTestKey  = "b3RoZXItMzItYnl0ZS1sb25nLW1hc3Rlci1rZXkhISE="
+)
+
+func newVaultProxy(t *testing.T, meta metadata.Storage, rs remote.RemoteStorage, key string) *Proxy {
+	t.Helper()
+	p, err := NewProxy(meta, rs, key)
+	if err != nil {
+		t.Fatalf("NewProxy failed: %v", err)
+	}
+	return p
+}
+
+func TestCheckVault(t *testing.T) {
+	ctx := context.Background()
+	meta := newTestMeta(t)
+	rs := newMockRemoteStorage()
+
+	d, err := newVaultProxy(t, meta, rs, testMasterKey).CheckVault(ctx)
+	if err != nil {
+		t.Fatalf("CheckVault failed: %v", err)
+	}
+	var stored VaultDescriptor
+	if err := json.Unmarshal(rs.files[VaultObjectName], &stored); err != nil || stored != *d {
+		t.Fatalf("Expected descriptor on remote, got %+v, %v", stored, err)
+	}
+	if data, _ := meta.(metadata.VaultStore).LoadVault(); !bytes.Contains(data, []byte(d.ID)) {
+		t.Fatalf("Expected descriptor in metadata, got %q", data)
+	}
+
+	// 再次启动沿用同一个保险库
+	again, err := newVaultProxy(t, meta, rs, testMasterKey).CheckVault(ctx)
+	if err != nil || again.ID != d.ID {
+		t.Errorf("Expected vault %s, got %+v, %v", d.ID, again, err)
+	}
+
+	if _, err := newVaultProxy(t, meta, rs, otherTestKey).CheckVault(ctx); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("Expected ErrWrongKey, got %v", err)
+	}
+
+	// 新的元数据目录（如重新部署）从远端取得描述并校验
+	fresh := newTestMeta(t)
+	if _, err := newVaultProxy(t, fresh, rs, otherTestKey).CheckVault(ctx); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("Expected ErrWrongKey from remote descriptor, got %v", err)
+	}
+	if _, err := newVaultProxy(t, fresh, rs, testMasterKey).CheckVault(ctx); err != nil {
+		t.Errorf("CheckVault failed: %v", err)
+	}
+
+	// 远端丢失描述时从元数据补写
+	delete(rs.files, VaultObjectName)
+	if _, err := newVaultProxy(t, meta, rs, testMasterKey).CheckVault(ctx); err != nil {
+		t.Fatalf("CheckVault failed: %v", err)
+	}
+	if _, ok := rs.files[VaultObjectName]; !ok {
+		t.Error("Expected descriptor to be restored on remote")
+	}
+
+	// 不使用远端的命令只校验元数据
+	if _, err := newVaultProxy(t, meta, nil, otherTestKey).CheckVault(ctx); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("Expected ErrWrongKey without remote, got %v", err)
+	}
+
+	// 元数据与远端属于不同的保险库
+	if _, err := newVaultProxy(t, newTestMeta(t), rs, testMasterKey).CheckVault(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newVaultProxy(t, meta, newMockRemoteStorage(), testMasterKey).CheckVault(ctx); err != nil {
+		t.Fatal(err)
+	}
+	other := newMockRemoteStorage()
+	if _, err := newVaultProxy(t, newTestMeta(t), other, testMasterKey).CheckVault(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newVaultProxy(t, meta, other, testMasterKey).CheckVault(ctx); !errors.Is(err, ErrVaultMismatch) {
+		t.Errorf("Expected ErrVaultMismatch, got %v", err)
+	}
+}
+
+func TestCheckVault_Legacy(t *testing.T) {
+	ctx := context.Background()
+	meta := newTestMeta(t)
+	rs := newMockRemoteStorage()
+
+	// 旧版本创建的数据没有描述，用已有文件的 FEK 校验主密钥
+	data := []byte("legacy file")
+	if err := newVaultProxy(t, meta, rs, testMasterKey).UploadFile(ctx, "/dir/a.txt", bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	if _, err := newVaultProxy(t, meta, rs, otherTestKey).CheckVault(ctx); !errors.Is(err, ErrWrongKey) {
+		t.Fatalf("Expected ErrWrongKey, got %v", err)
+	}
+	if _, ok := rs.files[VaultObjectName]; ok {
+		t.Fatal("Expected no descriptor to be created with the wrong key")
+	}
+	if _, err := newVaultProxy(t, meta, rs, testMasterKey).CheckVault(ctx); err != nil {
+		t.Fatalf("CheckVault failed: %v", err)
+	}
+	if _, ok := rs.files[VaultObjectName]; !ok {
+		t.Error("Expected descriptor to be created")
+	}
+}
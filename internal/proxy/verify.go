@@ -0,0 +1,74 @@
+package proxy
+
+import (
+	"clearvault/internal/remote"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+// ErrUploadMismatch 上传后远端对象的大小或摘要与写入的数据不一致
+var ErrUploadMismatch = errors.New("remote object does not match uploaded data")
+
+// SetVerifyUploads 设置上传后是否校验远端对象：比较远端大小，服务端提供 MD5 时同时比较摘要
+// 用于发现接受 PUT 后保存了截断或空文件的服务端
+func (p *Proxy) SetVerifyUploads(on bool) {
+	p.verify = on
+}
+
+// verifyUpload 读取远端对象信息与本地写入的数据比较，sum 为 nil 时只比较大小
+func (p *Proxy) verifyUpload(ctx context.Context, name string, size int64, sum []byte) error {
+	if !p.verify {
+		return nil
+	}
+	info, err := p.remote.Stat(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to verify upload of '%s': %w", name, err)
+	}
+	if info.Size() != size {
+		return fmt.Errorf("%w: '%s' is %d bytes on remote, expected %d", ErrUploadMismatch, name, info.Size(), size)
+	}
+	if c, ok := info.(remote.Checksummer); ok && sum != nil {
+		if got := c.MD5(); got != "" && !strings.EqualFold(got, hex.EncodeToString(sum)) {
+			return fmt.Errorf("%w: '%s' has MD5 %s on remote, expected %x", ErrUploadMismatch, name, got, sum)
+		}
+	}
+	return nil
+}
+
+// discardUpload 删除校验失败的远端对象，失败只记录日志
+func (p *Proxy) discardUpload(ctx context.Context, name string) {
+	if err := p.remote.Delete(ctx, name); err != nil {
+		log.Printf("Proxy: Failed to delete mismatched object '%s': %v", name, err)
+	}
+}
+
+// abandonUpload 直接上传失败后删除本次生成的对象名（尽力而为）
+// 对象名每次上传随机生成，不会再被续传；删除同时放弃可续传上传遗留的分块会话
+func (p *Proxy) abandonUpload(name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := p.remote.Delete(ctx, name); err != nil && !remote.IsNotFound(err) && !errors.Is(err, remote.ErrRemoteOffline) {
+		log.Printf("Proxy: Failed to clean up failed upload '%s': %v", name, err)
+	}
+}
+
+// hashWriter 统计写入的字节数并计算摘要
+type hashWriter struct {
+	w io.Writer
+	h hash.Hash
+	n int64
+}
+
+func (hw *hashWriter) Write(b []byte) (int, error) {
+	n, err := hw.w.Write(b)
+	hw.h.Write(b[:n])
+	hw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+// faultyRemote 可模拟服务端保存截断的文件，Stat 返回内容 MD5（可被篡改）
+type faultyRemote struct {
+	*mockRemoteStorage
+	truncate bool
+	wrongMD5 bool
+}
+
+type md5Info struct {
+	os.FileInfo
+	md5 string
+}
+
+func (i *md5Info) MD5() string { return i.md5 }
+
+func (m *faultyRemote) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	if err := m.mockRemoteStorage.Upload(ctx, name, data, size); err != nil {
+		return err
+	}
+	if m.truncate {
+		m.files[name] = m.files[name][:len(m.files[name])/2]
+	}
+	return nil
+}
+
+func (m *faultyRemote) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	info, err := m.mockRemoteStorage.Stat(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	sum := md5.Sum(m.files[name])
+	if m.wrongMD5 {
+		sum[0] ^= 0xff
+	}
+	return &md5Info{FileInfo: info, md5: hex.EncodeToString(sum[:])}, nil
+}
+
+func TestVerifyUploads(t *testing.T) {
+	ctx := context.Background()
+	remote := &faultyRemote{mockRemoteStorage: newMockRemoteStorage()}
+	meta := newTestMeta(t)
+	p, err := NewProxy(meta, remote, "dGhpcy1pcy1hLTMyLWJ5dGUtbG9uZy1tYXN0ZXJrZXk=")
+	if err != nil {
+		t.Fatalf("NewProxy failed: %v", err)
+	}
+	p.SetVerifyUploads(true)
+
+	v1 := bytes.Repeat([]byte("v1"), 50000)
+	if err := p.UploadFile(ctx, "/a.bin", bytes.NewReader(v1), int64(len(v1))); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	old, err := meta.Get("/a.bin")
+	if err != nil || old == nil {
+		t.Fatalf("Expected metadata after verified upload: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		truncate bool
+		wrongMD5 bool
+		size     int64
+	}{
+		{"truncated", true, false, 100000},
+		{"checksum mismatch", false, true, 100000},
+		{"truncated unknown size", true, false, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote.truncate, remote.wrongMD5 = tt.truncate, tt.wrongMD5
+			v2 := bytes.Repeat([]byte("v2"), 50000)
+			err := p.UploadFile(ctx, "/a.bin", bytes.NewReader(v2), tt.size)
+			if !errors.Is(err, ErrUploadMismatch) {
+				t.Fatalf("Expected ErrUploadMismatch, got %v", err)
+			}
+			// 元数据保持旧版本，校验失败的对象已删除
+			cur, _ := meta.Get("/a.bin")
+			if cur == nil || cur.RemoteName != old.RemoteName || cur.Size != old.Size {
+				t.Errorf("Expected metadata to keep the previous version, got %+v", cur)
+			}
+			if len(remote.files) != 1 {
+				t.Errorf("Expected mismatched object to be deleted, remote has %d objects", len(remote.files))
+			}
+		})
+	}
+
+	// 未启用校验时保持原有行为
+	p.SetVerifyUploads(false)
+	remote.truncate, remote.wrongMD5 = true, false
+	if err := p.UploadFile(ctx, "/b.bin", bytes.NewReader(v1), int64(len(v1))); err != nil {
+		t.Errorf("Expected upload without verification to succeed, got %v", err)
+	}
+}
+
+// brokenRemote 上传读取部分数据后失败，并留下残缺对象（如未完成的分块会话）
+type brokenRemote struct {
+	*mockRemoteStorage
+	deleted []string
+}
+
+func (m *brokenRemote) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	buf := make([]byte, 100)
+	n, _ := io.ReadFull(data, buf)
+	m.files[name] = buf[:n]
+	return errors.New("connection reset")
+}
+
+func (m *brokenRemote) Delete(ctx context.Context, name string) error {
+	m.deleted = append(m.deleted, name)
+	return m.mockRemoteStorage.Delete(ctx, name)
+}
+
+func TestUploadFailureCleansUp(t *testing.T) {
+	rs := &brokenRemote{mockRemoteStorage: newMockRemoteStorage()}
+	p, err := NewProxy(newTestMeta(t), rs, "dGhpcy1pcy1hLTMyLWJ5dGUtbG9uZy1tYXN0ZXJrZXk=")
+	if err != nil {
+		t.Fatalf("NewProxy failed: %v", err)
+	}
+	data := bytes.Repeat([]byte("x"), 4096)
+	if err := p.UploadFile(context.Background(), "/a.bin", bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Fatal("Expected upload to fail")
+	}
+	if len(rs.deleted) != 1 || len(rs.files) != 0 {
+		t.Errorf("Expected the failed object to be deleted, deleted=%v files=%d", rs.deleted, len(rs.files))
+	}
+	if m, _ := p.GetFileMeta("/a.bin"); m != nil {
+		t.Errorf("Expected no metadata for failed upload, got %+v", m)
+	}
+}
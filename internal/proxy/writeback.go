@@ -0,0 +1,501 @@
+package proxy
+
+import (
+	"clearvault/internal/remote"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WriteBackOptions 写回模式配置
+type WriteBackOptions struct {
+	StagingDir string        // 本地暂存目录（已加密、尚未上传的文件）
+	Workers    int           // 并发上传数
+	RetryDelay time.Duration // 首次重试等待，之后指数增长
+}
+
+const (
+	defaultWriteBackWorkers    = 2
+	defaultWriteBackRetryDelay = 10 * time.Second
+	maxWriteBackRetryDelay     = 10 * time.Minute
+
+	stagedDataSuffix = ".enc"
+	stagedInfoSuffix = ".json"
+)
+
+// 上传队列中条目的状态
+const (
+	StagedQueued    = "queued"
+	StagedUploading = "uploading"
+	StagedRetrying  = "retrying"
+)
+
+// StagedUpload 上传队列中的一个文件
+type StagedUpload struct {
+	RemoteName  string    `json:"remote_name"`
+	Path        string    `json:"path"` // 写入时的路径，之后重命名不会更新
+	Size        int64     `json:"size"` // 密文大小
+	QueuedAt    time.Time `json:"queued_at"`
+	State       string    `json:"state"`
+	Uploaded    int64     `json:"uploaded"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	NextAttempt time.Time `json:"next_attempt,omitempty"`
+}
+
+// stagedItem 队列条目及其运行时状态
+type stagedItem struct {
+	StagedUpload
+	uploaded atomic.Int64
+	cancel   context.CancelFunc // 上传中时取消上传
+	removed  bool               // 上传期间文件已被删除
+}
+
+// writeBack 写入先加密落到本地暂存目录并立即可见，后台队列再上传到远端
+// 暂存文件与描述文件（JSON）即持久化的队列，重启后重新加入队列
+type writeBack struct {
+	p    *Proxy
+	opts WriteBackOptions
+
+	mu     sync.Mutex
+	items  map[string]*stagedItem // 远端名 -> 条目
+	active int
+	closed bool
+	wake   chan struct{}
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// EnableWriteBack 启用写回模式
+// 启用时会把暂存目录中遗留的文件（例如进程重启前未上传完成的）重新加入上传队列
+func (p *Proxy) EnableWriteBack(opts WriteBackOptions) error {
+	if opts.Workers <= 0 {
+		opts.Workers = defaultWriteBackWorkers
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = defaultWriteBackRetryDelay
+	}
+	if opts.StagingDir == "" {
+		return fmt.Errorf("write-back staging directory is required")
+	}
+	if err := os.MkdirAll(opts.StagingDir, 0700); err != nil {
+		return fmt.Errorf("failed to create write-back staging directory: %w", err)
+	}
+
+	w := &writeBack{
+		p:     p,
+		opts:  opts,
+		items: make(map[string]*stagedItem),
+		wake:  make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+	if err := w.load(); err != nil {
+		return err
+	}
+	p.writeBack = w
+	if p.health != nil {
+		p.health.Watch(func(online bool) {
+			if online {
+				w.retryNow()
+			}
+		})
+	}
+	go w.run()
+	return nil
+}
+
+// WriteBackQueue 返回上传队列（按加入顺序），未启用写回时 ok 为 false
+func (p *Proxy) WriteBackQueue() (queue []StagedUpload, ok bool) {
+	if p.writeBack == nil {
+		return nil, false
+	}
+	return p.writeBack.queue(), true
+}
+
+func (w *writeBack) dataPath(name string) string {
+	return filepath.Join(w.opts.StagingDir, name+stagedDataSuffix)
+}
+
+func (w *writeBack) infoPath(name string) string {
+	return filepath.Join(w.opts.StagingDir, name+stagedInfoSuffix)
+}
+
+// load 扫描暂存目录恢复队列，没有描述文件的暂存数据视为未完成的写入并删除
+func (w *writeBack) load() error {
+	entries, err := os.ReadDir(w.opts.StagingDir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		p := filepath.Join(w.opts.StagingDir, e.Name())
+		if strings.HasSuffix(e.Name(), ".tmp") {
+			os.Remove(p)
+			continue
+		}
+		if e.IsDir() || !strings.HasSuffix(e.Name(), stagedInfoSuffix) {
+			continue
+		}
+		data, err := os.ReadFile(p)
+		var info StagedUpload
+		if err == nil {
+			err = json.Unmarshal(data, &info)
+		}
+		if err == nil {
+			_, err = os.Stat(w.dataPath(info.RemoteName))
+		}
+		if err != nil || info.RemoteName == "" {
+			log.Printf("Proxy: Ignoring invalid staged upload '%s': %v", e.Name(), err)
+			continue
+		}
+		info.State = StagedQueued
+		w.items[info.RemoteName] = &stagedItem{StagedUpload: info}
+	}
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), stagedDataSuffix)
+		if ok && w.items[name] == nil {
+			os.Remove(filepath.Join(w.opts.StagingDir, e.Name()))
+		}
+	}
+	if len(w.items) > 0 {
+		log.Printf("Proxy: Found %d staged uploads, scheduling upload", len(w.items))
+	}
+	return nil
+}
+
+// stage 把密文写入暂存文件并落盘，之后调用 commit 加入队列或 discard 放弃
+func (w *writeBack) stage(name, pname string, r io.Reader) error {
+	tmp := w.dataPath(name) + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create staged file: %w", err)
+	}
+	size, err := io.Copy(f, r)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, w.dataPath(name))
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write staged file: %w", err)
+	}
+
+	// 描述文件在元数据保存之前写入：进程崩溃时宁可多上传一个孤儿对象，也不丢失已保存元数据的文件
+	info := StagedUpload{RemoteName: name, Path: pname, Size: size, QueuedAt: time.Now()}
+	if err := writeJSONFile(w.infoPath(name), info); err != nil {
+		os.Remove(w.dataPath(name))
+		return fmt.Errorf("failed to write staged file: %w", err)
+	}
+	return nil
+}
+
+// commit 元数据保存后把暂存文件加入上传队列
+func (w *writeBack) commit(name string) {
+	data, err := os.ReadFile(w.infoPath(name))
+	var info StagedUpload
+	if err == nil {
+		err = json.Unmarshal(data, &info)
+	}
+	if err != nil {
+		log.Printf("Proxy: Failed to queue staged upload '%s': %v", name, err)
+		return
+	}
+	info.State = StagedQueued
+	w.mu.Lock()
+	w.items[name] = &stagedItem{StagedUpload: info}
+	w.mu.Unlock()
+	w.signal()
+}
+
+// discard 删除尚未加入队列的暂存文件
+func (w *writeBack) discard(name string) {
+	os.Remove(w.dataPath(name))
+	os.Remove(w.infoPath(name))
+}
+
+// remove 文件被删除时从队列移除，返回 true 表示对象尚未上传、无需删除远端
+// 正在上传的条目会被取消，若上传已经完成则由上传协程删除远端对象
+func (w *writeBack) remove(name string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	it, ok := w.items[name]
+	if !ok {
+		return false
+	}
+	if it.State == StagedUploading {
+		it.removed = true
+		it.cancel()
+		return true
+	}
+	delete(w.items, name)
+	w.discard(name)
+	return true
+}
+
+// staged 判断对象是否仍在暂存队列中
+func (w *writeBack) staged(name string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, ok := w.items[name]
+	return ok
+}
+
+// retryNow 远端恢复在线后立即重试所有等待中的上传
+func (w *writeBack) retryNow() {
+	w.mu.Lock()
+	for _, it := range w.items {
+		it.NextAttempt = time.Time{}
+	}
+	w.mu.Unlock()
+	w.signal()
+}
+
+// open 打开暂存文件，不在队列中时返回 nil
+func (w *writeBack) open(name string) (*os.File, error) {
+	w.mu.Lock()
+	_, ok := w.items[name]
+	w.mu.Unlock()
+	if !ok {
+		return nil, nil
+	}
+	f, err := os.Open(w.dataPath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		// 暂存文件可能刚刚上传完成并被删除
+		return nil, nil
+	}
+	return f, err
+}
+
+func (w *writeBack) queue() []StagedUpload {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	queue := make([]StagedUpload, 0, len(w.items))
+	for _, it := range w.items {
+		u := it.StagedUpload
+		u.Uploaded = it.uploaded.Load()
+		queue = append(queue, u)
+	}
+	sort.Slice(queue, func(i, j int) bool { return queue[i].QueuedAt.Before(queue[j].QueuedAt) })
+	return queue
+}
+
+func (w *writeBack) signal() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run 调度循环：按加入顺序启动到期的上传，并发数不超过 Workers
+func (w *writeBack) run() {
+	defer close(w.done)
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		w.mu.Lock()
+		if w.closed {
+			w.mu.Unlock()
+			return
+		}
+		var ready []*stagedItem
+		var next time.Time
+		now := time.Now()
+		// 远端离线时不发起上传，恢复在线后由 retryNow 唤醒
+		offline := w.p.health != nil && !w.p.health.Online()
+		for _, it := range w.items {
+			if it.State == StagedUploading || offline {
+				continue
+			}
+			if it.NextAttempt.After(now) {
+				if next.IsZero() || it.NextAttempt.Before(next) {
+					next = it.NextAttempt
+				}
+				continue
+			}
+			ready = append(ready, it)
+		}
+		sort.Slice(ready, func(i, j int) bool { return ready[i].QueuedAt.Before(ready[j].QueuedAt) })
+		for _, it := range ready {
+			if w.active >= w.opts.Workers {
+				break
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			it.State = StagedUploading
+			it.cancel = cancel
+			it.Attempts++
+			w.active++
+			w.wg.Add(1)
+			go w.upload(ctx, it)
+		}
+		w.mu.Unlock()
+
+		wait := time.Hour
+		if !next.IsZero() {
+			wait = time.Until(next)
+		}
+		timer.Reset(wait)
+		select {
+		case <-w.wake:
+		case <-timer.C:
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+	}
+}
+
+// upload 上传一个暂存文件，失败时按指数退避重新排队
+func (w *writeBack) upload(ctx context.Context, it *stagedItem) {
+	defer w.wg.Done()
+	name := it.RemoteName
+	err := w.uploadFile(ctx, it)
+
+	w.mu.Lock()
+	defer func() {
+		w.mu.Unlock()
+		w.signal()
+	}()
+	w.active--
+	it.cancel()
+	if it.removed {
+		delete(w.items, name)
+		w.discard(name)
+		if err == nil {
+			// 上传完成前文件已被删除
+			go w.p.remote.Delete(context.Background(), name)
+		}
+		return
+	}
+	if err != nil {
+		if w.closed {
+			// 关闭时中断的上传在下次启动后继续
+			it.State = StagedQueued
+			return
+		}
+		if errors.Is(err, remote.ErrRemoteOffline) {
+			// 远端离线不计入重试次数，恢复在线后立即重试
+			it.Attempts--
+			it.State = StagedQueued
+			it.LastError = err.Error()
+			it.uploaded.Store(0)
+			return
+		}
+		delay := w.opts.RetryDelay << min(it.Attempts-1, 16)
+		if delay > maxWriteBackRetryDelay {
+			delay = maxWriteBackRetryDelay
+		}
+		log.Printf("Proxy: Failed to upload staged file '%s' (attempt %d), retrying in %s: %v", it.Path, it.Attempts, delay, err)
+		it.State = StagedRetrying
+		it.LastError = err.Error()
+		it.NextAttempt = time.Now().Add(delay)
+		it.uploaded.Store(0)
+		return
+	}
+	log.Printf("Proxy: Uploaded staged file '%s' as '%s' (%d bytes)", it.Path, name, it.Size)
+	delete(w.items, name)
+	w.discard(name)
+}
+
+func (w *writeBack) uploadFile(ctx context.Context, it *stagedItem) error {
+	f, err := os.Open(w.dataPath(it.RemoteName))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	it.uploaded.Store(0)
+	ctx = w.p.storageClassContext(ctx, it.Path)
+	if err := w.p.remote.Upload(ctx, it.RemoteName, newProgressReader(f, &it.uploaded), it.Size); err != nil {
+		return err
+	}
+	// 校验失败按上传失败处理，稍后重新上传
+	return w.p.verifyUpload(ctx, it.RemoteName, it.Size, nil)
+}
+
+// Close 停止调度并取消进行中的上传，未完成的文件保留在暂存目录，下次启动后继续上传
+func (w *writeBack) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	for _, it := range w.items {
+		if it.State == StagedUploading {
+			it.cancel()
+		}
+	}
+	w.mu.Unlock()
+	w.signal()
+	<-w.done
+	w.wg.Wait()
+	return nil
+}
+
+// progressReader 统计已读取的字节数
+type progressReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n.Add(int64(n))
+	return n, err
+}
+
+// progressReadSeeker 数据源可 Seek 时使用，下层重试回退重放时进度同步回到新位置
+type progressReadSeeker struct {
+	*progressReader
+	seeker io.Seeker
+}
+
+func (r *progressReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	pos, err := r.seeker.Seek(offset, whence)
+	if err == nil {
+		r.n.Store(pos)
+	}
+	return pos, err
+}
+
+// newProgressReader 为数据源添加进度统计，数据源可 Seek 时返回的读取器同样可 Seek
+func newProgressReader(r io.Reader, n *atomic.Int64) io.Reader {
+	pr := &progressReader{r: r, n: n}
+	if s, ok := r.(io.Seeker); ok {
+		return &progressReadSeeker{progressReader: pr, seeker: s}
+	}
+	return pr
+}
+
+// writeJSONFile 原子写入 JSON 文件（先写临时文件再重命名）
+func writeJSONFile(p string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package proxy
+
+import (
+	"bytes"
+	"clearvault/internal/metadata"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// queueRemote 并发安全的内存远端，uploadHook 在每次上传前调用
+type queueRemote struct {
+	*mockRemoteStorage
+	mu         sync.Mutex
+	uploadHook func(ctx context.Context) error
+}
+
+func (m *queueRemote) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	m.mu.Lock()
+	hook := m.uploadHook
+	m.mu.Unlock()
+	if hook != nil {
+		if err := hook(ctx); err != nil {
+			return err
+		}
+	}
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[name] = content
+	return nil
+}
+
+func (m *queueRemote) has(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.files[name]
+	return ok
+}
+
+func (m *queueRemote) setHook(hook func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.uploadHook = hook
+}
+
+func newWriteBackTestProxy(t *testing.T, meta metadata.Storage, remote *queueRemote, dir string) *Proxy {
+	t.Helper()
+	p, err := NewProxy(meta, remote, "dGhpcy1pcy1hLTMyLWJ5dGUtbG9uZy1tYXN0ZXJrZXk=")
+	if err != nil {
+		t.Fatalf("NewProxy failed: %v", err)
+	}
+	if err := p.EnableWriteBack(WriteBackOptions{StagingDir: dir, RetryDelay: 10 * time.Millisecond}); err != nil {
+		t.Fatalf("EnableWriteBack failed: %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func newTestMeta(t *testing.T) metadata.Storage {
+	t.Helper()
+	meta, err := metadata.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to init metadata: %v", err)
+	}
+	return meta
+}
+
+func waitQueueEmpty(t *testing.T, p *Proxy) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if q, _ := p.WriteBackQueue(); len(q) == 0 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	q, _ := p.WriteBackQueue()
+	t.Fatalf("Upload queue not drained: %+v", q)
+}
+
+func stagedFiles(t *testing.T, dir string) int {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(entries)
+}
+
+func TestWriteBackUpload(t *testing.T) {
+	remote := &queueRemote{mockRemoteStorage: newMockRemoteStorage()}
+	release := make(chan struct{})
+	remote.setHook(func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	dir := t.TempDir()
+	meta := newTestMeta(t)
+	p := newWriteBackTestProxy(t, meta, remote, dir)
+
+	content := make([]byte, 200*1024+5)
+	rand.New(rand.NewSource(2)).Read(content)
+	if err := p.UploadFile(context.Background(), "/big.bin", bytes.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+
+	// 上传尚未完成时文件已可见、可读
+	fm, err := meta.Get("/big.bin")
+	if err != nil || fm == nil || fm.Size != int64(len(content)) {
+		t.Fatalf("Expected metadata to be saved, got %+v, %v", fm, err)
+	}
+	if got := readAllFile(t, p, "/big.bin"); !bytes.Equal(got, content) {
+		t.Error("Content mismatch while staged")
+	}
+	got := readRange(t, p, "/big.bin", 70000, 100)
+	if !bytes.Equal(got[70000%(64*1024):][:100], content[70000:70100]) {
+		t.Error("Range mismatch while staged")
+	}
+	q, _ := p.WriteBackQueue()
+	if len(q) != 1 || q[0].Path != "/big.bin" || q[0].RemoteName != fm.RemoteName || q[0].State == StagedRetrying {
+		t.Fatalf("Unexpected queue: %+v", q)
+	}
+
+	close(release)
+	waitQueueEmpty(t, p)
+	if !remote.has(fm.RemoteName) {
+		t.Fatal("Expected object to be uploaded")
+	}
+	if n := stagedFiles(t, dir); n != 0 {
+		t.Errorf("Expected staging dir to be empty, found %d entries", n)
+	}
+	if got := readAllFile(t, p, "/big.bin"); !bytes.Equal(got, content) {
+		t.Error("Content mismatch after upload")
+	}
+}
+
+func TestWriteBackRetry(t *testing.T) {
+	remote := &queueRemote{mockRemoteStorage: newMockRemoteStorage()}
+	var mu sync.Mutex
+	fails := 2
+	remote.setHook(func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if fails > 0 {
+			fails--
+			return errors.New("connection reset")
+		}
+		return nil
+	})
+	p := newWriteBackTestProxy(t, newTestMeta(t), remote, t.TempDir())
+
+	if err := p.UploadFile(context.Background(), "/a.txt", bytes.NewReader([]byte("hello")), 5); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	waitQueueEmpty(t, p)
+	if got := readAllFile(t, p, "/a.txt"); string(got) != "hello" {
+		t.Errorf("Content mismatch: %q", got)
+	}
+}
+
+func TestWriteBackRestart(t *testing.T) {
+	remote := &queueRemote{mockRemoteStorage: newMockRemoteStorage()}
+	remote.setHook(func(ctx context.Context) error { return errors.New("offline") })
+	dir := t.TempDir()
+	meta := newTestMeta(t)
+	p := newWriteBackTestProxy(t, meta, remote, dir)
+
+	if err := p.UploadFile(context.Background(), "/a.txt", bytes.NewReader([]byte("persist")), 7); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	q, _ := p.WriteBackQueue()
+	if len(q) != 1 || q[0].Attempts == 0 || q[0].LastError == "" {
+		t.Fatalf("Expected failed upload in queue, got %+v", q)
+	}
+	p.Close()
+
+	// 重启后暂存文件重新加入队列
+	remote.setHook(nil)
+	p = newWriteBackTestProxy(t, meta, remote, dir)
+	waitQueueEmpty(t, p)
+	fm, _ := meta.Get("/a.txt")
+	if !remote.has(fm.RemoteName) {
+		t.Fatal("Expected staged file to be uploaded after restart")
+	}
+	if got := readAllFile(t, p, "/a.txt"); string(got) != "persist" {
+		t.Errorf("Content mismatch: %q", got)
+	}
+}
+
+func TestWriteBackDelete(t *testing.T) {
+	remote := &queueRemote{mockRemoteStorage: newMockRemoteStorage()}
+	remote.setHook(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	dir := t.TempDir()
+	meta := newTestMeta(t)
+	p := newWriteBackTestProxy(t, meta, remote, dir)
+	ctx := context.Background()
+
+	for _, name := range []string{"/a.txt", "/b.txt", "/c.txt"} {
+		if err := p.UploadFile(ctx, name, bytes.NewReader([]byte("data")), 4); err != nil {
+			t.Fatalf("UploadFile failed: %v", err)
+		}
+	}
+	// 正在上传与排队中的文件都可以删除
+	for _, name := range []string{"/a.txt", "/b.txt", "/c.txt"} {
+		if err := p.RemoveAll(ctx, name); err != nil {
+			t.Fatalf("RemoveAll failed: %v", err)
+		}
+	}
+	waitQueueEmpty(t, p)
+	if n := stagedFiles(t, dir); n != 0 {
+		t.Errorf("Expected staging dir to be empty, found %d entries", n)
+	}
+	remote.mu.Lock()
+	defer remote.mu.Unlock()
+	if len(remote.files) != 0 {
+		t.Errorf("Expected nothing uploaded, got %d objects", len(remote.files))
+	}
+}
+
+func TestWriteBackDiscardIncomplete(t *testing.T) {
+	dir := t.TempDir()
+	// 没有描述文件的暂存数据是崩溃时尚未完成的写入
+	os.WriteFile(filepath.Join(dir, "orphan"+stagedDataSuffix), []byte("x"), 0600)
+	os.WriteFile(filepath.Join(dir, "partial"+stagedDataSuffix+".tmp"), []byte("x"), 0600)
+
+	remote := &queueRemote{mockRemoteStorage: newMockRemoteStorage()}
+	p := newWriteBackTestProxy(t, newTestMeta(t), remote, dir)
+	if q, _ := p.WriteBackQueue(); len(q) != 0 {
+		t.Errorf("Expected empty queue, got %+v", q)
+	}
+	if n := stagedFiles(t, dir); n != 0 {
+		t.Errorf("Expected incomplete files to be removed, found %d entries", n)
+	}
+}
+
+func TestProgressReaderSeek(t *testing.T) {
+	var n atomic.Int64
+	r := newProgressReader(bytes.NewReader(make([]byte, 100)), &n)
+	s, ok := r.(io.Seeker)
+	if !ok {
+		t.Fatal("Expected progress reader to keep io.Seeker")
+	}
+	io.CopyN(io.Discard, r, 60)
+	// 下层重试回退重放
+	if _, err := s.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if got := n.Load(); got != 0 {
+		t.Errorf("Expected progress reset on seek, got %d", got)
+	}
+	io.Copy(io.Discard, r)
+	if got := n.Load(); got != 100 {
+		t.Errorf("Expected progress 100 after replay, got %d", got)
+	}
+
+	if _, ok := newProgressReader(io.MultiReader(), &n).(io.Seeker); ok {
+		t.Error("Non-seekable source should not become seekable")
+	}
+}
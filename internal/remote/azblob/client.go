@@ -0,0 +1,281 @@
+package azblob
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+)
+
+const (
+	// blockSize 分块上传时每个 block 的大小
+	blockSize = 4 << 20
+	// copyPollInterval 等待服务端复制完成的轮询间隔
+	copyPollInterval = 200 * time.Millisecond
+)
+
+// AzureConfig Azure Blob 客户端配置
+type AzureConfig struct {
+	Endpoint    string // 服务地址，默认 https://<account>.blob.core.windows.net/
+	AccountName string
+	AccountKey  string
+	Container   string
+}
+
+// AzureClient Azure Blob 远端客户端实现
+type AzureClient struct {
+	client    *container.Client
+	container string
+}
+
+// azureFileInfo 实现 os.FileInfo 接口
+type azureFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (fi *azureFileInfo) Name() string       { return fi.name }
+func (fi *azureFileInfo) Size() int64        { return fi.size }
+func (fi *azureFileInfo) Mode() os.FileMode  { return 0644 }
+func (fi *azureFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *azureFileInfo) IsDir() bool        { return false }
+func (fi *azureFileInfo) Sys() interface{}   { return nil }
+
+// NewClient 创建 Azure Blob 客户端
+func NewClient(cfg AzureConfig) (*AzureClient, error) {
+	cred, err := container.NewSharedKeyCredential(cfg.AccountName, cfg.AccountKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid azure account key: %w", err)
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net/", cfg.AccountName)
+	}
+	containerURL := strings.TrimSuffix(endpoint, "/") + "/" + cfg.Container
+
+	// 重试由 remote.WithRetry 统一处理，关闭 SDK 自带的重试
+	client, err := container.NewClientWithSharedKeyCredential(containerURL, cred, &container.ClientOptions{
+		ClientOptions: azcore.ClientOptions{Retry: policy.RetryOptions{MaxRetries: -1}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create azure client: %w", err)
+	}
+
+	// 验证 container 是否存在
+	if _, err := client.GetProperties(context.Background(), nil); err != nil {
+		if bloberror.HasCode(err, bloberror.ContainerNotFound) {
+			return nil, fmt.Errorf("container '%s' does not exist", cfg.Container)
+		}
+		return nil, fmt.Errorf("failed to check container existence: %w", err)
+	}
+
+	return &AzureClient{
+		client:    client,
+		container: cfg.Container,
+	}, nil
+}
+
+// Upload 以 block blob 分块上传：依次 StageBlock，最后 CommitBlockList 原子生效
+// 提交前已上传的 block 不可见，失败时由服务端在一段时间后自动清理
+func (c *AzureClient) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	blobName := normalizePath(name)
+	log.Printf("Azure: Uploading '%s' to container '%s' (size: %d)", blobName, c.container, size)
+
+	bb := c.client.NewBlockBlobClient(blobName)
+	buf := make([]byte, blockSize)
+	var ids []string
+	for {
+		n, err := io.ReadFull(data, buf)
+		if n > 0 {
+			id := blockID(len(ids))
+			if _, serr := bb.StageBlock(ctx, id, streaming.NopCloser(bytes.NewReader(buf[:n])), nil); serr != nil {
+				return fmt.Errorf("failed to stage block %d of '%s': %w", len(ids), blobName, serr)
+			}
+			ids = append(ids, id)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read data for '%s': %w", blobName, err)
+		}
+	}
+
+	contentType := "application/octet-stream"
+	_, err := bb.CommitBlockList(ctx, ids, &blockblob.CommitBlockListOptions{
+		HTTPHeaders: &blob.HTTPHeaders{BlobContentType: &contentType},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit blob '%s': %w", blobName, err)
+	}
+	return nil
+}
+
+// blockID 生成定长的 block ID（同一 blob 的所有 ID 长度必须一致）
+func blockID(i int) string {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(i))
+	return base64.StdEncoding.EncodeToString(b[:])
+}
+
+// Download 从 Azure Blob 下载文件
+func (c *AzureClient) Download(ctx context.Context, name string) (io.ReadCloser, error) {
+	return c.DownloadRange(ctx, name, 0, 0)
+}
+
+// DownloadRange 使用 Range GET 下载指定字节范围，length 为 0 时读到文件末尾
+func (c *AzureClient) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	blobName := normalizePath(name)
+	log.Printf("Azure: Downloading range [%d:%d] for '%s'", start, length, blobName)
+
+	resp, err := c.client.NewBlobClient(blobName).DownloadStream(ctx, &blob.DownloadStreamOptions{
+		Range: blob.HTTPRange{Offset: start, Count: length},
+	})
+	if err != nil {
+		// 起始位置超出文件末尾
+		if bloberror.HasCode(err, bloberror.InvalidRange) {
+			return io.NopCloser(bytes.NewReader(nil)), nil
+		}
+		return nil, fmt.Errorf("failed to download blob '%s': %w", blobName, notExist(err))
+	}
+	return resp.Body, nil
+}
+
+// Delete 从 Azure Blob 删除文件
+func (c *AzureClient) Delete(ctx context.Context, path string) error {
+	blobName := normalizePath(path)
+	log.Printf("Azure: Deleting '%s' from container '%s'", blobName, c.container)
+
+	if _, err := c.client.NewBlobClient(blobName).Delete(ctx, nil); err != nil {
+		return fmt.Errorf("failed to delete blob '%s': %w", blobName, notExist(err))
+	}
+	return nil
+}
+
+// Rename 重命名文件（服务端复制 + 删除）
+func (c *AzureClient) Rename(ctx context.Context, oldPath, newPath string) error {
+	oldName := normalizePath(oldPath)
+	newName := normalizePath(newPath)
+	log.Printf("Azure: Renaming '%s' to '%s' in container '%s'", oldName, newName, c.container)
+
+	src := c.client.NewBlobClient(oldName)
+	dst := c.client.NewBlobClient(newName)
+	resp, err := dst.StartCopyFromURL(ctx, src.URL(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to copy blob from '%s' to '%s': %w", oldName, newName, notExist(err))
+	}
+
+	// 同一账户内的复制通常立即完成，否则轮询直到结束
+	status := resp.CopyStatus
+	for status != nil && *status == blob.CopyStatusTypePending {
+		select {
+		case <-ctx.Done():
+			dst.AbortCopyFromURL(context.Background(), *resp.CopyID, nil)
+			return ctx.Err()
+		case <-time.After(copyPollInterval):
+		}
+		props, err := dst.GetProperties(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to check copy status of '%s': %w", newName, err)
+		}
+		status = props.CopyStatus
+		if status != nil && *status != blob.CopyStatusTypePending && *status != blob.CopyStatusTypeSuccess {
+			desc := ""
+			if props.CopyStatusDescription != nil {
+				desc = *props.CopyStatusDescription
+			}
+			return fmt.Errorf("copy of '%s' to '%s' %s: %s", oldName, newName, *status, desc)
+		}
+	}
+
+	if _, err := src.Delete(ctx, nil); err != nil {
+		// 新对象已存在，删除失败只记录警告
+		log.Printf("Azure: Warning: Failed to delete original blob '%s' after rename: %v", oldName, err)
+	}
+	return nil
+}
+
+// Stat 获取 Azure Blob 文件信息
+func (c *AzureClient) Stat(ctx context.Context, path string) (os.FileInfo, error) {
+	blobName := normalizePath(path)
+
+	props, err := c.client.NewBlobClient(blobName).GetProperties(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat blob '%s': %w", blobName, notExist(err))
+	}
+
+	info := &azureFileInfo{name: filepath.Base(path)}
+	if props.ContentLength != nil {
+		info.size = *props.ContentLength
+	}
+	if props.LastModified != nil {
+		info.modTime = *props.LastModified
+	}
+	return info, nil
+}
+
+// List 枚举 container 中以 prefix 开头的 blob
+func (c *AzureClient) List(ctx context.Context, prefix string, fn func(name string, info os.FileInfo) error) error {
+	p := normalizePath(prefix)
+	pager := c.client.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{Prefix: &p})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list blobs with prefix '%s': %w", p, err)
+		}
+		for _, item := range page.Segment.BlobItems {
+			if item.Name == nil {
+				continue
+			}
+			info := &azureFileInfo{name: path.Base(*item.Name)}
+			if props := item.Properties; props != nil {
+				if props.ContentLength != nil {
+					info.size = *props.ContentLength
+				}
+				if props.LastModified != nil {
+					info.modTime = *props.LastModified
+				}
+			}
+			if err := fn(*item.Name, info); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close 清理资源（Azure 客户端不需要显式关闭）
+func (c *AzureClient) Close() error {
+	return nil
+}
+
+// notExist 将 BlobNotFound 转换为 os.ErrNotExist，便于上层统一判断
+func notExist(err error) error {
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return fmt.Errorf("%w: %w", os.ErrNotExist, err)
+	}
+	return err
+}
+
+// normalizePath 规范化 blob 名称：移除前导斜杠并转换为正斜杠
+func normalizePath(path string) string {
+	return strings.TrimPrefix(filepath.ToSlash(path), "/")
+}
@@ -0,0 +1,109 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// CopyOptions 远端间复制设置
+type CopyOptions struct {
+	Workers int // 并发复制数，不大于 0 时为 4
+}
+
+// CopyStats 远端间复制统计
+type CopyStats struct {
+	Objects int   // 需要复制的对象数
+	Copied  int   // 本次复制的对象数
+	Skipped int   // 目标已存在且大小一致而跳过的对象数
+	Missing int   // 源远端不存在的对象数
+	Failed  int   // 复制或校验失败的对象数
+	Bytes   int64 // 本次复制的字节数
+}
+
+// Copy 把 names 中的对象原样从 src 复制到 dst，复制后校验目标大小
+// 目标中已存在且大小一致的对象直接跳过，中断后重新运行即从未完成的对象继续
+func Copy(ctx context.Context, src, dst RemoteStorage, names []string, opts CopyOptions) (CopyStats, error) {
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+	stats := CopyStats{Objects: len(names)}
+	var mu sync.Mutex
+	done := 0
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range jobs {
+				var n int64
+				var skipped bool
+				info, err := src.Stat(ctx, name)
+				missing := IsNotFound(err)
+				if err == nil {
+					n, skipped, err = copyObject(ctx, src, dst, name, info.Size())
+				}
+				mu.Lock()
+				switch {
+				case missing:
+					log.Printf("Remote: Object '%s' not found on source, skipped", name)
+					stats.Missing++
+				case err != nil:
+					log.Printf("Remote: Failed to copy '%s': %v", name, err)
+					stats.Failed++
+				case skipped:
+					stats.Skipped++
+				default:
+					stats.Copied++
+					stats.Bytes += n
+				}
+				done++
+				if done%100 == 0 || done == len(names) {
+					log.Printf("Remote: Copy progress %d/%d objects", done, len(names))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, name := range names {
+		select {
+		case jobs <- name:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return stats, ctx.Err()
+}
+
+// copyObject 复制单个对象，返回复制的字节数及是否因目标已存在而跳过
+func copyObject(ctx context.Context, src, dst RemoteStorage, name string, size int64) (int64, bool, error) {
+	if di, err := dst.Stat(ctx, name); err == nil && di.Size() == size {
+		return 0, true, nil
+	}
+
+	rc, err := src.Download(ctx, name)
+	if err != nil {
+		return 0, false, err
+	}
+	err = dst.Upload(ctx, name, rc, size)
+	rc.Close()
+	if err != nil {
+		return 0, false, fmt.Errorf("upload failed: %w", err)
+	}
+
+	di, err := dst.Stat(ctx, name)
+	if err != nil {
+		return 0, false, fmt.Errorf("verify failed: %w", err)
+	}
+	if di.Size() != size {
+		return 0, false, fmt.Errorf("size mismatch after copy: source %d bytes, destination %d bytes", size, di.Size())
+	}
+	return size, false, nil
+}
@@ -0,0 +1,106 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"clearvault/internal/remote/local"
+)
+
+func newTestLocal(t *testing.T) (RemoteStorage, string) {
+	t.Helper()
+	dir := t.TempDir()
+	c, err := local.NewClient(dir)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c, dir
+}
+
+// shortStorage 上传时截断数据，模拟目标写入不完整
+type shortStorage struct {
+	RemoteStorage
+}
+
+func (s shortStorage) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	return s.RemoteStorage.Upload(ctx, name, io.LimitReader(data, size/2), size/2)
+}
+
+func TestCopy(t *testing.T) {
+	ctx := context.Background()
+	src, _ := newTestLocal(t)
+	dst, dstDir := newTestLocal(t)
+
+	objects := map[string]string{
+		"aa": "first object",
+		"bb": strings.Repeat("x", 100000),
+		"cc": "third",
+	}
+	for name, data := range objects {
+		if err := src.Upload(ctx, name, strings.NewReader(data), int64(len(data))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names := []string{"aa", "bb", "cc", "gone"}
+
+	stats, err := Copy(ctx, src, dst, names, CopyOptions{Workers: 2})
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	want := CopyStats{Objects: 4, Copied: 3, Missing: 1, Bytes: 12 + 100000 + 5}
+	if stats != want {
+		t.Errorf("Expected %+v, got %+v", want, stats)
+	}
+	for name, data := range objects {
+		got, err := os.ReadFile(filepath.Join(dstDir, name))
+		if err != nil || !bytes.Equal(got, []byte(data)) {
+			t.Errorf("Object %s not copied correctly: %v", name, err)
+		}
+	}
+
+	// 重新运行时跳过已完成的对象，只补齐缺少或不完整的
+	os.Remove(filepath.Join(dstDir, "aa"))
+	os.WriteFile(filepath.Join(dstDir, "cc"), []byte("thi"), 0644)
+	stats, err = Copy(ctx, src, dst, names, CopyOptions{})
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	want = CopyStats{Objects: 4, Copied: 2, Skipped: 1, Missing: 1, Bytes: 12 + 5}
+	if stats != want {
+		t.Errorf("Expected %+v, got %+v", want, stats)
+	}
+}
+
+func TestCopy_SizeMismatch(t *testing.T) {
+	ctx := context.Background()
+	src, _ := newTestLocal(t)
+	dst, _ := newTestLocal(t)
+	if err := src.Upload(ctx, "aa", strings.NewReader("0123456789"), 10); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := Copy(ctx, src, shortStorage{dst}, []string{"aa"}, CopyOptions{})
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if stats.Failed != 1 || stats.Copied != 0 {
+		t.Errorf("Expected size mismatch to be reported as failure, got %+v", stats)
+	}
+}
+
+func TestCopy_Canceled(t *testing.T) {
+	src, _ := newTestLocal(t)
+	dst, _ := newTestLocal(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := Copy(ctx, src, dst, []string{"aa", "bb"}, CopyOptions{}); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
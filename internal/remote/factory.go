@@ -1,36 +1,299 @@
 package remote
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"clearvault/internal/config"
+	"clearvault/internal/remote/azblob"
+	"clearvault/internal/remote/gcs"
 	"clearvault/internal/remote/local"
 	"clearvault/internal/remote/s3"
+	"clearvault/internal/remote/sftp"
 	"clearvault/internal/remote/webdav"
 )
 
 // NewRemoteStorage 根据配置创建远程存储客户端
 // 支持多种存储后端：WebDAV、S3、MinIO、Cloudflare R2、Local Filesystem 等
 func NewRemoteStorage(cfg config.RemoteConfig) (RemoteStorage, error) {
-	// 确定存储类型（默认为 WebDAV 以保持向后兼容）
-	storageType := strings.ToLower(strings.TrimSpace(cfg.Type))
-	if storageType == "" {
-		storageType = "webdav"
+	rs, err := newStorage(cfg)
+	if err != nil {
+		return nil, err
 	}
+	// 限速层总是存在，未配置时不限制，便于运行时通过管理接口调整
+	throttle, err := WithThrottle(rs, cfg.Bandwidth)
+	if err != nil {
+		rs.Close()
+		return nil, err
+	}
+	rs = throttle
+	if cfg.Health.Enabled {
+		rs = WithHealth(rs, Health{
+			FailureThreshold: cfg.Health.FailureThreshold,
+			ProbeInterval:    time.Duration(cfg.Health.ProbeInterval) * time.Second,
+		})
+	}
+	// 目录布局在最外层，组合存储的各子远端收到的都是分层后的路径
+	layout, err := WithLayout(rs, Layout{Levels: cfg.Layout.Levels, Width: cfg.Layout.Width})
+	if err != nil {
+		rs.Close()
+		return nil, err
+	}
+	return layout, nil
+}
+
+// newStorage 创建不含目录布局的存储，配置了容量上限时在最外层统计空间
+func newStorage(cfg config.RemoteConfig) (RemoteStorage, error) {
+	if cfg.Quota < 0 {
+		return nil, fmt.Errorf("quota must not be negative")
+	}
+	rs, err := newStack(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return WithQuotaLimit(rs, cfg.Quota<<30), nil
+}
+
+// newStack 创建存储后端及超时、重试、分块上传等中间层
+func newStack(cfg config.RemoteConfig) (RemoteStorage, error) {
+	switch storageType(cfg) {
+	case "mirror":
+		return newMirror(cfg)
+	case "striped":
+		return newStriped(cfg)
+	}
+	backend, err := newBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// 超时作用于每次尝试，重试在外层
+	rs := WithTimeouts(backend, Timeouts{
+		Operation: time.Duration(cfg.Timeouts.Operation) * time.Second,
+		Idle:      time.Duration(cfg.Timeouts.Idle) * time.Second,
+	})
+	rs = WithRetry(rs, retryPolicy(cfg.Retry))
+	if cfg.ParallelUpload.Enabled {
+		if cfg.Resumable.Enabled {
+			rs.Close()
+			return nil, fmt.Errorf("parallel_upload cannot be combined with resumable")
+		}
+		parallel, err := newParallel(cfg, rs, backend)
+		if err != nil {
+			rs.Close()
+			return nil, err
+		}
+		return parallel, nil
+	}
+	if !cfg.Resumable.Enabled {
+		return rs, nil
+	}
+	resumable, err := newResumable(cfg, rs, backend)
+	if err != nil {
+		rs.Close()
+		return nil, err
+	}
+	return resumable, nil
+}
+
+// newResumable 为后端添加可续传分块上传，分块请求直接发往后端并按重试策略逐块重试
+func newResumable(cfg config.RemoteConfig, rs, backend RemoteStorage) (RemoteStorage, error) {
+	cu, ok := backend.(ChunkUploader)
+	if !ok {
+		return nil, fmt.Errorf("resumable uploads are not supported by %s remotes", storageType(cfg))
+	}
+	chunkSize := cfg.Resumable.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = 16
+	}
+	if chunkSize < 5 {
+		return nil, fmt.Errorf("resumable: chunk_size must be at least 5 (MiB), got %d", chunkSize)
+	}
+	dir := cfg.Resumable.JournalDir
+	if dir == "" {
+		dir = "storage/cache/uploads"
+	}
+	return WithResumable(rs, cu, Resumable{
+		ID:         storageType(cfg) + " " + remoteTarget(cfg),
+		JournalDir: dir,
+		ChunkSize:  int64(chunkSize) << 20,
+		MaxAge:     time.Duration(cfg.Resumable.MaxAge) * time.Hour,
+	}, retryPolicy(cfg.Retry))
+}
 
-	switch storageType {
+// newParallel 为后端添加并行分块上传，分块请求直接发往后端并按重试策略逐块重试
+func newParallel(cfg config.RemoteConfig, rs, backend RemoteStorage) (RemoteStorage, error) {
+	cu, ok := backend.(ChunkUploader)
+	if !ok {
+		return nil, fmt.Errorf("parallel uploads are not supported by %s remotes", storageType(cfg))
+	}
+	partSize := cfg.ParallelUpload.PartSize
+	if partSize == 0 {
+		partSize = 16
+	}
+	if partSize < 5 {
+		return nil, fmt.Errorf("parallel_upload: part_size must be at least 5 (MiB), got %d", partSize)
+	}
+	if cfg.ParallelUpload.Parallel < 0 {
+		return nil, fmt.Errorf("parallel_upload: parallel must not be negative")
+	}
+	return WithParallelUpload(rs, cu, ParallelUpload{
+		PartSize: int64(partSize) << 20,
+		Parallel: cfg.ParallelUpload.Parallel,
+	}, retryPolicy(cfg.Retry)), nil
+}
+
+// retryPolicy 将配置转换为重试策略，未设置的字段使用默认值
+func retryPolicy(cfg config.RetryConfig) RetryPolicy {
+	p := RetryPolicy{
+		MaxAttempts: cfg.MaxAttempts,
+		BaseDelay:   time.Duration(cfg.BaseDelay) * time.Millisecond,
+		MaxDelay:    time.Duration(cfg.MaxDelay) * time.Millisecond,
+	}
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = 3
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = 30 * time.Second
+	}
+	return p
+}
+
+// newBackend 根据类型创建具体的存储后端
+func newBackend(cfg config.RemoteConfig) (RemoteStorage, error) {
+	switch storageType := storageType(cfg); storageType {
 	case "webdav", "dav":
 		return newWebDAVClient(cfg)
 	case "s3", "minio", "r2":
 		return newS3Client(cfg)
+	case "azblob", "azure":
+		return newAzureClient(cfg)
+	case "gcs", "gcp":
+		return newGCSClient(cfg)
 	case "local", "filesystem", "fs":
 		return newLocalClient(cfg)
+	case "sftp", "ssh":
+		return newSFTPClient(cfg)
 	default:
-		return nil, fmt.Errorf("unsupported storage type: %s (supported: webdav, s3, local)", storageType)
+		return nil, fmt.Errorf("unsupported storage type: %s (supported: webdav, s3, azblob, gcs, local, sftp, mirror, striped)", storageType)
 	}
 }
 
+// storageType 确定存储类型（默认为 WebDAV 以保持向后兼容）
+func storageType(cfg config.RemoteConfig) string {
+	t := strings.ToLower(strings.TrimSpace(cfg.Type))
+	if t == "" {
+		t = "webdav"
+	}
+	return t
+}
+
+// newMembers 创建组合存储的各个子远端，超时与重试由各子远端自行配置
+func newMembers(kind, field string, children []config.RemoteConfig) ([]RemoteStorage, []string, error) {
+	members := make([]RemoteStorage, 0, len(children))
+	names := make([]string, 0, len(children))
+	for i, child := range children {
+		if t := storageType(child); t == "mirror" || t == "striped" {
+			closeMembers(members)
+			return nil, nil, fmt.Errorf("%s: nested %s is not supported (%s[%d])", kind, t, field, i)
+		}
+		if child.Layout.Levels > 0 {
+			closeMembers(members)
+			return nil, nil, fmt.Errorf("%s: layout must be set on the top-level remote, not %s[%d]", kind, field, i)
+		}
+		rs, err := newStorage(child)
+		if err != nil {
+			closeMembers(members)
+			return nil, nil, fmt.Errorf("%s: %s[%d]: %w", kind, field, i, err)
+		}
+		members = append(members, rs)
+		names = append(names, memberName(field, i, child))
+	}
+	return members, names, nil
+}
+
+func closeMembers(members []RemoteStorage) {
+	for _, rs := range members {
+		rs.Close()
+	}
+}
+
+// newMirror 创建镜像存储
+func newMirror(cfg config.RemoteConfig) (RemoteStorage, error) {
+	if len(cfg.Mirrors) < 2 {
+		return nil, fmt.Errorf("mirror: at least two mirrors are required")
+	}
+	replicas, names, err := newMembers("mirror", "mirrors", cfg.Mirrors)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := 300 * time.Second
+	if cfg.ResyncInterval > 0 {
+		interval = time.Duration(cfg.ResyncInterval) * time.Second
+	}
+	state := cfg.MirrorState
+	if state == "" {
+		state = "storage/cache/mirror-state.json"
+	}
+	m, err := NewMirrorStorage(replicas, names, MirrorOptions{
+		WriteQuorum:    cfg.WriteQuorum,
+		ResyncInterval: interval,
+		StateFile:      state,
+	})
+	if err != nil {
+		closeMembers(replicas)
+		return nil, err
+	}
+	return m, nil
+}
+
+// newStriped 创建纠删码条带存储
+func newStriped(cfg config.RemoteConfig) (RemoteStorage, error) {
+	if cfg.DataShards < 1 || len(cfg.Shards) <= cfg.DataShards {
+		return nil, fmt.Errorf("striped: data_shards must be at least 1 and less than the number of shards (%d)", len(cfg.Shards))
+	}
+	members, names, err := newMembers("striped", "shards", cfg.Shards)
+	if err != nil {
+		return nil, err
+	}
+	s, err := NewStripedStorage(members, names, StripedOptions{
+		DataShards:  cfg.DataShards,
+		WriteQuorum: cfg.WriteQuorum,
+	})
+	if err != nil {
+		closeMembers(members)
+		return nil, err
+	}
+	return s, nil
+}
+
+// memberName 生成日志中使用的子远端名称
+func memberName(field string, i int, cfg config.RemoteConfig) string {
+	typ := cfg.Type
+	if typ == "" {
+		typ = "webdav"
+	}
+	return fmt.Sprintf("%s[%d] %s %s", field, i, typ, remoteTarget(cfg))
+}
+
+// remoteTarget 返回远端的访问目标（URL、bucket、目录等）
+func remoteTarget(cfg config.RemoteConfig) string {
+	target := cfg.URL
+	switch {
+	case cfg.Container != "":
+		target = cfg.AccountName + "/" + cfg.Container
+	case cfg.Bucket != "":
+		target = cfg.Endpoint + "/" + cfg.Bucket
+	case cfg.Host != "":
+		target = cfg.Host + "/" + cfg.RootPath
+	case cfg.LocalPath != "":
+		target = cfg.LocalPath
+	}
+	return target
+}
+
 // newWebDAVClient 创建 WebDAV 客户端
 func newWebDAVClient(cfg config.RemoteConfig) (RemoteStorage, error) {
 	if cfg.URL == "" {
@@ -38,9 +301,10 @@ func newWebDAVClient(cfg config.RemoteConfig) (RemoteStorage, error) {
 	}
 
 	return webdav.NewClient(webdav.WebDAVConfig{
-		URL:  cfg.URL,
-		User: cfg.User,
-		Pass: cfg.Pass,
+		URL:     cfg.URL,
+		User:    cfg.User,
+		Pass:    cfg.Pass,
+		Chunked: cfg.Resumable.Enabled || cfg.ParallelUpload.Enabled,
 	})
 }
 
@@ -72,13 +336,83 @@ func newS3Client(cfg config.RemoteConfig) (RemoteStorage, error) {
 		AccessKey: cfg.AccessKey,
 		SecretKey: cfg.SecretKey,
 		UseSSL:    cfg.UseSSL,
+
+		Addressing:     cfg.S3.Addressing,
+		CAFile:         cfg.S3.CAFile,
+		StorageClass:   cfg.S3.StorageClass,
+		SSE:            cfg.S3.SSE,
+		SSEKMSKeyID:    cfg.S3.SSEKMSKeyID,
+		SSECKey:        cfg.S3.SSECKey,
+		LockMode:       cfg.S3.ObjectLock.Mode,
+		LockDays:       cfg.S3.ObjectLock.Days,
+		DeleteVersions: cfg.S3.DeleteVersions,
 	})
 }
 
+// WithStorageClass 为本次上传指定存储类别，只有 S3 远端使用，其他类型忽略
+func WithStorageClass(ctx context.Context, class string) context.Context {
+	return s3.WithStorageClass(ctx, class)
+}
+
 // newLocalClient 创建本地文件系统客户端
 func newLocalClient(cfg config.RemoteConfig) (RemoteStorage, error) {
 	if cfg.LocalPath == "" {
 		return nil, fmt.Errorf("local: local_path is required")
 	}
+	if cfg.LocalReadOnly {
+		return local.NewReadOnlyClient(cfg.LocalPath)
+	}
 	return local.NewClient(cfg.LocalPath)
 }
+
+// newAzureClient 创建 Azure Blob 客户端
+func newAzureClient(cfg config.RemoteConfig) (RemoteStorage, error) {
+	if cfg.AccountName == "" {
+		return nil, fmt.Errorf("azblob: account_name is required")
+	}
+	if cfg.AccountKey == "" {
+		return nil, fmt.Errorf("azblob: account_key is required")
+	}
+	if cfg.Container == "" {
+		return nil, fmt.Errorf("azblob: container is required")
+	}
+
+	return azblob.NewClient(azblob.AzureConfig{
+		Endpoint:    cfg.Endpoint,
+		AccountName: cfg.AccountName,
+		AccountKey:  cfg.AccountKey,
+		Container:   cfg.Container,
+	})
+}
+
+// newGCSClient 创建 Google Cloud Storage 客户端
+func newGCSClient(cfg config.RemoteConfig) (RemoteStorage, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("gcs: bucket is required")
+	}
+
+	return gcs.NewClient(gcs.GCSConfig{
+		Endpoint:        cfg.Endpoint,
+		Bucket:          cfg.Bucket,
+		CredentialsFile: cfg.CredentialsFile,
+	})
+}
+
+// newSFTPClient 创建 SFTP 客户端
+func newSFTPClient(cfg config.RemoteConfig) (RemoteStorage, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("sftp: host is required")
+	}
+	if cfg.User == "" {
+		return nil, fmt.Errorf("sftp: user is required")
+	}
+	return sftp.NewClient(sftp.SFTPConfig{
+		Host:          cfg.Host,
+		User:          cfg.User,
+		Pass:          cfg.Pass,
+		KeyFile:       cfg.KeyFile,
+		KeyPassphrase: cfg.KeyPassphrase,
+		KnownHosts:    cfg.KnownHosts,
+		RootPath:      cfg.RootPath,
+	})
+}
@@ -1,9 +1,21 @@
 package remote
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"clearvault/internal/config"
+	"clearvault/internal/remote/local"
 )
 
 func TestNewRemoteStorage(t *testing.T) {
@@ -115,6 +127,64 @@ func TestNewRemoteStorage(t *testing.T) {
 		}
 	})
 
+	t.Run("create read-only local client", func(t *testing.T) {
+		cfg := config.RemoteConfig{
+			Type:          "local",
+			LocalPath:     filepath.Join(t.TempDir(), "missing"),
+			LocalReadOnly: true,
+		}
+		if _, err := NewRemoteStorage(cfg); err == nil {
+			t.Error("Expected error for missing read-only root")
+		}
+
+		cfg.LocalPath = t.TempDir()
+		client, err := NewRemoteStorage(cfg)
+		if err != nil {
+			t.Fatalf("NewRemoteStorage failed: %v", err)
+		}
+		defer client.Close()
+		if err := client.Upload(context.Background(), "obj", strings.NewReader("x"), 1); !errors.Is(err, local.ErrReadOnly) {
+			t.Errorf("Expected ErrReadOnly, got %v", err)
+		}
+	})
+
+	t.Run("create azblob client with missing container", func(t *testing.T) {
+		cfg := config.RemoteConfig{
+			Type:        "azblob",
+			AccountName: "account",
+			AccountKey:  "a2V5",
+		}
+
+		_, err := NewRemoteStorage(cfg)
+		if err == nil {
+			t.Error("Expected error for missing azblob container")
+		}
+	})
+
+	t.Run("create gcs client with missing bucket", func(t *testing.T) {
+		cfg := config.RemoteConfig{
+			Type: "gcs",
+		}
+
+		_, err := NewRemoteStorage(cfg)
+		if err == nil {
+			t.Error("Expected error for missing gcs bucket")
+		}
+	})
+
+	t.Run("create sftp client with missing host", func(t *testing.T) {
+		cfg := config.RemoteConfig{
+			Type: "sftp",
+			User: "user",
+			Pass: "pass",
+		}
+
+		_, err := NewRemoteStorage(cfg)
+		if err == nil {
+			t.Error("Expected error for missing sftp host")
+		}
+	})
+
 	t.Run("default to webdav", func(t *testing.T) {
 		cfg := config.RemoteConfig{
 			Type: "", // Empty type should default to webdav
@@ -141,6 +211,187 @@ func TestNewRemoteStorage(t *testing.T) {
 		}
 	})
 
+	t.Run("create mirror", func(t *testing.T) {
+		cfg := config.RemoteConfig{
+			Type:        "mirror",
+			WriteQuorum: 1,
+			Mirrors: []config.RemoteConfig{
+				{Type: "local", LocalPath: t.TempDir()},
+				{Type: "local", LocalPath: t.TempDir()},
+			},
+		}
+
+		client, err := NewRemoteStorage(cfg)
+		if err != nil {
+			t.Fatalf("NewRemoteStorage failed: %v", err)
+		}
+		if _, ok := Unwrap(client).(*MirrorStorage); !ok {
+			t.Errorf("Expected *MirrorStorage, got %T", Unwrap(client))
+		}
+		client.Close()
+	})
+
+	t.Run("mirror with invalid quorum", func(t *testing.T) {
+		cfg := config.RemoteConfig{
+			Type:        "mirror",
+			WriteQuorum: 3,
+			Mirrors: []config.RemoteConfig{
+				{Type: "local", LocalPath: t.TempDir()},
+				{Type: "local", LocalPath: t.TempDir()},
+			},
+		}
+
+		_, err := NewRemoteStorage(cfg)
+		if err == nil {
+			t.Error("Expected error for write_quorum larger than mirror count")
+		}
+	})
+
+	t.Run("mirror with single child", func(t *testing.T) {
+		cfg := config.RemoteConfig{
+			Type:    "mirror",
+			Mirrors: []config.RemoteConfig{{Type: "local", LocalPath: t.TempDir()}},
+		}
+
+		_, err := NewRemoteStorage(cfg)
+		if err == nil {
+			t.Error("Expected error for mirror with one child")
+		}
+	})
+
+	t.Run("create with layout", func(t *testing.T) {
+		cfg := config.RemoteConfig{
+			Type:      "local",
+			LocalPath: t.TempDir(),
+			Layout:    config.LayoutConfig{Levels: 2},
+		}
+
+		client, err := NewRemoteStorage(cfg)
+		if err != nil {
+			t.Fatalf("NewRemoteStorage failed: %v", err)
+		}
+		if _, ok := client.(*LayoutStorage); !ok {
+			t.Errorf("Expected *LayoutStorage, got %T", client)
+		}
+		client.Close()
+	})
+
+	t.Run("mirror child with layout", func(t *testing.T) {
+		cfg := config.RemoteConfig{
+			Type: "mirror",
+			Mirrors: []config.RemoteConfig{
+				{Type: "local", LocalPath: t.TempDir(), Layout: config.LayoutConfig{Levels: 2}},
+				{Type: "local", LocalPath: t.TempDir()},
+			},
+		}
+
+		_, err := NewRemoteStorage(cfg)
+		if err == nil {
+			t.Error("Expected error for layout on a mirror child")
+		}
+	})
+
+	t.Run("resumable on unsupported backend", func(t *testing.T) {
+		cfg := config.RemoteConfig{
+			Type:      "local",
+			LocalPath: t.TempDir(),
+			Resumable: config.ResumableConfig{Enabled: true, JournalDir: t.TempDir()},
+		}
+
+		_, err := NewRemoteStorage(cfg)
+		if err == nil {
+			t.Error("Expected error for resumable uploads on local backend")
+		}
+	})
+
+	t.Run("resumable webdav requires nextcloud url", func(t *testing.T) {
+		cfg := config.RemoteConfig{
+			Type:      "webdav",
+			URL:       "http://localhost:8080/dav",
+			Resumable: config.ResumableConfig{Enabled: true, JournalDir: t.TempDir()},
+		}
+		if _, err := NewRemoteStorage(cfg); err == nil {
+			t.Error("Expected error for non-Nextcloud URL")
+		}
+
+		cfg.URL = "http://localhost:8080/remote.php/dav/files/user/"
+		cfg.Resumable.ChunkSize = 1
+		if _, err := NewRemoteStorage(cfg); err == nil {
+			t.Error("Expected error for chunk_size below 5 MiB")
+		}
+
+		cfg.Resumable.ChunkSize = 0
+		client, err := NewRemoteStorage(cfg)
+		if err != nil {
+			t.Fatalf("NewRemoteStorage failed: %v", err)
+		}
+		if _, ok := Unwrap(client).(*resumableStorage); !ok {
+			t.Errorf("Expected *resumableStorage, got %T", Unwrap(client))
+		}
+		client.Close()
+	})
+
+	t.Run("parallel upload", func(t *testing.T) {
+		cfg := config.RemoteConfig{
+			Type:           "local",
+			LocalPath:      t.TempDir(),
+			ParallelUpload: config.ParallelUploadConfig{Enabled: true},
+		}
+		if _, err := NewRemoteStorage(cfg); err == nil {
+			t.Error("Expected error for parallel uploads on local backend")
+		}
+
+		cfg = config.RemoteConfig{
+			Type:           "webdav",
+			URL:            "http://localhost:8080/remote.php/dav/files/user/",
+			ParallelUpload: config.ParallelUploadConfig{Enabled: true, PartSize: 4},
+		}
+		if _, err := NewRemoteStorage(cfg); err == nil {
+			t.Error("Expected error for part_size below 5 MiB")
+		}
+
+		cfg.ParallelUpload.PartSize = 8
+		cfg.Resumable = config.ResumableConfig{Enabled: true, JournalDir: t.TempDir()}
+		if _, err := NewRemoteStorage(cfg); err == nil {
+			t.Error("Expected error when combined with resumable")
+		}
+
+		cfg.Resumable.Enabled = false
+		client, err := NewRemoteStorage(cfg)
+		if err != nil {
+			t.Fatalf("NewRemoteStorage failed: %v", err)
+		}
+		if _, ok := Unwrap(client).(*parallelStorage); !ok {
+			t.Errorf("Expected *parallelStorage, got %T", Unwrap(client))
+		}
+		client.Close()
+	})
+
+	t.Run("quota", func(t *testing.T) {
+		cfg := config.RemoteConfig{Type: "local", LocalPath: t.TempDir(), Quota: -1}
+		if _, err := NewRemoteStorage(cfg); err == nil {
+			t.Error("Expected error for negative quota")
+		}
+
+		cfg.Quota = 1
+		client, err := NewRemoteStorage(cfg)
+		if err != nil {
+			t.Fatalf("NewRemoteStorage failed: %v", err)
+		}
+		defer client.Close()
+		used, available, err := Quota(context.Background(), client)
+		if err != nil {
+			t.Fatalf("Quota failed: %v", err)
+		}
+		// 本地磁盘的剩余空间与 1 GiB 上限取较小值
+		if used < 0 || available < 0 || available > 1<<30 {
+			t.Errorf("Unexpected quota %d used, %d available", used, available)
+		}
+		if _, ok := Unwrap(client).(*quotaStorage); ok {
+			t.Error("Expected Unwrap to remove the quota layer")
+		}
+	})
+
 	t.Run("case insensitive type", func(t *testing.T) {
 		tmpDir := t.TempDir()
 		testCases := []string{"LOCAL", "Local", "local", "S3", "s3", "S3", "WEBDAV", "WebDAV", "webdav"}
@@ -175,3 +426,102 @@ func TestNewRemoteStorage(t *testing.T) {
 		}
 	})
 }
+
+// nopStorage 未实现 Lister 的最小 RemoteStorage
+type nopStorage struct{}
+
+func (nopStorage) Upload(context.Context, string, io.Reader, int64) error { return nil }
+func (nopStorage) Download(context.Context, string) (io.ReadCloser, error) {
+	return nil, os.ErrNotExist
+}
+func (nopStorage) DownloadRange(context.Context, string, int64, int64) (io.ReadCloser, error) {
+	return nil, os.ErrNotExist
+}
+func (nopStorage) Delete(context.Context, string) error              { return nil }
+func (nopStorage) Rename(context.Context, string, string) error      { return nil }
+func (nopStorage) Stat(context.Context, string) (os.FileInfo, error) { return nil, os.ErrNotExist }
+func (nopStorage) Close() error                                      { return nil }
+
+func TestList(t *testing.T) {
+	t.Run("backend without lister", func(t *testing.T) {
+		err := List(context.Background(), nopStorage{}, "", func(string, os.FileInfo) error { return nil })
+		if !errors.Is(err, ErrNotSupported) {
+			t.Errorf("Expected ErrNotSupported, got %v", err)
+		}
+	})
+
+	t.Run("local backend", func(t *testing.T) {
+		client, err := NewRemoteStorage(config.RemoteConfig{Type: "local", LocalPath: t.TempDir()})
+		if err != nil {
+			t.Fatalf("NewRemoteStorage failed: %v", err)
+		}
+		defer client.Close()
+
+		client.Upload(context.Background(), "obj1", strings.NewReader("abc"), 3)
+		var names []string
+		err = List(context.Background(), client, "", func(name string, info os.FileInfo) error {
+			names = append(names, name)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(names) != 1 || names[0] != "obj1" {
+			t.Errorf("Unexpected listing: %v", names)
+		}
+	})
+}
+
+// 通过完整的中间层（布局、限速、重试等）上传可 Seek 的数据源：
+// 第一次 PUT 读完请求体后失败，重试应回退数据源并重新发送全部内容
+func TestNewRemoteStorage_RetrySeekableUpload(t *testing.T) {
+	for _, limit := range []int64{0, 1024} {
+		t.Run(fmt.Sprintf("upload limit %d", limit), func(t *testing.T) {
+			var mu sync.Mutex
+			var puts int
+			var stored []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPut {
+					w.WriteHeader(http.StatusCreated)
+					return
+				}
+				body, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				defer mu.Unlock()
+				puts++
+				if puts == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				stored = body
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer srv.Close()
+
+			rs, err := NewRemoteStorage(config.RemoteConfig{
+				Type:      "webdav",
+				URL:       srv.URL,
+				Retry:     config.RetryConfig{MaxAttempts: 3, BaseDelay: 1},
+				Bandwidth: config.BandwidthConfig{Upload: limit},
+				Layout:    config.LayoutConfig{Levels: 1, Width: 2},
+			})
+			if err != nil {
+				t.Fatalf("NewRemoteStorage failed: %v", err)
+			}
+			defer rs.Close()
+
+			data := []byte(strings.Repeat("seekable upload ", 64))
+			if err := rs.Upload(context.Background(), "abcdef", bytes.NewReader(data), int64(len(data))); err != nil {
+				t.Fatalf("Upload failed: %v", err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if puts != 2 {
+				t.Errorf("Expected 2 PUT attempts, got %d", puts)
+			}
+			if !bytes.Equal(stored, data) {
+				t.Errorf("Retried upload sent %d bytes, want %d", len(stored), len(data))
+			}
+		})
+	}
+}
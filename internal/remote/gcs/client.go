@@ -0,0 +1,231 @@
+package gcs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// chunkSize 可续传上传每个分块的大小，单个分块失败时只重发该分块
+const chunkSize = 8 << 20
+
+// GCSConfig Google Cloud Storage 客户端配置
+type GCSConfig struct {
+	Endpoint        string // 自定义服务地址（如 fake-gcs-server），为空时使用官方地址
+	Bucket          string
+	CredentialsFile string // 服务账号 JSON 密钥，为空时使用默认凭据（设置 Endpoint 时不认证）
+}
+
+// GCSClient Google Cloud Storage 远端客户端实现
+type GCSClient struct {
+	client *storage.Client
+	bucket *storage.BucketHandle
+	name   string
+}
+
+// gcsFileInfo 实现 os.FileInfo 接口
+type gcsFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (fi *gcsFileInfo) Name() string       { return fi.name }
+func (fi *gcsFileInfo) Size() int64        { return fi.size }
+func (fi *gcsFileInfo) Mode() os.FileMode  { return 0644 }
+func (fi *gcsFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *gcsFileInfo) IsDir() bool        { return false }
+func (fi *gcsFileInfo) Sys() interface{}   { return nil }
+
+// NewClient 创建 GCS 客户端
+func NewClient(cfg GCSConfig) (*GCSClient, error) {
+	ctx := context.Background()
+
+	var opts []option.ClientOption
+	if cfg.Endpoint != "" {
+		// 默认的 XML API 下载不使用自定义地址，改用 JSON API
+		opts = append(opts, option.WithEndpoint(cfg.Endpoint), storage.WithJSONReads())
+	}
+	switch {
+	case cfg.CredentialsFile != "":
+		opts = append(opts, option.WithCredentialsFile(cfg.CredentialsFile))
+	case cfg.Endpoint != "":
+		opts = append(opts, option.WithoutAuthentication())
+	}
+
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+	// 重试由 remote.WithRetry 统一处理，关闭 SDK 自带的重试
+	client.SetRetry(storage.WithPolicy(storage.RetryNever))
+
+	bucket := client.Bucket(cfg.Bucket)
+	if _, err := bucket.Attrs(ctx); err != nil {
+		client.Close()
+		if errors.Is(err, storage.ErrBucketNotExist) {
+			return nil, fmt.Errorf("bucket '%s' does not exist", cfg.Bucket)
+		}
+		return nil, fmt.Errorf("failed to check bucket existence: %w", err)
+	}
+
+	return &GCSClient{
+		client: client,
+		bucket: bucket,
+		name:   cfg.Bucket,
+	}, nil
+}
+
+// Upload 使用可续传上传（resumable upload）写入对象
+// 数据按 chunkSize 分块发送，分块失败时 SDK 从服务端确认的位置续传；Close 成功后对象才可见
+func (c *GCSClient) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	objectName := normalizePath(name)
+	log.Printf("GCS: Uploading '%s' to bucket '%s' (size: %d)", objectName, c.name, size)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 会话内的分块续传不会产生重复数据，允许 SDK 重试
+	obj := c.bucket.Object(objectName).Retryer(storage.WithPolicy(storage.RetryAlways))
+	w := obj.NewWriter(ctx)
+	w.ChunkSize = chunkSize
+	w.ContentType = "application/octet-stream"
+
+	if _, err := io.Copy(w, data); err != nil {
+		cancel() // 取消上传会话，放弃已发送的分块
+		w.Close()
+		return fmt.Errorf("failed to upload object '%s': %w", objectName, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to upload object '%s': %w", objectName, err)
+	}
+	return nil
+}
+
+// Download 从 GCS 下载文件
+func (c *GCSClient) Download(ctx context.Context, name string) (io.ReadCloser, error) {
+	return c.DownloadRange(ctx, name, 0, 0)
+}
+
+// DownloadRange 使用 Range GET 下载指定字节范围，length 为 0 时读到文件末尾
+func (c *GCSClient) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	objectName := normalizePath(name)
+	log.Printf("GCS: Downloading range [%d:%d] for '%s'", start, length, objectName)
+
+	if length <= 0 {
+		length = -1
+	}
+	r, err := c.bucket.Object(objectName).NewRangeReader(ctx, start, length)
+	if err != nil {
+		// 起始位置超出文件末尾
+		var ge *googleapi.Error
+		if errors.As(err, &ge) && ge.Code == http.StatusRequestedRangeNotSatisfiable {
+			return io.NopCloser(bytes.NewReader(nil)), nil
+		}
+		return nil, fmt.Errorf("failed to get object '%s': %w", objectName, notExist(err))
+	}
+	return r, nil
+}
+
+// Delete 从 GCS 删除文件
+func (c *GCSClient) Delete(ctx context.Context, path string) error {
+	objectName := normalizePath(path)
+	log.Printf("GCS: Deleting '%s' from bucket '%s'", objectName, c.name)
+
+	if err := c.bucket.Object(objectName).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete object '%s': %w", objectName, notExist(err))
+	}
+	return nil
+}
+
+// Rename 重命名文件（服务端复制 + 删除）
+func (c *GCSClient) Rename(ctx context.Context, oldPath, newPath string) error {
+	oldName := normalizePath(oldPath)
+	newName := normalizePath(newPath)
+	log.Printf("GCS: Renaming '%s' to '%s' in bucket '%s'", oldName, newName, c.name)
+
+	src := c.bucket.Object(oldName)
+	// Copier 在对象较大时自动多次调用 rewrite 直到完成
+	if _, err := c.bucket.Object(newName).CopierFrom(src).Run(ctx); err != nil {
+		return fmt.Errorf("failed to copy object from '%s' to '%s': %w", oldName, newName, notExist(err))
+	}
+
+	if err := src.Delete(ctx); err != nil {
+		// 新对象已存在，删除失败只记录警告
+		log.Printf("GCS: Warning: Failed to delete original object '%s' after rename: %v", oldName, err)
+	}
+	return nil
+}
+
+// Stat 获取 GCS 文件信息
+func (c *GCSClient) Stat(ctx context.Context, path string) (os.FileInfo, error) {
+	objectName := normalizePath(path)
+
+	attrs, err := c.bucket.Object(objectName).Attrs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat object '%s': %w", objectName, notExist(err))
+	}
+	return &gcsFileInfo{
+		name:    filepath.Base(path),
+		size:    attrs.Size,
+		modTime: attrs.Updated,
+	}, nil
+}
+
+// List 枚举 bucket 中以 prefix 开头的对象
+func (c *GCSClient) List(ctx context.Context, prefix string, fn func(name string, info os.FileInfo) error) error {
+	p := normalizePath(prefix)
+	it := c.bucket.Objects(ctx, &storage.Query{Prefix: p})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list objects with prefix '%s': %w", p, err)
+		}
+		if strings.HasSuffix(attrs.Name, "/") {
+			continue
+		}
+		info := &gcsFileInfo{
+			name:    path.Base(attrs.Name),
+			size:    attrs.Size,
+			modTime: attrs.Updated,
+		}
+		if err := fn(attrs.Name, info); err != nil {
+			return err
+		}
+	}
+}
+
+// Close 关闭 GCS 客户端
+func (c *GCSClient) Close() error {
+	return c.client.Close()
+}
+
+// notExist 将 ErrObjectNotExist 转换为 os.ErrNotExist，便于上层统一判断
+func notExist(err error) error {
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return fmt.Errorf("%w: %w", os.ErrNotExist, err)
+	}
+	return err
+}
+
+// normalizePath 规范化对象名称：移除前导斜杠并转换为正斜杠
+func normalizePath(path string) string {
+	return strings.TrimPrefix(filepath.ToSlash(path), "/")
+}
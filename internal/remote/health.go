@@ -0,0 +1,288 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrRemoteOffline 远端已判定为离线，请求未发出直接失败
+var ErrRemoteOffline = errors.New("remote storage is offline")
+
+// healthProbeName 探测用的对象名，不存在（404）也说明远端可达
+const healthProbeName = ".clearvault-health"
+
+// Health 远端健康检查（熔断）设置
+type Health struct {
+	// FailureThreshold 连续失败多少次后判定为离线，不大于 0 时为 3
+	FailureThreshold int
+	// ProbeInterval 离线期间探测远端的间隔，不大于 0 时为 15 秒
+	ProbeInterval time.Duration
+}
+
+// HealthStatus 远端健康状态
+type HealthStatus struct {
+	Online    bool      `json:"online"`
+	Since     time.Time `json:"since"` // 进入当前状态的时间
+	Failures  int       `json:"failures"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// HealthStorage 带熔断的远端存储
+// 连续出现临时错误（超时、连接失败、5xx 等）达到阈值后判定为离线，之后所有请求直接返回 ErrRemoteOffline，
+// 不再等待超时与重试；后台定期探测远端，恢复后自动切回在线
+type HealthStorage struct {
+	RemoteStorage
+	h Health
+
+	mu        sync.Mutex
+	online    bool
+	since     time.Time
+	failures  int
+	lastError string
+	watchers  []func(online bool)
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// WithHealth 为远端存储添加健康检查
+func WithHealth(rs RemoteStorage, h Health) *HealthStorage {
+	if h.FailureThreshold <= 0 {
+		h.FailureThreshold = 3
+	}
+	if h.ProbeInterval <= 0 {
+		h.ProbeInterval = 15 * time.Second
+	}
+	s := &HealthStorage{
+		RemoteStorage: rs,
+		h:             h,
+		online:        true,
+		since:         time.Now(),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go s.probeLoop()
+	return s
+}
+
+// HealthOf 返回远端存储（可能带目录布局）中的健康检查层，未启用时返回 nil
+func HealthOf(rs RemoteStorage) *HealthStorage {
+	if l, ok := rs.(*LayoutStorage); ok {
+		rs = l.RemoteStorage
+	}
+	h, _ := rs.(*HealthStorage)
+	return h
+}
+
+// Online 远端当前是否在线
+func (s *HealthStorage) Online() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.online
+}
+
+// Status 返回远端健康状态
+func (s *HealthStorage) Status() HealthStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return HealthStatus{Online: s.online, Since: s.since, Failures: s.failures, LastError: s.lastError}
+}
+
+// Watch 注册状态变化回调（在线/离线切换时调用）
+func (s *HealthStorage) Watch(fn func(online bool)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.watchers = append(s.watchers, fn)
+}
+
+// check 离线时返回 ErrRemoteOffline
+func (s *HealthStorage) check(op, name string) error {
+	if !s.Online() {
+		return fmt.Errorf("remote %s '%s': %w", op, name, ErrRemoteOffline)
+	}
+	return nil
+}
+
+// record 记录一次请求的结果：临时错误累计失败次数，其他结果（包括 404 等）说明远端可达
+func (s *HealthStorage) record(ctx context.Context, err error) {
+	if err != nil && (ctx.Err() != nil || errors.Is(err, ErrRemoteOffline)) {
+		// 调用方取消的请求不能说明远端状态
+		return
+	}
+	if err != nil && IsRetryable(err) {
+		s.fail(err)
+		return
+	}
+	s.mu.Lock()
+	s.failures = 0
+	s.mu.Unlock()
+}
+
+func (s *HealthStorage) fail(err error) {
+	s.mu.Lock()
+	s.failures++
+	s.lastError = err.Error()
+	if !s.online || s.failures < s.h.FailureThreshold {
+		s.mu.Unlock()
+		return
+	}
+	log.Printf("Remote: Remote storage is offline after %d consecutive failures: %v", s.failures, err)
+	s.setLocked(false)
+}
+
+// setLocked 切换状态并通知回调，调用方持有 s.mu，返回时已释放
+func (s *HealthStorage) setLocked(online bool) {
+	s.online = online
+	s.since = time.Now()
+	watchers := append([]func(bool){}, s.watchers...)
+	s.mu.Unlock()
+	for _, fn := range watchers {
+		fn(online)
+	}
+}
+
+// probeLoop 离线期间定期探测远端
+func (s *HealthStorage) probeLoop() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.h.ProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+		if !s.Online() {
+			s.probe()
+		}
+	}
+}
+
+// probe 探测远端，可达时切回在线
+func (s *HealthStorage) probe() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.h.ProbeInterval)
+	defer cancel()
+	_, err := s.RemoteStorage.Stat(ctx, healthProbeName)
+	if err != nil && !IsNotFound(err) {
+		s.mu.Lock()
+		s.lastError = err.Error()
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Lock()
+	if s.online {
+		s.mu.Unlock()
+		return
+	}
+	log.Printf("Remote: Remote storage is back online (offline since %s)", s.since.Format(time.RFC3339))
+	s.failures = 0
+	s.lastError = ""
+	s.setLocked(true)
+}
+
+func (s *HealthStorage) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	if err := s.check("upload", name); err != nil {
+		return err
+	}
+	err := s.RemoteStorage.Upload(ctx, name, data, size)
+	s.record(ctx, err)
+	return err
+}
+
+func (s *HealthStorage) Download(ctx context.Context, name string) (io.ReadCloser, error) {
+	return s.DownloadRange(ctx, name, 0, 0)
+}
+
+func (s *HealthStorage) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	if err := s.check("download", name); err != nil {
+		return nil, err
+	}
+	var rc io.ReadCloser
+	var err error
+	if start == 0 && length <= 0 {
+		rc, err = s.RemoteStorage.Download(ctx, name)
+	} else {
+		rc, err = s.RemoteStorage.DownloadRange(ctx, name, start, length)
+	}
+	s.record(ctx, err)
+	if err != nil {
+		return nil, err
+	}
+	return &healthReader{ReadCloser: rc, s: s, ctx: ctx}, nil
+}
+
+func (s *HealthStorage) Delete(ctx context.Context, path string) error {
+	if err := s.check("delete", path); err != nil {
+		return err
+	}
+	err := s.RemoteStorage.Delete(ctx, path)
+	s.record(ctx, err)
+	return err
+}
+
+func (s *HealthStorage) Rename(ctx context.Context, oldPath, newPath string) error {
+	if err := s.check("rename", oldPath); err != nil {
+		return err
+	}
+	err := s.RemoteStorage.Rename(ctx, oldPath, newPath)
+	s.record(ctx, err)
+	return err
+}
+
+func (s *HealthStorage) Stat(ctx context.Context, path string) (os.FileInfo, error) {
+	if err := s.check("stat", path); err != nil {
+		return nil, err
+	}
+	info, err := s.RemoteStorage.Stat(ctx, path)
+	s.record(ctx, err)
+	return info, err
+}
+
+func (s *HealthStorage) List(ctx context.Context, prefix string, fn func(name string, info os.FileInfo) error) error {
+	if err := s.check("list", prefix); err != nil {
+		return err
+	}
+	err := List(ctx, s.RemoteStorage, prefix, fn)
+	if !errors.Is(err, ErrNotSupported) {
+		s.record(ctx, err)
+	}
+	return err
+}
+
+func (s *HealthStorage) Quota(ctx context.Context) (int64, int64, error) {
+	if err := s.check("quota", ""); err != nil {
+		return -1, -1, err
+	}
+	used, available, err := Quota(ctx, s.RemoteStorage)
+	if !errors.Is(err, ErrNotSupported) {
+		s.record(ctx, err)
+	}
+	return used, available, err
+}
+
+// Close 停止探测并关闭底层存储
+func (s *HealthStorage) Close() error {
+	close(s.stop)
+	<-s.done
+	return s.RemoteStorage.Close()
+}
+
+// healthReader 数据流中断（重试之后仍失败）同样计入失败
+type healthReader struct {
+	io.ReadCloser
+	s   *HealthStorage
+	ctx context.Context
+}
+
+func (r *healthReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	if err != nil && err != io.EOF {
+		r.s.record(r.ctx, err)
+	}
+	return n, err
+}
@@ -0,0 +1,188 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"clearvault/internal/config"
+)
+
+// outageStorage 可随时切换为不可达的远端（并发安全）
+type outageStorage struct {
+	nopStorage
+	mu    sync.Mutex
+	down  bool
+	calls int
+}
+
+func (s *outageStorage) setDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = down
+}
+
+func (s *outageStorage) err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.down {
+		return syscall.ECONNREFUSED
+	}
+	return nil
+}
+
+func (s *outageStorage) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func (s *outageStorage) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	return s.err()
+}
+
+func (s *outageStorage) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	if err := s.err(); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader([]byte("data"))), nil
+}
+
+func (s *outageStorage) Download(ctx context.Context, name string) (io.ReadCloser, error) {
+	return s.DownloadRange(ctx, name, 0, 0)
+}
+
+func (s *outageStorage) Stat(ctx context.Context, path string) (os.FileInfo, error) {
+	if err := s.err(); err != nil {
+		return nil, err
+	}
+	return nil, os.ErrNotExist
+}
+
+func waitOnline(t *testing.T, h *HealthStorage, online bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if h.Online() == online {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Expected online=%v, got status %+v", online, h.Status())
+}
+
+func TestHealthStorage(t *testing.T) {
+	inner := &outageStorage{}
+	h := WithHealth(inner, Health{FailureThreshold: 2, ProbeInterval: 5 * time.Millisecond})
+	defer h.Close()
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var changes []bool
+	h.Watch(func(online bool) {
+		mu.Lock()
+		changes = append(changes, online)
+		mu.Unlock()
+	})
+
+	// 非临时错误（404）不计入失败
+	if _, err := h.Stat(ctx, "missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected not found, got %v", err)
+	}
+	if !h.Online() {
+		t.Fatal("Expected remote to stay online after 404")
+	}
+
+	inner.setDown(true)
+	h.Upload(ctx, "a", bytes.NewReader(nil), 0)
+	if !h.Online() {
+		t.Fatal("Expected remote to stay online below threshold")
+	}
+	h.Upload(ctx, "a", bytes.NewReader(nil), 0)
+	if st := h.Status(); st.Online || st.Failures != 2 || st.LastError == "" {
+		t.Fatalf("Expected remote to be offline, got %+v", st)
+	}
+
+	// 离线期间请求不发往远端，直接失败
+	if _, err := h.Download(ctx, "a"); !errors.Is(err, ErrRemoteOffline) {
+		t.Fatalf("Expected ErrRemoteOffline, got %v", err)
+	}
+
+	// 探测成功后自动恢复在线
+	inner.setDown(false)
+	waitOnline(t, h, true)
+	rc, err := h.Download(ctx, "a")
+	if err != nil {
+		t.Fatalf("Download after recovery failed: %v", err)
+	}
+	rc.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(changes) != 2 || changes[0] || !changes[1] {
+		t.Errorf("Expected offline then online notifications, got %v", changes)
+	}
+}
+
+func TestHealthStorage_FailFast(t *testing.T) {
+	inner := &outageStorage{down: true}
+	h := WithHealth(inner, Health{FailureThreshold: 1, ProbeInterval: time.Hour})
+	defer h.Close()
+	ctx := context.Background()
+
+	h.Stat(ctx, "a")
+	calls := inner.callCount()
+	for i := 0; i < 5; i++ {
+		if err := h.Delete(ctx, "a"); !errors.Is(err, ErrRemoteOffline) {
+			t.Fatalf("Expected ErrRemoteOffline, got %v", err)
+		}
+	}
+	if inner.callCount() != calls {
+		t.Errorf("Expected no remote calls while offline, got %d", inner.callCount()-calls)
+	}
+
+	// 调用方取消的请求不影响状态
+	h2 := WithHealth(&outageStorage{down: true}, Health{FailureThreshold: 1, ProbeInterval: time.Hour})
+	defer h2.Close()
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	h2.Stat(cctx, "a")
+	if !h2.Online() {
+		t.Error("Expected cancelled request not to count as failure")
+	}
+}
+
+func TestHealthOf(t *testing.T) {
+	h := WithHealth(nopStorage{}, Health{ProbeInterval: time.Hour})
+	layout, err := WithLayout(h, Layout{Levels: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer layout.Close()
+	if HealthOf(layout) != h || HealthOf(h) != h {
+		t.Error("Expected HealthOf to find the health layer")
+	}
+	if HealthOf(nopStorage{}) != nil {
+		t.Error("Expected nil without health tracking")
+	}
+
+	rs, err := NewRemoteStorage(config.RemoteConfig{
+		Type:      "local",
+		LocalPath: t.TempDir(),
+		Health:    config.HealthConfig{Enabled: true},
+	})
+	if err != nil {
+		t.Fatalf("NewRemoteStorage failed: %v", err)
+	}
+	defer rs.Close()
+	if HealthOf(rs) == nil {
+		t.Errorf("Expected health tracking to be enabled, got %T", rs)
+	}
+}
@@ -0,0 +1,234 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// Layout 远端对象的目录布局。Levels 为 0 时所有对象平铺在同一目录
+type Layout struct {
+	// Levels 目录层数，例如 2 表示 ab/cd/abcd…
+	Levels int
+	// Width 每层目录名取对象名的字符数，默认 2
+	Width int
+}
+
+// LayoutStorage 将 Proxy 生成的十六进制对象名分散到多级目录，避免单个目录条目过多
+// 分层路径不存在时回退到平铺路径，未迁移的旧对象仍可访问
+type LayoutStorage struct {
+	RemoteStorage
+	levels int
+	width  int
+}
+
+// LayoutStats 目录布局迁移结果
+type LayoutStats struct {
+	Scanned int // 扫描的对象数
+	Moved   int // 移动到分层目录的对象数
+	Failed  int // 移动失败的对象数
+}
+
+// WithLayout 为远端存储添加目录布局，Levels 不大于 0 时原样返回
+func WithLayout(rs RemoteStorage, l Layout) (RemoteStorage, error) {
+	if l.Levels <= 0 {
+		return rs, nil
+	}
+	if l.Width <= 0 {
+		l.Width = 2
+	}
+	if l.Levels > 4 || l.Width > 4 {
+		return nil, fmt.Errorf("layout: levels and width must not exceed 4 (got levels=%d width=%d)", l.Levels, l.Width)
+	}
+	return &LayoutStorage{RemoteStorage: rs, levels: l.Levels, width: l.Width}, nil
+}
+
+// Unwrap 返回去掉目录布局、健康检查、限速与容量上限后的存储，用于访问镜像、条带等具体类型
+func Unwrap(rs RemoteStorage) RemoteStorage {
+	if l, ok := rs.(*LayoutStorage); ok {
+		rs = l.RemoteStorage
+	}
+	if h, ok := rs.(*HealthStorage); ok {
+		rs = h.RemoteStorage
+	}
+	if t, ok := rs.(*ThrottleStorage); ok {
+		rs = t.RemoteStorage
+	}
+	if q, ok := rs.(*quotaStorage); ok {
+		rs = q.RemoteStorage
+	}
+	return rs
+}
+
+// shardable 判断对象名是否按布局分层：仅处理足够长的十六进制名称
+func (s *LayoutStorage) shardable(name string) bool {
+	return len(name) > s.levels*s.width && isHex(name)
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// path 返回对象的分层路径，不适用布局的名称原样返回
+func (s *LayoutStorage) path(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if !s.shardable(name) {
+		return name
+	}
+	var b strings.Builder
+	for i := 0; i < s.levels; i++ {
+		b.WriteString(name[i*s.width : (i+1)*s.width])
+		b.WriteByte('/')
+	}
+	b.WriteString(name)
+	return b.String()
+}
+
+// unshard 将分层路径还原为对象名
+func (s *LayoutStorage) unshard(p string) (string, bool) {
+	i := strings.LastIndexByte(p, '/')
+	if i < 0 {
+		return "", false
+	}
+	name := p[i+1:]
+	if !s.shardable(name) || s.path(name) != p {
+		return "", false
+	}
+	return name, true
+}
+
+// dirPrefix 返回可能包含以 prefix 开头的对象的分层路径前缀
+func (s *LayoutStorage) dirPrefix(prefix string) string {
+	var b strings.Builder
+	for i := 0; i < s.levels; i++ {
+		seg := prefix[min(i*s.width, len(prefix)):min((i+1)*s.width, len(prefix))]
+		b.WriteString(seg)
+		if len(seg) < s.width {
+			return b.String()
+		}
+		b.WriteByte('/')
+	}
+	b.WriteString(prefix)
+	return b.String()
+}
+
+func (s *LayoutStorage) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	return s.RemoteStorage.Upload(ctx, s.path(name), data, size)
+}
+
+func (s *LayoutStorage) Download(ctx context.Context, name string) (io.ReadCloser, error) {
+	return s.DownloadRange(ctx, name, 0, 0)
+}
+
+func (s *LayoutStorage) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	p := s.path(name)
+	rc, err := s.RemoteStorage.DownloadRange(ctx, p, start, length)
+	if p != name && IsNotFound(err) {
+		return s.RemoteStorage.DownloadRange(ctx, name, start, length)
+	}
+	return rc, err
+}
+
+func (s *LayoutStorage) Delete(ctx context.Context, name string) error {
+	p := s.path(name)
+	err := s.RemoteStorage.Delete(ctx, p)
+	if p != name && IsNotFound(err) {
+		return s.RemoteStorage.Delete(ctx, name)
+	}
+	return err
+}
+
+func (s *LayoutStorage) Rename(ctx context.Context, oldName, newName string) error {
+	p := s.path(oldName)
+	err := s.RemoteStorage.Rename(ctx, p, s.path(newName))
+	if p != oldName && IsNotFound(err) {
+		return s.RemoteStorage.Rename(ctx, oldName, s.path(newName))
+	}
+	return err
+}
+
+func (s *LayoutStorage) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	p := s.path(name)
+	info, err := s.RemoteStorage.Stat(ctx, p)
+	if p != name && IsNotFound(err) {
+		return s.RemoteStorage.Stat(ctx, name)
+	}
+	return info, err
+}
+
+// List 枚举以 prefix 开头的对象，分层存放的对象以原始名称返回
+func (s *LayoutStorage) List(ctx context.Context, prefix string, fn func(name string, info os.FileInfo) error) error {
+	prefix = strings.TrimPrefix(prefix, "/")
+	inner := prefix
+	if isHex(prefix) {
+		inner = s.dirPrefix(prefix)
+	}
+	err := List(ctx, s.RemoteStorage, inner, func(name string, info os.FileInfo) error {
+		if base, ok := s.unshard(name); ok {
+			name = base
+		}
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+		return fn(name, info)
+	})
+	if err != nil || inner == prefix {
+		return err
+	}
+	// 尚未迁移的平铺对象
+	return List(ctx, s.RemoteStorage, prefix, func(name string, info os.FileInfo) error {
+		if strings.Contains(name, "/") {
+			return nil
+		}
+		return fn(name, info)
+	})
+}
+
+func (s *LayoutStorage) Quota(ctx context.Context) (int64, int64, error) {
+	return Quota(ctx, s.RemoteStorage)
+}
+
+// Migrate 将平铺存放的对象移动到分层目录，dryRun 时只统计不移动
+func (s *LayoutStorage) Migrate(ctx context.Context, dryRun bool) (LayoutStats, error) {
+	var stats LayoutStats
+	var flat []string
+	err := List(ctx, s.RemoteStorage, "", func(name string, info os.FileInfo) error {
+		stats.Scanned++
+		if !strings.Contains(name, "/") && s.shardable(name) {
+			flat = append(flat, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, fmt.Errorf("layout: failed to list objects: %w", err)
+	}
+	log.Printf("Layout: %d of %d objects need to be moved", len(flat), stats.Scanned)
+	if dryRun {
+		return stats, nil
+	}
+
+	for i, name := range flat {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		if err := s.RemoteStorage.Rename(ctx, name, s.path(name)); err != nil {
+			log.Printf("Layout: Failed to move '%s': %v", name, err)
+			stats.Failed++
+			continue
+		}
+		stats.Moved++
+		if (i+1)%1000 == 0 {
+			log.Printf("Layout: Moved %d/%d objects", i+1, len(flat))
+		}
+	}
+	return stats, nil
+}
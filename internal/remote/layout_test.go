@@ -0,0 +1,231 @@
+package remote
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"clearvault/internal/remote/local"
+	"clearvault/internal/remote/webdav"
+
+	xwebdav "golang.org/x/net/webdav"
+)
+
+const hexName = "0123456789abcdef0123456789abcdef"
+
+func newTestLayout(t *testing.T) (*LayoutStorage, RemoteStorage, string) {
+	t.Helper()
+	dir := t.TempDir()
+	c, err := local.NewClient(dir)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	rs, err := WithLayout(c, Layout{Levels: 2})
+	if err != nil {
+		t.Fatalf("WithLayout failed: %v", err)
+	}
+	t.Cleanup(func() { rs.Close() })
+	return rs.(*LayoutStorage), c, dir
+}
+
+func listNames(t *testing.T, rs RemoteStorage, prefix string) string {
+	t.Helper()
+	var names []string
+	err := List(context.Background(), rs, prefix, func(name string, info os.FileInfo) error {
+		names = append(names, name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("List(%q) failed: %v", prefix, err)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func TestLayoutStorage_Path(t *testing.T) {
+	s, _, _ := newTestLayout(t)
+
+	tests := []struct{ name, want string }{
+		{hexName, "01/23/" + hexName},
+		{"/" + hexName, "01/23/" + hexName},
+		{"abcd", "abcd"},
+		{"vault.json", "vault.json"},
+		{"dir/" + hexName, "dir/" + hexName},
+	}
+	for _, tt := range tests {
+		if got := s.path(tt.name); got != tt.want {
+			t.Errorf("path(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if name, ok := s.unshard("01/23/" + hexName); !ok || name != hexName {
+		t.Errorf("unshard = %q, %v", name, ok)
+	}
+	if _, ok := s.unshard("ff/23/" + hexName); ok {
+		t.Error("unshard should reject mismatched directories")
+	}
+
+	for prefix, want := range map[string]string{"": "", "0": "0", "01": "01/", "012": "01/2", "01234": "01/23/01234"} {
+		if got := s.dirPrefix(prefix); got != want {
+			t.Errorf("dirPrefix(%q) = %q, want %q", prefix, got, want)
+		}
+	}
+
+	if _, err := WithLayout(nopStorage{}, Layout{Levels: 5}); err == nil {
+		t.Error("Expected error for too many levels")
+	}
+	if rs, _ := WithLayout(nopStorage{}, Layout{}); rs != (nopStorage{}) {
+		t.Error("Expected storage to be returned unwrapped")
+	}
+}
+
+func TestLayoutStorage_Fallback(t *testing.T) {
+	ctx := context.Background()
+	s, inner, dir := newTestLayout(t)
+
+	// 启用布局前写入的平铺对象
+	legacy := strings.Repeat("a", 64)
+	if err := inner.Upload(ctx, legacy, strings.NewReader("old"), 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Upload(ctx, hexName, strings.NewReader("new"), 3); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "01", "23", hexName)); err != nil {
+		t.Errorf("Expected sharded object on disk: %v", err)
+	}
+
+	if got := readAll(t, s, legacy); got != "old" {
+		t.Errorf("Legacy read = %q", got)
+	}
+	if got := readAll(t, s, hexName); got != "new" {
+		t.Errorf("Sharded read = %q", got)
+	}
+	if info, err := s.Stat(ctx, legacy); err != nil || info.Size() != 3 {
+		t.Errorf("Stat legacy = %v, %v", info, err)
+	}
+
+	if got := listNames(t, s, ""); got != hexName+","+legacy {
+		t.Errorf("List all = %s", got)
+	}
+	if got := listNames(t, s, "012"); got != hexName {
+		t.Errorf("List 012 = %s", got)
+	}
+	if got := listNames(t, s, "aaa"); got != legacy {
+		t.Errorf("List aaa = %s", got)
+	}
+
+	// 重命名平铺对象时移动到分层目录
+	renamed := strings.Repeat("b", 64)
+	if err := s.Rename(ctx, legacy, renamed); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "bb", "bb", renamed)); err != nil {
+		t.Errorf("Expected renamed object in sharded path: %v", err)
+	}
+	if err := s.Delete(ctx, renamed); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := s.Stat(ctx, renamed); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist after delete, got %v", err)
+	}
+}
+
+func TestLayoutStorage_Migrate(t *testing.T) {
+	ctx := context.Background()
+	s, inner, dir := newTestLayout(t)
+
+	names := []string{hexName, strings.Repeat("c", 64), "notes.txt"}
+	for _, name := range names {
+		if err := inner.Upload(ctx, name, strings.NewReader(name), int64(len(name))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := s.Migrate(ctx, true)
+	if err != nil || stats.Scanned != 3 || stats.Moved != 0 {
+		t.Fatalf("Dry run = %+v, %v", stats, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, hexName)); err != nil {
+		t.Error("Dry run should not move objects")
+	}
+
+	stats, err = s.Migrate(ctx, false)
+	if err != nil || stats.Moved != 2 || stats.Failed != 0 {
+		t.Fatalf("Migrate = %+v, %v", stats, err)
+	}
+	for _, p := range []string{"01/23/" + hexName, "cc/cc/" + strings.Repeat("c", 64), "notes.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, p)); err != nil {
+			t.Errorf("Expected %s after migration: %v", p, err)
+		}
+	}
+	for _, name := range names {
+		if got := readAll(t, s, name); got != name {
+			t.Errorf("Read %s after migration = %q", name, got)
+		}
+	}
+
+	// 再次迁移无需移动
+	if stats, _ := s.Migrate(ctx, false); stats.Moved != 0 {
+		t.Errorf("Second migration moved %d objects", stats.Moved)
+	}
+}
+
+func TestLayoutStorage_WebDAV(t *testing.T) {
+	ctx := context.Background()
+	var mkcols atomic.Int32
+	handler := &xwebdav.Handler{
+		FileSystem: xwebdav.NewMemFS(),
+		LockSystem: xwebdav.NewMemLS(),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "MKCOL" {
+			mkcols.Add(1)
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	c, err := webdav.NewClient(webdav.WebDAVConfig{URL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := WithLayout(c, Layout{Levels: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 同一目录下的后续对象不再创建目录
+	var created int32
+	for i, name := range []string{hexName, "0123" + strings.Repeat("f", 60), "0123" + strings.Repeat("e", 60)} {
+		if err := rs.Upload(ctx, name, strings.NewReader(name), int64(len(name))); err != nil {
+			t.Fatalf("Upload %s failed: %v", name, err)
+		}
+		if got := readAll(t, rs, name); got != name {
+			t.Errorf("Read %s = %q", name, got)
+		}
+		if i == 0 {
+			created = mkcols.Load()
+		}
+	}
+	if n := mkcols.Load(); created == 0 || n != created {
+		t.Errorf("Expected MKCOL only for the first upload, got %d then %d", created, n)
+	}
+
+	// 目录被外部删除后重新创建
+	if err := c.Delete(ctx, "01"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rs.Upload(ctx, hexName, strings.NewReader("again"), 5); err != nil {
+		t.Fatalf("Upload after directory removal failed: %v", err)
+	}
+	if got := readAll(t, rs, hexName); got != "again" {
+		t.Errorf("Read after re-create = %q", got)
+	}
+}
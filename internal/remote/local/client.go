@@ -1,14 +1,28 @@
 package local
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
+// ErrReadOnly 只读模式下拒绝写操作
+var ErrReadOnly = fmt.Errorf("local remote storage is read-only: %w", os.ErrPermission)
+
+// tempSuffix 上传过程中临时文件的后缀，List 不返回这类文件
+const tempSuffix = ".clearvault-tmp"
+
+// staleTempAge 超过该时间的临时文件视为中断上传的残留，List 时顺带删除
+const staleTempAge = 24 * time.Hour
+
 type LocalClient struct {
 	rootPath string
+	readOnly bool
 }
 
 func NewClient(rootPath string) (*LocalClient, error) {
@@ -19,34 +33,69 @@ func NewClient(rootPath string) (*LocalClient, error) {
 	return &LocalClient{rootPath: rootPath}, nil
 }
 
+// NewReadOnlyClient 以只读方式打开已有目录（如写保护的 U 盘、只读挂载的 NAS 备份），写操作返回 ErrReadOnly
+func NewReadOnlyClient(rootPath string) (*LocalClient, error) {
+	info, err := os.Stat(rootPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open local remote storage root: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("local remote storage root '%s' is not a directory", rootPath)
+	}
+	return &LocalClient{rootPath: rootPath, readOnly: true}, nil
+}
+
 func (c *LocalClient) getPath(name string) string {
 	return filepath.Join(c.rootPath, name)
 }
 
-func (c *LocalClient) Upload(name string, data io.Reader, size int64) error {
+// Upload 先写入同目录下的临时文件并 fsync，再原子重命名为目标文件并 fsync 目录，
+// 中途出错或崩溃不会留下看似完整的对象
+func (c *LocalClient) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	if err := c.writable(ctx); err != nil {
+		return err
+	}
 	path := c.getPath(name)
-	
+	dir := filepath.Dir(path)
+
 	// Create parent directory if needed (though remote names are usually flat, but good to be safe)
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
 	}
 
-	file, err := os.Create(path)
+	file, err := os.CreateTemp(dir, filepath.Base(path)+".*"+tempSuffix)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	_, err = io.Copy(file, data)
-	return err
+	tmp := file.Name()
+	_, err = io.Copy(file, &ctxReader{ctx: ctx, r: data})
+	if err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return syncDir(dir)
 }
 
-func (c *LocalClient) Download(name string) (io.ReadCloser, error) {
-	path := c.getPath(name)
-	return os.Open(path)
+func (c *LocalClient) Download(ctx context.Context, name string) (io.ReadCloser, error) {
+	return c.DownloadRange(ctx, name, 0, 0)
 }
 
-func (c *LocalClient) DownloadRange(name string, start, length int64) (io.ReadCloser, error) {
+func (c *LocalClient) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	path := c.getPath(name)
 	file, err := os.Open(path)
 	if err != nil {
@@ -58,32 +107,123 @@ func (c *LocalClient) DownloadRange(name string, start, length int64) (io.ReadCl
 		return nil, err
 	}
 
-	if length <= 0 {
-		return file, nil
+	var r io.Reader = &ctxReader{ctx: ctx, r: file}
+	if length > 0 {
+		r = io.LimitReader(r, length)
 	}
-
 	return &limitReadCloser{
-		r: io.LimitReader(file, length),
+		r: r,
 		c: file,
 	}, nil
 }
 
-func (c *LocalClient) Delete(name string) error {
+func (c *LocalClient) Delete(ctx context.Context, name string) error {
+	if err := c.writable(ctx); err != nil {
+		return err
+	}
 	path := c.getPath(name)
 	return os.Remove(path)
 }
 
-func (c *LocalClient) Rename(oldName, newName string) error {
+func (c *LocalClient) Rename(ctx context.Context, oldName, newName string) error {
+	if err := c.writable(ctx); err != nil {
+		return err
+	}
 	oldPath := c.getPath(oldName)
 	newPath := c.getPath(newName)
-	return os.Rename(oldPath, newPath)
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+	if err := syncDir(filepath.Dir(newPath)); err != nil {
+		return err
+	}
+	if filepath.Dir(oldPath) != filepath.Dir(newPath) {
+		return syncDir(filepath.Dir(oldPath))
+	}
+	return nil
 }
 
-func (c *LocalClient) Stat(name string) (os.FileInfo, error) {
+// writable 检查 ctx 与只读模式
+func (c *LocalClient) writable(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c.readOnly {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+func (c *LocalClient) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	path := c.getPath(name)
 	return os.Stat(path)
 }
 
+// List 递归枚举名称以 prefix 开头的文件
+func (c *LocalClient) List(ctx context.Context, prefix string, fn func(name string, info os.FileInfo) error) error {
+	prefix = strings.TrimPrefix(filepath.ToSlash(prefix), "/")
+	// 从 prefix 所在目录开始遍历，避免扫描整个根目录
+	start := c.rootPath
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		start = c.getPath(prefix[:i])
+	}
+
+	err := filepath.WalkDir(start, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == start {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if strings.HasSuffix(d.Name(), tempSuffix) {
+			c.removeStaleTemp(p, d)
+			return nil
+		}
+		rel, err := filepath.Rel(c.rootPath, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		return fn(name, info)
+	})
+	if err == fs.SkipDir {
+		return nil
+	}
+	return err
+}
+
+// removeStaleTemp 删除中断上传留下的临时文件，进行中的上传不受影响
+func (c *LocalClient) removeStaleTemp(p string, d fs.DirEntry) {
+	if c.readOnly {
+		return
+	}
+	if info, err := d.Info(); err == nil && time.Since(info.ModTime()) > staleTempAge {
+		os.Remove(p)
+	}
+}
+
 func (c *LocalClient) Close() error {
 	return nil
 }
@@ -101,3 +241,16 @@ func (l *limitReadCloser) Read(p []byte) (n int, err error) {
 func (l *limitReadCloser) Close() error {
 	return l.c.Close()
 }
+
+// ctxReader 在 ctx 取消后中止读取
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
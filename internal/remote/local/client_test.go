@@ -2,10 +2,13 @@ package local
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestNewClient(t *testing.T) {
@@ -49,7 +52,7 @@ func TestLocalClient_Upload(t *testing.T) {
 		content := []byte("Hello, World!")
 		reader := bytes.NewReader(content)
 
-		err := client.Upload("testfile.txt", reader, int64(len(content)))
+		err := client.Upload(context.Background(), "testfile.txt", reader, int64(len(content)))
 		if err != nil {
 			t.Fatalf("Upload failed: %v", err)
 		}
@@ -68,7 +71,7 @@ func TestLocalClient_Upload(t *testing.T) {
 	t.Run("upload empty file", func(t *testing.T) {
 		reader := bytes.NewReader([]byte{})
 
-		err := client.Upload("emptyfile.txt", reader, 0)
+		err := client.Upload(context.Background(), "emptyfile.txt", reader, 0)
 		if err != nil {
 			t.Fatalf("Upload failed: %v", err)
 		}
@@ -90,7 +93,7 @@ func TestLocalClient_Upload(t *testing.T) {
 		}
 		reader := bytes.NewReader(content)
 
-		err := client.Upload("largefile.bin", reader, int64(len(content)))
+		err := client.Upload(context.Background(), "largefile.bin", reader, int64(len(content)))
 		if err != nil {
 			t.Fatalf("Upload failed: %v", err)
 		}
@@ -122,7 +125,7 @@ func TestLocalClient_Download(t *testing.T) {
 	}
 
 	t.Run("download existing file", func(t *testing.T) {
-		reader, err := client.Download("download_test.txt")
+		reader, err := client.Download(context.Background(), "download_test.txt")
 		if err != nil {
 			t.Fatalf("Download failed: %v", err)
 		}
@@ -138,7 +141,7 @@ func TestLocalClient_Download(t *testing.T) {
 	})
 
 	t.Run("download non-existent file", func(t *testing.T) {
-		_, err := client.Download("non_existent.txt")
+		_, err := client.Download(context.Background(), "non_existent.txt")
 		if err == nil {
 			t.Error("Expected error for non-existent file")
 		}
@@ -174,7 +177,7 @@ func TestLocalClient_DownloadRange(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			reader, err := client.DownloadRange("range_test.txt", tt.start, tt.length)
+			reader, err := client.DownloadRange(context.Background(), "range_test.txt", tt.start, tt.length)
 			if err != nil {
 				t.Fatalf("DownloadRange failed: %v", err)
 			}
@@ -191,7 +194,7 @@ func TestLocalClient_DownloadRange(t *testing.T) {
 	}
 
 	t.Run("range non-existent file", func(t *testing.T) {
-		_, err := client.DownloadRange("non_existent.txt", 0, 10)
+		_, err := client.DownloadRange(context.Background(), "non_existent.txt", 0, 10)
 		if err == nil {
 			t.Error("Expected error for non-existent file")
 		}
@@ -213,7 +216,7 @@ func TestLocalClient_Delete(t *testing.T) {
 			t.Fatalf("Failed to create test file: %v", err)
 		}
 
-		err := client.Delete("to_delete.txt")
+		err := client.Delete(context.Background(), "to_delete.txt")
 		if err != nil {
 			t.Fatalf("Delete failed: %v", err)
 		}
@@ -224,7 +227,7 @@ func TestLocalClient_Delete(t *testing.T) {
 	})
 
 	t.Run("delete non-existent file", func(t *testing.T) {
-		err := client.Delete("non_existent.txt")
+		err := client.Delete(context.Background(), "non_existent.txt")
 		if err == nil {
 			t.Error("Expected error for non-existent file")
 		}
@@ -247,7 +250,7 @@ func TestLocalClient_Rename(t *testing.T) {
 			t.Fatalf("Failed to create test file: %v", err)
 		}
 
-		err := client.Rename("old_name.txt", "new_name.txt")
+		err := client.Rename(context.Background(), "old_name.txt", "new_name.txt")
 		if err != nil {
 			t.Fatalf("Rename failed: %v", err)
 		}
@@ -269,7 +272,7 @@ func TestLocalClient_Rename(t *testing.T) {
 	})
 
 	t.Run("rename non-existent file", func(t *testing.T) {
-		err := client.Rename("non_existent.txt", "dest.txt")
+		err := client.Rename(context.Background(), "non_existent.txt", "dest.txt")
 		if err == nil {
 			t.Error("Expected error for non-existent source")
 		}
@@ -292,7 +295,7 @@ func TestLocalClient_Stat(t *testing.T) {
 			t.Fatalf("Failed to create test file: %v", err)
 		}
 
-		info, err := client.Stat("stat_test.txt")
+		info, err := client.Stat(context.Background(), "stat_test.txt")
 		if err != nil {
 			t.Fatalf("Stat failed: %v", err)
 		}
@@ -309,7 +312,7 @@ func TestLocalClient_Stat(t *testing.T) {
 	})
 
 	t.Run("stat non-existent file", func(t *testing.T) {
-		_, err := client.Stat("non_existent.txt")
+		_, err := client.Stat(context.Background(), "non_existent.txt")
 		if err == nil {
 			t.Error("Expected error for non-existent file")
 		}
@@ -367,3 +370,236 @@ func TestLimitReadCloser(t *testing.T) {
 		}
 	})
 }
+
+func TestLocalClient_List(t *testing.T) {
+	tmpDir := t.TempDir()
+	client, err := NewClient(tmpDir)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer client.Close()
+
+	files := map[string]string{
+		"aa11":       "one",
+		"aa22":       "two22",
+		"bb33":       "three",
+		"ab/cd/abcd": "nested",
+	}
+	for name, content := range files {
+		if err := client.Upload(context.Background(), name, bytes.NewReader([]byte(content)), int64(len(content))); err != nil {
+			t.Fatalf("Upload %s failed: %v", name, err)
+		}
+	}
+
+	list := func(prefix string) map[string]int64 {
+		got := map[string]int64{}
+		err := client.List(context.Background(), prefix, func(name string, info os.FileInfo) error {
+			got[name] = info.Size()
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("List(%q) failed: %v", prefix, err)
+		}
+		return got
+	}
+
+	t.Run("all objects", func(t *testing.T) {
+		got := list("")
+		if len(got) != len(files) {
+			t.Fatalf("Expected %d objects, got %v", len(files), got)
+		}
+		for name, content := range files {
+			if got[name] != int64(len(content)) {
+				t.Errorf("%s: size %d, want %d", name, got[name], len(content))
+			}
+		}
+	})
+
+	t.Run("name prefix", func(t *testing.T) {
+		got := list("aa")
+		if len(got) != 2 || got["aa11"] != 3 || got["aa22"] != 5 {
+			t.Errorf("Unexpected result: %v", got)
+		}
+	})
+
+	t.Run("nested prefix", func(t *testing.T) {
+		got := list("ab/cd/")
+		if len(got) != 1 || got["ab/cd/abcd"] != 6 {
+			t.Errorf("Unexpected result: %v", got)
+		}
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		if got := list("zz/yy"); len(got) != 0 {
+			t.Errorf("Expected no objects, got %v", got)
+		}
+	})
+
+	t.Run("stop on error", func(t *testing.T) {
+		stop := io.ErrClosedPipe
+		count := 0
+		err := client.List(context.Background(), "", func(name string, info os.FileInfo) error {
+			count++
+			return stop
+		})
+		if err != stop || count != 1 {
+			t.Errorf("Expected early stop, got err=%v count=%d", err, count)
+		}
+	})
+}
+
+func TestLocalClient_ContextCancel(t *testing.T) {
+	tmpDir := t.TempDir()
+	client, err := NewClient(tmpDir)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer client.Close()
+
+	content := bytes.Repeat([]byte("x"), 1024)
+	if err := client.Upload(context.Background(), "obj", bytes.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rc, err := client.Download(ctx, "obj")
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	defer rc.Close()
+	if _, err := rc.Read(make([]byte, 100)); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	cancel()
+	if _, err := rc.Read(make([]byte, 100)); err != context.Canceled {
+		t.Errorf("Expected context.Canceled after cancel, got %v", err)
+	}
+	if _, err := client.Stat(ctx, "obj"); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from Stat, got %v", err)
+	}
+	if err := client.Upload(ctx, "obj2", bytes.NewReader(content), int64(len(content))); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from Upload, got %v", err)
+	}
+}
+
+func TestLocalClient_Quota(t *testing.T) {
+	client, err := NewClient(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer client.Close()
+
+	used, available, err := client.Quota(context.Background())
+	if err != nil {
+		t.Fatalf("Quota failed: %v", err)
+	}
+	if used < 0 || available <= 0 {
+		t.Errorf("Quota = %d used, %d available, want known values", used, available)
+	}
+}
+
+// failingReader 读出部分数据后返回错误
+type failingReader struct {
+	n int
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		return 0, errors.New("connection lost")
+	}
+	n := min(len(p), r.n)
+	r.n -= n
+	return n, nil
+}
+
+func TestLocalClient_AtomicUpload(t *testing.T) {
+	tmpDir := t.TempDir()
+	client, err := NewClient(tmpDir)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	if err := client.Upload(ctx, "ab/obj", bytes.NewReader([]byte("old")), 3); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	// 中途失败的上传不影响已有对象，也不留下临时文件
+	if err := client.Upload(ctx, "ab/obj", &failingReader{n: 100}, 1000); err == nil {
+		t.Fatal("Expected upload to fail")
+	}
+	if got, _ := os.ReadFile(filepath.Join(tmpDir, "ab", "obj")); string(got) != "old" {
+		t.Errorf("Expected previous content to be kept, got %q", got)
+	}
+	entries, _ := os.ReadDir(filepath.Join(tmpDir, "ab"))
+	if len(entries) != 1 {
+		t.Errorf("Expected only the object in the directory, got %d entries", len(entries))
+	}
+
+	// 崩溃残留的临时文件不出现在 List 中，过期的被删除
+	fresh := filepath.Join(tmpDir, "ab", "new.123"+tempSuffix)
+	stale := filepath.Join(tmpDir, "ab", "obj.456"+tempSuffix)
+	os.WriteFile(fresh, []byte("partial"), 0644)
+	os.WriteFile(stale, []byte("partial"), 0644)
+	old := time.Now().Add(-2 * staleTempAge)
+	os.Chtimes(stale, old, old)
+	var names []string
+	if err := client.List(ctx, "", func(name string, info os.FileInfo) error {
+		names = append(names, name)
+		return nil
+	}); err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(names) != 1 || names[0] != "ab/obj" {
+		t.Errorf("Expected only ab/obj, got %v", names)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("Expected stale temp file to be removed")
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Error("Expected recent temp file to be kept")
+	}
+}
+
+func TestLocalClient_ReadOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "obj"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewReadOnlyClient(filepath.Join(tmpDir, "missing")); err == nil {
+		t.Error("Expected error for missing root")
+	}
+	client, err := NewReadOnlyClient(tmpDir)
+	if err != nil {
+		t.Fatalf("NewReadOnlyClient failed: %v", err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	rc, err := client.Download(ctx, "obj")
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(got) != "data" {
+		t.Errorf("Download = %q, want data", got)
+	}
+
+	if err := client.Upload(ctx, "new", bytes.NewReader([]byte("x")), 1); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Upload: expected ErrReadOnly, got %v", err)
+	}
+	if err := client.Delete(ctx, "obj"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Delete: expected ErrReadOnly, got %v", err)
+	}
+	if err := client.Rename(ctx, "obj", "moved"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Rename: expected ErrReadOnly, got %v", err)
+	}
+	if !errors.Is(ErrReadOnly, os.ErrPermission) {
+		t.Error("Expected ErrReadOnly to be a permission error")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "obj")); err != nil {
+		t.Errorf("Expected object to be untouched: %v", err)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+
+package local
+
+import (
+	"context"
+	"syscall"
+)
+
+// Quota 返回根目录所在文件系统的已用和可用空间（非特权用户可用的部分）
+func (c *LocalClient) Quota(ctx context.Context) (int64, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return -1, -1, err
+	}
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(c.rootPath, &st); err != nil {
+		return -1, -1, err
+	}
+	bsize := int64(st.Bsize)
+	return int64(st.Blocks-st.Bfree) * bsize, int64(st.Bavail) * bsize, nil
+}
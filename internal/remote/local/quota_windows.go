@@ -0,0 +1,25 @@
+//go:build windows
+
+package local
+
+import (
+	"context"
+
+	"golang.org/x/sys/windows"
+)
+
+// Quota 返回根目录所在卷的已用和可用空间（当前用户可用的部分）
+func (c *LocalClient) Quota(ctx context.Context) (int64, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return -1, -1, err
+	}
+	dir, err := windows.UTF16PtrFromString(c.rootPath)
+	if err != nil {
+		return -1, -1, err
+	}
+	var avail, total, free uint64
+	if err := windows.GetDiskFreeSpaceEx(dir, &avail, &total, &free); err != nil {
+		return -1, -1, err
+	}
+	return int64(total - free), int64(avail), nil
+}
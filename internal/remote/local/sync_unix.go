@@ -0,0 +1,15 @@
+//go:build !windows
+
+package local
+
+import "os"
+
+// syncDir 将目录项（新建、重命名的文件）落盘
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package local
+
+// syncDir Windows 不支持对目录 fsync，NTFS 的元数据日志保证重命名的原子性
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,632 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// unhealthyPeriod 副本出错后在该时间内读取优先使用其他副本
+const unhealthyPeriod = 30 * time.Second
+
+// MirrorOptions 镜像存储设置
+type MirrorOptions struct {
+	// WriteQuorum 写入/删除成功所需的副本数，0 表示全部副本
+	WriteQuorum int
+	// ResyncInterval 后台修复落后副本的间隔，0 表示不启动后台修复
+	ResyncInterval time.Duration
+	// StateFile 保存待修复对象（含待删除对象）的文件，进程重启后继续修复；为空时只保存在内存中
+	StateFile string
+}
+
+// MirrorStorage 将写入复制到多个远端，读取时故障转移到可用副本
+// 未成功写入的副本记入 dirty 集合（可持久化到 StateFile），由 Resync 从其他副本复制修复
+type MirrorStorage struct {
+	replicas  []RemoteStorage
+	names     []string
+	quorum    int
+	stateFile string
+
+	mu       sync.Mutex
+	dirty    []map[string]bool // 每个副本待修复的对象：true 需要复制，false 需要删除
+	failedAt []time.Time
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// ResyncStats 副本修复统计
+type ResyncStats struct {
+	Copied  int // 复制到落后副本的对象数
+	Deleted int // 从落后副本删除的对象数
+	Failed  int // 修复失败的对象数
+}
+
+// NewMirrorStorage 创建镜像存储，names 用于日志（可为 nil）
+func NewMirrorStorage(replicas []RemoteStorage, names []string, opts MirrorOptions) (*MirrorStorage, error) {
+	if len(replicas) == 0 {
+		return nil, fmt.Errorf("mirror: at least one replica is required")
+	}
+	quorum := opts.WriteQuorum
+	if quorum == 0 {
+		quorum = len(replicas)
+	}
+	if quorum < 0 || quorum > len(replicas) {
+		return nil, fmt.Errorf("mirror: write_quorum %d out of range [1, %d]", quorum, len(replicas))
+	}
+	if len(names) != len(replicas) {
+		names = make([]string, len(replicas))
+		for i := range names {
+			names[i] = fmt.Sprintf("replica %d", i)
+		}
+	}
+
+	m := &MirrorStorage{
+		replicas:  replicas,
+		names:     names,
+		quorum:    quorum,
+		stateFile: opts.StateFile,
+		dirty:     make([]map[string]bool, len(replicas)),
+		failedAt:  make([]time.Time, len(replicas)),
+		stop:      make(chan struct{}),
+	}
+	for i := range m.dirty {
+		m.dirty[i] = make(map[string]bool)
+	}
+	if err := m.loadState(); err != nil {
+		return nil, err
+	}
+	if opts.ResyncInterval > 0 {
+		m.wg.Add(1)
+		go m.resyncLoop(opts.ResyncInterval)
+	}
+	return m, nil
+}
+
+func (m *MirrorStorage) resyncLoop(interval time.Duration) {
+	defer m.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			if m.Pending() == 0 {
+				continue
+			}
+			stats := m.ResyncDirty(context.Background())
+			log.Printf("Mirror: resync copied=%d deleted=%d failed=%d", stats.Copied, stats.Deleted, stats.Failed)
+		}
+	}
+}
+
+// Pending 返回待修复的对象数
+func (m *MirrorStorage) Pending() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, d := range m.dirty {
+		n += len(d)
+	}
+	return n
+}
+
+// loadState 读取上次退出时尚未修复的对象，按副本名称对应
+func (m *MirrorStorage) loadState() error {
+	if m.stateFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(m.stateFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("mirror: read state: %w", err)
+	}
+	var state map[string]map[string]bool
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("mirror: parse state %s: %w", m.stateFile, err)
+	}
+	for i, name := range m.names {
+		for obj, present := range state[name] {
+			m.dirty[i][obj] = present
+		}
+	}
+	return nil
+}
+
+// saveStateLocked 原子写入待修复对象，失败只记录日志
+func (m *MirrorStorage) saveStateLocked() {
+	if m.stateFile == "" {
+		return
+	}
+	state := make(map[string]map[string]bool)
+	for i, d := range m.dirty {
+		if len(d) > 0 {
+			state[m.names[i]] = d
+		}
+	}
+	err := func() error {
+		data, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(m.stateFile), 0700); err != nil {
+			return err
+		}
+		tmp := m.stateFile + ".tmp"
+		if err := os.WriteFile(tmp, data, 0600); err != nil {
+			return err
+		}
+		return os.Rename(tmp, m.stateFile)
+	}()
+	if err != nil {
+		log.Printf("Mirror: failed to save state: %v", err)
+	}
+}
+
+// markResult 记录一次写操作在各副本上的结果
+func (m *MirrorStorage) markResult(name string, present bool, errs []error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	changed := false
+	for i, err := range errs {
+		if err != nil {
+			m.dirty[i][name] = present
+			m.failedAt[i] = time.Now()
+			changed = true
+		} else if _, ok := m.dirty[i][name]; ok {
+			delete(m.dirty[i], name)
+			changed = true
+		}
+	}
+	if changed {
+		m.saveStateLocked()
+	}
+}
+
+func (m *MirrorStorage) markFailed(i int) {
+	m.mu.Lock()
+	m.failedAt[i] = time.Now()
+	m.mu.Unlock()
+}
+
+// readOrder 返回可读取 name 的副本顺序：跳过缺少该对象的副本，近期出错的副本排在最后
+func (m *MirrorStorage) readOrder(name string) []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var order []int
+	for i := range m.replicas {
+		if present, ok := m.dirty[i][name]; ok && present {
+			continue
+		}
+		order = append(order, i)
+	}
+	now := time.Now()
+	sort.SliceStable(order, func(a, b int) bool {
+		return now.Sub(m.failedAt[order[a]]) > unhealthyPeriod && now.Sub(m.failedAt[order[b]]) <= unhealthyPeriod
+	})
+	return order
+}
+
+// quorumError 成功数不足 quorum 时汇总各成员的错误，否则只记录日志
+func quorumError(kind, op, name string, names []string, quorum int, errs []error) error {
+	ok := 0
+	var failed []error
+	for i, err := range errs {
+		if err == nil {
+			ok++
+		} else {
+			failed = append(failed, fmt.Errorf("%s: %w", names[i], err))
+		}
+	}
+	if ok >= quorum {
+		for _, err := range failed {
+			log.Printf("%s: %s '%s' failed on %v", kind, op, name, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s: %s '%s' succeeded on %d/%d members (quorum %d): %w",
+		strings.ToLower(kind), op, name, ok, len(errs), quorum, errors.Join(failed...))
+}
+
+func (m *MirrorStorage) quorumError(op, name string, errs []error) error {
+	return quorumError("Mirror", op, name, m.names, m.quorum, errs)
+}
+
+// Upload 将数据同时写入所有副本，慢副本会拖慢整体速度，出错的副本被丢弃不影响其他副本
+func (m *MirrorStorage) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	n := len(m.replicas)
+	pws := make([]*io.PipeWriter, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i, rs := range m.replicas {
+		pr, pw := io.Pipe()
+		pws[i] = pw
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = rs.Upload(ctx, name, pr, size)
+			if errs[i] != nil {
+				pr.CloseWithError(errs[i])
+			} else {
+				pr.Close()
+			}
+		}()
+	}
+
+	srcErr := fanOut(data, pws)
+	for _, pw := range pws {
+		pw.CloseWithError(srcErr)
+	}
+	wg.Wait()
+
+	if srcErr != nil {
+		return srcErr
+	}
+	if err := m.quorumError("upload", name, errs); err != nil {
+		return err
+	}
+	m.markResult(name, true, errs)
+	return nil
+}
+
+// fanOut 将 src 复制到所有仍在接收的 writer，只返回读取 src 的错误
+func fanOut(src io.Reader, ws []*io.PipeWriter) error {
+	live := make([]bool, len(ws))
+	for i := range live {
+		live[i] = true
+	}
+	remaining := len(ws)
+	buf := make([]byte, 32*1024)
+	for remaining > 0 {
+		n, err := src.Read(buf)
+		if n > 0 {
+			for i, w := range ws {
+				if !live[i] {
+					continue
+				}
+				if _, werr := w.Write(buf[:n]); werr != nil {
+					live[i] = false
+					remaining--
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MirrorStorage) Download(ctx context.Context, name string) (io.ReadCloser, error) {
+	return m.DownloadRange(ctx, name, 0, 0)
+}
+
+// DownloadRange 依次尝试可用副本，读取中途出错时从其他副本的相同位置继续
+func (m *MirrorStorage) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	r := &mirrorReader{m: m, ctx: ctx, name: name, start: start, length: length, order: m.readOrder(name)}
+	if err := r.next(nil); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+type mirrorReader struct {
+	m             *MirrorStorage
+	ctx           context.Context
+	name          string
+	start, length int64
+	off           int64
+	order         []int
+	cur           int
+	rc            io.ReadCloser
+	err           error
+}
+
+// next 打开下一个副本上的剩余范围
+func (r *mirrorReader) next(lastErr error) error {
+	for len(r.order) > 0 && r.ctx.Err() == nil {
+		r.cur, r.order = r.order[0], r.order[1:]
+		var length int64
+		if r.length > 0 {
+			length = r.length - r.off
+		}
+		rc, err := r.m.replicas[r.cur].DownloadRange(r.ctx, r.name, r.start+r.off, length)
+		if err == nil {
+			r.rc = rc
+			return nil
+		}
+		if !IsNotFound(err) {
+			log.Printf("Mirror: download '%s' from %s failed: %v", r.name, r.m.names[r.cur], err)
+			r.m.markFailed(r.cur)
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = r.ctx.Err()
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("mirror: no replica holds '%s': %w", r.name, os.ErrNotExist)
+	}
+	return lastErr
+}
+
+func (r *mirrorReader) Read(b []byte) (int, error) {
+	for {
+		if r.err != nil {
+			return 0, r.err
+		}
+		n, err := r.rc.Read(b)
+		r.off += int64(n)
+		if err == nil || err == io.EOF {
+			return n, err
+		}
+		if r.length > 0 && r.off >= r.length {
+			return n, io.EOF
+		}
+		r.rc.Close()
+		r.rc = nil
+		log.Printf("Mirror: read '%s' from %s failed at offset %d: %v", r.name, r.m.names[r.cur], r.start+r.off, err)
+		r.m.markFailed(r.cur)
+		if r.err = r.next(err); r.err != nil {
+			return n, r.err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+func (r *mirrorReader) Close() error {
+	if r.rc == nil {
+		return nil
+	}
+	return r.rc.Close()
+}
+
+// each 在所有成员上并发执行 fn
+func each(members []RemoteStorage, fn func(i int, rs RemoteStorage) error) []error {
+	errs := make([]error, len(members))
+	var wg sync.WaitGroup
+	for i, rs := range members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fn(i, rs)
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+// Delete 从所有副本删除，对象不存在视为成功
+func (m *MirrorStorage) Delete(ctx context.Context, path string) error {
+	errs := each(m.replicas, func(i int, rs RemoteStorage) error {
+		if err := rs.Delete(ctx, path); err != nil && !IsNotFound(err) {
+			return err
+		}
+		return nil
+	})
+	if err := m.quorumError("delete", path, errs); err != nil {
+		return err
+	}
+	m.markResult(path, false, errs)
+	return nil
+}
+
+// Rename 在所有副本上重命名，失败的副本记为新名称待复制、旧名称待删除
+func (m *MirrorStorage) Rename(ctx context.Context, oldPath, newPath string) error {
+	behind := make([]bool, len(m.replicas))
+	errs := each(m.replicas, func(i int, rs RemoteStorage) error {
+		m.mu.Lock()
+		present, dirty := m.dirty[i][oldPath]
+		m.mu.Unlock()
+		if dirty && present {
+			// 该副本本来就缺少旧对象，交给 Resync 以新名称补齐
+			behind[i] = true
+			return nil
+		}
+		return rs.Rename(ctx, oldPath, newPath)
+	})
+	if err := m.quorumError("rename", oldPath, errs); err != nil {
+		return err
+	}
+	m.markResult(newPath, true, errs)
+	m.markResult(oldPath, false, errs)
+	m.mu.Lock()
+	changed := false
+	for i, b := range behind {
+		if b {
+			m.dirty[i][newPath] = true
+			changed = true
+		}
+	}
+	if changed {
+		m.saveStateLocked()
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+// Stat 返回第一个可用副本上的文件信息
+func (m *MirrorStorage) Stat(ctx context.Context, path string) (os.FileInfo, error) {
+	var lastErr error = fmt.Errorf("mirror: no replica holds '%s': %w", path, os.ErrNotExist)
+	for _, i := range m.readOrder(path) {
+		info, err := m.replicas[i].Stat(ctx, path)
+		if err == nil {
+			return info, nil
+		}
+		if !IsNotFound(err) {
+			m.markFailed(i)
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// List 枚举第一个支持 Lister 且可用的副本
+func (m *MirrorStorage) List(ctx context.Context, prefix string, fn func(name string, info os.FileInfo) error) error {
+	err := ErrNotSupported
+	for _, i := range m.readOrder("") {
+		called := false
+		err = List(ctx, m.replicas[i], prefix, func(name string, info os.FileInfo) error {
+			called = true
+			return fn(name, info)
+		})
+		if err == nil || called {
+			return err
+		}
+	}
+	return err
+}
+
+// Quota 每个副本都保存全部对象，剩余空间取各副本的最小值
+func (m *MirrorStorage) Quota(ctx context.Context) (int64, int64, error) {
+	return memberQuota(ctx, m.replicas)
+}
+
+// ResyncDirty 修复本进程记录的落后对象
+func (m *MirrorStorage) ResyncDirty(ctx context.Context) ResyncStats {
+	var stats ResyncStats
+	for i := range m.replicas {
+		m.mu.Lock()
+		pending := make(map[string]bool, len(m.dirty[i]))
+		for name, present := range m.dirty[i] {
+			pending[name] = present
+		}
+		m.mu.Unlock()
+
+		for name, present := range pending {
+			if ctx.Err() != nil {
+				return stats
+			}
+			var err error
+			if present {
+				err = m.copyTo(ctx, i, name)
+			} else if err = m.replicas[i].Delete(ctx, name); IsNotFound(err) {
+				err = nil
+			}
+			if err != nil {
+				log.Printf("Mirror: resync '%s' on %s failed: %v", name, m.names[i], err)
+				stats.Failed++
+				continue
+			}
+			m.mu.Lock()
+			// 修复期间有新的写操作时保留新状态
+			if p, ok := m.dirty[i][name]; ok && p == present {
+				delete(m.dirty[i], name)
+				m.saveStateLocked()
+			}
+			m.mu.Unlock()
+			if present {
+				stats.Copied++
+			} else {
+				stats.Deleted++
+			}
+		}
+	}
+	return stats
+}
+
+// Resync 枚举所有副本，将只存在于部分副本上的对象复制到其余副本（需要所有副本支持 Lister），
+// 之后再处理记录的落后对象。记录为待删除的对象不会被复制回来，而是从残留的副本上删除；
+// 没有记录的多余对象不会删除：无法区分缺失与已删除
+func (m *MirrorStorage) Resync(ctx context.Context) (ResyncStats, error) {
+	have := make([]map[string]int64, len(m.replicas))
+	for i, rs := range m.replicas {
+		have[i] = make(map[string]int64)
+		err := List(ctx, rs, "", func(name string, info os.FileInfo) error {
+			have[i][name] = info.Size()
+			return nil
+		})
+		if err != nil {
+			return ResyncStats{}, fmt.Errorf("mirror: list %s: %w", m.names[i], err)
+		}
+	}
+
+	for i := range m.replicas {
+		for j := range m.replicas {
+			if i == j {
+				continue
+			}
+			for name := range have[j] {
+				if _, ok := have[i][name]; ok {
+					continue
+				}
+				m.mu.Lock()
+				if _, ok := m.dirty[i][name]; !ok && !m.deletedLocked(name) {
+					m.dirty[i][name] = true
+				}
+				m.mu.Unlock()
+			}
+		}
+	}
+
+	stats := m.ResyncDirty(ctx)
+	return stats, ctx.Err()
+}
+
+// deletedLocked 判断 name 是否已删除、只是在部分副本上待删除
+func (m *MirrorStorage) deletedLocked(name string) bool {
+	for _, d := range m.dirty {
+		if present, ok := d[name]; ok && !present {
+			return true
+		}
+	}
+	return false
+}
+
+// copyTo 从其他持有 name 的副本复制到副本 i
+func (m *MirrorStorage) copyTo(ctx context.Context, i int, name string) error {
+	var lastErr error = fmt.Errorf("no source replica for '%s'", name)
+	for _, j := range m.readOrder(name) {
+		if j == i {
+			continue
+		}
+		info, err := m.replicas[j].Stat(ctx, name)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		rc, err := m.replicas[j].Download(ctx, name)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		err = m.replicas[i].Upload(ctx, name, rc, info.Size())
+		rc.Close()
+		if err == nil {
+			return nil
+		}
+		return err
+	}
+	return lastErr
+}
+
+// Close 停止后台修复并关闭所有副本
+func (m *MirrorStorage) Close() error {
+	close(m.stop)
+	m.wg.Wait()
+	if n := m.Pending(); n > 0 {
+		log.Printf("Mirror: %d object(s) still out of sync, run 'clearvault resync' to repair", n)
+	}
+	var errs []error
+	for _, rs := range m.replicas {
+		errs = append(errs, rs.Close())
+	}
+	return errors.Join(errs...)
+}
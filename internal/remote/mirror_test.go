@@ -0,0 +1,292 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"clearvault/internal/remote/local"
+)
+
+var errReplicaDown = errors.New("replica down")
+
+// switchStorage down 为 true 时所有操作失败，reads 统计下载次数
+type switchStorage struct {
+	RemoteStorage
+	down  atomic.Bool
+	reads atomic.Int32
+}
+
+func (s *switchStorage) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	if s.down.Load() {
+		return errReplicaDown
+	}
+	return s.RemoteStorage.Upload(ctx, name, data, size)
+}
+
+func (s *switchStorage) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	s.reads.Add(1)
+	if s.down.Load() {
+		return nil, errReplicaDown
+	}
+	return s.RemoteStorage.DownloadRange(ctx, name, start, length)
+}
+
+func (s *switchStorage) Stat(ctx context.Context, path string) (os.FileInfo, error) {
+	if s.down.Load() {
+		return nil, errReplicaDown
+	}
+	return s.RemoteStorage.Stat(ctx, path)
+}
+
+func (s *switchStorage) Delete(ctx context.Context, path string) error {
+	if s.down.Load() {
+		return errReplicaDown
+	}
+	return s.RemoteStorage.Delete(ctx, path)
+}
+
+func (s *switchStorage) List(ctx context.Context, prefix string, fn func(name string, info os.FileInfo) error) error {
+	return List(ctx, s.RemoteStorage, prefix, fn)
+}
+
+func newTestMirror(t *testing.T, quorum int) (*MirrorStorage, []string, []*switchStorage) {
+	t.Helper()
+	var dirs []string
+	var switches []*switchStorage
+	var replicas []RemoteStorage
+	for i := 0; i < 2; i++ {
+		dir := t.TempDir()
+		c, err := local.NewClient(dir)
+		if err != nil {
+			t.Fatalf("NewClient failed: %v", err)
+		}
+		s := &switchStorage{RemoteStorage: c}
+		dirs = append(dirs, dir)
+		switches = append(switches, s)
+		replicas = append(replicas, s)
+	}
+	m, err := NewMirrorStorage(replicas, nil, MirrorOptions{WriteQuorum: quorum})
+	if err != nil {
+		t.Fatalf("NewMirrorStorage failed: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m, dirs, switches
+}
+
+func readAll(t *testing.T, rs RemoteStorage, name string) string {
+	t.Helper()
+	rc, err := rs.Download(context.Background(), name)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	return string(b)
+}
+
+func TestMirrorStorage_Upload(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("fan out", func(t *testing.T) {
+		m, dirs, _ := newTestMirror(t, 0)
+		if err := m.Upload(ctx, "a/obj", strings.NewReader("hello"), 5); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		for _, dir := range dirs {
+			b, err := os.ReadFile(filepath.Join(dir, "a/obj"))
+			if err != nil || string(b) != "hello" {
+				t.Errorf("Replica %s: got %q, %v", dir, b, err)
+			}
+		}
+	})
+
+	t.Run("quorum not met", func(t *testing.T) {
+		m, _, sw := newTestMirror(t, 0)
+		sw[1].down.Store(true)
+		if err := m.Upload(ctx, "obj", strings.NewReader("hello"), 5); !errors.Is(err, errReplicaDown) {
+			t.Errorf("Expected quorum error, got %v", err)
+		}
+	})
+
+	t.Run("quorum met", func(t *testing.T) {
+		m, dirs, sw := newTestMirror(t, 1)
+		sw[0].down.Store(true)
+		if err := m.Upload(ctx, "obj", strings.NewReader("hello"), 5); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		if m.Pending() != 1 {
+			t.Errorf("Expected 1 pending object, got %d", m.Pending())
+		}
+
+		// 恢复后读取仍跳过缺少对象的副本
+		sw[0].down.Store(false)
+		if got := readAll(t, m, "obj"); got != "hello" {
+			t.Errorf("Expected 'hello', got %q", got)
+		}
+
+		stats := m.ResyncDirty(ctx)
+		if stats.Copied != 1 || m.Pending() != 0 {
+			t.Errorf("Expected 1 copied and nothing pending, got %+v pending=%d", stats, m.Pending())
+		}
+		if b, _ := os.ReadFile(filepath.Join(dirs[0], "obj")); string(b) != "hello" {
+			t.Errorf("Replica not healed, got %q", b)
+		}
+	})
+}
+
+func TestMirrorStorage_ReadFailover(t *testing.T) {
+	ctx := context.Background()
+	m, dirs, sw := newTestMirror(t, 0)
+	if err := m.Upload(ctx, "obj", strings.NewReader("0123456789"), 10); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	sw[0].down.Store(true)
+	rc, err := m.DownloadRange(ctx, "obj", 2, 5)
+	if err != nil {
+		t.Fatalf("DownloadRange failed: %v", err)
+	}
+	b, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(b) != "23456" {
+		t.Errorf("Expected '23456', got %q", b)
+	}
+
+	// 副本上的对象丢失时同样切换
+	sw[0].down.Store(false)
+	os.Remove(filepath.Join(dirs[1], "obj"))
+	os.Remove(filepath.Join(dirs[0], "obj"))
+	if _, err := m.Download(ctx, "obj"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist, got %v", err)
+	}
+}
+
+func TestMirrorStorage_Delete(t *testing.T) {
+	ctx := context.Background()
+	m, dirs, sw := newTestMirror(t, 1)
+	if err := m.Upload(ctx, "obj", strings.NewReader("hello"), 5); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	sw[1].down.Store(true)
+	if err := m.Delete(ctx, "obj"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	sw[1].down.Store(false)
+
+	stats := m.ResyncDirty(ctx)
+	if stats.Deleted != 1 {
+		t.Errorf("Expected 1 deleted, got %+v", stats)
+	}
+	if _, err := os.Stat(filepath.Join(dirs[1], "obj")); !os.IsNotExist(err) {
+		t.Errorf("Expected object removed from lagging replica, got %v", err)
+	}
+}
+
+func TestMirrorStorage_Resync(t *testing.T) {
+	ctx := context.Background()
+	m, dirs, _ := newTestMirror(t, 0)
+
+	// 模拟副本离线期间写入（例如上次进程退出前未修复）
+	if err := os.MkdirAll(filepath.Join(dirs[1], "x"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dirs[1], "x/only-b"), []byte("bbb"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dirs[0], "only-a"), []byte("aa"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := m.Resync(ctx)
+	if err != nil {
+		t.Fatalf("Resync failed: %v", err)
+	}
+	if stats.Copied != 2 || stats.Failed != 0 {
+		t.Errorf("Expected 2 copied, got %+v", stats)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dirs[0], "x/only-b")); string(b) != "bbb" {
+		t.Errorf("Expected x/only-b on replica 0, got %q", b)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dirs[1], "only-a")); string(b) != "aa" {
+		t.Errorf("Expected only-a on replica 1, got %q", b)
+	}
+}
+
+func TestMirrorStorage_PersistedState(t *testing.T) {
+	ctx := context.Background()
+	var dirs []string
+	var switches []*switchStorage
+	var replicas []RemoteStorage
+	for i := 0; i < 2; i++ {
+		dir := t.TempDir()
+		c, err := local.NewClient(dir)
+		if err != nil {
+			t.Fatalf("NewClient failed: %v", err)
+		}
+		s := &switchStorage{RemoteStorage: c}
+		dirs = append(dirs, dir)
+		switches = append(switches, s)
+		replicas = append(replicas, s)
+	}
+	opts := MirrorOptions{WriteQuorum: 1, StateFile: filepath.Join(t.TempDir(), "mirror-state.json")}
+	m, err := NewMirrorStorage(replicas, nil, opts)
+	if err != nil {
+		t.Fatalf("NewMirrorStorage failed: %v", err)
+	}
+	if err := m.Upload(ctx, "old", strings.NewReader("old"), 3); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	// 副本 1 离线期间删除一个对象、写入一个对象，随后进程退出
+	switches[1].down.Store(true)
+	if err := m.Delete(ctx, "old"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := m.Upload(ctx, "new", strings.NewReader("new"), 3); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	m.Close()
+	switches[1].down.Store(false)
+
+	m, err = NewMirrorStorage(replicas, nil, opts)
+	if err != nil {
+		t.Fatalf("NewMirrorStorage failed: %v", err)
+	}
+	defer m.Close()
+	if n := m.Pending(); n != 2 {
+		t.Fatalf("Expected 2 pending objects after restart, got %d", n)
+	}
+
+	// 已删除的对象从落后副本删除，而不是复制回来
+	stats, err := m.Resync(ctx)
+	if err != nil {
+		t.Fatalf("Resync failed: %v", err)
+	}
+	if stats.Copied != 1 || stats.Deleted != 1 || stats.Failed != 0 {
+		t.Errorf("Expected 1 copied and 1 deleted, got %+v", stats)
+	}
+	for i, dir := range dirs {
+		if _, err := os.Stat(filepath.Join(dir, "old")); !os.IsNotExist(err) {
+			t.Errorf("Deleted object still on replica %d: %v", i, err)
+		}
+		if b, _ := os.ReadFile(filepath.Join(dir, "new")); string(b) != "new" {
+			t.Errorf("Expected new on replica %d, got %q", i, b)
+		}
+	}
+	if m.Pending() != 0 {
+		t.Errorf("Expected nothing pending, got %d", m.Pending())
+	}
+	if b, _ := os.ReadFile(opts.StateFile); string(b) != "{}" {
+		t.Errorf("Expected empty state file, got %q", b)
+	}
+}
@@ -0,0 +1,198 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+// ParallelUpload 并行分块上传设置
+type ParallelUpload struct {
+	// PartSize 分块大小，不大于 0 时为 16MiB；对象过大时自动增大以不超过 maxChunks
+	PartSize int64
+	// Parallel 同时上传的分块数，不大于 0 时为 4
+	Parallel int
+}
+
+// WithParallelUpload 为支持分块会话的后端添加并行分块上传
+// 大于一个分块（或大小未知）的对象从数据流依次读出分块，最多 Parallel 个分块同时上传，
+// 每次上传占用的内存不超过 (Parallel+1) × PartSize；单个分块失败按重试策略重传，仍失败时放弃整个会话
+func WithParallelUpload(rs RemoteStorage, cu ChunkUploader, opts ParallelUpload, p RetryPolicy) RemoteStorage {
+	if opts.PartSize <= 0 {
+		opts.PartSize = 16 << 20
+	}
+	if opts.Parallel <= 0 {
+		opts.Parallel = 4
+	}
+	return &parallelStorage{RemoteStorage: rs, cu: cu, opts: opts, retry: newRetryStorage(nil, p)}
+}
+
+type parallelStorage struct {
+	RemoteStorage
+	cu    ChunkUploader
+	opts  ParallelUpload
+	retry *retryStorage // 只使用其退避策略
+	bufs  sync.Pool     // 复用分块缓冲区
+}
+
+// buffer 返回至少 size 字节的缓冲区
+func (s *parallelStorage) buffer(size int64) []byte {
+	if b, ok := s.bufs.Get().([]byte); ok && int64(cap(b)) >= size {
+		return b[:size]
+	}
+	return make([]byte, size)
+}
+
+func (s *parallelStorage) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	partSize := fitChunkSize(s.opts.PartSize, size)
+	if size >= 0 && size <= partSize {
+		return s.RemoteStorage.Upload(ctx, name, data, size)
+	}
+
+	buf := s.buffer(partSize)
+	k, rerr := io.ReadFull(data, buf)
+	if rerr != nil && rerr != io.EOF && rerr != io.ErrUnexpectedEOF {
+		s.bufs.Put(buf)
+		return fmt.Errorf("parallel upload: failed to read data for '%s': %w", name, rerr)
+	}
+	if rerr != nil {
+		// 实际不足一个分块，直接上传
+		defer s.bufs.Put(buf)
+		return s.RemoteStorage.Upload(ctx, name, bytes.NewReader(buf[:k]), int64(k))
+	}
+
+	var id string
+	err := s.retry.do(ctx, "create upload", name, func(int) error {
+		var err error
+		id, err = s.cu.CreateUpload(ctx, name)
+		return err
+	})
+	if err != nil {
+		s.bufs.Put(buf)
+		return fmt.Errorf("parallel upload: failed to create upload for '%s': %w", name, err)
+	}
+
+	u := &parallelUpload{s: s, name: name, id: id, sem: make(chan struct{}, s.opts.Parallel)}
+	u.ctx, u.cancel = context.WithCancel(ctx)
+	defer u.cancel()
+
+	var total int64
+	for n := 1; ; n++ {
+		if n > maxChunks {
+			u.fail(fmt.Errorf("'%s' exceeds %d chunks of %d bytes", name, maxChunks, partSize))
+			s.bufs.Put(buf)
+			break
+		}
+		total += int64(k)
+		if !u.send(n, buf, k) {
+			break
+		}
+		if rerr != nil {
+			break
+		}
+		// 读取下一个分块时最多 Parallel 个分块在上传
+		buf = s.buffer(partSize)
+		k, rerr = io.ReadFull(data, buf)
+		if rerr != nil && rerr != io.EOF && rerr != io.ErrUnexpectedEOF {
+			u.fail(fmt.Errorf("failed to read data for '%s': %w", name, rerr))
+			s.bufs.Put(buf)
+			break
+		}
+		if k == 0 {
+			s.bufs.Put(buf)
+			break
+		}
+	}
+	u.wg.Wait()
+
+	if u.err == nil && size >= 0 && total != size {
+		u.err = fmt.Errorf("read %d bytes for '%s', expected %d", total, name, size)
+	}
+	if u.err != nil {
+		// 原 ctx 可能已取消，放弃会话不受其影响
+		if err := s.cu.AbortUpload(context.WithoutCancel(ctx), name, id); err != nil && !IsNotFound(err) {
+			log.Printf("Remote: Failed to abort upload of '%s': %v", name, err)
+		}
+		return fmt.Errorf("parallel upload: %w", u.err)
+	}
+
+	err = s.retry.do(ctx, "complete upload", name, func(int) error {
+		return s.cu.CompleteUpload(ctx, name, id, u.etags, total)
+	})
+	if err != nil {
+		return fmt.Errorf("parallel upload: failed to complete upload of '%s': %w", name, err)
+	}
+	return nil
+}
+
+func (s *parallelStorage) List(ctx context.Context, prefix string, fn func(name string, info os.FileInfo) error) error {
+	return List(ctx, s.RemoteStorage, prefix, fn)
+}
+
+func (s *parallelStorage) Quota(ctx context.Context) (int64, int64, error) {
+	return Quota(ctx, s.RemoteStorage)
+}
+
+// parallelUpload 一次进行中的并行上传
+type parallelUpload struct {
+	s      *parallelStorage
+	name   string
+	id     string
+	ctx    context.Context
+	cancel context.CancelFunc
+	sem    chan struct{}
+	wg     sync.WaitGroup
+
+	mu    sync.Mutex
+	etags []string
+	err   error
+}
+
+// send 等待空闲的上传槽位后在后台上传第 n 个分块，已有分块失败时返回 false
+func (u *parallelUpload) send(n int, buf []byte, k int) bool {
+	select {
+	case u.sem <- struct{}{}:
+	case <-u.ctx.Done():
+		u.s.bufs.Put(buf)
+		u.fail(u.ctx.Err())
+		return false
+	}
+	u.mu.Lock()
+	u.etags = append(u.etags, "")
+	u.mu.Unlock()
+
+	u.wg.Add(1)
+	go func() {
+		defer u.wg.Done()
+		defer func() { <-u.sem }()
+		defer u.s.bufs.Put(buf)
+		var etag string
+		err := u.s.retry.do(u.ctx, "upload chunk", u.name, func(int) error {
+			var err error
+			etag, err = u.s.cu.UploadChunk(u.ctx, u.name, u.id, n, buf[:k])
+			return err
+		})
+		if err != nil {
+			u.fail(fmt.Errorf("failed to upload chunk %d of '%s': %w", n, u.name, err))
+			return
+		}
+		u.mu.Lock()
+		u.etags[n-1] = etag
+		u.mu.Unlock()
+	}()
+	return true
+}
+
+// fail 记录第一个错误并取消其余分块
+func (u *parallelUpload) fail(err error) {
+	u.mu.Lock()
+	if u.err == nil {
+		u.err = err
+	}
+	u.mu.Unlock()
+	u.cancel()
+}
@@ -0,0 +1,102 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestParallel(cs *chunkStore, parallel int) RemoteStorage {
+	return WithParallelUpload(cs, cs, ParallelUpload{PartSize: testChunk, Parallel: parallel},
+		RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+}
+
+func TestParallelUpload(t *testing.T) {
+	ctx := context.Background()
+	cs := newChunkStore(t)
+
+	// 记录同时进行的分块上传数
+	var active, peak atomic.Int32
+	cs.fail = func(n int) error {
+		cur := active.Add(1)
+		for {
+			p := peak.Load()
+			if cur <= p || peak.CompareAndSwap(p, cur) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		active.Add(-1)
+		return nil
+	}
+	rs := newTestParallel(cs, 3)
+
+	for _, tt := range []struct {
+		name string
+		size int
+		arg  int64
+	}{
+		{"small", 100, 100},
+		{"multi", 10*testChunk + 7, 10*testChunk + 7},
+		{"aligned", 8 * testChunk, -1},
+		{"unknown", 5*testChunk + 1, -1},
+		{"unknown-small", 10, -1},
+	} {
+		data := randomData(tt.size)
+		if err := rs.Upload(ctx, tt.name, bytes.NewReader(data), tt.arg); err != nil {
+			t.Fatalf("%s: Upload failed: %v", tt.name, err)
+		}
+		rc, err := rs.Download(ctx, tt.name)
+		if err != nil {
+			t.Fatalf("%s: Download failed: %v", tt.name, err)
+		}
+		got, _ := io.ReadAll(rc)
+		rc.Close()
+		if !bytes.Equal(got, data) {
+			t.Errorf("%s: content mismatch (%d bytes, want %d)", tt.name, len(got), len(data))
+		}
+	}
+	if p := peak.Load(); p < 2 || p > 3 {
+		t.Errorf("Expected 2-3 concurrent chunk uploads, got %d", p)
+	}
+	if len(cs.sessions) != 0 {
+		t.Errorf("Expected all sessions to be completed, got %d", len(cs.sessions))
+	}
+}
+
+func TestParallelUpload_Failure(t *testing.T) {
+	ctx := context.Background()
+	cs := newChunkStore(t)
+	cs.fail = func(n int) error {
+		if n == 3 {
+			return errors.New("permanent failure")
+		}
+		return nil
+	}
+	rs := newTestParallel(cs, 2)
+
+	data := randomData(8 * testChunk)
+	if err := rs.Upload(ctx, "obj", bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Fatal("Expected upload to fail")
+	}
+	if cs.aborts != 1 || len(cs.sessions) != 0 {
+		t.Errorf("Expected session to be aborted, aborts=%d sessions=%d", cs.aborts, len(cs.sessions))
+	}
+	if _, err := cs.Stat(ctx, "obj"); err == nil {
+		t.Error("Expected object not to exist")
+	}
+
+	// 数据比声明的大小短
+	cs.fail = nil
+	err := rs.Upload(ctx, "short", bytes.NewReader(data[:3*testChunk]), int64(len(data)))
+	if err == nil {
+		t.Fatal("Expected size mismatch error")
+	}
+	if cs.aborts != 2 {
+		t.Errorf("Expected session to be aborted, aborts=%d", cs.aborts)
+	}
+}
@@ -0,0 +1,81 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+// WithQuotaLimit 为远端设置容量上限（字节），用于 S3 等无法查询剩余空间的后端
+// 上限只针对存放的对象：已用空间为枚举到的对象大小之和，后端报告的剩余空间更小时以后端为准
+func WithQuotaLimit(rs RemoteStorage, limit int64) RemoteStorage {
+	if limit <= 0 {
+		return rs
+	}
+	return &quotaStorage{RemoteStorage: rs, limit: limit}
+}
+
+type quotaStorage struct {
+	RemoteStorage
+	limit int64
+}
+
+func (s *quotaStorage) Quota(ctx context.Context) (int64, int64, error) {
+	used, available, err := Quota(ctx, s.RemoteStorage)
+	if err != nil && !errors.Is(err, ErrNotSupported) {
+		return -1, -1, err
+	}
+	var sum int64
+	err = List(ctx, s.RemoteStorage, "", func(name string, info os.FileInfo) error {
+		sum += info.Size()
+		return nil
+	})
+	switch {
+	case err == nil:
+		used = sum
+	case !errors.Is(err, ErrNotSupported):
+		return -1, -1, err
+	case used < 0:
+		// 无法统计已用空间
+		return -1, minKnown(available, s.limit), nil
+	}
+	return used, minKnown(available, max(s.limit-used, 0)), nil
+}
+
+func (s *quotaStorage) List(ctx context.Context, prefix string, fn func(name string, info os.FileInfo) error) error {
+	return List(ctx, s.RemoteStorage, prefix, fn)
+}
+
+// memberQuota 汇总组合存储各成员的空间：每个成员都要能写入，剩余空间取最小值，已用空间取最大值
+// 忽略不支持或查询失败的成员，全部失败时返回最后一个错误
+func memberQuota(ctx context.Context, members []RemoteStorage) (used, available int64, err error) {
+	used, available, err = -1, -1, ErrNotSupported
+	ok := false
+	for _, rs := range members {
+		u, a, qerr := Quota(ctx, rs)
+		if qerr != nil {
+			if !errors.Is(qerr, ErrNotSupported) {
+				err = qerr
+			}
+			continue
+		}
+		ok = true
+		used = max(used, u)
+		available = minKnown(available, a)
+	}
+	if !ok {
+		return -1, -1, err
+	}
+	return used, available, nil
+}
+
+// minKnown 返回两者中较小的已知值（-1 表示未知）
+func minKnown(a, b int64) int64 {
+	if a < 0 {
+		return b
+	}
+	if b < 0 {
+		return a
+	}
+	return min(a, b)
+}
@@ -0,0 +1,94 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// fixedQuota 报告固定空间的存储
+type fixedQuota struct {
+	RemoteStorage
+	used, available int64
+	err             error
+}
+
+func (s fixedQuota) Quota(ctx context.Context) (int64, int64, error) {
+	return s.used, s.available, s.err
+}
+
+func (s fixedQuota) List(ctx context.Context, prefix string, fn func(name string, info os.FileInfo) error) error {
+	return List(ctx, s.RemoteStorage, prefix, fn)
+}
+
+// listOnly 只能枚举、不报告空间的存储
+type listOnly struct {
+	RemoteStorage
+}
+
+func (s listOnly) List(ctx context.Context, prefix string, fn func(name string, info os.FileInfo) error) error {
+	return List(ctx, s.RemoteStorage, prefix, fn)
+}
+
+func TestQuotaLimit(t *testing.T) {
+	ctx := context.Background()
+	rs, _ := newTestLocal(t)
+	for _, name := range []string{"aa", "bb"} {
+		if err := rs.Upload(ctx, name, strings.NewReader(strings.Repeat("x", 1000)), 1000); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 已用空间为对象大小之和
+	used, available, err := Quota(ctx, WithQuotaLimit(listOnly{rs}, 5000))
+	if err != nil || used != 2000 || available != 3000 {
+		t.Errorf("Expected 2000 used, 3000 available, got %d, %d, %v", used, available, err)
+	}
+	used, available, err = Quota(ctx, WithQuotaLimit(listOnly{rs}, 1500))
+	if err != nil || used != 2000 || available != 0 {
+		t.Errorf("Expected limit to be exhausted, got %d, %d, %v", used, available, err)
+	}
+
+	// 后端剩余空间更小时以后端为准，后端报告的已用空间（如整个磁盘）不计入上限
+	used, available, err = Quota(ctx, WithQuotaLimit(fixedQuota{rs, 1 << 40, 50, nil}, 5000))
+	if err != nil || used != 2000 || available != 50 {
+		t.Errorf("Expected 2000 used, 50 available, got %d, %d, %v", used, available, err)
+	}
+
+	// 无法枚举时沿用后端报告的已用空间
+	used, available, err = Quota(ctx, WithQuotaLimit(fixedQuota{struct{ RemoteStorage }{rs}, 100, -1, nil}, 5000))
+	if err != nil || used != 100 || available != 4900 {
+		t.Errorf("Expected 100 used, 4900 available, got %d, %d, %v", used, available, err)
+	}
+
+	if _, _, err := Quota(ctx, listOnly{rs}); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+}
+
+func TestMemberQuota(t *testing.T) {
+	ctx := context.Background()
+	offline := errors.New("offline")
+	tests := []struct {
+		name            string
+		members         []RemoteStorage
+		used, available int64
+		err             error
+	}{
+		{"smallest available", []RemoteStorage{fixedQuota{nil, 10, 500, nil}, fixedQuota{nil, 30, 200, nil}}, 30, 200, nil},
+		{"unknown ignored", []RemoteStorage{fixedQuota{nil, -1, -1, nil}, fixedQuota{nil, 30, 200, nil}}, 30, 200, nil},
+		{"failed member ignored", []RemoteStorage{fixedQuota{nil, 0, 0, offline}, fixedQuota{nil, 30, 200, nil}}, 30, 200, nil},
+		{"all failed", []RemoteStorage{fixedQuota{nil, 0, 0, offline}, listOnly{}}, -1, -1, offline},
+		{"not supported", []RemoteStorage{listOnly{}, listOnly{}}, -1, -1, ErrNotSupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			used, available, err := memberQuota(ctx, tt.members)
+			if used != tt.used || available != tt.available || !errors.Is(err, tt.err) {
+				t.Errorf("Expected %d, %d, %v, got %d, %d, %v", tt.used, tt.available, tt.err, used, available, err)
+			}
+		})
+	}
+}
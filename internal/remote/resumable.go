@@ -0,0 +1,310 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxChunks S3 与 Nextcloud 单个上传会话允许的最大分块数
+const maxChunks = 10000
+
+// Resumable 可续传上传设置
+type Resumable struct {
+	// ID 远端标识，区分共用同一日志目录的多个远端
+	ID string
+	// JournalDir 上传日志目录，记录会话 ID 与已完成的分块
+	JournalDir string
+	// ChunkSize 分块大小，不大于 0 时为 16MiB；对象过大时自动增大以不超过 maxChunks
+	ChunkSize int64
+	// MaxAge 超过该时间的未完成会话在启动时放弃，不大于 0 时为 24 小时
+	MaxAge time.Duration
+}
+
+// WithResumable 为支持分块会话的后端添加可续传上传
+// 大于一个分块的对象按块上传，单个分块失败只重传该分块；每个分块完成后写入日志，
+// 进程重启后再次上传同名对象时，内容（SHA-256）与日志一致的分块直接沿用，不再重传
+func WithResumable(rs RemoteStorage, cu ChunkUploader, r Resumable, p RetryPolicy) (RemoteStorage, error) {
+	if r.ChunkSize <= 0 {
+		r.ChunkSize = 16 << 20
+	}
+	if r.MaxAge <= 0 {
+		r.MaxAge = 24 * time.Hour
+	}
+	if err := os.MkdirAll(r.JournalDir, 0700); err != nil {
+		return nil, fmt.Errorf("resumable: failed to create journal dir: %w", err)
+	}
+	s := &resumableStorage{RemoteStorage: rs, cu: cu, r: r, retry: newRetryStorage(nil, p)}
+	s.expire()
+	return s, nil
+}
+
+type resumableStorage struct {
+	RemoteStorage
+	cu    ChunkUploader
+	r     Resumable
+	retry *retryStorage // 只使用其退避策略
+}
+
+// uploadJournal 未完成上传的日志
+type uploadJournal struct {
+	Remote    string         `json:"remote"`
+	Name      string         `json:"name"`
+	UploadID  string         `json:"upload_id"`
+	ChunkSize int64          `json:"chunk_size"`
+	Chunks    []journalChunk `json:"chunks"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type journalChunk struct {
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+	ETag   string `json:"etag"`
+}
+
+// chunkSize 返回本次上传使用的分块大小
+func (s *resumableStorage) chunkSize(size int64) int64 {
+	return fitChunkSize(s.r.ChunkSize, size)
+}
+
+// fitChunkSize 对象过大时增大分块（向上取整到 MiB），使分块数不超过 maxChunks
+func fitChunkSize(cs, size int64) int64 {
+	if size > cs*maxChunks {
+		cs = ((size+maxChunks-1)/maxChunks + 1<<20 - 1) &^ (1<<20 - 1)
+	}
+	return cs
+}
+
+func (s *resumableStorage) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	chunkSize := s.chunkSize(size)
+	if size >= 0 && size <= chunkSize {
+		return s.RemoteStorage.Upload(ctx, name, data, size)
+	}
+
+	j := s.load(name)
+	if j != nil && j.ChunkSize != chunkSize {
+		s.discard(ctx, j)
+		j = nil
+	}
+
+	buf := make([]byte, chunkSize)
+	var etags []string
+	var total int64
+	resumed := 0
+	for n := 1; ; n++ {
+		k, rerr := io.ReadFull(data, buf)
+		if rerr != nil && rerr != io.EOF && rerr != io.ErrUnexpectedEOF {
+			return fmt.Errorf("resumable: failed to read data for '%s': %w", name, rerr)
+		}
+		last := rerr != nil
+		if n == 1 && last {
+			// 实际不足一个分块，直接上传
+			if j != nil {
+				s.discard(ctx, j)
+			}
+			return s.RemoteStorage.Upload(ctx, name, bytes.NewReader(buf[:k]), int64(k))
+		}
+		if k == 0 {
+			break
+		}
+		if n > maxChunks {
+			return fmt.Errorf("resumable: '%s' exceeds %d chunks of %d bytes", name, maxChunks, chunkSize)
+		}
+
+		chunk := buf[:k]
+		sum := sha256.Sum256(chunk)
+		hash := hex.EncodeToString(sum[:])
+		if j != nil && n <= len(j.Chunks) && j.Chunks[n-1].Size == k && j.Chunks[n-1].SHA256 == hash {
+			etags = append(etags, j.Chunks[n-1].ETag)
+			total += int64(k)
+			resumed++
+			if last {
+				break
+			}
+			continue
+		}
+		if resumed > 0 && len(etags) == resumed {
+			log.Printf("Remote: Resuming upload of '%s' after %d chunks", name, resumed)
+		}
+
+		if j == nil {
+			var err error
+			if j, err = s.create(ctx, name, chunkSize); err != nil {
+				return err
+			}
+		}
+		var etag string
+		err := s.retry.do(ctx, "upload chunk", name, func(int) error {
+			var err error
+			etag, err = s.cu.UploadChunk(ctx, name, j.UploadID, n, chunk)
+			return err
+		})
+		if err != nil {
+			if IsNotFound(err) {
+				// 服务端会话已失效，下次上传重新开始
+				s.remove(name)
+			}
+			return fmt.Errorf("resumable: failed to upload chunk %d of '%s': %w", n, name, err)
+		}
+		j.Chunks = append(j.Chunks[:n-1], journalChunk{Size: k, SHA256: hash, ETag: etag})
+		if err := s.save(j); err != nil {
+			return err
+		}
+		etags = append(etags, etag)
+		total += int64(k)
+		if last {
+			break
+		}
+	}
+
+	err := s.retry.do(ctx, "complete upload", name, func(int) error {
+		return s.cu.CompleteUpload(ctx, name, j.UploadID, etags, total)
+	})
+	if err != nil {
+		if IsNotFound(err) {
+			s.remove(name)
+		}
+		return fmt.Errorf("resumable: failed to complete upload of '%s': %w", name, err)
+	}
+	s.remove(name)
+	return nil
+}
+
+// Delete 删除对象，同时放弃该对象未完成的上传会话
+// 代理上传失败时删除本次随机生成的对象名，会话不会再被续传，无需等到 max_age 才清理
+func (s *resumableStorage) Delete(ctx context.Context, name string) error {
+	if j := s.load(name); j != nil {
+		s.discard(ctx, j)
+	}
+	return s.RemoteStorage.Delete(ctx, name)
+}
+
+// List 透传到底层存储
+func (s *resumableStorage) List(ctx context.Context, prefix string, fn func(name string, info os.FileInfo) error) error {
+	return List(ctx, s.RemoteStorage, prefix, fn)
+}
+
+func (s *resumableStorage) Quota(ctx context.Context) (int64, int64, error) {
+	return Quota(ctx, s.RemoteStorage)
+}
+
+// create 开始新会话并写入日志
+func (s *resumableStorage) create(ctx context.Context, name string, chunkSize int64) (*uploadJournal, error) {
+	var id string
+	err := s.retry.do(ctx, "create upload", name, func(int) error {
+		var err error
+		id, err = s.cu.CreateUpload(ctx, name)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("resumable: failed to create upload for '%s': %w", name, err)
+	}
+	j := &uploadJournal{
+		Remote:    s.r.ID,
+		Name:      name,
+		UploadID:  id,
+		ChunkSize: chunkSize,
+		CreatedAt: time.Now(),
+	}
+	if err := s.save(j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// discard 放弃会话（尽力而为）并删除日志
+func (s *resumableStorage) discard(ctx context.Context, j *uploadJournal) {
+	if err := s.cu.AbortUpload(ctx, j.Name, j.UploadID); err != nil && !IsNotFound(err) {
+		log.Printf("Remote: Failed to abort upload of '%s': %v", j.Name, err)
+	}
+	s.remove(j.Name)
+}
+
+// expire 放弃本远端超过 MaxAge 的未完成会话
+func (s *resumableStorage) expire() {
+	entries, err := os.ReadDir(s.r.JournalDir)
+	if err != nil {
+		log.Printf("Remote: Failed to read upload journal: %v", err)
+		return
+	}
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		j, err := readJournal(filepath.Join(s.r.JournalDir, e.Name()))
+		if err != nil || j.Remote != s.r.ID || time.Since(j.CreatedAt) < s.r.MaxAge {
+			continue
+		}
+		log.Printf("Remote: Abandoning upload of '%s' started at %s", j.Name, j.CreatedAt.Format(time.RFC3339))
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		s.discard(ctx, j)
+		cancel()
+	}
+}
+
+func (s *resumableStorage) journalPath(name string) string {
+	sum := sha256.Sum256([]byte(s.r.ID + "\x00" + name))
+	return filepath.Join(s.r.JournalDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// load 读取对象的上传日志，不存在或已损坏时返回 nil
+func (s *resumableStorage) load(name string) *uploadJournal {
+	p := s.journalPath(name)
+	j, err := readJournal(p)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Remote: Ignoring unreadable upload journal for '%s': %v", name, err)
+			os.Remove(p)
+		}
+		return nil
+	}
+	if j.Remote != s.r.ID || j.Name != name || j.UploadID == "" {
+		return nil
+	}
+	return j
+}
+
+func readJournal(p string) (*uploadJournal, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	var j uploadJournal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// save 原子写入日志（先写临时文件再重命名）
+func (s *resumableStorage) save(j *uploadJournal) error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	p := s.journalPath(j.Name)
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("resumable: failed to write journal: %w", err)
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		return fmt.Errorf("resumable: failed to write journal: %w", err)
+	}
+	return nil
+}
+
+func (s *resumableStorage) remove(name string) {
+	if err := os.Remove(s.journalPath(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Remote: Failed to remove upload journal for '%s': %v", name, err)
+	}
+}
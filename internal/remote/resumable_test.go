@@ -0,0 +1,312 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"clearvault/internal/remote/local"
+)
+
+const testChunk = 1 << 10
+
+// chunkStore 内存中的分块会话，Complete 时把分块合并写入底层存储
+type chunkStore struct {
+	RemoteStorage
+	mu       sync.Mutex
+	sessions map[string]map[int][]byte
+	puts     int
+	aborts   int
+	// fail 返回非 nil 时第 n 个分块上传失败
+	fail func(n int) error
+}
+
+func newChunkStore(t *testing.T) *chunkStore {
+	t.Helper()
+	c, err := local.NewClient(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	return &chunkStore{RemoteStorage: c, sessions: map[string]map[int][]byte{}}
+}
+
+func (s *chunkStore) CreateUpload(ctx context.Context, name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := fmt.Sprintf("upload-%d", len(s.sessions)+1)
+	s.sessions[id] = map[int][]byte{}
+	return id, nil
+}
+
+func (s *chunkStore) UploadChunk(ctx context.Context, name, uploadID string, n int, data []byte) (string, error) {
+	if s.fail != nil {
+		if err := s.fail(n); err != nil {
+			return "", err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chunks, ok := s.sessions[uploadID]
+	if !ok {
+		return "", os.ErrNotExist
+	}
+	s.puts++
+	chunks[n] = bytes.Clone(data)
+	return fmt.Sprintf("etag-%d-%d", n, len(data)), nil
+}
+
+func (s *chunkStore) CompleteUpload(ctx context.Context, name, uploadID string, etags []string, size int64) error {
+	s.mu.Lock()
+	chunks, ok := s.sessions[uploadID]
+	delete(s.sessions, uploadID)
+	s.mu.Unlock()
+	if !ok {
+		return os.ErrNotExist
+	}
+	var buf bytes.Buffer
+	for i, etag := range etags {
+		data := chunks[i+1]
+		if etag != fmt.Sprintf("etag-%d-%d", i+1, len(data)) {
+			return fmt.Errorf("chunk %d: etag mismatch %q", i+1, etag)
+		}
+		buf.Write(data)
+	}
+	if int64(buf.Len()) != size {
+		return fmt.Errorf("size mismatch: %d != %d", buf.Len(), size)
+	}
+	return s.RemoteStorage.Upload(ctx, name, &buf, size)
+}
+
+func (s *chunkStore) AbortUpload(ctx context.Context, name, uploadID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.aborts++
+	delete(s.sessions, uploadID)
+	return nil
+}
+
+func newTestResumable(t *testing.T, cs *chunkStore, dir string) RemoteStorage {
+	t.Helper()
+	rs, err := WithResumable(cs, cs, Resumable{ID: "test", JournalDir: dir, ChunkSize: testChunk},
+		RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	if err != nil {
+		t.Fatalf("WithResumable failed: %v", err)
+	}
+	return rs
+}
+
+func journalFiles(t *testing.T, dir string) int {
+	t.Helper()
+	m, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	return len(m)
+}
+
+func TestResumable_Upload(t *testing.T) {
+	ctx := context.Background()
+	cs := newChunkStore(t)
+	dir := t.TempDir()
+	rs := newTestResumable(t, cs, dir)
+
+	for _, tt := range []struct {
+		name string
+		size int
+		arg  int64
+	}{
+		{"small", 100, 100},
+		{"exact", testChunk, testChunk},
+		{"multi", 3*testChunk + 7, 3*testChunk + 7},
+		{"aligned", 2 * testChunk, -1},
+		{"unknown-small", 10, -1},
+		{"empty", 0, -1},
+	} {
+		data := randomData(tt.size)
+		if err := rs.Upload(ctx, tt.name, bytes.NewReader(data), tt.arg); err != nil {
+			t.Fatalf("Upload(%s) failed: %v", tt.name, err)
+		}
+		if got := readAll(t, rs, tt.name); got != string(data) {
+			t.Errorf("%s: got %d bytes, want %d", tt.name, len(got), len(data))
+		}
+	}
+	if cs.puts != 4+2 {
+		t.Errorf("Expected 6 chunk uploads, got %d", cs.puts)
+	}
+	if n := journalFiles(t, dir); n != 0 {
+		t.Errorf("Expected journal to be empty, found %d entries", n)
+	}
+}
+
+func TestResumable_RetryChunk(t *testing.T) {
+	cs := newChunkStore(t)
+	failed := false
+	cs.fail = func(n int) error {
+		if n == 2 && !failed {
+			failed = true
+			return fmt.Errorf("write: %w", syscall.ECONNRESET)
+		}
+		return nil
+	}
+	rs := newTestResumable(t, cs, t.TempDir())
+
+	data := randomData(3 * testChunk)
+	// 数据源不可重放，只能逐块重试
+	if err := rs.Upload(context.Background(), "obj", io.MultiReader(bytes.NewReader(data)), -1); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if cs.puts != 3 {
+		t.Errorf("Expected 3 chunk uploads, got %d", cs.puts)
+	}
+	if got := readAll(t, rs, "obj"); got != string(data) {
+		t.Error("Content mismatch")
+	}
+}
+
+func TestResumable_ResumeAfterRestart(t *testing.T) {
+	ctx := context.Background()
+	cs := newChunkStore(t)
+	dir := t.TempDir()
+	data := randomData(4*testChunk + 100)
+
+	errDisk := errors.New("disk full")
+	cs.fail = func(n int) error {
+		if n == 3 {
+			return errDisk
+		}
+		return nil
+	}
+	rs := newTestResumable(t, cs, dir)
+	if err := rs.Upload(ctx, "obj", bytes.NewReader(data), int64(len(data))); !errors.Is(err, errDisk) {
+		t.Fatalf("Expected upload to fail, got %v", err)
+	}
+	if journalFiles(t, dir) != 1 {
+		t.Fatal("Expected journal entry for the interrupted upload")
+	}
+
+	// 新实例模拟进程重启：前两个分块不再上传
+	cs.fail = nil
+	cs.puts = 0
+	rs = newTestResumable(t, cs, dir)
+	if err := rs.Upload(ctx, "obj", bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatalf("Resumed upload failed: %v", err)
+	}
+	if cs.puts != 3 {
+		t.Errorf("Expected 3 chunk uploads after resume, got %d", cs.puts)
+	}
+	if got := readAll(t, rs, "obj"); got != string(data) {
+		t.Error("Content mismatch after resume")
+	}
+	if journalFiles(t, dir) != 0 {
+		t.Error("Expected journal entry to be removed")
+	}
+}
+
+func TestResumable_DeleteAbortsSession(t *testing.T) {
+	ctx := context.Background()
+	cs := newChunkStore(t)
+	dir := t.TempDir()
+	cs.fail = func(n int) error {
+		if n == 2 {
+			return errors.New("disk full")
+		}
+		return nil
+	}
+	rs := newTestResumable(t, cs, dir)
+	data := randomData(3 * testChunk)
+	if err := rs.Upload(ctx, "obj", bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Fatal("Expected upload to fail")
+	}
+	if journalFiles(t, dir) != 1 || len(cs.sessions) != 1 {
+		t.Fatal("Expected an interrupted session")
+	}
+
+	// 对象本身不存在，删除返回 not found，但会话已放弃
+	if err := rs.Delete(ctx, "obj"); err != nil && !IsNotFound(err) {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if journalFiles(t, dir) != 0 || len(cs.sessions) != 0 || cs.aborts != 1 {
+		t.Errorf("Expected session to be aborted (journal=%d, sessions=%d, aborts=%d)", journalFiles(t, dir), len(cs.sessions), cs.aborts)
+	}
+}
+
+func TestResumable_ChangedContent(t *testing.T) {
+	ctx := context.Background()
+	cs := newChunkStore(t)
+	dir := t.TempDir()
+	data := randomData(3 * testChunk)
+
+	errStop := errors.New("stop")
+	cs.fail = func(n int) error {
+		if n == 3 {
+			return errStop
+		}
+		return nil
+	}
+	rs := newTestResumable(t, cs, dir)
+	rs.Upload(ctx, "obj", bytes.NewReader(data), int64(len(data)))
+
+	// 第二块内容变化且总长变短：从变化处重新上传
+	cs.fail = nil
+	cs.puts = 0
+	changed := append(bytes.Clone(data[:testChunk]), randomData(testChunk+5)...)
+	if err := rs.Upload(ctx, "obj", bytes.NewReader(changed), int64(len(changed))); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if cs.puts != 2 {
+		t.Errorf("Expected 2 chunk uploads, got %d", cs.puts)
+	}
+	if got := readAll(t, rs, "obj"); got != string(changed) {
+		t.Error("Content mismatch")
+	}
+}
+
+func TestResumable_ExpiredSession(t *testing.T) {
+	ctx := context.Background()
+	cs := newChunkStore(t)
+	dir := t.TempDir()
+	data := randomData(3 * testChunk)
+
+	cs.fail = func(n int) error {
+		if n == 2 {
+			return errors.New("stop")
+		}
+		return nil
+	}
+	rs := newTestResumable(t, cs, dir).(*resumableStorage)
+	rs.Upload(ctx, "obj", bytes.NewReader(data), int64(len(data)))
+
+	j := rs.load("obj")
+	if j == nil {
+		t.Fatal("Expected journal entry")
+	}
+	j.CreatedAt = time.Now().Add(-48 * time.Hour)
+	if err := rs.save(j); err != nil {
+		t.Fatal(err)
+	}
+
+	newTestResumable(t, cs, dir)
+	if cs.aborts != 1 || len(cs.sessions) != 0 {
+		t.Errorf("Expected expired session to be aborted (aborts=%d, sessions=%d)", cs.aborts, len(cs.sessions))
+	}
+	if journalFiles(t, dir) != 0 {
+		t.Error("Expected expired journal entry to be removed")
+	}
+}
+
+func TestResumable_ChunkSize(t *testing.T) {
+	s := &resumableStorage{r: Resumable{ChunkSize: 16 << 20}}
+	if got := s.chunkSize(1 << 30); got != 16<<20 {
+		t.Errorf("chunkSize(1GiB) = %d", got)
+	}
+	size := int64(500) << 30
+	got := s.chunkSize(size)
+	if got%(1<<20) != 0 || got*maxChunks < size {
+		t.Errorf("chunkSize(500GiB) = %d", got)
+	}
+}
@@ -0,0 +1,395 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"clearvault/pkg/gowebdav"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/minio/minio-go/v7"
+	"google.golang.org/api/googleapi"
+)
+
+// maxRetryAfter 服务端要求等待超过该时间时不再重试
+const maxRetryAfter = 5 * time.Minute
+
+// RetryPolicy 远端操作重试策略
+type RetryPolicy struct {
+	// MaxAttempts 单次操作的最大尝试次数（含首次），不大于 1 时不重试
+	MaxAttempts int
+	// BaseDelay 首次重试前的等待时间，之后每次翻倍
+	BaseDelay time.Duration
+	// MaxDelay 单次等待的上限（Retry-After 不受此限制）
+	MaxDelay time.Duration
+}
+
+// WithRetry 为远端存储添加临时错误重试（指数退避 + 抖动，遵循 Retry-After）
+// 下载中断后从已读取的位置续传；上传仅在数据可重放（io.Seeker 或尚未读取）时重试
+func WithRetry(rs RemoteStorage, p RetryPolicy) RemoteStorage {
+	if p.MaxAttempts <= 1 {
+		return rs
+	}
+	return newRetryStorage(rs, p)
+}
+
+// newRetryStorage 补全策略默认值
+func newRetryStorage(rs RemoteStorage, p RetryPolicy) *retryStorage {
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = 500 * time.Millisecond
+	}
+	if p.MaxDelay < p.BaseDelay {
+		p.MaxDelay = p.BaseDelay
+	}
+	return &retryStorage{RemoteStorage: rs, p: p}
+}
+
+type retryStorage struct {
+	RemoteStorage
+	p RetryPolicy
+}
+
+// IsRetryable 判断错误是否为临时错误（5xx、429、连接重置、超时等）
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, os.ErrPermission) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var se gowebdav.StatusError
+	if errors.As(err, &se) {
+		return retryableStatus(se.Status)
+	}
+	var me minio.ErrorResponse
+	if errors.As(err, &me) {
+		switch me.Code {
+		case "SlowDown", "RequestTimeout", "InternalError", "ServiceUnavailable":
+			return true
+		}
+		return retryableStatus(me.StatusCode)
+	}
+
+	var ae *azcore.ResponseError
+	if errors.As(err, &ae) {
+		return retryableStatus(ae.StatusCode)
+	}
+	var ge *googleapi.Error
+	if errors.As(err, &ge) {
+		return retryableStatus(ge.Code)
+	}
+
+	for _, errno := range []syscall.Errno{syscall.ECONNRESET, syscall.ECONNREFUSED, syscall.ECONNABORTED, syscall.EPIPE} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	var oe *net.OpError
+	return errors.As(err, &oe)
+}
+
+func retryableStatus(status int) bool {
+	switch status {
+	case 408, 425, 429, 500, 502, 503, 504:
+		return true
+	}
+	return false
+}
+
+// IsNotFound 判断错误是否表示远端对象不存在
+func IsNotFound(err error) bool {
+	if errors.Is(err, os.ErrNotExist) {
+		return true
+	}
+	var se gowebdav.StatusError
+	if errors.As(err, &se) {
+		return se.Status == 404
+	}
+	var me minio.ErrorResponse
+	if errors.As(err, &me) {
+		return me.Code == "NoSuchKey" || me.StatusCode == 404
+	}
+	return false
+}
+
+// retryAfter 返回服务端通过 Retry-After 要求的等待时间
+func retryAfter(err error) time.Duration {
+	var se gowebdav.StatusError
+	if errors.As(err, &se) {
+		return se.RetryAfter
+	}
+	return 0
+}
+
+// backoff 计算第 attempt 次失败后的等待时间，返回 false 表示不应重试
+func (s *retryStorage) backoff(ctx context.Context, attempt int, err error) (time.Duration, bool) {
+	if attempt >= s.p.MaxAttempts || ctx.Err() != nil || !IsRetryable(err) {
+		return 0, false
+	}
+	d := s.p.BaseDelay << (attempt - 1)
+	if d <= 0 || d > s.p.MaxDelay {
+		d = s.p.MaxDelay
+	}
+	// 等量抖动：[d/2, d]
+	d = d/2 + rand.N(d/2+1)
+	if ra := retryAfter(err); ra > d {
+		if ra > maxRetryAfter {
+			return 0, false
+		}
+		d = ra
+	}
+	return d, true
+}
+
+// wait 记录日志并等待，ctx 取消时提前返回
+func (s *retryStorage) wait(ctx context.Context, op, name string, attempt int, d time.Duration, err error) error {
+	log.Printf("Remote: %s '%s' failed (attempt %d/%d), retrying in %v: %v", op, name, attempt, s.p.MaxAttempts, d, err)
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// do 重试幂等操作
+func (s *retryStorage) do(ctx context.Context, op, name string, fn func(attempt int) error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn(attempt)
+		if err == nil {
+			return nil
+		}
+		d, ok := s.backoff(ctx, attempt, err)
+		if !ok {
+			return err
+		}
+		if s.wait(ctx, op, name, attempt, d, err) != nil {
+			return err
+		}
+	}
+}
+
+func (s *retryStorage) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	// 可 Seek 的数据源记录起点，重试前回退；否则只在数据尚未被读取时重试
+	seeker, _ := data.(io.Seeker)
+	var pos int64
+	if seeker != nil {
+		var err error
+		if pos, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			seeker = nil
+		}
+	}
+	var cr *countingReader
+	if seeker == nil {
+		cr = &countingReader{r: data}
+		data = cr
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := s.RemoteStorage.Upload(ctx, name, data, size)
+		if err == nil {
+			return nil
+		}
+		if cr != nil && cr.n > 0 {
+			return err
+		}
+		d, ok := s.backoff(ctx, attempt, err)
+		if !ok {
+			return err
+		}
+		if s.wait(ctx, "upload", name, attempt, d, err) != nil {
+			return err
+		}
+		if seeker != nil {
+			if _, serr := seeker.Seek(pos, io.SeekStart); serr != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *retryStorage) Download(ctx context.Context, name string) (io.ReadCloser, error) {
+	return s.open(ctx, name, 0, 0, func(ctx context.Context) (io.ReadCloser, error) {
+		return s.RemoteStorage.Download(ctx, name)
+	})
+}
+
+func (s *retryStorage) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	return s.open(ctx, name, start, length, func(ctx context.Context) (io.ReadCloser, error) {
+		return s.RemoteStorage.DownloadRange(ctx, name, start, length)
+	})
+}
+
+func (s *retryStorage) open(ctx context.Context, name string, start, length int64, fn func(context.Context) (io.ReadCloser, error)) (io.ReadCloser, error) {
+	var rc io.ReadCloser
+	err := s.do(ctx, "download", name, func(int) error {
+		var err error
+		rc, err = fn(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &retryReader{s: s, ctx: ctx, name: name, start: start, length: length, rc: rc}, nil
+}
+
+func (s *retryStorage) Delete(ctx context.Context, path string) error {
+	return s.do(ctx, "delete", path, func(attempt int) error {
+		err := s.RemoteStorage.Delete(ctx, path)
+		// 之前的尝试可能已在服务端生效，只是响应丢失
+		if attempt > 1 && IsNotFound(err) {
+			return nil
+		}
+		return err
+	})
+}
+
+func (s *retryStorage) Rename(ctx context.Context, oldPath, newPath string) error {
+	return s.do(ctx, "rename", oldPath, func(attempt int) error {
+		err := s.RemoteStorage.Rename(ctx, oldPath, newPath)
+		if attempt > 1 && IsNotFound(err) {
+			if _, serr := s.RemoteStorage.Stat(ctx, newPath); serr == nil {
+				return nil
+			}
+		}
+		return err
+	})
+}
+
+func (s *retryStorage) Stat(ctx context.Context, path string) (os.FileInfo, error) {
+	var info os.FileInfo
+	err := s.do(ctx, "stat", path, func(int) error {
+		var err error
+		info, err = s.RemoteStorage.Stat(ctx, path)
+		return err
+	})
+	return info, err
+}
+
+// List 只在尚未回调任何对象时重试，避免重复回调
+func (s *retryStorage) List(ctx context.Context, prefix string, fn func(name string, info os.FileInfo) error) error {
+	for attempt := 1; ; attempt++ {
+		called := false
+		err := List(ctx, s.RemoteStorage, prefix, func(name string, info os.FileInfo) error {
+			called = true
+			return fn(name, info)
+		})
+		if err == nil || called {
+			return err
+		}
+		d, ok := s.backoff(ctx, attempt, err)
+		if !ok {
+			return err
+		}
+		if s.wait(ctx, "list", prefix, attempt, d, err) != nil {
+			return err
+		}
+	}
+}
+
+func (s *retryStorage) Quota(ctx context.Context) (used, available int64, err error) {
+	err = s.do(ctx, "quota", "", func(int) error {
+		var err error
+		used, available, err = Quota(ctx, s.RemoteStorage)
+		return err
+	})
+	return used, available, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// retryReader 读取出错时从已读取的位置重新发起范围下载
+type retryReader struct {
+	s             *retryStorage
+	ctx           context.Context
+	name          string
+	start, length int64 // 原始请求范围，length 为 0 表示到文件末尾
+	off           int64 // 已返回给调用方的字节数
+	failures      int   // 自上次读到数据以来的连续失败次数
+	rc            io.ReadCloser
+	err           error
+}
+
+func (r *retryReader) Read(b []byte) (int, error) {
+	for {
+		if r.err != nil {
+			return 0, r.err
+		}
+		n, err := r.rc.Read(b)
+		r.off += int64(n)
+		if n > 0 {
+			r.failures = 0
+		}
+		if err == nil || err == io.EOF {
+			return n, err
+		}
+		if r.length > 0 && r.off >= r.length {
+			return n, io.EOF
+		}
+		r.rc.Close()
+		if r.err = r.reopen(err); r.err != nil {
+			r.rc = nil
+			return n, r.err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// reopen 按退避策略重新打开剩余范围
+func (r *retryReader) reopen(err error) error {
+	for {
+		r.failures++
+		d, ok := r.s.backoff(r.ctx, r.failures, err)
+		if !ok {
+			return err
+		}
+		if r.s.wait(r.ctx, "download", r.name, r.failures, d, err) != nil {
+			return err
+		}
+		var length int64
+		if r.length > 0 {
+			length = r.length - r.off
+		}
+		rc, oerr := r.s.RemoteStorage.DownloadRange(r.ctx, r.name, r.start+r.off, length)
+		if oerr == nil {
+			r.rc = rc
+			return nil
+		}
+		err = oerr
+	}
+}
+
+func (r *retryReader) Close() error {
+	if r.rc == nil {
+		return nil
+	}
+	return r.rc.Close()
+}
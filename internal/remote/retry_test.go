@@ -0,0 +1,240 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"clearvault/pkg/gowebdav"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"google.golang.org/api/googleapi"
+)
+
+var errUnavailable = gowebdav.NewPathError("PROPFIND", "obj", 503)
+
+// flakyStorage 前 failures 次调用返回 err，下载在 breakAt 字节处断开一次
+type flakyStorage struct {
+	nopStorage
+	data     []byte
+	failures int
+	err      error
+	breakAt  int64
+	calls    int
+	uploads  [][]byte
+	ranges   [][2]int64
+}
+
+func (f *flakyStorage) fail() error {
+	f.calls++
+	if f.calls <= f.failures {
+		return f.err
+	}
+	return nil
+}
+
+func (f *flakyStorage) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	b, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	if err := f.fail(); err != nil {
+		return err
+	}
+	f.uploads = append(f.uploads, b)
+	return nil
+}
+
+func (f *flakyStorage) Download(ctx context.Context, name string) (io.ReadCloser, error) {
+	return f.DownloadRange(ctx, name, 0, 0)
+}
+
+func (f *flakyStorage) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	if err := f.fail(); err != nil {
+		return nil, err
+	}
+	f.ranges = append(f.ranges, [2]int64{start, length})
+	end := int64(len(f.data))
+	if length > 0 && start+length < end {
+		end = start + length
+	}
+	var r io.Reader = bytes.NewReader(f.data[start:end])
+	if f.breakAt > start && f.breakAt < end {
+		r = io.MultiReader(bytes.NewReader(f.data[start:f.breakAt]), errReader{syscall.ECONNRESET})
+		f.breakAt = 0
+	}
+	return io.NopCloser(r), nil
+}
+
+func (f *flakyStorage) Stat(ctx context.Context, path string) (os.FileInfo, error) {
+	if err := f.fail(); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read(p []byte) (int, error) { return 0, r.err }
+
+var testPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errUnavailable, true},
+		{gowebdav.NewPathError("PUT", "obj", 429), true},
+		{gowebdav.NewPathError("GET", "obj", 404), false},
+		{gowebdav.NewPathError("GET", "obj", 401), false},
+		{&os.PathError{Op: "read", Path: "obj", Err: syscall.ECONNRESET}, true},
+		{&azcore.ResponseError{StatusCode: 503}, true},
+		{&azcore.ResponseError{StatusCode: 403}, false},
+		{fmt.Errorf("wrapped: %w", &googleapi.Error{Code: 429}), true},
+		{&googleapi.Error{Code: 412}, false},
+		{io.ErrUnexpectedEOF, true},
+		{os.ErrNotExist, false},
+		{context.Canceled, false},
+		{errors.New("bad request"), false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		var rs RemoteStorage = nopStorage{}
+		if WithRetry(rs, RetryPolicy{MaxAttempts: 1}) != rs {
+			t.Error("Expected storage to be returned unwrapped")
+		}
+	})
+
+	t.Run("transient errors", func(t *testing.T) {
+		f := &flakyStorage{failures: 2, err: errUnavailable}
+		if _, err := WithRetry(f, testPolicy).Stat(context.Background(), "obj"); err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if f.calls != 3 {
+			t.Errorf("Expected 3 calls, got %d", f.calls)
+		}
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		f := &flakyStorage{failures: 5, err: errUnavailable}
+		if _, err := WithRetry(f, testPolicy).Stat(context.Background(), "obj"); !errors.Is(err, errUnavailable) {
+			t.Errorf("Expected last error, got %v", err)
+		}
+		if f.calls != 3 {
+			t.Errorf("Expected 3 calls, got %d", f.calls)
+		}
+	})
+
+	t.Run("permanent error", func(t *testing.T) {
+		f := &flakyStorage{failures: 5, err: gowebdav.NewPathError("PROPFIND", "obj", 404)}
+		WithRetry(f, testPolicy).Stat(context.Background(), "obj")
+		if f.calls != 1 {
+			t.Errorf("Expected 1 call, got %d", f.calls)
+		}
+	})
+
+	t.Run("retry after", func(t *testing.T) {
+		f := &flakyStorage{failures: 1, err: &os.PathError{
+			Op: "PROPFIND", Path: "obj",
+			Err: gowebdav.StatusError{Status: 429, RetryAfter: 100 * time.Millisecond},
+		}}
+		start := time.Now()
+		if _, err := WithRetry(f, testPolicy).Stat(context.Background(), "obj"); err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if d := time.Since(start); d < 100*time.Millisecond {
+			t.Errorf("Retry-After not honored, retried after %v", d)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		f := &flakyStorage{failures: 5, err: errUnavailable}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		WithRetry(f, testPolicy).Stat(ctx, "obj")
+		if f.calls != 1 {
+			t.Errorf("Expected 1 call, got %d", f.calls)
+		}
+	})
+}
+
+func TestWithRetry_Download(t *testing.T) {
+	data := []byte(strings.Repeat("0123456789", 100))
+
+	t.Run("resume", func(t *testing.T) {
+		f := &flakyStorage{data: data, breakAt: 300}
+		rc, err := WithRetry(f, testPolicy).DownloadRange(context.Background(), "obj", 100, 500)
+		if err != nil {
+			t.Fatalf("DownloadRange failed: %v", err)
+		}
+		defer rc.Close()
+		got, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatalf("ReadAll failed: %v", err)
+		}
+		if !bytes.Equal(got, data[100:600]) {
+			t.Errorf("Data mismatch after resume: got %d bytes", len(got))
+		}
+		if len(f.ranges) != 2 || f.ranges[1] != [2]int64{300, 300} {
+			t.Errorf("Expected resume at [300, 300], got %v", f.ranges)
+		}
+	})
+
+	t.Run("resume to end", func(t *testing.T) {
+		f := &flakyStorage{data: data, breakAt: 700}
+		rc, err := WithRetry(f, testPolicy).Download(context.Background(), "obj")
+		if err != nil {
+			t.Fatalf("Download failed: %v", err)
+		}
+		defer rc.Close()
+		got, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatalf("ReadAll failed: %v", err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("Data mismatch after resume: got %d bytes", len(got))
+		}
+	})
+}
+
+func TestWithRetry_Upload(t *testing.T) {
+	t.Run("seekable", func(t *testing.T) {
+		f := &flakyStorage{failures: 1, err: errUnavailable}
+		if err := WithRetry(f, testPolicy).Upload(context.Background(), "obj", strings.NewReader("payload"), 7); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		if len(f.uploads) != 1 || string(f.uploads[0]) != "payload" {
+			t.Errorf("Expected replayed payload, got %q", f.uploads)
+		}
+	})
+
+	t.Run("not replayable", func(t *testing.T) {
+		f := &flakyStorage{failures: 1, err: errUnavailable}
+		pr, pw := io.Pipe()
+		go func() {
+			pw.Write([]byte("payload"))
+			pw.Close()
+		}()
+		if err := WithRetry(f, testPolicy).Upload(context.Background(), "obj", pr, -1); !errors.Is(err, errUnavailable) {
+			t.Errorf("Expected upload error, got %v", err)
+		}
+		if f.calls != 1 {
+			t.Errorf("Expected 1 call, got %d", f.calls)
+		}
+	})
+}
@@ -1,17 +1,24 @@
 package s3
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
 )
 
 // S3Config S3 客户端配置
@@ -22,12 +29,28 @@ type S3Config struct {
 	AccessKey string
 	SecretKey string
 	UseSSL    bool
+
+	Addressing     string // auto（默认）、path 或 virtual
+	CAFile         string // 自定义 CA 证书（PEM）
+	StorageClass   string // 默认存储类别，空表示使用存储桶默认值
+	SSE            string // 服务端加密：s3、kms 或 c，空表示不指定
+	SSEKMSKeyID    string // SSE-KMS 密钥 ID，空表示使用默认密钥
+	SSECKey        string // SSE-C 密钥（base64 编码的 32 字节）
+	LockMode       string // 对象锁定模式：GOVERNANCE 或 COMPLIANCE，空表示不锁定
+	LockDays       int    // 对象锁定天数
+	DeleteVersions bool   // 删除时移除对象的所有版本（版本化存储桶）
 }
 
 // S3Client S3 远端客户端实现
 type S3Client struct {
 	client *minio.Client
 	bucket string
+
+	defaultClass   string
+	sse            encrypt.ServerSide // 未启用服务端加密时为 nil
+	lockMode       minio.RetentionMode
+	lockDays       int
+	deleteVersions bool
 }
 
 // s3FileInfo 实现 os.FileInfo 接口
@@ -36,6 +59,7 @@ type s3FileInfo struct {
 	size    int64
 	modTime time.Time
 	isDir   bool
+	etag    string
 }
 
 func (fi *s3FileInfo) Name() string       { return fi.name }
@@ -45,14 +69,52 @@ func (fi *s3FileInfo) ModTime() time.Time { return fi.modTime }
 func (fi *s3FileInfo) IsDir() bool        { return fi.isDir }
 func (fi *s3FileInfo) Sys() interface{}   { return nil }
 
+// MD5 普通上传的 ETag 即内容 MD5；分片上传（带 -N 后缀）或服务端加密的 ETag 不是 MD5，返回空串
+func (fi *s3FileInfo) MD5() string {
+	etag := strings.ToLower(strings.Trim(fi.etag, `"`))
+	if len(etag) != md5.Size*2 {
+		return ""
+	}
+	if _, err := hex.DecodeString(etag); err != nil {
+		return ""
+	}
+	return etag
+}
+
 // NewClient 创建 S3 客户端
 func NewClient(cfg S3Config) (*S3Client, error) {
 	ctx := context.Background()
 
+	lookup, err := bucketLookup(cfg.Addressing)
+	if err != nil {
+		return nil, err
+	}
+	transport, err := newTransport(cfg.UseSSL, cfg.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	sse, err := newSSE(cfg.SSE, cfg.SSEKMSKeyID, cfg.SSECKey)
+	if err != nil {
+		return nil, err
+	}
+	lockMode, err := retentionMode(cfg.LockMode)
+	if err != nil {
+		return nil, err
+	}
+	if lockMode != "" && cfg.LockDays <= 0 {
+		return nil, fmt.Errorf("object lock requires days > 0")
+	}
+	if lockMode != "" && cfg.DeleteVersions {
+		// 锁定期内的版本无法删除
+		return nil, fmt.Errorf("object lock cannot be combined with delete_versions")
+	}
+
 	client, err := minio.New(cfg.Endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
-		Region: cfg.Region,
-		Secure: cfg.UseSSL,
+		Creds:        credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Region:       cfg.Region,
+		Secure:       cfg.UseSSL,
+		Transport:    transport,
+		BucketLookup: lookup,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create S3 client: %w", err)
@@ -68,8 +130,13 @@ func NewClient(cfg S3Config) (*S3Client, error) {
 	}
 
 	return &S3Client{
-		client: client,
-		bucket: cfg.Bucket,
+		client:         client,
+		bucket:         cfg.Bucket,
+		defaultClass:   cfg.StorageClass,
+		sse:            sse,
+		lockMode:       lockMode,
+		lockDays:       cfg.LockDays,
+		deleteVersions: cfg.DeleteVersions,
 	}, nil
 }
 
@@ -77,35 +144,84 @@ func NewClient(cfg S3Config) (*S3Client, error) {
 // minio-go 的 PutObject 会自动处理分片上传：
 // - 文件 < 16MB: 使用普通 PUT
 // - 文件 >= 16MB: 自动使用 Multipart Upload
-func (c *S3Client) Upload(name string, data io.Reader, size int64) error {
-	ctx := context.Background()
-
+func (c *S3Client) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
 	// 路径规范化：移除 leading slash
 	objectName := normalizePath(name)
 
 	log.Printf("S3: Uploading '%s' to bucket '%s' (size: %d)", objectName, c.bucket, size)
 
-	opts := minio.PutObjectOptions{}
-	if size > 0 {
-		opts.ContentType = "application/octet-stream"
+	_, err := c.client.PutObject(ctx, c.bucket, objectName, data, size, c.putOptions(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to upload object '%s': %w", objectName, err)
 	}
 
-	_, err := c.client.PutObject(ctx, c.bucket, objectName, data, size, opts)
+	return nil
+}
+
+// CreateUpload 开始 multipart upload 会话
+func (c *S3Client) CreateUpload(ctx context.Context, name string) (string, error) {
+	objectName := normalizePath(name)
+	core := minio.Core{Client: c.client}
+	id, err := core.NewMultipartUpload(ctx, c.bucket, objectName, c.putOptions(ctx))
 	if err != nil {
-		return fmt.Errorf("failed to upload object '%s': %w", objectName, err)
+		return "", fmt.Errorf("failed to create multipart upload for '%s': %w", objectName, err)
+	}
+	log.Printf("S3: Started multipart upload for '%s' (upload ID: %s)", objectName, id)
+	return id, nil
+}
+
+// UploadChunk 上传第 n 个分片，返回分片 ETag
+func (c *S3Client) UploadChunk(ctx context.Context, name, uploadID string, n int, data []byte) (string, error) {
+	objectName := normalizePath(name)
+	// 分片已在内存中，附带校验和由服务端验证内容
+	sha := sha256.Sum256(data)
+	md := md5.Sum(data)
+	core := minio.Core{Client: c.client}
+	part, err := core.PutObjectPart(ctx, c.bucket, objectName, uploadID, n, bytes.NewReader(data), int64(len(data)), minio.PutObjectPartOptions{
+		Sha256Hex: hex.EncodeToString(sha[:]),
+		Md5Base64: base64.StdEncoding.EncodeToString(md[:]),
+		SSE:       c.readSSE(),
+		// 已提供 SHA-256，按普通 V4 签名发送，不使用 aws-chunked 流式签名
+		DisableContentSha256: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload part %d of '%s': %w", n, objectName, err)
+	}
+	return part.ETag, nil
+}
+
+// CompleteUpload 合并已上传的分片
+func (c *S3Client) CompleteUpload(ctx context.Context, name, uploadID string, etags []string, size int64) error {
+	objectName := normalizePath(name)
+	parts := make([]minio.CompletePart, len(etags))
+	for i, etag := range etags {
+		parts[i] = minio.CompletePart{PartNumber: i + 1, ETag: etag}
+	}
+	core := minio.Core{Client: c.client}
+	if _, err := core.CompleteMultipartUpload(ctx, c.bucket, objectName, uploadID, parts, minio.PutObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to complete multipart upload for '%s': %w", objectName, err)
 	}
+	log.Printf("S3: Completed multipart upload for '%s' (%d parts, size: %d)", objectName, len(parts), size)
+	return nil
+}
 
+// AbortUpload 放弃 multipart upload 并释放已上传的分片
+func (c *S3Client) AbortUpload(ctx context.Context, name, uploadID string) error {
+	objectName := normalizePath(name)
+	core := minio.Core{Client: c.client}
+	if err := core.AbortMultipartUpload(ctx, c.bucket, objectName, uploadID); err != nil {
+		return fmt.Errorf("failed to abort multipart upload for '%s': %w", objectName, err)
+	}
 	return nil
 }
 
 // Download 从 S3 下载文件
-func (c *S3Client) Download(name string) (io.ReadCloser, error) {
-	ctx := context.Background()
+func (c *S3Client) Download(ctx context.Context, name string) (io.ReadCloser, error) {
 	objectName := normalizePath(name)
 
 	log.Printf("S3: Downloading '%s' from bucket '%s'", objectName, c.bucket)
 
-	obj, err := c.client.GetObject(ctx, c.bucket, objectName, minio.GetObjectOptions{})
+	obj, err := c.client.GetObject(ctx, c.bucket, objectName, minio.GetObjectOptions{ServerSideEncryption: c.readSSE()})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get object '%s': %w", objectName, err)
 	}
@@ -121,14 +237,13 @@ func (c *S3Client) Download(name string) (io.ReadCloser, error) {
 }
 
 // DownloadRange 下载文件的指定字节范围
-func (c *S3Client) DownloadRange(name string, start, length int64) (io.ReadCloser, error) {
-	ctx := context.Background()
+func (c *S3Client) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
 	objectName := normalizePath(name)
 
 	log.Printf("S3: Downloading range [%d:%d] for '%s' from bucket '%s'", start, length, objectName, c.bucket)
 
 	// 先获取文件大小，以确定实际的范围
-	objInfo, err := c.client.StatObject(ctx, c.bucket, objectName, minio.StatObjectOptions{})
+	objInfo, err := c.client.StatObject(ctx, c.bucket, objectName, minio.StatObjectOptions{ServerSideEncryption: c.readSSE()})
 	if err != nil {
 		return nil, fmt.Errorf("failed to stat object '%s': %w", objectName, err)
 	}
@@ -144,7 +259,7 @@ func (c *S3Client) DownloadRange(name string, start, length int64) (io.ReadClose
 		log.Printf("S3: Adjusting range from [%d:%d] to [%d:%d] (file size: %d)", start, length, start, actualLength, objInfo.Size)
 	}
 
-	opts := minio.GetObjectOptions{}
+	opts := minio.GetObjectOptions{ServerSideEncryption: c.readSSE()}
 	if actualLength > 0 {
 		// S3 使用 inclusive range (bytes=start-end)
 		end := start + actualLength - 1
@@ -165,12 +280,15 @@ func (c *S3Client) DownloadRange(name string, start, length int64) (io.ReadClose
 }
 
 // Delete 从 S3 删除文件
-func (c *S3Client) Delete(path string) error {
-	ctx := context.Background()
+func (c *S3Client) Delete(ctx context.Context, path string) error {
 	objectName := normalizePath(path)
 
 	log.Printf("S3: Deleting '%s' from bucket '%s'", objectName, c.bucket)
 
+	if c.deleteVersions {
+		return c.deleteAllVersions(ctx, objectName)
+	}
+
 	err := c.client.RemoveObject(ctx, c.bucket, objectName, minio.RemoveObjectOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to delete object '%s': %w", objectName, err)
@@ -179,10 +297,29 @@ func (c *S3Client) Delete(path string) error {
 	return nil
 }
 
-// Rename 重命名 S3 文件（使用 copy + delete）
-func (c *S3Client) Rename(oldPath, newPath string) error {
-	ctx := context.Background()
+// deleteAllVersions 删除对象的所有版本及删除标记，版本化存储桶中普通删除只会添加删除标记
+func (c *S3Client) deleteAllVersions(ctx context.Context, objectName string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	opts := minio.ListObjectsOptions{Prefix: objectName, WithVersions: true}
+	for obj := range c.client.ListObjects(ctx, c.bucket, opts) {
+		if obj.Err != nil {
+			return fmt.Errorf("failed to list versions of '%s': %w", objectName, obj.Err)
+		}
+		if obj.Key != objectName {
+			continue
+		}
+		err := c.client.RemoveObject(ctx, c.bucket, objectName, minio.RemoveObjectOptions{VersionID: obj.VersionID})
+		if err != nil {
+			return fmt.Errorf("failed to delete version '%s' of '%s': %w", obj.VersionID, objectName, err)
+		}
+	}
+	return nil
+}
 
+// Rename 重命名 S3 文件（使用 copy + delete）
+func (c *S3Client) Rename(ctx context.Context, oldPath, newPath string) error {
 	oldObjectName := normalizePath(oldPath)
 	newObjectName := normalizePath(newPath)
 
@@ -191,13 +328,19 @@ func (c *S3Client) Rename(oldPath, newPath string) error {
 	// S3 不支持原生重命名，使用 copy + delete
 	// 1. 复制对象
 	srcOpts := minio.CopySrcOptions{
-		Bucket: c.bucket,
-		Object: oldObjectName,
+		Bucket:     c.bucket,
+		Object:     oldObjectName,
+		Encryption: c.readSSE(),
 	}
 
 	destOpts := minio.CopyDestOptions{
-		Bucket: c.bucket,
-		Object: newObjectName,
+		Bucket:     c.bucket,
+		Object:     newObjectName,
+		Encryption: c.sse,
+	}
+	if c.lockMode != "" {
+		destOpts.Mode = c.lockMode
+		destOpts.RetainUntilDate = c.retainUntil()
 	}
 
 	_, err := c.client.CopyObject(ctx, destOpts, srcOpts)
@@ -206,7 +349,7 @@ func (c *S3Client) Rename(oldPath, newPath string) error {
 	}
 
 	// 2. 删除原对象
-	err = c.Delete(oldPath)
+	err = c.Delete(ctx, oldPath)
 	if err != nil {
 		// 如果删除失败，记录警告但不返回错误（新对象已存在）
 		log.Printf("S3: Warning: Failed to delete original object '%s' after rename: %v", oldObjectName, err)
@@ -216,11 +359,10 @@ func (c *S3Client) Rename(oldPath, newPath string) error {
 }
 
 // Stat 获取 S3 文件信息
-func (c *S3Client) Stat(path string) (os.FileInfo, error) {
-	ctx := context.Background()
+func (c *S3Client) Stat(ctx context.Context, path string) (os.FileInfo, error) {
 	objectName := normalizePath(path)
 
-	objInfo, err := c.client.StatObject(ctx, c.bucket, objectName, minio.StatObjectOptions{})
+	objInfo, err := c.client.StatObject(ctx, c.bucket, objectName, minio.StatObjectOptions{ServerSideEncryption: c.readSSE()})
 	if err != nil {
 		return nil, fmt.Errorf("failed to stat object '%s': %w", objectName, err)
 	}
@@ -230,9 +372,38 @@ func (c *S3Client) Stat(path string) (os.FileInfo, error) {
 		size:    objInfo.Size,
 		modTime: objInfo.LastModified,
 		isDir:   false, // S3 没有真正的目录
+		etag:    c.plainETag(objInfo.ETag),
 	}, nil
 }
 
+// List 枚举 bucket 中以 prefix 开头的对象（minio-go 自动处理 ListObjectsV2 分页）
+func (c *S3Client) List(ctx context.Context, prefix string, fn func(name string, info os.FileInfo) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	opts := minio.ListObjectsOptions{
+		Prefix:    normalizePath(prefix),
+		Recursive: true,
+	}
+	for obj := range c.client.ListObjects(ctx, c.bucket, opts) {
+		if obj.Err != nil {
+			return fmt.Errorf("failed to list objects with prefix '%s': %w", opts.Prefix, obj.Err)
+		}
+		if strings.HasSuffix(obj.Key, "/") {
+			continue
+		}
+		info := &s3FileInfo{
+			name:    path.Base(obj.Key),
+			size:    obj.Size,
+			modTime: obj.LastModified,
+		}
+		if err := fn(obj.Key, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Close 清理资源（S3 客户端不需要显式关闭）
 func (c *S3Client) Close() error {
 	return nil
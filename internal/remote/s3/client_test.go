@@ -181,13 +181,13 @@ func TestS3Client_Upload(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			data := bytes.NewReader([]byte(tt.content))
-			err := client.Upload(tt.filename, data, int64(len(tt.content)))
+			err := client.Upload(context.Background(), tt.filename, data, int64(len(tt.content)))
 			if err != nil {
 				t.Errorf("Upload() failed: %v", err)
 			}
 
 			// Cleanup
-			defer client.Delete(tt.filename)
+			defer client.Delete(context.Background(), tt.filename)
 		})
 	}
 }
@@ -210,14 +210,14 @@ func TestS3Client_Download(t *testing.T) {
 	content := "Test content for download"
 	filename := "test-download.txt"
 	data := bytes.NewReader([]byte(content))
-	err = client.Upload(filename, data, int64(len(content)))
+	err = client.Upload(context.Background(), filename, data, int64(len(content)))
 	if err != nil {
 		t.Fatalf("Upload failed: %v", err)
 	}
-	defer client.Delete(filename)
+	defer client.Delete(context.Background(), filename)
 
 	// Download and verify
-	reader, err := client.Download(filename)
+	reader, err := client.Download(context.Background(), filename)
 	if err != nil {
 		t.Fatalf("Download() failed: %v", err)
 	}
@@ -247,7 +247,7 @@ func TestS3Client_Download_NotFound(t *testing.T) {
 	}
 	defer client.Close()
 
-	_, err = client.Download("non-existent-file-12345.txt")
+	_, err = client.Download(context.Background(), "non-existent-file-12345.txt")
 	if err == nil {
 		t.Error("Download() of non-existent file should return error")
 	}
@@ -271,11 +271,11 @@ func TestS3Client_DownloadRange(t *testing.T) {
 	content := "0123456789ABCDEF"
 	filename := "test-range.txt"
 	data := bytes.NewReader([]byte(content))
-	err = client.Upload(filename, data, int64(len(content)))
+	err = client.Upload(context.Background(), filename, data, int64(len(content)))
 	if err != nil {
 		t.Fatalf("Upload failed: %v", err)
 	}
-	defer client.Delete(filename)
+	defer client.Delete(context.Background(), filename)
 
 	tests := []struct {
 		name         string
@@ -311,7 +311,7 @@ func TestS3Client_DownloadRange(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			reader, err := client.DownloadRange(filename, tt.start, tt.length)
+			reader, err := client.DownloadRange(context.Background(), filename, tt.start, tt.length)
 			if err != nil {
 				t.Fatalf("DownloadRange() failed: %v", err)
 			}
@@ -347,18 +347,18 @@ func TestS3Client_Delete(t *testing.T) {
 	content := "To be deleted"
 	filename := "test-delete.txt"
 	data := bytes.NewReader([]byte(content))
-	err = client.Upload(filename, data, int64(len(content)))
+	err = client.Upload(context.Background(), filename, data, int64(len(content)))
 	if err != nil {
 		t.Fatalf("Upload failed: %v", err)
 	}
 
-	err = client.Delete(filename)
+	err = client.Delete(context.Background(), filename)
 	if err != nil {
 		t.Errorf("Delete() failed: %v", err)
 	}
 
 	// Verify deletion
-	_, err = client.Download(filename)
+	_, err = client.Download(context.Background(), filename)
 	if err == nil {
 		t.Error("Download after delete should fail")
 	}
@@ -383,26 +383,26 @@ func TestS3Client_Rename(t *testing.T) {
 	oldName := "test-old-name.txt"
 	newName := "test-new-name.txt"
 	data := bytes.NewReader([]byte(content))
-	err = client.Upload(oldName, data, int64(len(content)))
+	err = client.Upload(context.Background(), oldName, data, int64(len(content)))
 	if err != nil {
 		t.Fatalf("Upload failed: %v", err)
 	}
-	defer client.Delete(newName) // Cleanup
+	defer client.Delete(context.Background(), newName) // Cleanup
 
 	// Rename
-	err = client.Rename(oldName, newName)
+	err = client.Rename(context.Background(), oldName, newName)
 	if err != nil {
 		t.Fatalf("Rename() failed: %v", err)
 	}
 
 	// Verify old name doesn't exist
-	_, err = client.Download(oldName)
+	_, err = client.Download(context.Background(), oldName)
 	if err == nil {
 		t.Error("Download with old name should fail after rename")
 	}
 
 	// Verify new name exists
-	reader, err := client.Download(newName)
+	reader, err := client.Download(context.Background(), newName)
 	if err != nil {
 		t.Fatalf("Download with new name failed: %v", err)
 	}
@@ -432,14 +432,14 @@ func TestS3Client_Stat(t *testing.T) {
 	content := "Test content for stat"
 	filename := "test-stat.txt"
 	data := bytes.NewReader([]byte(content))
-	err = client.Upload(filename, data, int64(len(content)))
+	err = client.Upload(context.Background(), filename, data, int64(len(content)))
 	if err != nil {
 		t.Fatalf("Upload failed: %v", err)
 	}
-	defer client.Delete(filename)
+	defer client.Delete(context.Background(), filename)
 
 	// Stat
-	info, err := client.Stat(filename)
+	info, err := client.Stat(context.Background(), filename)
 	if err != nil {
 		t.Fatalf("Stat() failed: %v", err)
 	}
@@ -469,7 +469,7 @@ func TestS3Client_Stat_NotFound(t *testing.T) {
 	}
 	defer client.Close()
 
-	_, err = client.Stat("non-existent-file-12345.txt")
+	_, err = client.Stat(context.Background(), "non-existent-file-12345.txt")
 	if err == nil {
 		t.Error("Stat() of non-existent file should return error")
 	}
@@ -543,9 +543,9 @@ func TestS3Client_ConcurrentOperations(t *testing.T) {
 			content := []byte("Concurrent content")
 			filename := "concurrent-" + string(rune('a'+id)) + ".txt"
 			data := bytes.NewReader(content)
-			err := client.Upload(filename, data, int64(len(content)))
+			err := client.Upload(context.Background(), filename, data, int64(len(content)))
 			if err == nil {
-				client.Delete(filename)
+				client.Delete(context.Background(), filename)
 			}
 			done <- err
 		}(i)
@@ -559,3 +559,104 @@ func TestS3Client_ConcurrentOperations(t *testing.T) {
 }
 
 
+
+// TestS3Client_List tests listing objects by prefix
+func TestS3Client_List(t *testing.T) {
+	if !checkS3Available(t) {
+		t.Skip("S3 server (zs3) not available")
+	}
+
+	ensureTestBucket(t)
+
+	cfg := getTestConfig()
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	defer client.Close()
+
+	files := []string{"list-test/aa11", "list-test/aa22", "list-test/sub/bb33"}
+	for _, name := range files {
+		if err := client.Upload(context.Background(), name, strings.NewReader("data"), 4); err != nil {
+			t.Fatalf("Upload(%s) failed: %v", name, err)
+		}
+		defer client.Delete(context.Background(), name)
+	}
+
+	got := map[string]int64{}
+	err = client.List(context.Background(), "list-test/", func(name string, info os.FileInfo) error {
+		got[name] = info.Size()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	for _, name := range files {
+		if got[name] != 4 {
+			t.Errorf("Expected %s in listing, got %v", name, got)
+		}
+	}
+
+	got = map[string]int64{}
+	client.List(context.Background(), "list-test/aa", func(name string, info os.FileInfo) error {
+		got[name] = info.Size()
+		return nil
+	})
+	if len(got) != 2 {
+		t.Errorf("Expected 2 objects with prefix, got %v", got)
+	}
+}
+
+// TestS3Client_ChunkedUpload tests multipart upload sessions used for resumable uploads
+func TestS3Client_ChunkedUpload(t *testing.T) {
+	if !checkS3Available(t) {
+		t.Skip("S3 server (zs3) not available")
+	}
+
+	ensureTestBucket(t)
+
+	cfg := getTestConfig()
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	name := "chunked-test/obj"
+	id, err := client.CreateUpload(ctx, name)
+	if err != nil {
+		t.Fatalf("CreateUpload() failed: %v", err)
+	}
+
+	part1 := bytes.Repeat([]byte("a"), 5<<20)
+	etag1, err := client.UploadChunk(ctx, name, id, 1, part1)
+	if err != nil {
+		t.Fatalf("UploadChunk(1) failed: %v", err)
+	}
+	etag2, err := client.UploadChunk(ctx, name, id, 2, []byte("tail"))
+	if err != nil {
+		t.Fatalf("UploadChunk(2) failed: %v", err)
+	}
+	if err := client.CompleteUpload(ctx, name, id, []string{etag1, etag2}, int64(len(part1)+4)); err != nil {
+		t.Fatalf("CompleteUpload() failed: %v", err)
+	}
+	defer client.Delete(ctx, name)
+
+	info, err := client.Stat(ctx, name)
+	if err != nil || info.Size() != int64(len(part1)+4) {
+		t.Errorf("Stat() = %v, %v", info, err)
+	}
+
+	// 放弃的会话不能再上传分片
+	id, err = client.CreateUpload(ctx, "chunked-test/aborted")
+	if err != nil {
+		t.Fatalf("CreateUpload() failed: %v", err)
+	}
+	if err := client.AbortUpload(ctx, "chunked-test/aborted", id); err != nil {
+		t.Fatalf("AbortUpload() failed: %v", err)
+	}
+	if _, err := client.UploadChunk(ctx, "chunked-test/aborted", id, 1, []byte("x")); err == nil {
+		t.Error("UploadChunk() after abort should return error")
+	}
+}
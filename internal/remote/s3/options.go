@@ -0,0 +1,149 @@
+package s3
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+)
+
+// storageClassKey 上下文中的存储类别
+type storageClassKey struct{}
+
+// WithStorageClass 为本次上传指定存储类别，覆盖客户端配置的默认值
+func WithStorageClass(ctx context.Context, class string) context.Context {
+	if class == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, storageClassKey{}, class)
+}
+
+// StorageClassFrom 返回 WithStorageClass 指定的存储类别
+func StorageClassFrom(ctx context.Context) (string, bool) {
+	class, ok := ctx.Value(storageClassKey{}).(string)
+	return class, ok
+}
+
+// storageClass 返回上传使用的存储类别
+func (c *S3Client) storageClass(ctx context.Context) string {
+	if class, ok := StorageClassFrom(ctx); ok {
+		return class
+	}
+	return c.defaultClass
+}
+
+// bucketLookup 解析寻址方式
+func bucketLookup(addressing string) (minio.BucketLookupType, error) {
+	switch strings.ToLower(addressing) {
+	case "", "auto":
+		return minio.BucketLookupAuto, nil
+	case "path":
+		return minio.BucketLookupPath, nil
+	case "virtual":
+		return minio.BucketLookupDNS, nil
+	}
+	return 0, fmt.Errorf("invalid addressing '%s', expected auto, path or virtual", addressing)
+}
+
+// newTransport 创建 HTTP 传输层，caFile 不为空时在系统证书之外信任其中的证书
+func newTransport(secure bool, caFile string) (http.RoundTripper, error) {
+	tr, err := minio.DefaultTransport(secure)
+	if err != nil {
+		return nil, err
+	}
+	if caFile == "" {
+		return tr, nil
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle '%s'", caFile)
+	}
+	if tr.TLSClientConfig == nil {
+		tr.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	tr.TLSClientConfig.RootCAs = pool
+	return tr, nil
+}
+
+// newSSE 解析服务端加密设置，未启用时返回 nil
+func newSSE(mode, kmsKeyID, cKey string) (encrypt.ServerSide, error) {
+	switch strings.ToLower(mode) {
+	case "":
+		return nil, nil
+	case "s3":
+		return encrypt.NewSSE(), nil
+	case "kms":
+		return encrypt.NewSSEKMS(kmsKeyID, nil)
+	case "c":
+		key, err := base64.StdEncoding.DecodeString(cKey)
+		if err != nil {
+			return nil, fmt.Errorf("sse_c_key must be base64 encoded: %w", err)
+		}
+		return encrypt.NewSSEC(key)
+	}
+	return nil, fmt.Errorf("invalid sse '%s', expected s3, kms or c", mode)
+}
+
+// retentionMode 解析对象锁定模式
+func retentionMode(mode string) (minio.RetentionMode, error) {
+	if mode == "" {
+		return "", nil
+	}
+	m := minio.RetentionMode(strings.ToUpper(mode))
+	if !m.IsValid() {
+		return "", fmt.Errorf("invalid object lock mode '%s', expected GOVERNANCE or COMPLIANCE", mode)
+	}
+	return m, nil
+}
+
+// readSSE 读取对象时需要附带的加密参数：只有 SSE-C 需要在每次请求中提供密钥
+func (c *S3Client) readSSE() encrypt.ServerSide {
+	if c.sse != nil && c.sse.Type() == encrypt.SSEC {
+		return c.sse
+	}
+	return nil
+}
+
+// putOptions 返回上传（含分片上传会话）使用的选项
+func (c *S3Client) putOptions(ctx context.Context) minio.PutObjectOptions {
+	opts := minio.PutObjectOptions{
+		ContentType:          "application/octet-stream",
+		StorageClass:         c.storageClass(ctx),
+		ServerSideEncryption: c.sse,
+	}
+	if c.lockMode != "" {
+		opts.Mode = c.lockMode
+		opts.RetainUntilDate = c.retainUntil()
+		// 对象锁定要求请求附带 Content-MD5
+		opts.SendContentMd5 = true
+	}
+	return opts
+}
+
+// retainUntil 返回新对象的锁定截止时间
+func (c *S3Client) retainUntil() time.Time {
+	return time.Now().Add(time.Duration(c.lockDays) * 24 * time.Hour).UTC()
+}
+
+// plainETag SSE-KMS 与 SSE-C 对象的 ETag 不是内容 MD5，不对外提供
+func (c *S3Client) plainETag(etag string) string {
+	if c.sse != nil && c.sse.Type() != encrypt.S3 {
+		return ""
+	}
+	return etag
+}
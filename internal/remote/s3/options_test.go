@@ -0,0 +1,96 @@
+package s3
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+)
+
+func TestBucketLookup(t *testing.T) {
+	tests := map[string]minio.BucketLookupType{
+		"":        minio.BucketLookupAuto,
+		"auto":    minio.BucketLookupAuto,
+		"path":    minio.BucketLookupPath,
+		"Virtual": minio.BucketLookupDNS,
+	}
+	for in, want := range tests {
+		if got, err := bucketLookup(in); err != nil || got != want {
+			t.Errorf("bucketLookup(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := bucketLookup("dns"); err == nil {
+		t.Error("Expected error for invalid addressing")
+	}
+}
+
+func TestNewSSE(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	tests := []struct {
+		mode, key string
+		want      encrypt.Type
+	}{
+		{"s3", "", encrypt.S3},
+		{"kms", "", encrypt.KMS},
+		{"c", key, encrypt.SSEC},
+	}
+	for _, tt := range tests {
+		sse, err := newSSE(tt.mode, "key-id", tt.key)
+		if err != nil || sse == nil || sse.Type() != tt.want {
+			t.Errorf("newSSE(%q) = %v, %v", tt.mode, sse, err)
+		}
+	}
+	if sse, err := newSSE("", "", ""); sse != nil || err != nil {
+		t.Errorf("Expected no encryption, got %v, %v", sse, err)
+	}
+	for _, mode := range []string{"aes", "c"} {
+		if _, err := newSSE(mode, "", "c2hvcnQ="); err == nil {
+			t.Errorf("Expected error for sse=%q with short key", mode)
+		}
+	}
+}
+
+func TestRetentionMode(t *testing.T) {
+	if m, err := retentionMode("governance"); err != nil || m != minio.Governance {
+		t.Errorf("Unexpected mode %v, %v", m, err)
+	}
+	if m, err := retentionMode(""); err != nil || m != "" {
+		t.Errorf("Expected no lock, got %v, %v", m, err)
+	}
+	if _, err := retentionMode("legal"); err == nil {
+		t.Error("Expected error for invalid mode")
+	}
+}
+
+func TestPutOptions(t *testing.T) {
+	sse, _ := newSSE("kms", "", "")
+	c := &S3Client{defaultClass: "STANDARD_IA", sse: sse, lockMode: minio.Compliance, lockDays: 30}
+
+	opts := c.putOptions(context.Background())
+	if opts.StorageClass != "STANDARD_IA" || opts.ServerSideEncryption == nil || opts.Mode != minio.Compliance || !opts.SendContentMd5 {
+		t.Errorf("Unexpected options %+v", opts)
+	}
+	if opts.RetainUntilDate.IsZero() {
+		t.Error("Expected retain-until date")
+	}
+	if opts := c.putOptions(WithStorageClass(context.Background(), "GLACIER_IR")); opts.StorageClass != "GLACIER_IR" {
+		t.Errorf("Expected storage class from context, got %q", opts.StorageClass)
+	}
+
+	// 只有 SSE-C 在读取时需要附带密钥；SSE-KMS 的 ETag 不是 MD5
+	if c.readSSE() != nil {
+		t.Error("Expected no read encryption for SSE-KMS")
+	}
+	if c.plainETag("5d41402abc4b2a76b9719d911017c592") != "" {
+		t.Error("Expected ETag to be hidden for SSE-KMS")
+	}
+	fi := &s3FileInfo{etag: `"5D41402ABC4B2A76B9719D911017C592"`}
+	if fi.MD5() != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("Unexpected MD5 %q", fi.MD5())
+	}
+	if fi := (&s3FileInfo{etag: "5d41402abc4b2a76b9719d911017c592-3"}); fi.MD5() != "" {
+		t.Errorf("Expected multipart ETag to be ignored, got %q", fi.MD5())
+	}
+}
@@ -0,0 +1,386 @@
+package sftp
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	gosftp "github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SFTPConfig SFTP 客户端配置
+type SFTPConfig struct {
+	Host          string // host 或 host:port，默认端口 22
+	User          string
+	Pass          string // 密码（也用于 keyboard-interactive）
+	KeyFile       string // 私钥文件路径
+	KeyPassphrase string // 私钥密码
+	KnownHosts    string // known_hosts 文件路径，默认 ~/.ssh/known_hosts
+	RootPath      string // 远端存放目录
+}
+
+// SFTPClient SFTP 远端客户端实现，连接断开后在下次操作时自动重连
+type SFTPClient struct {
+	addr string
+	root string
+	conf *ssh.ClientConfig
+
+	mu     sync.Mutex
+	conn   *ssh.Client
+	client *gosftp.Client
+}
+
+// NewClient 创建 SFTP 客户端并建立连接
+func NewClient(cfg SFTPConfig) (*SFTPClient, error) {
+	addr := cfg.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+
+	auth, err := authMethods(cfg)
+	if err != nil {
+		return nil, err
+	}
+	hostKey, algos, err := hostKeyCallback(cfg.KnownHosts, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &SFTPClient{
+		addr: addr,
+		root: path.Clean(filepath.ToSlash(cfg.RootPath)), // 相对路径以登录目录为基准
+		conf: &ssh.ClientConfig{
+			User:              cfg.User,
+			Auth:              auth,
+			HostKeyCallback:   hostKey,
+			HostKeyAlgorithms: algos,
+			Timeout:           30 * time.Second,
+		},
+	}
+	cl, err := c.sftp()
+	if err != nil {
+		return nil, err
+	}
+	if err := cl.MkdirAll(c.root); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("sftp: failed to create root path '%s': %w", c.root, err)
+	}
+	return c, nil
+}
+
+// authMethods 按配置生成认证方式：私钥优先，其次密码
+func authMethods(cfg SFTPConfig) ([]ssh.AuthMethod, error) {
+	var methods []ssh.AuthMethod
+	if cfg.KeyFile != "" {
+		pem, err := os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("sftp: failed to read key file: %w", err)
+		}
+		var signer ssh.Signer
+		if cfg.KeyPassphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(pem, []byte(cfg.KeyPassphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(pem)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("sftp: failed to parse key file: %w", err)
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+	if cfg.Pass != "" {
+		pass := cfg.Pass
+		methods = append(methods,
+			ssh.Password(pass),
+			ssh.KeyboardInteractive(func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+				answers := make([]string, len(questions))
+				for i := range answers {
+					answers[i] = pass
+				}
+				return answers, nil
+			}),
+		)
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("sftp: pass or key_file is required")
+	}
+	return methods, nil
+}
+
+// hostKeyCallback 使用 known_hosts 校验服务器公钥，并返回该主机已登记的公钥算法，
+// 避免服务器优先提供另一种未登记的公钥导致校验失败
+func hostKeyCallback(file, addr string) (ssh.HostKeyCallback, []string, error) {
+	if file == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil, fmt.Errorf("sftp: known_hosts is required: %w", err)
+		}
+		file = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	cb, err := knownhosts.New(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("sftp: failed to load known_hosts: %w", err)
+	}
+
+	_, probe, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	signer, err := ssh.NewSignerFromKey(probe)
+	if err != nil {
+		return nil, nil, err
+	}
+	var algos []string
+	var keyErr *knownhosts.KeyError
+	if err := cb(addr, &net.TCPAddr{}, signer.PublicKey()); errors.As(err, &keyErr) {
+		for _, k := range keyErr.Want {
+			switch t := k.Key.Type(); t {
+			case ssh.KeyAlgoRSA:
+				algos = append(algos, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA)
+			default:
+				algos = append(algos, t)
+			}
+		}
+	}
+	return cb, algos, nil
+}
+
+// sftp 返回当前连接，断开时重新连接
+func (c *SFTPClient) sftp() (*gosftp.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != nil {
+		return c.client, nil
+	}
+
+	conn, err := ssh.Dial("tcp", c.addr, c.conf)
+	if err != nil {
+		return nil, fmt.Errorf("sftp: failed to connect to %s: %w", c.addr, err)
+	}
+	client, err := gosftp.NewClient(conn, gosftp.UseConcurrentWrites(true))
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("sftp: failed to start sftp subsystem: %w", err)
+	}
+	c.conn, c.client = conn, client
+
+	go func() {
+		err := conn.Wait()
+		c.mu.Lock()
+		if c.conn == conn {
+			log.Printf("SFTP: Connection to %s closed: %v", c.addr, err)
+			c.conn, c.client = nil, nil
+		}
+		c.mu.Unlock()
+		client.Close()
+	}()
+	return client, nil
+}
+
+func (c *SFTPClient) getPath(name string) string {
+	return path.Join(c.root, filepath.ToSlash(name))
+}
+
+// relPath 将远端完整路径转换为对象名
+func (c *SFTPClient) relPath(p string) string {
+	if c.root == "." {
+		return p
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(p, c.root), "/")
+}
+
+// closeOnCancel ctx 取消时关闭文件以中止阻塞的读写
+func closeOnCancel(ctx context.Context, f *gosftp.File) func() bool {
+	return context.AfterFunc(ctx, func() { f.Close() })
+}
+
+// Upload 上传文件到 SFTP 服务器，失败时删除不完整的文件
+func (c *SFTPClient) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	cl, err := c.sftp()
+	if err != nil {
+		return err
+	}
+	p := c.getPath(name)
+	log.Printf("SFTP: Uploading '%s' (size: %d)", p, size)
+
+	if err := cl.MkdirAll(path.Dir(p)); err != nil {
+		return fmt.Errorf("failed to create directory for '%s': %w", p, err)
+	}
+	f, err := cl.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("failed to create '%s': %w", p, err)
+	}
+	stop := closeOnCancel(ctx, f)
+	_, err = f.ReadFrom(data)
+	stop()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		cl.Remove(p)
+		return fmt.Errorf("failed to upload '%s': %w", p, err)
+	}
+	return nil
+}
+
+// Download 从 SFTP 服务器下载文件
+func (c *SFTPClient) Download(ctx context.Context, name string) (io.ReadCloser, error) {
+	return c.DownloadRange(ctx, name, 0, 0)
+}
+
+// DownloadRange 打开文件并 Seek 到 start，length 为 0 时读到文件末尾
+func (c *SFTPClient) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cl, err := c.sftp()
+	if err != nil {
+		return nil, err
+	}
+	f, err := cl.Open(c.getPath(name))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	var r io.Reader = f
+	if length > 0 {
+		r = io.LimitReader(f, length)
+	}
+	return &fileReader{r: r, f: f, stop: closeOnCancel(ctx, f)}, nil
+}
+
+// Delete 删除 SFTP 服务器上的文件
+func (c *SFTPClient) Delete(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	cl, err := c.sftp()
+	if err != nil {
+		return err
+	}
+	return cl.Remove(c.getPath(name))
+}
+
+// Rename 重命名文件，目标已存在时覆盖（服务器不支持 posix-rename 扩展时先删除目标）
+func (c *SFTPClient) Rename(ctx context.Context, oldName, newName string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	cl, err := c.sftp()
+	if err != nil {
+		return err
+	}
+	oldPath, newPath := c.getPath(oldName), c.getPath(newName)
+	if err := cl.MkdirAll(path.Dir(newPath)); err != nil {
+		return err
+	}
+	if _, ok := cl.HasExtension("posix-rename@openssh.com"); ok {
+		return cl.PosixRename(oldPath, newPath)
+	}
+	if err := cl.Remove(newPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return cl.Rename(oldPath, newPath)
+}
+
+// Stat 获取 SFTP 文件信息
+func (c *SFTPClient) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cl, err := c.sftp()
+	if err != nil {
+		return nil, err
+	}
+	return cl.Stat(c.getPath(name))
+}
+
+// List 递归枚举名称以 prefix 开头的文件
+func (c *SFTPClient) List(ctx context.Context, prefix string, fn func(name string, info os.FileInfo) error) error {
+	cl, err := c.sftp()
+	if err != nil {
+		return err
+	}
+	prefix = strings.TrimPrefix(filepath.ToSlash(prefix), "/")
+	// 从 prefix 所在目录开始遍历，避免扫描整个根目录
+	start := c.root
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		start = c.getPath(prefix[:i])
+	}
+
+	walker := cl.Walk(start)
+	for walker.Step() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := walker.Err(); err != nil {
+			if errors.Is(err, fs.ErrNotExist) && walker.Path() == start {
+				return nil
+			}
+			return err
+		}
+		info := walker.Stat()
+		if info.IsDir() {
+			continue
+		}
+		name := c.relPath(walker.Path())
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if err := fn(name, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close 关闭 SFTP 连接
+func (c *SFTPClient) Close() error {
+	c.mu.Lock()
+	conn := c.conn
+	c.conn, c.client = nil, nil
+	c.mu.Unlock()
+	if conn == nil {
+		return nil
+	}
+	return conn.Close()
+}
+
+// fileReader 关闭时释放 ctx 监听
+type fileReader struct {
+	r    io.Reader
+	f    *gosftp.File
+	stop func() bool
+}
+
+func (r *fileReader) Read(p []byte) (int, error) {
+	return r.r.Read(p)
+}
+
+func (r *fileReader) Close() error {
+	r.stop()
+	return r.f.Close()
+}
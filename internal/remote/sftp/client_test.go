@@ -0,0 +1,430 @@
+package sftp
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	gosftp "github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	testUser = "vault"
+	testPass = "secret"
+)
+
+// testServer 进程内 SFTP 服务器，文件存放在 dir 下
+type testServer struct {
+	addr       string
+	dir        string
+	knownHosts string
+	keyFile    string
+}
+
+// newTestServer 启动接受密码或 clientKey 登录的 SFTP 服务器
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	tmp := t.TempDir()
+	s := &testServer{dir: filepath.Join(tmp, "data")}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	hostSigner := newSigner(t)
+	clientPub, clientKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(clientKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.keyFile = filepath.Join(tmp, "id_ed25519")
+	if err := os.WriteFile(s.keyFile, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	authorized, err := ssh.NewPublicKey(clientPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if c.User() == testUser && string(pass) == testPass {
+				return nil, nil
+			}
+			return nil, errors.New("invalid password")
+		},
+		PublicKeyCallback: func(c ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if c.User() == testUser && bytes.Equal(key.Marshal(), authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unknown key")
+		},
+	}
+	conf.AddHostKey(hostSigner)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s.addr = ln.Addr().String()
+
+	s.knownHosts = filepath.Join(tmp, "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(s.addr)}, hostSigner.PublicKey())
+	if err := os.WriteFile(s.knownHosts, []byte(line+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(nc, conf)
+		}
+	}()
+	return s
+}
+
+func newSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func (s *testServer) serve(nc net.Conn, conf *ssh.ServerConfig) {
+	conn, chans, reqs, err := ssh.NewServerConn(nc, conf)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	go ssh.DiscardRequests(reqs)
+
+	for nch := range chans {
+		if nch.ChannelType() != "session" {
+			nch.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+		ch, requests, err := nch.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range requests {
+				ok := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+			}
+		}()
+		go func() {
+			defer ch.Close()
+			server, err := gosftp.NewServer(ch, gosftp.WithServerWorkingDirectory(s.dir))
+			if err != nil {
+				return
+			}
+			server.Serve()
+		}()
+	}
+}
+
+func (s *testServer) config() SFTPConfig {
+	return SFTPConfig{
+		Host:       s.addr,
+		User:       testUser,
+		Pass:       testPass,
+		KnownHosts: s.knownHosts,
+		RootPath:   "vault",
+	}
+}
+
+func newTestClient(t *testing.T, s *testServer) *SFTPClient {
+	t.Helper()
+	c, err := NewClient(s.config())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func readAll(t *testing.T, rc io.ReadCloser, err error) string {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	return string(b)
+}
+
+func TestNewClient(t *testing.T) {
+	s := newTestServer(t)
+
+	t.Run("password", func(t *testing.T) {
+		newTestClient(t, s)
+		if fi, err := os.Stat(filepath.Join(s.dir, "vault")); err != nil || !fi.IsDir() {
+			t.Errorf("Root path should be created, got %v", err)
+		}
+	})
+
+	t.Run("private key", func(t *testing.T) {
+		cfg := s.config()
+		cfg.Pass = ""
+		cfg.KeyFile = s.keyFile
+		c, err := NewClient(cfg)
+		if err != nil {
+			t.Fatalf("NewClient with key failed: %v", err)
+		}
+		c.Close()
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		cfg := s.config()
+		cfg.Pass = "wrong"
+		if _, err := NewClient(cfg); err == nil {
+			t.Error("Expected authentication failure")
+		}
+	})
+
+	t.Run("no credentials", func(t *testing.T) {
+		cfg := s.config()
+		cfg.Pass = ""
+		if _, err := NewClient(cfg); err == nil {
+			t.Error("Expected error without pass or key_file")
+		}
+	})
+
+	t.Run("host key mismatch", func(t *testing.T) {
+		cfg := s.config()
+		cfg.KnownHosts = filepath.Join(t.TempDir(), "known_hosts")
+		line := knownhosts.Line([]string{knownhosts.Normalize(s.addr)}, newSigner(t).PublicKey())
+		if err := os.WriteFile(cfg.KnownHosts, []byte(line+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := NewClient(cfg)
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			t.Errorf("Expected host key error, got %v", err)
+		}
+	})
+
+	t.Run("unknown host", func(t *testing.T) {
+		cfg := s.config()
+		cfg.KnownHosts = filepath.Join(t.TempDir(), "known_hosts")
+		if err := os.WriteFile(cfg.KnownHosts, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewClient(cfg); err == nil {
+			t.Error("Expected unknown host to be rejected")
+		}
+	})
+}
+
+func TestSFTPClient_UploadDownload(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := newTestClient(t, s)
+
+	content := "0123456789abcdef"
+	if err := c.Upload(ctx, "a/b/obj", strings.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if b, err := os.ReadFile(filepath.Join(s.dir, "vault/a/b/obj")); err != nil || string(b) != content {
+		t.Errorf("Server file = %q, %v", b, err)
+	}
+
+	rc, err := c.Download(ctx, "a/b/obj")
+	if got := readAll(t, rc, err); got != content {
+		t.Errorf("Download = %q, want %q", got, content)
+	}
+
+	tests := []struct {
+		start, length int64
+		want          string
+	}{
+		{0, 4, "0123"},
+		{10, 3, "abc"},
+		{12, 0, "cdef"},
+		{14, 100, "ef"},
+		{20, 5, ""},
+	}
+	for _, tt := range tests {
+		rc, err := c.DownloadRange(ctx, "a/b/obj", tt.start, tt.length)
+		if got := readAll(t, rc, err); got != tt.want {
+			t.Errorf("DownloadRange(%d, %d) = %q, want %q", tt.start, tt.length, got, tt.want)
+		}
+	}
+
+	// 覆盖写入时截断旧内容
+	if err := c.Upload(ctx, "a/b/obj", strings.NewReader("xy"), 2); err != nil {
+		t.Fatalf("Upload overwrite failed: %v", err)
+	}
+	rc, err = c.Download(ctx, "a/b/obj")
+	if got := readAll(t, rc, err); got != "xy" {
+		t.Errorf("Download after overwrite = %q", got)
+	}
+
+	if _, err := c.Download(ctx, "missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist, got %v", err)
+	}
+}
+
+func TestSFTPClient_DeleteRenameStat(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := newTestClient(t, s)
+
+	for _, name := range []string{"old", "target"} {
+		if err := c.Upload(ctx, name, strings.NewReader(name), int64(len(name))); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+	}
+
+	info, err := c.Stat(ctx, "old")
+	if err != nil || info.Size() != 3 {
+		t.Errorf("Stat = %v, %v", info, err)
+	}
+
+	// 目标已存在时覆盖
+	if err := c.Rename(ctx, "old", "target"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	rc, err := c.Download(ctx, "target")
+	if got := readAll(t, rc, err); got != "old" {
+		t.Errorf("Renamed content = %q", got)
+	}
+	if err := c.Rename(ctx, "target", "x/y/moved"); err != nil {
+		t.Fatalf("Rename into new directory failed: %v", err)
+	}
+	if _, err := c.Stat(ctx, "target"); !os.IsNotExist(err) {
+		t.Errorf("Expected old name gone, got %v", err)
+	}
+
+	if err := c.Delete(ctx, "x/y/moved"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := c.Stat(ctx, "x/y/moved"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist after delete, got %v", err)
+	}
+	if err := c.Delete(ctx, "x/y/moved"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist deleting missing file, got %v", err)
+	}
+}
+
+func TestSFTPClient_List(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := newTestClient(t, s)
+
+	for _, name := range []string{"a1", "a2", "dir/a3", "dir/b", "b"} {
+		if err := c.Upload(ctx, name, strings.NewReader("x"), 1); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+	}
+
+	list := func(prefix string) []string {
+		var names []string
+		err := c.List(ctx, prefix, func(name string, info os.FileInfo) error {
+			names = append(names, name)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("List(%q) failed: %v", prefix, err)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	if got := strings.Join(list(""), ","); got != "a1,a2,b,dir/a3,dir/b" {
+		t.Errorf("List all = %s", got)
+	}
+	if got := strings.Join(list("a"), ","); got != "a1,a2" {
+		t.Errorf("List a = %s", got)
+	}
+	if got := strings.Join(list("dir/a"), ","); got != "dir/a3" {
+		t.Errorf("List dir/a = %s", got)
+	}
+	if got := list("nothing/here"); len(got) != 0 {
+		t.Errorf("List missing dir = %v", got)
+	}
+}
+
+func TestSFTPClient_Reconnect(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	c := newTestClient(t, s)
+
+	if err := c.Upload(ctx, "obj", strings.NewReader("hello"), 5); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	// 模拟连接断开
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	conn.Close()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		c.mu.Lock()
+		reset := c.conn == nil
+		c.mu.Unlock()
+		if reset {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Connection loss not detected")
+		}
+	}
+
+	rc, err := c.Download(ctx, "obj")
+	if got := readAll(t, rc, err); got != "hello" {
+		t.Errorf("Download after reconnect = %q", got)
+	}
+}
+
+func TestSFTPClient_Canceled(t *testing.T) {
+	s := newTestServer(t)
+	c := newTestClient(t, s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write([]byte("partial"))
+		cancel()
+		pw.Close()
+	}()
+	if err := c.Upload(ctx, "obj", pr, -1); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(s.dir, "vault/obj")); !os.IsNotExist(err) {
+		t.Errorf("Partial upload should be removed, got %v", err)
+	}
+
+	if _, err := c.Stat(ctx, "obj"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -1,46 +1,108 @@
 package remote
 
 import (
+	"context"
+	"errors"
 	"io"
 	"os"
 )
 
+// ErrNotSupported 后端不支持某项可选能力
+var ErrNotSupported = errors.New("operation not supported by remote storage")
+
 // RemoteStorage 远端存储抽象接口
 // 支持多种存储后端：WebDAV、S3、MinIO、Cloudflare R2 等
+// 所有操作都接收 ctx：取消 ctx 会中止进行中的请求，以及 Download/DownloadRange 返回的数据流
 type RemoteStorage interface {
 	// Upload 上传文件到远端存储
 	// name: 远端文件名（路径）
 	// data: 文件数据流
 	// size: 文件大小（-1 表示未知大小）
-	Upload(name string, data io.Reader, size int64) error
+	Upload(ctx context.Context, name string, data io.Reader, size int64) error
 
 	// Download 从远端存储下载文件
 	// name: 远端文件名（路径）
 	// 返回: 文件数据流（需要调用者关闭）
-	Download(name string) (io.ReadCloser, error)
+	Download(ctx context.Context, name string) (io.ReadCloser, error)
 
 	// DownloadRange 从远端存储下载文件的指定范围
 	// name: 远端文件名（路径）
 	// start: 起始字节偏移
 	// length: 要读取的字节数（0 表示到文件末尾）
-	DownloadRange(name string, start, length int64) (io.ReadCloser, error)
+	DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error)
 
 	// Delete 从远端存储删除文件
 	// path: 远端文件路径
-	Delete(path string) error
+	Delete(ctx context.Context, path string) error
 
 	// Rename 重命名/移动远端文件
 	// 注意：S3 不支持原生重命名，需要使用 copy+delete 实现
 	// oldPath: 旧文件路径
 	// newPath: 新文件路径
-	Rename(oldPath, newPath string) error
+	Rename(ctx context.Context, oldPath, newPath string) error
 
 	// Stat 获取远端文件信息
 	// path: 远端文件路径
 	// 返回: 文件信息（实现了 os.FileInfo 接口）
-	Stat(path string) (os.FileInfo, error)
+	Stat(ctx context.Context, path string) (os.FileInfo, error)
 
 	// Close 清理资源（如连接池等）
 	// 注意：某些实现可能不需要显式关闭
 	Close() error
 }
+
+// Lister 可选接口：支持枚举远端对象的存储后端
+type Lister interface {
+	// List 递归枚举名称以 prefix 开头的对象（不含目录），对每个对象调用 fn
+	// name: 与 Upload 时一致的对象名（不含前导斜杠，使用 / 分隔）
+	// info: 对象信息（大小、修改时间）
+	// fn 返回错误时停止枚举并原样返回该错误
+	List(ctx context.Context, prefix string, fn func(name string, info os.FileInfo) error) error
+}
+
+// List 枚举远端对象，后端未实现 Lister 时返回 ErrNotSupported
+func List(ctx context.Context, rs RemoteStorage, prefix string, fn func(name string, info os.FileInfo) error) error {
+	l, ok := rs.(Lister)
+	if !ok {
+		return ErrNotSupported
+	}
+	return l.List(ctx, prefix, fn)
+}
+
+// Checksummer 可选接口：Stat 返回的文件信息可提供服务端记录的内容摘要（S3 ETag、Nextcloud oc:checksums）
+type Checksummer interface {
+	// MD5 返回十六进制 MD5，服务端未记录（如分片上传的对象）时返回空串
+	MD5() string
+}
+
+// ChunkUploader 可选接口：支持分块上传会话的后端（S3 multipart、Nextcloud chunking v2）
+// 会话完成前对象不可见；已上传的分块保留在服务端，进程重启后可继续使用
+type ChunkUploader interface {
+	// CreateUpload 开始上传会话，返回会话 ID
+	CreateUpload(ctx context.Context, name string) (string, error)
+
+	// UploadChunk 上传第 n 个分块（从 1 开始），重复上传同一编号会覆盖之前的分块
+	// 返回服务端对该分块的标识（如 ETag），完成会话时原样传回
+	UploadChunk(ctx context.Context, name, uploadID string, n int, data []byte) (string, error)
+
+	// CompleteUpload 按编号顺序合并分块 1..len(etags) 为最终对象，size 为对象总大小
+	CompleteUpload(ctx context.Context, name, uploadID string, etags []string, size int64) error
+
+	// AbortUpload 放弃会话并清理已上传的分块
+	AbortUpload(ctx context.Context, name, uploadID string) error
+}
+
+// Quoter 可选接口：可报告空间使用情况的存储后端（WebDAV RFC 4331、本地文件系统、配置的容量上限）
+type Quoter interface {
+	// Quota 返回已用和剩余空间（字节），未知的值为 -1
+	Quota(ctx context.Context) (used, available int64, err error)
+}
+
+// Quota 查询远端空间，后端未实现 Quoter 时返回 ErrNotSupported
+func Quota(ctx context.Context, rs RemoteStorage) (used, available int64, err error) {
+	q, ok := rs.(Quoter)
+	if !ok {
+		return -1, -1, ErrNotSupported
+	}
+	return q.Quota(ctx)
+}
@@ -0,0 +1,744 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	pathpkg "path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/klauspost/reedsolomon"
+)
+
+const (
+	// stripeBlock 每个分片在一个条带中的块大小
+	stripeBlock = 64 * 1024
+	// shardTrailerLen 分片末尾的描述信息长度
+	shardTrailerLen = 24
+	shardVersion    = 1
+	// stripeMetaCacheSize 缓存的对象描述信息条数上限
+	stripeMetaCacheSize = 4096
+)
+
+var shardMagic = [4]byte{'C', 'V', 'R', 'S'}
+
+// ErrInvalidShard 分片末尾描述信息无效或与配置不符
+var ErrInvalidShard = errors.New("invalid shard trailer")
+
+// StripedOptions 条带存储设置
+type StripedOptions struct {
+	// DataShards 数据分片数 k，其余成员存放校验分片
+	DataShards int
+	// WriteQuorum 写入/删除成功所需的分片数，0 表示全部，不能小于 DataShards
+	WriteQuorum int
+}
+
+// StripedStorage 将对象 Reed-Solomon 编码为 k 个数据分片和 m 个校验分片，
+// 第 i 个分片以相同名称存放在第 i 个成员上，任意 k 个分片即可恢复对象
+//
+// 对象按条带切分：每个条带包含 k 个 64KiB 数据块，第 j 块写入分片 j，
+// 因此对象中任意范围都能映射到各分片上的连续范围，范围读取无需下载整个对象
+type StripedStorage struct {
+	members []RemoteStorage
+	names   []string
+	k, m    int
+	quorum  int
+	enc     reedsolomon.Encoder
+
+	mu   sync.Mutex
+	meta map[string]stripeMeta
+}
+
+// stripeMeta 分片末尾记录的对象信息
+type stripeMeta struct {
+	k, m    int
+	index   int
+	block   int
+	size    int64 // 原始对象大小
+	modTime time.Time
+}
+
+// stripes 返回对象占用的条带数
+func (sm stripeMeta) stripes() int64 {
+	width := int64(sm.k) * int64(sm.block)
+	return (sm.size + width - 1) / width
+}
+
+// shardSize 返回每个分片的大小（含末尾描述信息）
+func shardSize(size int64, k int) int64 {
+	width := int64(k) * stripeBlock
+	return (size+width-1)/width*stripeBlock + shardTrailerLen
+}
+
+func encodeShardTrailer(k, m, index int, size int64) []byte {
+	b := make([]byte, shardTrailerLen)
+	copy(b, shardMagic[:])
+	b[4] = shardVersion
+	b[5] = byte(k)
+	b[6] = byte(m)
+	b[7] = byte(index)
+	binary.BigEndian.PutUint32(b[8:], stripeBlock)
+	binary.BigEndian.PutUint64(b[12:], uint64(size))
+	binary.BigEndian.PutUint32(b[20:], crc32.ChecksumIEEE(b[:20]))
+	return b
+}
+
+func decodeShardTrailer(b []byte) (stripeMeta, error) {
+	if len(b) != shardTrailerLen || !bytes.Equal(b[:4], shardMagic[:]) || b[4] != shardVersion {
+		return stripeMeta{}, ErrInvalidShard
+	}
+	if crc32.ChecksumIEEE(b[:20]) != binary.BigEndian.Uint32(b[20:]) {
+		return stripeMeta{}, ErrInvalidShard
+	}
+	return stripeMeta{
+		k:     int(b[5]),
+		m:     int(b[6]),
+		index: int(b[7]),
+		block: int(binary.BigEndian.Uint32(b[8:])),
+		size:  int64(binary.BigEndian.Uint64(b[12:])),
+	}, nil
+}
+
+// NewStripedStorage 创建条带存储，members 的顺序即分片编号，不能调整，names 用于日志（可为 nil）
+func NewStripedStorage(members []RemoteStorage, names []string, opts StripedOptions) (*StripedStorage, error) {
+	k := opts.DataShards
+	m := len(members) - k
+	if k < 1 || m < 1 || len(members) > 255 {
+		return nil, fmt.Errorf("striped: need at least one data and one parity shard (data_shards=%d, members=%d)", k, len(members))
+	}
+	quorum := opts.WriteQuorum
+	if quorum == 0 {
+		quorum = len(members)
+	}
+	if quorum < k || quorum > len(members) {
+		return nil, fmt.Errorf("striped: write_quorum %d out of range [%d, %d]", quorum, k, len(members))
+	}
+	enc, err := reedsolomon.New(k, m)
+	if err != nil {
+		return nil, fmt.Errorf("striped: %w", err)
+	}
+	if len(names) != len(members) {
+		names = make([]string, len(members))
+		for i := range names {
+			names[i] = fmt.Sprintf("shard %d", i)
+		}
+	}
+	return &StripedStorage{
+		members: members,
+		names:   names,
+		k:       k,
+		m:       m,
+		quorum:  quorum,
+		enc:     enc,
+		meta:    make(map[string]stripeMeta),
+	}, nil
+}
+
+func (s *StripedStorage) forget(name string) {
+	s.mu.Lock()
+	delete(s.meta, name)
+	s.mu.Unlock()
+}
+
+// readMeta 读取对象信息：Stat 分片得到大小，再读取分片末尾的描述信息
+func (s *StripedStorage) readMeta(ctx context.Context, name string) (stripeMeta, error) {
+	s.mu.Lock()
+	sm, ok := s.meta[name]
+	s.mu.Unlock()
+	if ok {
+		return sm, nil
+	}
+
+	var lastErr error
+	for i, rs := range s.members {
+		sm, err := s.readTrailer(ctx, i, rs, name)
+		if err != nil {
+			if ctx.Err() != nil {
+				return stripeMeta{}, ctx.Err()
+			}
+			lastErr = err
+			continue
+		}
+		s.mu.Lock()
+		if len(s.meta) >= stripeMetaCacheSize {
+			s.meta = make(map[string]stripeMeta)
+		}
+		s.meta[name] = sm
+		s.mu.Unlock()
+		return sm, nil
+	}
+	return stripeMeta{}, lastErr
+}
+
+func (s *StripedStorage) readTrailer(ctx context.Context, i int, rs RemoteStorage, name string) (stripeMeta, error) {
+	info, err := rs.Stat(ctx, name)
+	if err != nil {
+		return stripeMeta{}, err
+	}
+	if info.Size() < shardTrailerLen {
+		return stripeMeta{}, fmt.Errorf("shard '%s' on %s: %w", name, s.names[i], ErrInvalidShard)
+	}
+	rc, err := rs.DownloadRange(ctx, name, info.Size()-shardTrailerLen, shardTrailerLen)
+	if err != nil {
+		return stripeMeta{}, err
+	}
+	defer rc.Close()
+	b := make([]byte, shardTrailerLen)
+	if _, err := io.ReadFull(rc, b); err != nil {
+		return stripeMeta{}, err
+	}
+	sm, err := decodeShardTrailer(b)
+	if err == nil && (sm.k != s.k || sm.m != s.m || sm.index != i || sm.block != stripeBlock ||
+		shardSize(sm.size, sm.k) != info.Size()) {
+		err = ErrInvalidShard
+	}
+	if err != nil {
+		return stripeMeta{}, fmt.Errorf("shard '%s' on %s: %w", name, s.names[i], err)
+	}
+	sm.modTime = info.ModTime()
+	return sm, nil
+}
+
+// shardWriters 向各分片的上传流写入数据，出错的分片被丢弃
+type shardWriters struct {
+	pws  []*io.PipeWriter
+	live []bool
+}
+
+func (w *shardWriters) write(i int, b []byte) {
+	if w.pws[i] == nil || !w.live[i] {
+		return
+	}
+	if _, err := w.pws[i].Write(b); err != nil {
+		w.live[i] = false
+	}
+}
+
+func (w *shardWriters) closeWithError(err error) {
+	for _, pw := range w.pws {
+		if pw != nil {
+			pw.CloseWithError(err)
+		}
+	}
+}
+
+// upload 为 targets 中的分片启动上传，返回写入端和等待函数
+func (s *StripedStorage) upload(ctx context.Context, name string, targets []bool, size int64) (*shardWriters, func() []error) {
+	n := len(s.members)
+	w := &shardWriters{pws: make([]*io.PipeWriter, n), live: make([]bool, n)}
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i, rs := range s.members {
+		if !targets[i] {
+			continue
+		}
+		pr, pw := io.Pipe()
+		w.pws[i], w.live[i] = pw, true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = rs.Upload(ctx, name, pr, size)
+			if errs[i] != nil {
+				pr.CloseWithError(errs[i])
+			} else {
+				pr.Close()
+			}
+		}()
+	}
+	return w, func() []error {
+		wg.Wait()
+		return errs
+	}
+}
+
+func (s *StripedStorage) newShards() [][]byte {
+	shards := make([][]byte, len(s.members))
+	for i := range shards {
+		shards[i] = make([]byte, stripeBlock)
+	}
+	return shards
+}
+
+// Upload 按条带编码后并发写入所有成员，最后一个条带补零
+func (s *StripedStorage) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	s.forget(name)
+	n := len(s.members)
+	targets := make([]bool, n)
+	for i := range targets {
+		targets[i] = true
+	}
+	ssize := int64(-1)
+	if size >= 0 {
+		ssize = shardSize(size, s.k)
+	}
+	w, wait := s.upload(ctx, name, targets, ssize)
+
+	err := func() error {
+		shards := s.newShards()
+		stripe := make([]byte, s.k*stripeBlock)
+		var total int64
+		for {
+			nr, err := io.ReadFull(data, stripe)
+			if nr == 0 && err == io.EOF {
+				break
+			}
+			if err != nil && err != io.ErrUnexpectedEOF {
+				return err
+			}
+			total += int64(nr)
+			clear(stripe[nr:])
+			for j := 0; j < s.k; j++ {
+				copy(shards[j], stripe[j*stripeBlock:])
+			}
+			if err := s.enc.Encode(shards); err != nil {
+				return err
+			}
+			for i := range shards {
+				w.write(i, shards[i])
+			}
+			if err == io.ErrUnexpectedEOF {
+				break
+			}
+		}
+		if size >= 0 && total != size {
+			return fmt.Errorf("striped: upload '%s': read %d bytes, expected %d", name, total, size)
+		}
+		for i := range s.members {
+			w.write(i, encodeShardTrailer(s.k, s.m, i, total))
+		}
+		return nil
+	}()
+	w.closeWithError(err)
+	errs := wait()
+	if err != nil {
+		return err
+	}
+	return quorumError("Striped", "upload", name, s.names, s.quorum, errs)
+}
+
+func (s *StripedStorage) Download(ctx context.Context, name string) (io.ReadCloser, error) {
+	return s.DownloadRange(ctx, name, 0, 0)
+}
+
+// DownloadRange 只读取范围覆盖的条带；范围在单个条带内时只读取涉及的数据分片，
+// 分片不可用时改为读取其他分片并重建
+func (s *StripedStorage) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	sm, err := s.readMeta(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	end := sm.size
+	if length > 0 && start+length < end {
+		end = start + length
+	}
+	if start >= end {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	n := len(s.members)
+	return &stripeReader{
+		s:          s,
+		ctx:        ctx,
+		name:       name,
+		pos:        start,
+		end:        end,
+		lastStripe: (end - 1) / int64(s.k*stripeBlock),
+		streams:    make([]io.ReadCloser, n),
+		at:         make([]int64, n),
+		bad:        make([]bool, n),
+		got:        make([]bool, n),
+		bufs:       s.newShards(),
+	}, nil
+}
+
+type stripeReader struct {
+	s          *StripedStorage
+	ctx        context.Context
+	name       string
+	pos, end   int64 // 下一个要返回的对象偏移和范围终点
+	lastStripe int64
+	streams    []io.ReadCloser
+	at         []int64 // 每个分片流当前所在的条带
+	bad        []bool
+	got        []bool // 当前条带已读取的分片
+	bufs       [][]byte
+	out        []byte
+	err        error
+}
+
+func (r *stripeReader) Read(b []byte) (int, error) {
+	if len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.pos >= r.end {
+			return 0, io.EOF
+		}
+		if r.err = r.fill(); r.err != nil {
+			return 0, r.err
+		}
+	}
+	n := copy(b, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// fill 解码 pos 所在条带中范围覆盖的部分
+func (r *stripeReader) fill() error {
+	k := r.s.k
+	width := int64(k * stripeBlock)
+	stripe := r.pos / width
+	lo := r.pos - stripe*width
+	hi := min(r.end-stripe*width, width)
+	j0, j1 := int(lo/stripeBlock), int((hi-1)/stripeBlock)
+
+	clear(r.got)
+	complete := true
+	for j := j0; j <= j1; j++ {
+		if !r.readShard(j, stripe) {
+			complete = false
+		}
+	}
+	if !complete {
+		have := 0
+		for i := range r.got {
+			if r.got[i] {
+				have++
+			}
+		}
+		for i := 0; i < len(r.got) && have < k; i++ {
+			if !r.got[i] && r.readShard(i, stripe) {
+				have++
+			}
+		}
+		if err := r.ctx.Err(); err != nil {
+			return err
+		}
+		if have < k {
+			return fmt.Errorf("striped: '%s' stripe %d: only %d of %d required shards available", r.name, stripe, have, k)
+		}
+		shards := make([][]byte, len(r.bufs))
+		for i := range shards {
+			if r.got[i] {
+				shards[i] = r.bufs[i][:stripeBlock]
+			} else {
+				shards[i] = r.bufs[i][:0]
+			}
+		}
+		if err := r.s.enc.ReconstructData(shards); err != nil {
+			return fmt.Errorf("striped: reconstruct '%s' stripe %d: %w", r.name, stripe, err)
+		}
+		for j := j0; j <= j1; j++ {
+			r.bufs[j] = shards[j]
+		}
+	}
+
+	out := make([]byte, 0, (j1-j0+1)*stripeBlock)
+	for j := j0; j <= j1; j++ {
+		out = append(out, r.bufs[j][:stripeBlock]...)
+	}
+	base := int64(j0) * stripeBlock
+	r.out = out[lo-base : hi-base]
+	r.pos = stripe*width + hi
+	return nil
+}
+
+// readShard 读取分片 i 在 stripe 条带中的块，失败时标记该分片不可用
+func (r *stripeReader) readShard(i int, stripe int64) bool {
+	if r.bad[i] {
+		return false
+	}
+	rs := r.s.members[i]
+	if r.streams[i] != nil && r.at[i] < stripe {
+		// 之前的条带不需要该分片，跳过落后的数据
+		if _, err := io.CopyN(io.Discard, r.streams[i], (stripe-r.at[i])*stripeBlock); err != nil {
+			r.streams[i].Close()
+			r.streams[i] = nil
+		}
+	}
+	if r.streams[i] == nil {
+		rc, err := rs.DownloadRange(r.ctx, r.name, stripe*stripeBlock, (r.lastStripe-stripe+1)*stripeBlock)
+		if err != nil {
+			r.fail(i, err)
+			return false
+		}
+		r.streams[i] = rc
+	}
+	if _, err := io.ReadFull(r.streams[i], r.bufs[i][:stripeBlock]); err != nil {
+		r.fail(i, err)
+		return false
+	}
+	r.at[i] = stripe + 1
+	r.got[i] = true
+	return true
+}
+
+func (r *stripeReader) fail(i int, err error) {
+	if r.ctx.Err() == nil {
+		log.Printf("Striped: read '%s' from %s failed: %v", r.name, r.s.names[i], err)
+	}
+	r.bad[i] = true
+	if r.streams[i] != nil {
+		r.streams[i].Close()
+		r.streams[i] = nil
+	}
+}
+
+func (r *stripeReader) Close() error {
+	for i, rc := range r.streams {
+		if rc != nil {
+			rc.Close()
+			r.streams[i] = nil
+		}
+	}
+	return nil
+}
+
+// Delete 删除所有分片，分片不存在视为成功
+func (s *StripedStorage) Delete(ctx context.Context, path string) error {
+	s.forget(path)
+	errs := each(s.members, func(i int, rs RemoteStorage) error {
+		if err := rs.Delete(ctx, path); err != nil && !IsNotFound(err) {
+			return err
+		}
+		return nil
+	})
+	return quorumError("Striped", "delete", path, s.names, s.quorum, errs)
+}
+
+// Rename 重命名所有分片
+func (s *StripedStorage) Rename(ctx context.Context, oldPath, newPath string) error {
+	s.forget(oldPath)
+	s.forget(newPath)
+	errs := each(s.members, func(i int, rs RemoteStorage) error {
+		return rs.Rename(ctx, oldPath, newPath)
+	})
+	return quorumError("Striped", "rename", oldPath, s.names, s.quorum, errs)
+}
+
+// Stat 返回原始对象的大小
+func (s *StripedStorage) Stat(ctx context.Context, path string) (os.FileInfo, error) {
+	sm, err := s.readMeta(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return &stripedFileInfo{name: pathpkg.Base(path), size: sm.size, modTime: sm.modTime}, nil
+}
+
+type stripedFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (fi *stripedFileInfo) Name() string       { return fi.name }
+func (fi *stripedFileInfo) Size() int64        { return fi.size }
+func (fi *stripedFileInfo) Mode() os.FileMode  { return 0644 }
+func (fi *stripedFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *stripedFileInfo) IsDir() bool        { return false }
+func (fi *stripedFileInfo) Sys() interface{}   { return nil }
+
+// List 枚举第一个可用成员上的分片，大小按条带对齐（不读取每个分片的描述信息）
+func (s *StripedStorage) List(ctx context.Context, prefix string, fn func(name string, info os.FileInfo) error) error {
+	err := ErrNotSupported
+	for _, rs := range s.members {
+		called := false
+		err = List(ctx, rs, prefix, func(name string, info os.FileInfo) error {
+			called = true
+			size := max(info.Size()-shardTrailerLen, 0) * int64(s.k)
+			return fn(name, &stripedFileInfo{name: info.Name(), size: size, modTime: info.ModTime()})
+		})
+		if err == nil || called {
+			return err
+		}
+	}
+	return err
+}
+
+// Quota 每个成员保存对象的 1/k（外加校验分片），按最满的成员换算可写入的数据量
+func (s *StripedStorage) Quota(ctx context.Context) (int64, int64, error) {
+	used, available, err := memberQuota(ctx, s.members)
+	if err != nil {
+		return -1, -1, err
+	}
+	if used > 0 {
+		used *= int64(s.k)
+	}
+	if available > 0 {
+		available *= int64(s.k)
+	}
+	return used, available, nil
+}
+
+// RepairStats 分片修复统计
+type RepairStats struct {
+	Objects  int // 检查的对象数
+	Repaired int // 重建了缺失分片的对象数
+	Lost     int // 可用分片不足 k 个、无法恢复的对象数
+	Failed   int // 修复出错的对象数
+}
+
+// Repair 枚举所有成员（需要支持 Lister），重建缺失或大小不一致的分片
+func (s *StripedStorage) Repair(ctx context.Context) (RepairStats, error) {
+	n := len(s.members)
+	sizes := make(map[string][]int64)
+	for i, rs := range s.members {
+		err := List(ctx, rs, "", func(name string, info os.FileInfo) error {
+			if sizes[name] == nil {
+				sizes[name] = make([]int64, n)
+				for j := range sizes[name] {
+					sizes[name][j] = -1
+				}
+			}
+			sizes[name][i] = info.Size()
+			return nil
+		})
+		if err != nil {
+			return RepairStats{}, fmt.Errorf("striped: list %s: %w", s.names[i], err)
+		}
+	}
+
+	names := make([]string, 0, len(sizes))
+	for name := range sizes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var stats RepairStats
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		stats.Objects++
+		repaired, err := s.repairObject(ctx, name, sizes[name])
+		switch {
+		case errors.Is(err, errShardsLost):
+			log.Printf("Striped: '%s' cannot be recovered: %v", name, err)
+			stats.Lost++
+		case err != nil:
+			log.Printf("Striped: repair '%s' failed: %v", name, err)
+			stats.Failed++
+		case repaired:
+			stats.Repaired++
+		}
+	}
+	return stats, nil
+}
+
+var errShardsLost = errors.New("fewer than data_shards shards available")
+
+// repairObject 从完好的分片重建其他分片，sizes 为各成员上分片的大小（-1 表示缺失）
+func (s *StripedStorage) repairObject(ctx context.Context, name string, sizes []int64) (bool, error) {
+	// 以描述信息有效的分片为准
+	var sm stripeMeta
+	found := false
+	for i, size := range sizes {
+		if size < 0 {
+			continue
+		}
+		if m, err := s.readTrailer(ctx, i, s.members[i], name); err == nil {
+			sm, found = m, true
+			break
+		}
+	}
+	if !found {
+		return false, errShardsLost
+	}
+	want := shardSize(sm.size, s.k)
+	good := make([]bool, len(sizes))
+	missing := make([]bool, len(sizes))
+	have, lack := 0, 0
+	for i, size := range sizes {
+		if size == want {
+			good[i] = true
+			have++
+		} else {
+			missing[i] = true
+			lack++
+		}
+	}
+	if lack == 0 {
+		return false, nil
+	}
+	if have < s.k {
+		return false, fmt.Errorf("%w (%d of %d)", errShardsLost, have, s.k)
+	}
+	log.Printf("Striped: rebuilding %d shard(s) of '%s'", lack, name)
+	s.forget(name)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	streams := make([]io.ReadCloser, len(sizes))
+	defer func() {
+		for _, rc := range streams {
+			if rc != nil {
+				rc.Close()
+			}
+		}
+	}()
+	for i := range good {
+		if !good[i] {
+			continue
+		}
+		rc, err := s.members[i].DownloadRange(ctx, name, 0, want-shardTrailerLen)
+		if err != nil {
+			return false, fmt.Errorf("open shard on %s: %w", s.names[i], err)
+		}
+		streams[i] = rc
+	}
+
+	w, wait := s.upload(ctx, name, missing, want)
+	err := func() error {
+		bufs := s.newShards()
+		shards := make([][]byte, len(bufs))
+		for stripe := int64(0); stripe < sm.stripes(); stripe++ {
+			for i := range shards {
+				shards[i] = bufs[i][:0]
+				if !good[i] {
+					continue
+				}
+				if _, err := io.ReadFull(streams[i], bufs[i][:stripeBlock]); err != nil {
+					return fmt.Errorf("read shard on %s: %w", s.names[i], err)
+				}
+				shards[i] = bufs[i][:stripeBlock]
+			}
+			if err := s.enc.Reconstruct(shards); err != nil {
+				return err
+			}
+			for i := range shards {
+				if missing[i] {
+					w.write(i, shards[i])
+				}
+				bufs[i] = shards[i]
+			}
+		}
+		for i := range missing {
+			if missing[i] {
+				w.write(i, encodeShardTrailer(s.k, s.m, i, sm.size))
+			}
+		}
+		return nil
+	}()
+	w.closeWithError(err)
+	errs := wait()
+	if err != nil {
+		return false, err
+	}
+	return true, errors.Join(errs...)
+}
+
+// Close 关闭所有成员
+func (s *StripedStorage) Close() error {
+	var errs []error
+	for _, rs := range s.members {
+		errs = append(errs, rs.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,199 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"clearvault/internal/remote/local"
+)
+
+// newTestStriped 创建 3 个数据分片 + 2 个校验分片的条带存储
+func newTestStriped(t *testing.T) (*StripedStorage, []string, []*switchStorage) {
+	t.Helper()
+	var dirs []string
+	var switches []*switchStorage
+	var members []RemoteStorage
+	for i := 0; i < 5; i++ {
+		dir := t.TempDir()
+		c, err := local.NewClient(dir)
+		if err != nil {
+			t.Fatalf("NewClient failed: %v", err)
+		}
+		s := &switchStorage{RemoteStorage: c}
+		dirs = append(dirs, dir)
+		switches = append(switches, s)
+		members = append(members, s)
+	}
+	s, err := NewStripedStorage(members, nil, StripedOptions{DataShards: 3})
+	if err != nil {
+		t.Fatalf("NewStripedStorage failed: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s, dirs, switches
+}
+
+func randomData(n int) []byte {
+	b := make([]byte, n)
+	r := rand.New(rand.NewPCG(1, uint64(n)))
+	for i := range b {
+		b[i] = byte(r.Uint32())
+	}
+	return b
+}
+
+func readRange(t *testing.T, rs RemoteStorage, name string, start, length int64) []byte {
+	t.Helper()
+	rc, err := rs.DownloadRange(context.Background(), name, start, length)
+	if err != nil {
+		t.Fatalf("DownloadRange(%d, %d) failed: %v", start, length, err)
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("ReadAll(%d, %d) failed: %v", start, length, err)
+	}
+	return b
+}
+
+func TestStripedStorage_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s, dirs, _ := newTestStriped(t)
+	width := 3 * stripeBlock
+
+	for _, size := range []int{0, 1, stripeBlock + 7, width, 2*width + 100} {
+		data := randomData(size)
+		for _, declared := range []int64{int64(size), -1} {
+			if err := s.Upload(ctx, "obj", bytes.NewReader(data), declared); err != nil {
+				t.Fatalf("Upload(size=%d) failed: %v", size, err)
+			}
+			if got := readRange(t, s, "obj", 0, 0); !bytes.Equal(got, data) {
+				t.Errorf("size=%d declared=%d: got %d bytes", size, declared, len(got))
+			}
+			info, err := s.Stat(ctx, "obj")
+			if err != nil || info.Size() != int64(size) {
+				t.Errorf("Stat(size=%d) = %v, %v", size, info, err)
+			}
+			fi, _ := os.Stat(filepath.Join(dirs[4], "obj"))
+			if fi.Size() != shardSize(int64(size), 3) {
+				t.Errorf("size=%d: shard size %d, want %d", size, fi.Size(), shardSize(int64(size), 3))
+			}
+		}
+	}
+}
+
+func TestStripedStorage_DownloadRange(t *testing.T) {
+	ctx := context.Background()
+	s, _, sw := newTestStriped(t)
+	data := randomData(3*3*stripeBlock + 1234)
+	if err := s.Upload(ctx, "obj", bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	ranges := [][2]int64{
+		{0, 10},
+		{stripeBlock - 5, 10},
+		{3*stripeBlock - 1, 2},
+		{100000, 400000},
+		{int64(len(data)) - 10, 0},
+		{int64(len(data)) - 10, 100},
+		{int64(len(data)) + 10, 100},
+	}
+	for _, r := range ranges {
+		end := int64(len(data))
+		if r[1] > 0 && r[0]+r[1] < end {
+			end = r[0] + r[1]
+		}
+		want := []byte{}
+		if r[0] < end {
+			want = data[r[0]:end]
+		}
+		if got := readRange(t, s, "obj", r[0], r[1]); !bytes.Equal(got, want) {
+			t.Errorf("range %v: got %d bytes, want %d", r, len(got), len(want))
+		}
+	}
+
+	// 单个块内的小范围只读取一个数据分片
+	for _, m := range sw {
+		m.reads.Store(0)
+	}
+	readRange(t, s, "obj", stripeBlock+100, 4096)
+	for i, m := range sw {
+		want := int32(0)
+		if i == 1 {
+			want = 1
+		}
+		if got := m.reads.Load(); got != want {
+			t.Errorf("shard %d: %d reads, want %d", i, got, want)
+		}
+	}
+}
+
+func TestStripedStorage_Degraded(t *testing.T) {
+	ctx := context.Background()
+	s, _, sw := newTestStriped(t)
+	data := randomData(2*3*stripeBlock + 999)
+	if err := s.Upload(ctx, "obj", bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	// 任意两个分片不可用时仍可读取
+	sw[0].down.Store(true)
+	sw[2].down.Store(true)
+	if got := readRange(t, s, "obj", 0, 0); !bytes.Equal(got, data) {
+		t.Errorf("Degraded read mismatch: got %d bytes", len(got))
+	}
+	if got := readRange(t, s, "obj", 10, 5000); !bytes.Equal(got, data[10:5010]) {
+		t.Errorf("Degraded range read mismatch")
+	}
+
+	sw[4].down.Store(true)
+	rc, err := s.DownloadRange(ctx, "obj", 0, 0)
+	if err == nil {
+		_, err = io.ReadAll(rc)
+		rc.Close()
+	}
+	if err == nil {
+		t.Error("Expected error with only 2 of 3 required shards")
+	}
+
+	// 写入不足 quorum 时失败
+	if err := s.Upload(ctx, "other", bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Error("Expected upload to fail with members down")
+	}
+}
+
+func TestStripedStorage_Repair(t *testing.T) {
+	ctx := context.Background()
+	s, dirs, sw := newTestStriped(t)
+	data := randomData(3*stripeBlock + 4321)
+	if err := s.Upload(ctx, "dir/obj", bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if err := s.Upload(ctx, "intact", bytes.NewReader(data[:100]), 100); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	// 丢失一个数据分片，损坏一个校验分片
+	os.Remove(filepath.Join(dirs[1], "dir/obj"))
+	os.WriteFile(filepath.Join(dirs[3], "dir/obj"), []byte("garbage"), 0644)
+
+	stats, err := s.Repair(ctx)
+	if err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if stats.Objects != 2 || stats.Repaired != 1 || stats.Lost != 0 || stats.Failed != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	// 修复后只依赖被重建的分片也能读取
+	sw[0].down.Store(true)
+	sw[2].down.Store(true)
+	if got := readRange(t, s, "dir/obj", 0, 0); !bytes.Equal(got, data) {
+		t.Errorf("Read after repair mismatch: got %d bytes", len(got))
+	}
+}
@@ -0,0 +1,264 @@
+package remote
+
+import (
+	"clearvault/internal/config"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// throttleBurst 令牌桶容量，也是单次读写的最大字节数
+const throttleBurst = 64 * 1024
+
+// BandwidthLimits 当前生效的限速（KiB/s，0 表示不限制）
+type BandwidthLimits struct {
+	Upload   int64 `json:"upload"`
+	Download int64 `json:"download"`
+	Total    int64 `json:"total"`
+}
+
+// ThrottleStorage 限制远端上传、下载及合计带宽，可按每日时间段切换限速，运行时可调整
+type ThrottleStorage struct {
+	RemoteStorage
+
+	up, down, total *rate.Limiter
+
+	mu     sync.Mutex
+	cfg    config.BandwidthConfig
+	rules  []bandwidthRule
+	active BandwidthLimits
+	now    func() time.Time
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// bandwidthRule 解析后的时间段规则，start/end 为当天的分钟数
+type bandwidthRule struct {
+	start, end int
+	limits     BandwidthLimits
+}
+
+// WithThrottle 为远端存储添加带宽限制
+func WithThrottle(rs RemoteStorage, cfg config.BandwidthConfig) (*ThrottleStorage, error) {
+	t := &ThrottleStorage{
+		RemoteStorage: rs,
+		up:            rate.NewLimiter(rate.Inf, throttleBurst),
+		down:          rate.NewLimiter(rate.Inf, throttleBurst),
+		total:         rate.NewLimiter(rate.Inf, throttleBurst),
+		now:           time.Now,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	if err := t.SetBandwidth(cfg); err != nil {
+		return nil, err
+	}
+	go t.scheduleLoop()
+	return t, nil
+}
+
+// ThrottleOf 返回远端存储（可能带目录布局、健康检查）中的限速层，不存在时返回 nil
+func ThrottleOf(rs RemoteStorage) *ThrottleStorage {
+	if l, ok := rs.(*LayoutStorage); ok {
+		rs = l.RemoteStorage
+	}
+	if h, ok := rs.(*HealthStorage); ok {
+		rs = h.RemoteStorage
+	}
+	t, _ := rs.(*ThrottleStorage)
+	return t
+}
+
+// Bandwidth 返回限速配置
+func (t *ThrottleStorage) Bandwidth() config.BandwidthConfig {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cfg
+}
+
+// Active 返回当前生效的限速
+func (t *ThrottleStorage) Active() BandwidthLimits {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.active
+}
+
+// SetBandwidth 更新限速配置并立即生效
+func (t *ThrottleStorage) SetBandwidth(cfg config.BandwidthConfig) error {
+	if cfg.Upload < 0 || cfg.Download < 0 || cfg.Total < 0 {
+		return fmt.Errorf("bandwidth limits must not be negative")
+	}
+	rules := make([]bandwidthRule, 0, len(cfg.Schedule))
+	for i, r := range cfg.Schedule {
+		start, err := parseClock(r.Start)
+		if err != nil {
+			return fmt.Errorf("bandwidth schedule #%d: %w", i+1, err)
+		}
+		end, err := parseClock(r.End)
+		if err != nil {
+			return fmt.Errorf("bandwidth schedule #%d: %w", i+1, err)
+		}
+		if r.Upload < 0 || r.Download < 0 || r.Total < 0 {
+			return fmt.Errorf("bandwidth schedule #%d: limits must not be negative", i+1)
+		}
+		rules = append(rules, bandwidthRule{start: start, end: end, limits: BandwidthLimits{Upload: r.Upload, Download: r.Download, Total: r.Total}})
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cfg = cfg
+	t.rules = rules
+	t.applyLocked()
+	return nil
+}
+
+// parseClock 解析 HH:MM，返回当天的分钟数
+func parseClock(s string) (int, error) {
+	tm, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time '%s', expected HH:MM", s)
+	}
+	return tm.Hour()*60 + tm.Minute(), nil
+}
+
+// applyLocked 按当前时间选出生效的限速：第一条匹配的时间段规则，否则为默认值
+func (t *ThrottleStorage) applyLocked() {
+	now := t.now()
+	minute := now.Hour()*60 + now.Minute()
+	limits := BandwidthLimits{Upload: t.cfg.Upload, Download: t.cfg.Download, Total: t.cfg.Total}
+	for _, r := range t.rules {
+		if r.contains(minute) {
+			limits = r.limits
+			break
+		}
+	}
+	if limits == t.active {
+		return
+	}
+	t.active = limits
+	setLimit(t.up, limits.Upload)
+	setLimit(t.down, limits.Download)
+	setLimit(t.total, limits.Total)
+	log.Printf("Remote: Bandwidth limits (KiB/s, 0 = unlimited): upload=%d download=%d total=%d", limits.Upload, limits.Download, limits.Total)
+}
+
+// contains 判断时间段是否包含该分钟，end 不晚于 start 时跨越午夜
+func (r bandwidthRule) contains(minute int) bool {
+	if r.start < r.end {
+		return minute >= r.start && minute < r.end
+	}
+	return minute >= r.start || minute < r.end
+}
+
+func setLimit(l *rate.Limiter, kib int64) {
+	if kib <= 0 {
+		l.SetLimit(rate.Inf)
+		return
+	}
+	l.SetLimit(rate.Limit(kib * 1024))
+}
+
+// scheduleLoop 每分钟重新选择生效的限速
+func (t *ThrottleStorage) scheduleLoop() {
+	defer close(t.done)
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+		}
+		t.mu.Lock()
+		t.applyLocked()
+		t.mu.Unlock()
+	}
+}
+
+func (t *ThrottleStorage) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	// 始终包装，上传过程中调整的限速同样生效；保留 io.Seeker，下层重试才能回退重放
+	return t.RemoteStorage.Upload(ctx, name, throttle(ctx, data, t.up, t.total), size)
+}
+
+func (t *ThrottleStorage) Download(ctx context.Context, name string) (io.ReadCloser, error) {
+	rc, err := t.RemoteStorage.Download(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return t.wrapDownload(ctx, rc), nil
+}
+
+func (t *ThrottleStorage) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	rc, err := t.RemoteStorage.DownloadRange(ctx, name, start, length)
+	if err != nil {
+		return nil, err
+	}
+	return t.wrapDownload(ctx, rc), nil
+}
+
+func (t *ThrottleStorage) wrapDownload(ctx context.Context, rc io.ReadCloser) io.ReadCloser {
+	return struct {
+		io.Reader
+		io.Closer
+	}{&throttledReader{Reader: rc, ctx: ctx, limiters: []*rate.Limiter{t.down, t.total}}, rc}
+}
+
+func (t *ThrottleStorage) List(ctx context.Context, prefix string, fn func(name string, info os.FileInfo) error) error {
+	return List(ctx, t.RemoteStorage, prefix, fn)
+}
+
+func (t *ThrottleStorage) Quota(ctx context.Context) (int64, int64, error) {
+	return Quota(ctx, t.RemoteStorage)
+}
+
+// Close 停止时间段调度并关闭底层存储
+func (t *ThrottleStorage) Close() error {
+	close(t.stop)
+	<-t.done
+	return t.RemoteStorage.Close()
+}
+
+// throttledReader 每次读取后按读到的字节数从令牌桶扣减，令牌不足时等待
+type throttledReader struct {
+	io.Reader
+	ctx      context.Context
+	limiters []*rate.Limiter
+}
+
+// throttledReadSeeker 数据源可 Seek 时使用，Seek 直接转发
+type throttledReadSeeker struct {
+	*throttledReader
+	seeker io.Seeker
+}
+
+func (r *throttledReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	return r.seeker.Seek(offset, whence)
+}
+
+// throttle 为数据源添加限速，数据源可 Seek 时返回的读取器同样可 Seek
+func throttle(ctx context.Context, r io.Reader, limiters ...*rate.Limiter) io.Reader {
+	tr := &throttledReader{Reader: r, ctx: ctx, limiters: limiters}
+	if s, ok := r.(io.Seeker); ok {
+		return &throttledReadSeeker{throttledReader: tr, seeker: s}
+	}
+	return tr
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > throttleBurst {
+		p = p[:throttleBurst]
+	}
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		for _, l := range r.limiters {
+			if werr := l.WaitN(r.ctx, n); werr != nil {
+				return n, werr
+			}
+		}
+	}
+	return n, err
+}
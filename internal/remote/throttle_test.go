@@ -0,0 +1,192 @@
+package remote
+
+import (
+	"bytes"
+	"clearvault/internal/config"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestThrottleSchedule(t *testing.T) {
+	cfg := config.BandwidthConfig{
+		Upload: 100,
+		Schedule: []config.BandwidthRule{
+			{Start: "08:00", End: "22:00", Upload: 2048, Download: 4096},
+			{Start: "22:00", End: "07:00", Total: 0},
+		},
+	}
+	ts, err := WithThrottle(nopStorage{}, cfg)
+	if err != nil {
+		t.Fatalf("WithThrottle failed: %v", err)
+	}
+	defer ts.Close()
+
+	tests := []struct {
+		clock string
+		want  BandwidthLimits
+	}{
+		{"12:30", BandwidthLimits{Upload: 2048, Download: 4096}},
+		{"08:00", BandwidthLimits{Upload: 2048, Download: 4096}},
+		{"23:15", BandwidthLimits{}},
+		{"03:00", BandwidthLimits{}},
+		{"07:30", BandwidthLimits{Upload: 100}},
+	}
+	for _, tt := range tests {
+		clock, _ := time.Parse("15:04", tt.clock)
+		ts.mu.Lock()
+		ts.now = func() time.Time { return clock }
+		ts.applyLocked()
+		ts.mu.Unlock()
+		if got := ts.Active(); got != tt.want {
+			t.Errorf("At %s expected %+v, got %+v", tt.clock, tt.want, got)
+		}
+	}
+
+	// 运行时调整立即生效
+	if err := ts.SetBandwidth(config.BandwidthConfig{Download: 10}); err != nil {
+		t.Fatalf("SetBandwidth failed: %v", err)
+	}
+	if got := ts.Active(); got != (BandwidthLimits{Download: 10}) {
+		t.Errorf("Expected new limits to apply, got %+v", got)
+	}
+	if got := ts.Bandwidth(); got.Download != 10 || len(got.Schedule) != 0 {
+		t.Errorf("Unexpected config %+v", got)
+	}
+}
+
+func TestThrottleInvalid(t *testing.T) {
+	invalid := []config.BandwidthConfig{
+		{Upload: -1},
+		{Schedule: []config.BandwidthRule{{Start: "8am", End: "22:00"}}},
+		{Schedule: []config.BandwidthRule{{Start: "08:00", End: "25:00"}}},
+		{Schedule: []config.BandwidthRule{{Start: "08:00", End: "09:00", Total: -5}}},
+	}
+	for _, cfg := range invalid {
+		if _, err := WithThrottle(nopStorage{}, cfg); err == nil {
+			t.Errorf("Expected error for %+v", cfg)
+		}
+	}
+
+	ts, _ := WithThrottle(nopStorage{}, config.BandwidthConfig{Upload: 5})
+	defer ts.Close()
+	if err := ts.SetBandwidth(config.BandwidthConfig{Download: -1}); err == nil {
+		t.Error("Expected error for negative limit")
+	}
+	if got := ts.Active(); got.Upload != 5 {
+		t.Errorf("Expected previous limits to be kept, got %+v", got)
+	}
+}
+
+func TestThrottleRate(t *testing.T) {
+	inner := &flakyStorage{data: make([]byte, throttleBurst+256*1024)}
+	ts, err := WithThrottle(inner, config.BandwidthConfig{Download: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+
+	// 令牌桶初始已满，其余 256KiB 按 1MiB/s 约需 250ms
+	start := time.Now()
+	rc, err := ts.Download(context.Background(), "obj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, _ := io.Copy(io.Discard, rc)
+	rc.Close()
+	elapsed := time.Since(start)
+	if n != int64(len(inner.data)) {
+		t.Fatalf("Expected %d bytes, got %d", len(inner.data), n)
+	}
+	if elapsed < 200*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("Expected download to take about 250ms, took %v", elapsed)
+	}
+
+	// 上传方向不受下载限速影响
+	start = time.Now()
+	if err := ts.Upload(context.Background(), "obj", bytes.NewReader(inner.data), int64(len(inner.data))); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected unlimited upload, took %v", elapsed)
+	}
+
+	// 等待会超过 ctx 期限时立即失败
+	ts.SetBandwidth(config.BandwidthConfig{Total: 1})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start = time.Now()
+	err = ts.Upload(ctx, "obj", bytes.NewReader(inner.data), int64(len(inner.data)))
+	if err == nil || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("Expected upload to fail early, got %v after %v", err, time.Since(start))
+	}
+}
+
+// midUploadStorage 读完第一块后调用 hook，模拟上传过程中调整限速
+type midUploadStorage struct {
+	nopStorage
+	hook func()
+}
+
+func (m *midUploadStorage) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	if _, err := io.CopyN(io.Discard, data, throttleBurst); err != nil {
+		return err
+	}
+	m.hook()
+	_, err := io.Copy(io.Discard, data)
+	return err
+}
+
+func TestThrottleLimitDuringUpload(t *testing.T) {
+	inner := &midUploadStorage{}
+	ts, err := WithThrottle(inner, config.BandwidthConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+
+	var start time.Time
+	inner.hook = func() {
+		ts.SetBandwidth(config.BandwidthConfig{Upload: 1024})
+		start = time.Now()
+	}
+	// 开始时未限速，剩余 256KiB 按 1MiB/s 至少需要约 190ms
+	data := make([]byte, throttleBurst+256*1024)
+	if err := ts.Upload(context.Background(), "obj", bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("Expected limit set during upload to apply, took %v", elapsed)
+	}
+}
+
+func TestThrottleOf(t *testing.T) {
+	rs, err := NewRemoteStorage(config.RemoteConfig{
+		Type:      "local",
+		LocalPath: t.TempDir(),
+		Health:    config.HealthConfig{Enabled: true},
+		Layout:    config.LayoutConfig{Levels: 2},
+		Bandwidth: config.BandwidthConfig{Upload: 512},
+	})
+	if err != nil {
+		t.Fatalf("NewRemoteStorage failed: %v", err)
+	}
+	defer rs.Close()
+	ts := ThrottleOf(rs)
+	if ts == nil || ts.Active().Upload != 512 {
+		t.Fatalf("Expected throttle layer with upload limit, got %v", ts)
+	}
+	if _, ok := Unwrap(rs).(*ThrottleStorage); ok {
+		t.Error("Expected Unwrap to remove the throttle layer")
+	}
+
+	_, err = NewRemoteStorage(config.RemoteConfig{
+		Type:      "local",
+		LocalPath: t.TempDir(),
+		Bandwidth: config.BandwidthConfig{Schedule: []config.BandwidthRule{{Start: "x"}}},
+	})
+	if err == nil {
+		t.Error("Expected error for invalid schedule")
+	}
+}
@@ -0,0 +1,188 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Timeouts 远端操作超时设置（0 表示不限制）
+type Timeouts struct {
+	// Operation 单次请求（Stat/Delete/Rename）以及下载建立连接（首字节）的超时
+	Operation time.Duration
+	// Idle 上传/下载过程中单次读取阻塞（无数据进展）的最长时间
+	Idle time.Duration
+}
+
+// WithTimeouts 为远端存储添加超时控制，超时后取消对应请求
+func WithTimeouts(rs RemoteStorage, t Timeouts) RemoteStorage {
+	if t.Operation <= 0 && t.Idle <= 0 {
+		return rs
+	}
+	return &timeoutStorage{RemoteStorage: rs, t: t}
+}
+
+type timeoutStorage struct {
+	RemoteStorage
+	t Timeouts
+}
+
+// watchdog 在指定时间内未被 kick 时取消 ctx
+type watchdog struct {
+	d     time.Duration
+	timer *time.Timer
+	fired atomic.Bool
+}
+
+func newWatchdog(d time.Duration, cancel context.CancelFunc) *watchdog {
+	w := &watchdog{d: d}
+	if d > 0 {
+		w.timer = time.AfterFunc(d, func() {
+			w.fired.Store(true)
+			cancel()
+		})
+	}
+	return w
+}
+
+func (w *watchdog) kick() {
+	if w.timer != nil {
+		w.timer.Reset(w.d)
+	}
+}
+
+func (w *watchdog) stop() {
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
+
+func (w *watchdog) wrap(op, name string, err error) error {
+	if err != nil && w.fired.Load() {
+		return fmt.Errorf("remote %s '%s': no progress for %v: %w", op, name, w.d, context.DeadlineExceeded)
+	}
+	return err
+}
+
+func (s *timeoutStorage) opContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.t.Operation > 0 {
+		return context.WithTimeout(ctx, s.t.Operation)
+	}
+	return context.WithCancel(ctx)
+}
+
+func (s *timeoutStorage) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wd := newWatchdog(s.t.Idle, cancel)
+	defer wd.stop()
+
+	err := s.RemoteStorage.Upload(ctx, name, &progressReader{r: data, wd: wd}, size)
+	return wd.wrap("upload", name, err)
+}
+
+func (s *timeoutStorage) Download(ctx context.Context, name string) (io.ReadCloser, error) {
+	return s.open(ctx, name, func(ctx context.Context) (io.ReadCloser, error) {
+		return s.RemoteStorage.Download(ctx, name)
+	})
+}
+
+func (s *timeoutStorage) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	return s.open(ctx, name, func(ctx context.Context) (io.ReadCloser, error) {
+		return s.RemoteStorage.DownloadRange(ctx, name, start, length)
+	})
+}
+
+// open 建立连接阶段使用 Operation 超时，之后改为 Idle 超时，数据流关闭时释放 ctx
+func (s *timeoutStorage) open(ctx context.Context, name string, fn func(context.Context) (io.ReadCloser, error)) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	first := newWatchdog(s.t.Operation, cancel)
+	rc, err := fn(ctx)
+	first.stop()
+	if err != nil {
+		cancel()
+		return nil, first.wrap("download", name, err)
+	}
+
+	// 只在 Read 阻塞期间计时，调用方暂停读取（如播放器暂停）不算超时
+	idle := newWatchdog(s.t.Idle, cancel)
+	idle.stop()
+	return &watchedReadCloser{
+		rc:     rc,
+		wd:     idle,
+		cancel: cancel,
+		name:   name,
+	}, nil
+}
+
+func (s *timeoutStorage) Delete(ctx context.Context, path string) error {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	return s.RemoteStorage.Delete(ctx, path)
+}
+
+func (s *timeoutStorage) Rename(ctx context.Context, oldPath, newPath string) error {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	return s.RemoteStorage.Rename(ctx, oldPath, newPath)
+}
+
+func (s *timeoutStorage) Stat(ctx context.Context, path string) (os.FileInfo, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	return s.RemoteStorage.Stat(ctx, path)
+}
+
+// List 枚举可能很耗时，只传递调用方的 ctx
+func (s *timeoutStorage) List(ctx context.Context, prefix string, fn func(name string, info os.FileInfo) error) error {
+	return List(ctx, s.RemoteStorage, prefix, fn)
+}
+
+func (s *timeoutStorage) Quota(ctx context.Context) (int64, int64, error) {
+	ctx, cancel := s.opContext(ctx)
+	defer cancel()
+	return Quota(ctx, s.RemoteStorage)
+}
+
+// progressReader 衡量远端取数据的间隔：读取数据源期间暂停计时（数据源慢不算远端超时），
+// 返回后重新计时，直到远端再次读取或请求结束
+type progressReader struct {
+	r  io.Reader
+	wd *watchdog
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	p.wd.stop()
+	n, err := p.r.Read(b)
+	p.wd.kick()
+	return n, err
+}
+
+type watchedReadCloser struct {
+	rc     io.ReadCloser
+	wd     *watchdog
+	cancel context.CancelFunc
+	name   string
+}
+
+func (w *watchedReadCloser) Read(b []byte) (int, error) {
+	w.wd.kick()
+	n, err := w.rc.Read(b)
+	w.wd.stop()
+	if err != nil && err != io.EOF {
+		err = w.wd.wrap("download", w.name, err)
+	}
+	return n, err
+}
+
+func (w *watchedReadCloser) Close() error {
+	w.wd.stop()
+	err := w.rc.Close()
+	w.cancel()
+	return err
+}
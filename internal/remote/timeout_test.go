@@ -0,0 +1,108 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockingStorage 所有操作阻塞直到 ctx 被取消
+type blockingStorage struct {
+	nopStorage
+}
+
+func (blockingStorage) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (blockingStorage) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	return io.NopCloser(&blockingReader{ctx: ctx}), nil
+}
+
+func (blockingStorage) Stat(ctx context.Context, path string) (os.FileInfo, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+type blockingReader struct {
+	ctx context.Context
+}
+
+func (r *blockingReader) Read(p []byte) (int, error) {
+	<-r.ctx.Done()
+	return 0, r.ctx.Err()
+}
+
+func TestWithTimeouts(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		var rs RemoteStorage = nopStorage{}
+		if WithTimeouts(rs, Timeouts{}) != rs {
+			t.Error("Expected storage to be returned unwrapped")
+		}
+	})
+
+	rs := WithTimeouts(blockingStorage{}, Timeouts{
+		Operation: 50 * time.Millisecond,
+		Idle:      50 * time.Millisecond,
+	})
+
+	t.Run("operation timeout", func(t *testing.T) {
+		start := time.Now()
+		_, err := rs.Stat(context.Background(), "obj")
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected DeadlineExceeded, got %v", err)
+		}
+		if time.Since(start) > 2*time.Second {
+			t.Errorf("Stat took too long: %v", time.Since(start))
+		}
+	})
+
+	t.Run("idle download", func(t *testing.T) {
+		rc, err := rs.DownloadRange(context.Background(), "obj", 0, 0)
+		if err != nil {
+			t.Fatalf("DownloadRange failed: %v", err)
+		}
+		defer rc.Close()
+		// 调用方暂停读取不应触发超时
+		time.Sleep(100 * time.Millisecond)
+		_, err = rc.Read(make([]byte, 16))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected DeadlineExceeded, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "no progress") {
+			t.Errorf("Expected idle timeout error, got %v", err)
+		}
+	})
+
+	t.Run("idle upload", func(t *testing.T) {
+		err := rs.Upload(context.Background(), "obj", strings.NewReader("data"), 4)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected DeadlineExceeded, got %v", err)
+		}
+	})
+
+	t.Run("caller cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		slow := WithTimeouts(blockingStorage{}, Timeouts{Operation: time.Hour})
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			cancel()
+		}()
+		_, err := slow.Stat(ctx, "obj")
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected Canceled, got %v", err)
+		}
+	})
+
+	t.Run("list passthrough", func(t *testing.T) {
+		err := List(context.Background(), rs, "", func(string, os.FileInfo) error { return nil })
+		if !errors.Is(err, ErrNotSupported) {
+			t.Errorf("Expected ErrNotSupported, got %v", err)
+		}
+	})
+}
@@ -1,10 +1,18 @@
 package webdav
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"clearvault/pkg/gowebdav"
@@ -12,15 +20,17 @@ import (
 
 // WebDAVConfig WebDAV 客户端配置
 type WebDAVConfig struct {
-	URL  string
-	User string
-	Pass string
+	URL     string
+	User    string
+	Pass    string
+	Chunked bool // 使用 Nextcloud 分块上传（chunking v2），URL 须为 .../remote.php/dav/files/<user>/
 }
 
 // WebDAVClient WebDAV 远端客户端实现
 type WebDAVClient struct {
 	client *gowebdav.Client
 	url    string
+	dirs   sync.Map // 已确认存在的目录，避免每次上传都发送 MKCOL
 }
 
 // NewClient 创建 WebDAV 客户端
@@ -35,6 +45,12 @@ func NewClient(cfg WebDAVConfig) (*WebDAVClient, error) {
 	}
 	c.SetTransport(t)
 
+	if cfg.Chunked {
+		if err := c.CheckChunkedUpload(); err != nil {
+			return nil, err
+		}
+	}
+
 	return &WebDAVClient{
 		client: c,
 		url:    cfg.URL,
@@ -46,35 +62,213 @@ func (c *WebDAVClient) SetTransport(rt http.RoundTripper) {
 	c.client.SetTransport(rt)
 }
 
-// Upload 上传文件到 WebDAV 服务器
-func (c *WebDAVClient) Upload(name string, data io.Reader, size int64) error {
+// mkdirAll 按需创建目录，已创建过的目录直接跳过
+func (c *WebDAVClient) mkdirAll(client *gowebdav.Client, dir string) error {
+	dir = path.Clean("/" + dir)
+	if dir == "/" {
+		return nil
+	}
+	if _, ok := c.dirs.Load(dir); ok {
+		return nil
+	}
+	if err := client.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	c.dirs.Store(dir, struct{}{})
+	return nil
+}
+
+// forgetDirs 清除 p 及其子目录的缓存（目录可能已被删除）
+func (c *WebDAVClient) forgetDirs(p string) {
+	p = path.Clean("/" + p)
+	c.dirs.Range(func(k, _ any) bool {
+		if d := k.(string); d == p || strings.HasPrefix(d, p+"/") {
+			c.dirs.Delete(d)
+		}
+		return true
+	})
+}
+
+// Upload 上传文件到 WebDAV 服务器，父目录不存在时先创建
+func (c *WebDAVClient) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
 	log.Printf("WebDAV: Uploading '%s' (size: %d)", name, size)
-	return c.client.WriteStreamWithLength(name, data, size, os.ModePerm)
+	client := c.client.WithContext(ctx)
+	dir := path.Dir(path.Clean("/" + name))
+	if err := c.mkdirAll(client, dir); err != nil {
+		return err
+	}
+	err := client.PutStreamWithLength(name, data, size)
+	if gowebdav.IsErrCode(err, 409) {
+		// 父目录被外部删除，下次上传时重新创建
+		c.forgetDirs(dir)
+	}
+	return err
+}
+
+// CreateUpload 创建 Nextcloud 分块上传目录，返回目录名作为会话 ID
+func (c *WebDAVClient) CreateUpload(ctx context.Context, name string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := "clearvault-" + hex.EncodeToString(b)
+	if err := c.client.WithContext(ctx).BeginChunkedUpload(id, name, -1); err != nil {
+		return "", err
+	}
+	log.Printf("WebDAV: Started chunked upload for '%s' (upload ID: %s)", name, id)
+	return id, nil
+}
+
+// UploadChunk 上传第 n 个分块
+func (c *WebDAVClient) UploadChunk(ctx context.Context, name, uploadID string, n int, data []byte) (string, error) {
+	return c.client.WithContext(ctx).PutChunk(uploadID, n, data, name, -1)
+}
+
+// CompleteUpload 合并分块到目标文件
+// 服务端合并上传目录中的所有分块，先删除上次上传遗留的多余分块，并确认所需分块都在
+func (c *WebDAVClient) CompleteUpload(ctx context.Context, name, uploadID string, etags []string, size int64) error {
+	client := c.client.WithContext(ctx)
+	chunks, err := client.ReadChunks(uploadID)
+	if err != nil {
+		return err
+	}
+	found := 0
+	for _, fi := range chunks {
+		n, err := strconv.Atoi(fi.Name())
+		if err != nil {
+			continue
+		}
+		if n > len(etags) {
+			if err := client.RemoveChunk(uploadID, n); err != nil {
+				return err
+			}
+			continue
+		}
+		found++
+	}
+	if found != len(etags) {
+		return fmt.Errorf("%w: upload '%s' has %d of %d chunks", os.ErrNotExist, uploadID, found, len(etags))
+	}
+
+	dir := path.Dir(path.Clean("/" + name))
+	if err := c.mkdirAll(client, dir); err != nil {
+		return err
+	}
+	err = client.FinishChunkedUpload(uploadID, name, size)
+	if gowebdav.IsErrCode(err, 409) {
+		c.forgetDirs(dir)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("WebDAV: Completed chunked upload for '%s' (%d chunks, size: %d)", name, len(etags), size)
+	return nil
+}
+
+// AbortUpload 删除上传目录
+func (c *WebDAVClient) AbortUpload(ctx context.Context, name, uploadID string) error {
+	return c.client.WithContext(ctx).AbortChunkedUpload(uploadID)
 }
 
 // Download 从 WebDAV 服务器下载文件
-func (c *WebDAVClient) Download(name string) (io.ReadCloser, error) {
-	return c.client.ReadStream(name)
+func (c *WebDAVClient) Download(ctx context.Context, name string) (io.ReadCloser, error) {
+	return c.client.WithContext(ctx).ReadStream(name)
 }
 
 // DownloadRange 从 WebDAV 服务器下载文件的指定范围
-func (c *WebDAVClient) DownloadRange(name string, start, length int64) (io.ReadCloser, error) {
-	return c.client.ReadStreamRange(name, start, length)
+func (c *WebDAVClient) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	return c.client.WithContext(ctx).ReadStreamRange(name, start, length)
 }
 
 // Delete 从 WebDAV 服务器删除文件
-func (c *WebDAVClient) Delete(path string) error {
-	return c.client.RemoveAll(path)
+func (c *WebDAVClient) Delete(ctx context.Context, path string) error {
+	c.forgetDirs(path)
+	return c.client.WithContext(ctx).RemoveAll(path)
 }
 
-// Rename 重命名 WebDAV 服务器上的文件
-func (c *WebDAVClient) Rename(oldPath, newPath string) error {
-	return c.client.Rename(oldPath, newPath, true)
+// Rename 重命名 WebDAV 服务器上的文件，目标目录不存在时先创建
+func (c *WebDAVClient) Rename(ctx context.Context, oldPath, newPath string) error {
+	client := c.client.WithContext(ctx)
+	if err := c.mkdirAll(client, path.Dir(path.Clean("/"+newPath))); err != nil {
+		return err
+	}
+	c.forgetDirs(oldPath)
+	return client.Rename(oldPath, newPath, true)
 }
 
 // Stat 获取 WebDAV 文件信息
-func (c *WebDAVClient) Stat(path string) (os.FileInfo, error) {
-	return c.client.Stat(path)
+func (c *WebDAVClient) Stat(ctx context.Context, path string) (os.FileInfo, error) {
+	info, err := c.client.WithContext(ctx).Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if f, ok := info.(*gowebdav.File); ok && f.Checksums() != "" {
+		return &checksumInfo{FileInfo: info, md5: parseChecksums(f.Checksums(), "MD5")}, nil
+	}
+	return info, nil
+}
+
+// Quota 通过 RFC 4331 属性查询根目录的已用和剩余空间，服务端未报告的值为 -1
+func (c *WebDAVClient) Quota(ctx context.Context) (int64, int64, error) {
+	return c.client.WithContext(ctx).Quota("/")
+}
+
+// checksumInfo 附带服务端记录的 MD5（Nextcloud oc:checksums）
+type checksumInfo struct {
+	os.FileInfo
+	md5 string
+}
+
+func (i *checksumInfo) MD5() string { return i.md5 }
+
+// parseChecksums 从 "SHA1:... MD5:... ADLER32:..." 中取出指定算法的十六进制摘要
+func parseChecksums(s, algo string) string {
+	for _, field := range strings.Fields(s) {
+		if name, sum, ok := strings.Cut(field, ":"); ok && strings.EqualFold(name, algo) {
+			return strings.ToLower(sum)
+		}
+	}
+	return ""
+}
+
+// List 递归枚举名称以 prefix 开头的文件（逐级 PROPFIND Depth: 1）
+func (c *WebDAVClient) List(ctx context.Context, prefix string, fn func(name string, info os.FileInfo) error) error {
+	prefix = strings.TrimPrefix(prefix, "/")
+	dir := ""
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dir = prefix[:i]
+	}
+	err := c.listDir(c.client.WithContext(ctx), dir, prefix, fn)
+	if dir != "" && gowebdav.IsErrNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (c *WebDAVClient) listDir(client *gowebdav.Client, dir, prefix string, fn func(name string, info os.FileInfo) error) error {
+	entries, err := client.ReadDir("/" + dir)
+	if err != nil {
+		return err
+	}
+	for _, fi := range entries {
+		name := path.Join(dir, fi.Name())
+		if fi.IsDir() {
+			// 只进入可能包含匹配对象的子目录
+			if strings.HasPrefix(name+"/", prefix) || strings.HasPrefix(prefix, name+"/") {
+				if err := c.listDir(client, name, prefix, fn); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if err := fn(name, fi); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Close 清理资源（WebDAV 客户端不需要显式关闭）
@@ -2,6 +2,7 @@ package webdav
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -60,7 +61,7 @@ func checkWebDAVAvailable(t *testing.T) bool {
 	defer client.Close()
 
 	// Try to stat root
-	_, err = client.Stat("/")
+	_, err = client.Stat(context.Background(), "/")
 	return err == nil
 }
 
@@ -128,13 +129,13 @@ func TestWebDAVClient_Upload(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			data := bytes.NewReader([]byte(tt.content))
-			err := client.Upload(tt.filename, data, int64(len(tt.content)))
+			err := client.Upload(context.Background(), tt.filename, data, int64(len(tt.content)))
 			if err != nil {
 				t.Errorf("Upload() failed: %v", err)
 			}
 
 			// Cleanup
-			defer client.Delete(tt.filename)
+			defer client.Delete(context.Background(), tt.filename)
 		})
 	}
 }
@@ -156,14 +157,14 @@ func TestWebDAVClient_Download(t *testing.T) {
 	content := "Test content for download"
 	filename := "/test-download.txt"
 	data := bytes.NewReader([]byte(content))
-	err = client.Upload(filename, data, int64(len(content)))
+	err = client.Upload(context.Background(), filename, data, int64(len(content)))
 	if err != nil {
 		t.Fatalf("Upload failed: %v", err)
 	}
-	defer client.Delete(filename)
+	defer client.Delete(context.Background(), filename)
 
 	// Download and verify
-	reader, err := client.Download(filename)
+	reader, err := client.Download(context.Background(), filename)
 	if err != nil {
 		t.Fatalf("Download() failed: %v", err)
 	}
@@ -192,7 +193,7 @@ func TestWebDAVClient_Download_NotFound(t *testing.T) {
 	}
 	defer client.Close()
 
-	_, err = client.Download("/non-existent-file-12345.txt")
+	_, err = client.Download(context.Background(), "/non-existent-file-12345.txt")
 	if err == nil {
 		t.Error("Download() of non-existent file should return error")
 	}
@@ -215,11 +216,11 @@ func TestWebDAVClient_DownloadRange(t *testing.T) {
 	content := "0123456789ABCDEF"
 	filename := "/test-range.txt"
 	data := bytes.NewReader([]byte(content))
-	err = client.Upload(filename, data, int64(len(content)))
+	err = client.Upload(context.Background(), filename, data, int64(len(content)))
 	if err != nil {
 		t.Fatalf("Upload failed: %v", err)
 	}
-	defer client.Delete(filename)
+	defer client.Delete(context.Background(), filename)
 
 	tests := []struct {
 		name         string
@@ -249,7 +250,7 @@ func TestWebDAVClient_DownloadRange(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			reader, err := client.DownloadRange(filename, tt.start, tt.length)
+			reader, err := client.DownloadRange(context.Background(), filename, tt.start, tt.length)
 			if err != nil {
 				t.Fatalf("DownloadRange() failed: %v", err)
 			}
@@ -284,18 +285,18 @@ func TestWebDAVClient_Delete(t *testing.T) {
 	content := "To be deleted"
 	filename := "/test-delete.txt"
 	data := bytes.NewReader([]byte(content))
-	err = client.Upload(filename, data, int64(len(content)))
+	err = client.Upload(context.Background(), filename, data, int64(len(content)))
 	if err != nil {
 		t.Fatalf("Upload failed: %v", err)
 	}
 
-	err = client.Delete(filename)
+	err = client.Delete(context.Background(), filename)
 	if err != nil {
 		t.Errorf("Delete() failed: %v", err)
 	}
 
 	// Verify deletion
-	_, err = client.Download(filename)
+	_, err = client.Download(context.Background(), filename)
 	if err == nil {
 		t.Error("Download after delete should fail")
 	}
@@ -319,26 +320,26 @@ func TestWebDAVClient_Rename(t *testing.T) {
 	oldName := "/test-old-name.txt"
 	newName := "/test-new-name.txt"
 	data := bytes.NewReader([]byte(content))
-	err = client.Upload(oldName, data, int64(len(content)))
+	err = client.Upload(context.Background(), oldName, data, int64(len(content)))
 	if err != nil {
 		t.Fatalf("Upload failed: %v", err)
 	}
-	defer client.Delete(newName) // Cleanup
+	defer client.Delete(context.Background(), newName) // Cleanup
 
 	// Rename
-	err = client.Rename(oldName, newName)
+	err = client.Rename(context.Background(), oldName, newName)
 	if err != nil {
 		t.Fatalf("Rename() failed: %v", err)
 	}
 
 	// Verify old name doesn't exist
-	_, err = client.Download(oldName)
+	_, err = client.Download(context.Background(), oldName)
 	if err == nil {
 		t.Error("Download with old name should fail after rename")
 	}
 
 	// Verify new name exists
-	reader, err := client.Download(newName)
+	reader, err := client.Download(context.Background(), newName)
 	if err != nil {
 		t.Fatalf("Download with new name failed: %v", err)
 	}
@@ -367,14 +368,14 @@ func TestWebDAVClient_Stat(t *testing.T) {
 	content := "Test content for stat"
 	filename := "/test-stat.txt"
 	data := bytes.NewReader([]byte(content))
-	err = client.Upload(filename, data, int64(len(content)))
+	err = client.Upload(context.Background(), filename, data, int64(len(content)))
 	if err != nil {
 		t.Fatalf("Upload failed: %v", err)
 	}
-	defer client.Delete(filename)
+	defer client.Delete(context.Background(), filename)
 
 	// Stat
-	info, err := client.Stat(filename)
+	info, err := client.Stat(context.Background(), filename)
 	if err != nil {
 		t.Fatalf("Stat() failed: %v", err)
 	}
@@ -400,7 +401,7 @@ func TestWebDAVClient_Stat_NotFound(t *testing.T) {
 	}
 	defer client.Close()
 
-	_, err = client.Stat("/non-existent-file-12345.txt")
+	_, err = client.Stat(context.Background(), "/non-existent-file-12345.txt")
 	if err == nil {
 		t.Error("Stat() of non-existent file should return error")
 	}
@@ -426,9 +427,9 @@ func TestWebDAVClient_ConcurrentOperations(t *testing.T) {
 			content := []byte("Concurrent content")
 			filename := "/concurrent-" + string(rune('a'+id)) + ".txt"
 			data := bytes.NewReader(content)
-			err := client.Upload(filename, data, int64(len(content)))
+			err := client.Upload(context.Background(), filename, data, int64(len(content)))
 			if err == nil {
-				client.Delete(filename)
+				client.Delete(context.Background(), filename)
 			}
 			done <- err
 		}(i)
@@ -459,13 +460,13 @@ func TestWebDAVClient_DirectoryOperations(t *testing.T) {
 	filePath := dirPath + "/file.txt"
 	content := "File in directory"
 	data := bytes.NewReader([]byte(content))
-	err = client.Upload(filePath, data, int64(len(content)))
+	err = client.Upload(context.Background(), filePath, data, int64(len(content)))
 	if err != nil {
 		t.Fatalf("Upload to directory failed: %v", err)
 	}
 
 	// Stat directory
-	info, err := client.Stat(dirPath)
+	info, err := client.Stat(context.Background(), dirPath)
 	if err != nil {
 		t.Fatalf("Stat directory failed: %v", err)
 	}
@@ -474,6 +475,51 @@ func TestWebDAVClient_DirectoryOperations(t *testing.T) {
 	}
 
 	// Cleanup
-	client.Delete(filePath)
-	client.Delete(dirPath)
+	client.Delete(context.Background(), filePath)
+	client.Delete(context.Background(), dirPath)
+}
+
+// TestWebDAVClient_List tests recursive listing with prefixes
+func TestWebDAVClient_List(t *testing.T) {
+	if !checkWebDAVAvailable(t) {
+		t.Skip("WebDAV server (sweb) not available")
+	}
+
+	cfg := getTestConfig()
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	defer client.Close()
+
+	files := []string{"/list-test/aa11", "/list-test/aa22", "/list-test/sub/bb33"}
+	for _, name := range files {
+		if err := client.Upload(context.Background(), name, strings.NewReader("data"), 4); err != nil {
+			t.Fatalf("Upload(%s) failed: %v", name, err)
+		}
+	}
+	defer client.Delete(context.Background(), "/list-test")
+
+	got := map[string]int64{}
+	err = client.List(context.Background(), "list-test/", func(name string, info os.FileInfo) error {
+		got[name] = info.Size()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	for _, name := range files {
+		if got[strings.TrimPrefix(name, "/")] != 4 {
+			t.Errorf("Expected %s in listing, got %v", name, got)
+		}
+	}
+
+	got = map[string]int64{}
+	client.List(context.Background(), "list-test/aa", func(name string, info os.FileInfo) error {
+		got[name] = info.Size()
+		return nil
+	})
+	if len(got) != 2 {
+		t.Errorf("Expected 2 objects with prefix, got %v", got)
+	}
 }
@@ -1,6 +1,7 @@
 package webdav
 
 import (
+	"context"
 	"io"
 	"log"
 	"net/http"
@@ -57,34 +58,34 @@ func (c *WebDAVClient) SetTransport(rt http.RoundTripper) {
 }
 
 // Upload 上传文件到 WebDAV 服务器
-func (c *WebDAVClient) Upload(name string, data io.Reader, size int64) error {
+func (c *WebDAVClient) Upload(ctx context.Context, name string, data io.Reader, size int64) error {
 	log.Printf("WebDAV: Uploading '%s' (size: %d)", name, size)
-	return c.client.WriteStreamWithLength(name, data, size, os.ModePerm)
+	return c.client.WithContext(ctx).WriteStreamWithLength(name, data, size, os.ModePerm)
 }
 
 // Download 从 WebDAV 服务器下载文件
-func (c *WebDAVClient) Download(name string) (io.ReadCloser, error) {
-	return c.client.ReadStream(name)
+func (c *WebDAVClient) Download(ctx context.Context, name string) (io.ReadCloser, error) {
+	return c.client.WithContext(ctx).ReadStream(name)
 }
 
 // DownloadRange 下载文件的指定字节范围
-func (c *WebDAVClient) DownloadRange(name string, start, length int64) (io.ReadCloser, error) {
-	return c.client.ReadStreamRange(name, start, length)
+func (c *WebDAVClient) DownloadRange(ctx context.Context, name string, start, length int64) (io.ReadCloser, error) {
+	return c.client.WithContext(ctx).ReadStreamRange(name, start, length)
 }
 
 // Delete 从 WebDAV 服务器删除文件
-func (c *WebDAVClient) Delete(path string) error {
-	return c.client.RemoveAll(path)
+func (c *WebDAVClient) Delete(ctx context.Context, path string) error {
+	return c.client.WithContext(ctx).RemoveAll(path)
 }
 
 // Rename 重命名 WebDAV 文件
-func (c *WebDAVClient) Rename(oldPath, newPath string) error {
-	return c.client.Rename(oldPath, newPath, true)
+func (c *WebDAVClient) Rename(ctx context.Context, oldPath, newPath string) error {
+	return c.client.WithContext(ctx).Rename(oldPath, newPath, true)
 }
 
 // Stat 获取 WebDAV 文件信息
-func (c *WebDAVClient) Stat(path string) (os.FileInfo, error) {
-	return c.client.Stat(path)
+func (c *WebDAVClient) Stat(ctx context.Context, path string) (os.FileInfo, error) {
+	return c.client.WithContext(ctx).Stat(path)
 }
 
 // Close 清理资源（WebDAV 客户端不需要显式关闭）
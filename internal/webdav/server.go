@@ -19,6 +19,11 @@ type SizeSetter interface {
 	SetPendingSize(name string, size int64)
 }
 
+// AvailabilityChecker 远端离线时检查文件能否从本地读取
+type AvailabilityChecker interface {
+	CheckAvailable(name string) error
+}
+
 func NewLocalServer(prefix string, fs webdav.FileSystem, ls webdav.LockSystem, authUser, authPass string) *LocalServer {
 	return &LocalServer{
 		handler: &webdav.Handler{
@@ -60,17 +65,20 @@ func (s *LocalServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if r.Method == "PUT" && r.ContentLength > 0 {
-		path := r.URL.Path
-		if s.handler.Prefix != "" {
-			path = strings.TrimPrefix(path, s.handler.Prefix)
-		}
-		// Ensure path starts with / for consistency with WebDAV internal handling
-		if !strings.HasPrefix(path, "/") {
-			path = "/" + path
+		if setter, ok := s.handler.FileSystem.(SizeSetter); ok {
+			setter.SetPendingSize(s.localPath(r), r.ContentLength)
 		}
+	}
 
-		if setter, ok := s.handler.FileSystem.(SizeSetter); ok {
-			setter.SetPendingSize(path, r.ContentLength)
+	// 远端离线且文件未缓存时直接返回 503，避免客户端长时间等待
+	if r.Method == "GET" && r.Header.Get("Range") == "" {
+		if checker, ok := s.handler.FileSystem.(AvailabilityChecker); ok {
+			if err := checker.CheckAvailable(s.localPath(r)); err != nil {
+				log.Printf("WebDAV: %v", err)
+				w.Header().Set("Retry-After", "30")
+				http.Error(w, "Remote storage is offline", http.StatusServiceUnavailable)
+				return
+			}
 		}
 	}
 
@@ -78,6 +86,18 @@ func (s *LocalServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	log.Printf("ServeHTTP Done: %s %s -> %d", r.Method, r.URL.Path, sw.status)
 }
 
+// localPath 去掉前缀后的请求路径，与 WebDAV 内部处理保持一致以 / 开头
+func (s *LocalServer) localPath(r *http.Request) string {
+	path := r.URL.Path
+	if s.handler.Prefix != "" {
+		path = strings.TrimPrefix(path, s.handler.Prefix)
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
 // handleOptions handles OPTIONS requests for WebDAV discovery
 // Based on sweb project - Windows WebDAV client compatibility
 func (s *LocalServer) handleOptions(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,156 @@
+package gowebdav
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Nextcloud 分块上传（chunking v2）
+// https://docs.nextcloud.com/server/latest/developer_manual/client_apis/WebDAV/chunking.html
+//
+//	MKCOL /remote.php/dav/uploads/<user>/<id>          创建上传目录
+//	PUT   /remote.php/dav/uploads/<user>/<id>/<n>      上传分块，n 为 1..10000
+//	MOVE  /remote.php/dav/uploads/<user>/<id>/.file    服务端按编号合并分块并移动到目标文件
+//
+// 每个请求都带上 Destination 头（目标文件 URL），S3 主存储的 Nextcloud 据此直接使用 multipart upload
+
+// ErrChunkingNotSupported 客户端地址不是 Nextcloud 的 files 地址
+var ErrChunkingNotSupported = errors.New("chunked upload requires a Nextcloud URL (.../remote.php/dav/files/<user>/)")
+
+const nextcloudFilesPath = "/remote.php/dav/files/"
+
+// uploadsClient 返回以 /remote.php/dav/uploads/<user>/ 为根地址、共用认证信息的客户端
+func (c *Client) uploadsClient() (*Client, error) {
+	i := strings.Index(c.root, nextcloudFilesPath)
+	if i < 0 {
+		return nil, ErrChunkingNotSupported
+	}
+	user, _, _ := strings.Cut(c.root[i+len(nextcloudFilesPath):], "/")
+	if user == "" {
+		return nil, ErrChunkingNotSupported
+	}
+	u := *c
+	u.root = c.root[:i] + "/remote.php/dav/uploads/" + user + "/"
+	return &u, nil
+}
+
+// CheckChunkedUpload 检查客户端地址是否支持 Nextcloud 分块上传，不支持时返回 ErrChunkingNotSupported
+func (c *Client) CheckChunkedUpload() error {
+	_, err := c.uploadsClient()
+	return err
+}
+
+// chunkName 分块文件名，补零使字典序与编号顺序一致
+func chunkName(id string, n int) string {
+	return fmt.Sprintf("%s/%05d", id, n)
+}
+
+// chunkHeaders 设置 Destination 与 OC-Total-Length（总大小未知时不设置）
+func (c *Client) chunkHeaders(rq *http.Request, dest string, totalLength int64) {
+	rq.Header.Set("Destination", PathEscape(Join(c.root, dest)))
+	if totalLength >= 0 {
+		rq.Header.Set("OC-Total-Length", strconv.FormatInt(totalLength, 10))
+	}
+}
+
+// BeginChunkedUpload 创建上传目录 id，dest 为最终文件路径，目录已存在时视为成功
+func (c *Client) BeginChunkedUpload(id, dest string, totalLength int64) error {
+	u, err := c.uploadsClient()
+	if err != nil {
+		return err
+	}
+	rs, err := u.req("MKCOL", id, nil, func(rq *http.Request) {
+		c.chunkHeaders(rq, dest, totalLength)
+	})
+	if err != nil {
+		return NewPathErrorErr("BeginChunkedUpload", id, err)
+	}
+	defer rs.Body.Close()
+
+	switch rs.StatusCode {
+	case 201, 405:
+		return nil
+	default:
+		return NewPathErrorResponse("BeginChunkedUpload", id, rs)
+	}
+}
+
+// PutChunk 上传第 n 个分块（从 1 开始），同一编号重复上传会覆盖，返回服务端 ETag
+func (c *Client) PutChunk(id string, n int, data []byte, dest string, totalLength int64) (string, error) {
+	u, err := c.uploadsClient()
+	if err != nil {
+		return "", err
+	}
+	name := chunkName(id, n)
+	rs, err := u.req("PUT", name, bytes.NewReader(data), func(rq *http.Request) {
+		rq.ContentLength = int64(len(data))
+		c.chunkHeaders(rq, dest, totalLength)
+	})
+	if err != nil {
+		return "", NewPathErrorErr("PutChunk", name, err)
+	}
+	rs.Body.Close()
+
+	switch rs.StatusCode {
+	case 200, 201, 204:
+		return rs.Header.Get("ETag"), nil
+	default:
+		return "", NewPathErrorResponse("PutChunk", name, rs)
+	}
+}
+
+// ReadChunks 列出上传目录中已有的分块
+func (c *Client) ReadChunks(id string) ([]os.FileInfo, error) {
+	u, err := c.uploadsClient()
+	if err != nil {
+		return nil, err
+	}
+	return u.ReadDir(id)
+}
+
+// RemoveChunk 删除第 n 个分块
+func (c *Client) RemoveChunk(id string, n int) error {
+	u, err := c.uploadsClient()
+	if err != nil {
+		return err
+	}
+	return u.RemoveAll(chunkName(id, n))
+}
+
+// FinishChunkedUpload 合并上传目录中的所有分块并移动到 dest（覆盖已有文件），完成后服务端删除上传目录
+func (c *Client) FinishChunkedUpload(id, dest string, totalLength int64) error {
+	u, err := c.uploadsClient()
+	if err != nil {
+		return err
+	}
+	src := id + "/.file"
+	rs, err := u.req("MOVE", src, nil, func(rq *http.Request) {
+		c.chunkHeaders(rq, dest, totalLength)
+		rq.Header.Set("Overwrite", "T")
+	})
+	if err != nil {
+		return NewPathErrorErr("FinishChunkedUpload", src, err)
+	}
+	defer rs.Body.Close()
+
+	switch rs.StatusCode {
+	case 201, 204:
+		return nil
+	default:
+		return NewPathErrorResponse("FinishChunkedUpload", src, rs)
+	}
+}
+
+// AbortChunkedUpload 删除上传目录及其中的分块
+func (c *Client) AbortChunkedUpload(id string) error {
+	u, err := c.uploadsClient()
+	if err != nil {
+		return err
+	}
+	return u.RemoveAll(id)
+}
@@ -0,0 +1,205 @@
+package gowebdav
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/webdav"
+)
+
+// newNextcloudServer 模拟 Nextcloud 的 /remote.php/dav：files 与 uploads 共用一个 MemFS，
+// MOVE .file 时按分块名称顺序合并到 Destination
+func newNextcloudServer(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+	ctx := context.Background()
+	fs := webdav.NewMemFS()
+	for _, dir := range []string{"/files", "/files/user", "/uploads", "/uploads/user"} {
+		if err := fs.Mkdir(ctx, dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := &webdav.Handler{Prefix: "/remote.php/dav", FileSystem: fs, LockSystem: webdav.NewMemLS()}
+
+	var missingDest []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upload := strings.HasPrefix(r.URL.Path, "/remote.php/dav/uploads/")
+		if upload && r.Method != "DELETE" && r.Method != "PROPFIND" && r.Header.Get("Destination") == "" {
+			missingDest = append(missingDest, r.Method+" "+r.URL.Path)
+		}
+		if !upload || r.Method != "MOVE" || !strings.HasSuffix(r.URL.Path, "/.file") {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		dir := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/remote.php/dav"), "/.file")
+		d, err := fs.OpenFile(ctx, dir, os.O_RDONLY, 0)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		infos, _ := d.Readdir(-1)
+		d.Close()
+		sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+
+		u, _ := url.Parse(r.Header.Get("Destination"))
+		dst, err := fs.OpenFile(ctx, strings.TrimPrefix(u.Path, "/remote.php/dav"), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		for _, fi := range infos {
+			f, _ := fs.OpenFile(ctx, dir+"/"+fi.Name(), os.O_RDONLY, 0)
+			io.Copy(dst, f)
+			f.Close()
+		}
+		dst.Close()
+		fs.RemoveAll(ctx, dir)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &missingDest
+}
+
+func TestChunkedUpload(t *testing.T) {
+	srv, missingDest := newNextcloudServer(t)
+	c := NewClient(srv.URL+"/remote.php/dav/files/user/", "", "")
+	if err := c.Mkdir("/dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	const id = "upload-1"
+	if err := c.BeginChunkedUpload(id, "/dir/obj", -1); err != nil {
+		t.Fatalf("BeginChunkedUpload failed: %v", err)
+	}
+	// 再次创建已存在的上传目录视为成功
+	if err := c.BeginChunkedUpload(id, "/dir/obj", -1); err != nil {
+		t.Fatalf("BeginChunkedUpload (existing) failed: %v", err)
+	}
+	for n, data := range []string{"", "aaa", "stale", "ccc"} {
+		if n == 0 {
+			continue
+		}
+		if _, err := c.PutChunk(id, n, []byte(data), "/dir/obj", -1); err != nil {
+			t.Fatalf("PutChunk(%d) failed: %v", n, err)
+		}
+	}
+	// 覆盖第 2 块，删除第 3 块
+	if _, err := c.PutChunk(id, 2, []byte("bb"), "/dir/obj", -1); err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := c.ReadChunks(id)
+	if err != nil || len(chunks) != 3 {
+		t.Fatalf("ReadChunks = %d, %v", len(chunks), err)
+	}
+	if err := c.RemoveChunk(id, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.FinishChunkedUpload(id, "/dir/obj", 5); err != nil {
+		t.Fatalf("FinishChunkedUpload failed: %v", err)
+	}
+	got, err := c.Read("/dir/obj")
+	if err != nil || string(got) != "aaabb" {
+		t.Errorf("Read = %q, %v", got, err)
+	}
+	if _, err := c.ReadChunks(id); !IsErrNotFound(err) {
+		t.Errorf("Expected upload dir to be removed, got %v", err)
+	}
+	if len(*missingDest) != 0 {
+		t.Errorf("Requests without Destination header: %v", *missingDest)
+	}
+}
+
+func TestChunkedUploadAbort(t *testing.T) {
+	srv, _ := newNextcloudServer(t)
+	c := NewClient(srv.URL+"/remote.php/dav/files/user/vault", "", "")
+
+	if err := c.BeginChunkedUpload("upload-2", "obj", -1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.PutChunk("upload-2", 1, []byte("data"), "obj", 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AbortChunkedUpload("upload-2"); err != nil {
+		t.Fatalf("AbortChunkedUpload failed: %v", err)
+	}
+	if _, err := c.ReadChunks("upload-2"); !IsErrNotFound(err) {
+		t.Errorf("Expected upload dir to be removed, got %v", err)
+	}
+}
+
+func TestChunkedUploadAuth(t *testing.T) {
+	srv, _ := newNextcloudServer(t)
+	auth := httptest.NewServer(basicAuth(srv.Config.Handler))
+	defer auth.Close()
+	uri := auth.URL + "/remote.php/dav/files/user/"
+
+	if err := NewClient(uri, "user", "password").BeginChunkedUpload("upload-3", "obj", -1); err != nil {
+		t.Fatal(err)
+	}
+	// 重启后的第一个请求就是带数据的分块上传，认证协商后须重发分块内容
+	c := NewClient(uri, "user", "password")
+	if _, err := c.PutChunk("upload-3", 1, []byte("data"), "obj", -1); err != nil {
+		t.Fatalf("PutChunk failed: %v", err)
+	}
+	if err := c.FinishChunkedUpload("upload-3", "obj", 4); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.Read("obj"); err != nil || string(got) != "data" {
+		t.Errorf("Read = %q, %v", got, err)
+	}
+}
+
+func TestChunkedUploadNotSupported(t *testing.T) {
+	for _, uri := range []string{"http://host/webdav/", "http://host/remote.php/dav/files/"} {
+		c := NewClient(uri, "", "")
+		if err := c.CheckChunkedUpload(); !errors.Is(err, ErrChunkingNotSupported) {
+			t.Errorf("CheckChunkedUpload(%s) = %v", uri, err)
+		}
+	}
+	c := NewClient("http://host/nc/remote.php/dav/files/alice/sub/", "", "")
+	if err := c.CheckChunkedUpload(); err != nil {
+		t.Errorf("CheckChunkedUpload = %v", err)
+	}
+	u, _ := c.uploadsClient()
+	if u.root != "http://host/nc/remote.php/dav/uploads/alice/" {
+		t.Errorf("uploads root = %s", u.root)
+	}
+}
+
+func TestStatChecksums(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(207)
+		io.WriteString(w, `<?xml version="1.0"?>
+<d:multistatus xmlns:d="DAV:" xmlns:oc="http://owncloud.org/ns">
+ <d:response>
+  <d:href>/remote.php/dav/files/user/obj</d:href>
+  <d:propstat>
+   <d:prop>
+    <d:getcontentlength>5</d:getcontentlength>
+    <oc:checksums><oc:checksum>SHA1:aaaa MD5:5d41402abc4b2a76b9719d911017c592 ADLER32:bbbb</oc:checksum></oc:checksums>
+   </d:prop>
+   <d:status>HTTP/1.1 200 OK</d:status>
+  </d:propstat>
+ </d:response>
+</d:multistatus>`)
+	}))
+	defer srv.Close()
+
+	info, err := NewClient(srv.URL+"/remote.php/dav/files/user/", "", "").Stat("obj")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	f := info.(*File)
+	if f.Size() != 5 || f.Checksums() != "SHA1:aaaa MD5:5d41402abc4b2a76b9719d911017c592 ADLER32:bbbb" {
+		t.Errorf("Unexpected file info: %v, checksums %q", f, f.Checksums())
+	}
+}
@@ -2,6 +2,7 @@ package gowebdav
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io"
@@ -9,6 +10,7 @@ import (
 	"net/url"
 	"os"
 	pathpkg "path"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -22,6 +24,7 @@ type Client struct {
 	interceptor func(method string, rq *http.Request)
 	c           *http.Client
 	auth        Authorizer
+	ctx         context.Context
 }
 
 // NewClient creates a new instance of client
@@ -65,6 +68,14 @@ func (c *Client) SetTransport(transport http.RoundTripper) {
 	c.c.Transport = transport
 }
 
+// WithContext returns a shallow copy of the client whose requests are bound to ctx.
+// Cancelling ctx aborts in-flight requests and response bodies.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	cc := *c
+	cc.ctx = ctx
+	return &cc
+}
+
 // SetJar exposes the ability to set a cookie jar to the client.
 func (c *Client) SetJar(jar http.CookieJar) {
 	c.c.Jar = jar
@@ -97,6 +108,7 @@ type props struct {
 	ContentType string   `xml:"DAV: prop>getcontenttype,omitempty"`
 	ETag        string   `xml:"DAV: prop>getetag,omitempty"`
 	Modified    string   `xml:"DAV: prop>getlastmodified,omitempty"`
+	Checksums   string   `xml:"http://owncloud.org/ns prop>checksums>checksum,omitempty"`
 }
 
 type response struct {
@@ -191,6 +203,7 @@ func (c *Client) Stat(path string) (os.FileInfo, error) {
 			f.path = path
 			f.etag = p.ETag
 			f.contentType = p.ContentType
+			f.checksums = p.Checksums
 
 			if p.Type.Local == "collection" {
 				if !strings.HasSuffix(f.path, "/") {
@@ -211,7 +224,7 @@ func (c *Client) Stat(path string) (os.FileInfo, error) {
 	}
 
 	err := c.propfind(path, true,
-		`<d:propfind xmlns:d='DAV:'>
+		`<d:propfind xmlns:d='DAV:' xmlns:oc='http://owncloud.org/ns'>
 			<d:prop>
 				<d:displayname/>
 				<d:resourcetype/>
@@ -219,6 +232,7 @@ func (c *Client) Stat(path string) (os.FileInfo, error) {
 				<d:getcontenttype/>
 				<d:getetag/>
 				<d:getlastmodified/>
+				<oc:checksums/>
 			</d:prop>
 		</d:propfind>`,
 		&response{},
@@ -232,6 +246,57 @@ func (c *Client) Stat(path string) (os.FileInfo, error) {
 	return f, err
 }
 
+type quotaProps struct {
+	Status    string `xml:"DAV: status"`
+	Used      string `xml:"DAV: prop>quota-used-bytes,omitempty"`
+	Available string `xml:"DAV: prop>quota-available-bytes,omitempty"`
+}
+
+type quotaResponse struct {
+	Href  string       `xml:"DAV: href"`
+	Props []quotaProps `xml:"DAV: propstat"`
+}
+
+// Quota returns the RFC 4331 quota of the collection at path.
+// Values the server does not report (or reports as negative, like Nextcloud's
+// "unlimited") are returned as -1.
+func (c *Client) Quota(path string) (used, available int64, err error) {
+	used, available = -1, -1
+	parse := func(resp interface{}) error {
+		r := resp.(*quotaResponse)
+		for _, p := range r.Props {
+			if !strings.Contains(p.Status, "200") {
+				continue
+			}
+			if n, err := strconv.ParseInt(strings.TrimSpace(p.Used), 10, 64); err == nil && n >= 0 {
+				used = n
+			}
+			if n, err := strconv.ParseInt(strings.TrimSpace(p.Available), 10, 64); err == nil && n >= 0 {
+				available = n
+			}
+		}
+		r.Props = nil
+		return nil
+	}
+
+	err = c.propfind(path, true,
+		`<d:propfind xmlns:d='DAV:'>
+			<d:prop>
+				<d:quota-available-bytes/>
+				<d:quota-used-bytes/>
+			</d:prop>
+		</d:propfind>`,
+		&quotaResponse{},
+		parse)
+
+	if err != nil {
+		if _, ok := err.(*os.PathError); !ok {
+			err = NewPathErrorErr("Quota", path, err)
+		}
+	}
+	return used, available, err
+}
+
 // Remove removes a remote file
 func (c *Client) Remove(path string) error {
 	return c.RemoveAll(path)
@@ -252,7 +317,7 @@ func (c *Client) RemoveAll(path string) error {
 		return nil
 	}
 
-	return NewPathError("Remove", path, rs.StatusCode)
+	return NewPathErrorResponse("Remove", path, rs)
 }
 
 // Mkdir makes a directory
@@ -341,7 +406,7 @@ func (c *Client) ReadStream(path string) (io.ReadCloser, error) {
 	}
 
 	rs.Body.Close()
-	return nil, NewPathError("ReadStream", path, rs.StatusCode)
+	return nil, NewPathErrorResponse("ReadStream", path, rs)
 }
 
 // ReadStreamRange reads the stream representing a subset of bytes for a given path,
@@ -377,22 +442,27 @@ func (c *Client) ReadStreamRange(path string, offset, length int64) (io.ReadClos
 			return nil, NewPathErrorErr("ReadStreamRange", path, err)
 		}
 
+		// length <= 0 means up to the end of the stream.
+		if length <= 0 {
+			return rs.Body, nil
+		}
+
 		// return a io.ReadCloser that is limited to `length` bytes.
 		return &limitedReadCloser{rc: rs.Body, remaining: int(length)}, nil
 	}
 
 	rs.Body.Close()
-	return nil, NewPathError("ReadStream", path, rs.StatusCode)
+	return nil, NewPathErrorResponse("ReadStream", path, rs)
 }
 
 // Write writes data to a given path
 func (c *Client) Write(path string, data []byte, _ os.FileMode) (err error) {
-	s, err := c.put(path, bytes.NewReader(data), int64(len(data)))
+	rs, err := c.put(path, bytes.NewReader(data), int64(len(data)))
 	if err != nil {
 		return
 	}
 
-	switch s {
+	switch rs.StatusCode {
 
 	case 200, 201, 204:
 		return nil
@@ -403,16 +473,16 @@ func (c *Client) Write(path string, data []byte, _ os.FileMode) (err error) {
 			return
 		}
 
-		s, err = c.put(path, bytes.NewReader(data), int64(len(data)))
+		rs, err = c.put(path, bytes.NewReader(data), int64(len(data)))
 		if err != nil {
 			return
 		}
-		if s == 200 || s == 201 || s == 204 {
+		if rs.StatusCode == 200 || rs.StatusCode == 201 || rs.StatusCode == 204 {
 			return
 		}
 	}
 
-	return NewPathError("Write", path, s)
+	return NewPathErrorResponse("Write", path, rs)
 }
 
 // WriteStream writes a stream - it will copy to memory for non-seekable streams
@@ -445,17 +515,17 @@ func (c *Client) WriteStream(path string, stream io.Reader, _ os.FileMode) (err
 		stream = buffer
 	}
 
-	s, err := c.put(path, stream, contentLength)
+	rs, err := c.put(path, stream, contentLength)
 	if err != nil {
 		return err
 	}
 
-	switch s {
+	switch rs.StatusCode {
 	case 200, 201, 204:
 		return nil
 
 	default:
-		return NewPathError("WriteStream", path, s)
+		return NewPathErrorResponse("WriteStream", path, rs)
 	}
 }
 
@@ -466,7 +536,11 @@ func (c *Client) WriteStreamWithLength(path string, stream io.Reader, contentLen
 	if err != nil {
 		return err
 	}
+	return c.PutStreamWithLength(path, stream, contentLength)
+}
 
+// PutStreamWithLength 与 WriteStreamWithLength 相同，但不创建父目录（调用方需确保其已存在）
+func (c *Client) PutStreamWithLength(path string, stream io.Reader, contentLength int64) error {
 	// =================================================================
 	// 2. 【核心修复】预热认证 (Warm-up / Probe)
 	// 我们先发一个 HEAD 请求。
@@ -483,15 +557,15 @@ func (c *Client) WriteStreamWithLength(path string, stream io.Reader, contentLen
 	// 此时 c.auth 应该已经持有有效的认证信息。
 	// 当 c.put 发起请求时，它会直接带上正确的 Authorization 头，
 	// 服务器直接返回 201 Created，不再返回 401，从而避免了“重读 Pipe”的灾难。
-	s, err := c.put(path, stream, contentLength)
+	rs, err := c.put(path, stream, contentLength)
 	if err != nil {
 		return err
 	}
 
-	switch s {
+	switch rs.StatusCode {
 	case 200, 201, 204:
 		return nil
 	default:
-		return NewPathError("WriteStreamWithLength", path, s)
+		return NewPathErrorResponse("WriteStreamWithLength", path, rs)
 	}
 }
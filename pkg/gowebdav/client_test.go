@@ -397,6 +397,43 @@ func TestStat(t *testing.T) {
 	}
 }
 
+func TestQuota(t *testing.T) {
+	avail := "4096"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PROPFIND" || r.Header.Get("Depth") != "0" {
+			t.Errorf("unexpected request %s depth %q", r.Method, r.Header.Get("Depth"))
+		}
+		w.WriteHeader(207)
+		io.WriteString(w, `<?xml version="1.0"?>
+<d:multistatus xmlns:d="DAV:">
+ <d:response>
+  <d:href>/dav/</d:href>
+  <d:propstat>
+   <d:prop>
+    <d:quota-used-bytes>1000</d:quota-used-bytes>
+    <d:quota-available-bytes>`+avail+`</d:quota-available-bytes>
+   </d:prop>
+   <d:status>HTTP/1.1 200 OK</d:status>
+  </d:propstat>
+ </d:response>
+</d:multistatus>`)
+	}))
+	defer srv.Close()
+	cli := NewClient(srv.URL+"/dav/", "", "")
+
+	used, available, err := cli.Quota("/")
+	if err != nil || used != 1000 || available != 4096 {
+		t.Fatalf("got: %d, %d, %v, want 1000, 4096", used, available, err)
+	}
+
+	// Nextcloud reports -3 for unlimited
+	avail = "-3"
+	used, available, err = cli.Quota("/")
+	if err != nil || used != 1000 || available != -1 {
+		t.Fatalf("got: %d, %d, %v, want 1000, -1", used, available, err)
+	}
+}
+
 func TestMkdir(t *testing.T) {
 	cli, srv, fs, ctx := newServer(t)
 	defer srv.Close()
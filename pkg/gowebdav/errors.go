@@ -3,7 +3,10 @@ package gowebdav
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"strconv"
+	"time"
 )
 
 // ErrAuthChanged must be returned from the Verify method as an error
@@ -17,6 +20,9 @@ var ErrTooManyRedirects = errors.New("stopped after 10 redirects")
 // an erroneous status code.
 type StatusError struct {
 	Status int
+	// RetryAfter is the delay requested by the server's
+	// Retry-After header, zero if absent.
+	RetryAfter time.Duration
 }
 
 func (se StatusError) Error() string {
@@ -44,10 +50,43 @@ func NewPathError(op string, path string, statusCode int) error {
 	return &os.PathError{
 		Op:   op,
 		Path: path,
-		Err:  StatusError{statusCode},
+		Err:  StatusError{Status: statusCode},
 	}
 }
 
+// NewPathErrorResponse is like NewPathError but also keeps
+// the Retry-After hint of the response.
+func NewPathErrorResponse(op string, path string, rs *http.Response) error {
+	return &os.PathError{
+		Op:   op,
+		Path: path,
+		Err: StatusError{
+			Status:     rs.StatusCode,
+			RetryAfter: parseRetryAfter(rs.Header.Get("Retry-After")),
+		},
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given either
+// as delay-seconds or as an HTTP-date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
 func NewPathErrorErr(op string, path string, err error) error {
 	return &os.PathError{
 		Op:   op,
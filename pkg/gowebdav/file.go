@@ -14,6 +14,7 @@ type File struct {
 	size        int64
 	modified    time.Time
 	etag        string
+	checksums   string
 	isdir       bool
 }
 
@@ -57,6 +58,12 @@ func (f File) ETag() string {
 	return f.etag
 }
 
+// Checksums returns the checksums reported by the server (Nextcloud/ownCloud oc:checksums),
+// e.g. "SHA1:... MD5:... ADLER32:...", or an empty string
+func (f File) Checksums() string {
+	return f.checksums
+}
+
 // IsDir let us see if a given file is a directory or not
 func (f File) IsDir() bool {
 	return f.isdir
@@ -2,6 +2,7 @@ package gowebdav
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"log"
@@ -17,8 +18,13 @@ func (c *Client) req(method, path string, body io.Reader, intercept func(*http.R
 	auth, body := c.auth.NewAuthenticator(body)
 	defer auth.Close()
 
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
 	for { // TODO auth.continue() strategy(true|n times|until)?
-		if r, err = http.NewRequest(method, uri, body); err != nil {
+		if r, err = http.NewRequestWithContext(ctx, method, uri, body); err != nil {
 			return
 		}
 
@@ -116,7 +122,7 @@ func (c *Client) propfind(path string, self bool, body string, resp interface{},
 	defer rs.Body.Close()
 
 	if rs.StatusCode != 207 {
-		return NewPathError("PROPFIND", path, rs.StatusCode)
+		return NewPathErrorResponse("PROPFIND", path, rs)
 	}
 
 	return parseXML(rs.Body, resp, parse)
@@ -177,8 +183,9 @@ func (c *Client) copymove(method string, oldpath string, newpath string, overwri
 	return NewPathError(method, oldpath, s)
 }
 
-func (c *Client) put(path string, stream io.Reader, contentLength int64) (status int, err error) {
-	rs, err := c.req("PUT", path, stream, func(r *http.Request) {
+// put returns the response with its body already closed
+func (c *Client) put(path string, stream io.Reader, contentLength int64) (rs *http.Response, err error) {
+	rs, err = c.req("PUT", path, stream, func(r *http.Request) {
 		r.ContentLength = contentLength
 
 		// 【关键修复】显式禁止 GetBody
@@ -195,9 +202,7 @@ func (c *Client) put(path string, stream io.Reader, contentLength int64) (status
 	if err != nil {
 		return
 	}
-	defer rs.Body.Close()
-
-	status = rs.StatusCode
+	rs.Body.Close()
 	return
 }
 
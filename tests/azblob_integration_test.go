@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"clearvault/internal/config"
+	"clearvault/internal/remote"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+)
+
+// Azurite 默认地址与开发账户（公开的固定凭据）：
+//
+//	azurite-blob --blobHost 127.0.0.1 --blobPort 10000
+const (
+	azuriteDefaultEndpoint = "http://127.0.0.1:10000/devstoreaccount1"
+	azuriteAccountName     = "devstoreaccount1"
+	azuriteAccountKey      = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IfsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+	azuriteTestContainer   = "clearvault-test"
+)
+
+// ensureAzureContainer 在 Azurite 中创建测试 container
+func ensureAzureContainer(t *testing.T, endpoint string) {
+	t.Helper()
+	cred, err := container.NewSharedKeyCredential(azuriteAccountName, azuriteAccountKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := container.NewClientWithSharedKeyCredential(strings.TrimSuffix(endpoint, "/")+"/"+azuriteTestContainer, cred, nil)
+	if err != nil {
+		t.Fatalf("Failed to create container client: %v", err)
+	}
+	if _, err := client.Create(context.Background(), nil); err != nil && !bloberror.HasCode(err, bloberror.ContainerAlreadyExists) {
+		t.Fatalf("Failed to create container: %v", err)
+	}
+}
+
+func TestAzureBlobBackend(t *testing.T) {
+	endpoint := envOr("AZURITE_ENDPOINT", azuriteDefaultEndpoint)
+	requireEmulator(t, "Azurite", endpoint)
+	ensureAzureContainer(t, endpoint)
+
+	rs, err := remote.NewRemoteStorage(config.RemoteConfig{
+		Type:        "azblob",
+		Endpoint:    endpoint,
+		AccountName: azuriteAccountName,
+		AccountKey:  azuriteAccountKey,
+		Container:   azuriteTestContainer,
+	})
+	if err != nil {
+		t.Fatalf("NewRemoteStorage failed: %v", err)
+	}
+	defer rs.Close()
+
+	testRemoteBackend(t, rs, fmt.Sprintf("run-%d/", time.Now().UnixNano()))
+}
+
+func TestAzureBlobBackend_MissingContainer(t *testing.T) {
+	endpoint := envOr("AZURITE_ENDPOINT", azuriteDefaultEndpoint)
+	requireEmulator(t, "Azurite", endpoint)
+
+	_, err := remote.NewRemoteStorage(config.RemoteConfig{
+		Type:        "azblob",
+		Endpoint:    endpoint,
+		AccountName: azuriteAccountName,
+		AccountKey:  azuriteAccountKey,
+		Container:   "clearvault-missing-container",
+	})
+	if err == nil {
+		t.Error("Expected error for missing container")
+	}
+}
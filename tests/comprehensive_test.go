@@ -6,6 +6,7 @@ import (
 	"clearvault/internal/metadata"
 	"clearvault/internal/proxy"
 	"clearvault/internal/webdav"
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
@@ -129,7 +130,7 @@ func TestComprehensiveExportImport(t *testing.T) {
 			}
 		}
 
-		err := proxyA.UploadFile(fpath, bytes.NewReader(content), int64(len(content)))
+		err := proxyA.UploadFile(context.Background(), fpath, bytes.NewReader(content), int64(len(content)))
 		if err != nil {
 			t.Fatalf("Failed to upload %s: %v", fpath, err)
 		}
@@ -183,7 +184,7 @@ func TestComprehensiveExportImport(t *testing.T) {
 			}
 
 			// Check content
-			rc, err := proxyB.DownloadFile(fpath)
+			rc, err := proxyB.DownloadFile(context.Background(), fpath)
 			if err != nil {
 				t.Fatalf("Download failed: %v", err)
 			}
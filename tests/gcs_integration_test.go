@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"clearvault/internal/config"
+	"clearvault/internal/remote"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// fake-gcs-server 默认配置：
+//
+//	fake-gcs-server -scheme http -port 4443
+const (
+	gcsDefaultEndpoint = "http://localhost:4443/storage/v1/"
+	gcsTestBucket      = "clearvault-test"
+)
+
+// ensureGCSBucket 在模拟器中创建测试 bucket
+func ensureGCSBucket(t *testing.T, endpoint string) {
+	t.Helper()
+	ctx := context.Background()
+	client, err := storage.NewClient(ctx, option.WithEndpoint(endpoint), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("Failed to create GCS client: %v", err)
+	}
+	defer client.Close()
+
+	err = client.Bucket(gcsTestBucket).Create(ctx, "test-project", nil)
+	var ge *googleapi.Error
+	if err != nil && !(errors.As(err, &ge) && ge.Code == http.StatusConflict) {
+		t.Fatalf("Failed to create bucket: %v", err)
+	}
+}
+
+func TestGCSBackend(t *testing.T) {
+	endpoint := envOr("GCS_EMULATOR_ENDPOINT", gcsDefaultEndpoint)
+	requireEmulator(t, "fake-gcs-server", endpoint)
+	ensureGCSBucket(t, endpoint)
+
+	rs, err := remote.NewRemoteStorage(config.RemoteConfig{
+		Type:     "gcs",
+		Endpoint: endpoint,
+		Bucket:   gcsTestBucket,
+	})
+	if err != nil {
+		t.Fatalf("NewRemoteStorage failed: %v", err)
+	}
+	defer rs.Close()
+
+	testRemoteBackend(t, rs, fmt.Sprintf("run-%d/", time.Now().UnixNano()))
+}
+
+func TestGCSBackend_MissingBucket(t *testing.T) {
+	endpoint := envOr("GCS_EMULATOR_ENDPOINT", gcsDefaultEndpoint)
+	requireEmulator(t, "fake-gcs-server", endpoint)
+
+	_, err := remote.NewRemoteStorage(config.RemoteConfig{
+		Type:     "gcs",
+		Endpoint: endpoint,
+		Bucket:   "clearvault-missing-bucket",
+	})
+	if err == nil {
+		t.Error("Expected error for missing bucket")
+	}
+}
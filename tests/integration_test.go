@@ -55,7 +55,7 @@ func TestIntegration(t *testing.T) {
 	content := []byte("Clearvault Integration Test Content")
 
 	t.Logf("Starting UploadFile...")
-	err = p.UploadFile(testFile, bytes.NewReader(content), -1)
+	err = p.UploadFile(context.Background(), testFile, bytes.NewReader(content), -1)
 	if err != nil {
 		t.Fatalf("Upload failed: %v", err)
 	}
@@ -72,7 +72,7 @@ func TestIntegration(t *testing.T) {
 
 	t.Logf("Starting DownloadFile...")
 	// Test Download
-	rc, err := p.DownloadFile(testFile)
+	rc, err := p.DownloadFile(context.Background(), testFile)
 	if err != nil {
 		t.Fatalf("Download failed: %v", err)
 	}
@@ -90,6 +90,6 @@ func TestIntegration(t *testing.T) {
 	// Cleanup remote
 	metaInfo, _ := p.GetFileMeta(testFile)
 	if metaInfo != nil {
-		remoteStorage.Delete(metaInfo.RemoteName)
+		remoteStorage.Delete(context.Background(), metaInfo.RemoteName)
 	}
 }
@@ -65,7 +65,7 @@ func TestLocalRemoteStorage(t *testing.T) {
 	content := []byte("Content stored in local filesystem remote")
 	
 	t.Logf("Uploading %s...", testFile)
-	err = p.UploadFile(testFile, bytes.NewReader(content), int64(len(content)))
+	err = p.UploadFile(context.Background(), testFile, bytes.NewReader(content), int64(len(content)))
 	if err != nil {
 		t.Fatalf("Upload failed: %v", err)
 	}
@@ -95,7 +95,7 @@ func TestLocalRemoteStorage(t *testing.T) {
 
 	// 7. Test Download
 	t.Logf("Downloading %s...", testFile)
-	rc, err := p.DownloadFile(testFile)
+	rc, err := p.DownloadFile(context.Background(), testFile)
 	if err != nil {
 		t.Fatalf("Download failed: %v", err)
 	}
@@ -112,7 +112,7 @@ func TestLocalRemoteStorage(t *testing.T) {
 
 	// 8. Test Delete
 	t.Logf("Deleting %s...", testFile)
-	err = p.RemoveAll(testFile)
+	err = p.RemoveAll(context.Background(), testFile)
 	if err != nil {
 		t.Fatalf("Delete failed: %v", err)
 	}
@@ -63,7 +63,7 @@ func TestIntegrationLocalMock(t *testing.T) {
 	// Test Upload
 	testFile := "/test.txt"
 	content := []byte("Mock Integration Test Content")
-	err = p.UploadFile(testFile, bytes.NewReader(content), -1)
+	err = p.UploadFile(context.Background(), testFile, bytes.NewReader(content), -1)
 	if err != nil {
 		t.Fatalf("Upload failed: %v", err)
 	}
@@ -86,7 +86,7 @@ func TestIntegrationLocalMock(t *testing.T) {
 	}
 
 	// Test Download
-	rc, err := p.DownloadFile(testFile)
+	rc, err := p.DownloadFile(context.Background(), testFile)
 	if err != nil {
 		t.Fatalf("Download failed: %v", err)
 	}
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"clearvault/internal/remote"
+)
+
+// requireEmulator 模拟器未运行时跳过测试
+func requireEmulator(t *testing.T, name, endpoint string) {
+	t.Helper()
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		t.Fatalf("Invalid %s endpoint %q: %v", name, endpoint, err)
+	}
+	conn, err := net.DialTimeout("tcp", u.Host, time.Second)
+	if err != nil {
+		t.Skipf("%s not available at %s: %v", name, endpoint, err)
+	}
+	conn.Close()
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// testRemoteBackend 对任意 RemoteStorage 后端执行通用的读写测试，对象名均以 prefix 开头
+func testRemoteBackend(t *testing.T, rs remote.RemoteStorage, prefix string) {
+	ctx := context.Background()
+
+	upload := func(t *testing.T, name string, data []byte) {
+		t.Helper()
+		if err := rs.Upload(ctx, name, bytes.NewReader(data), int64(len(data))); err != nil {
+			t.Fatalf("Upload(%s) failed: %v", name, err)
+		}
+	}
+	download := func(t *testing.T, name string, start, length int64) []byte {
+		t.Helper()
+		rc, err := rs.DownloadRange(ctx, name, start, length)
+		if err != nil {
+			t.Fatalf("DownloadRange(%s, %d, %d) failed: %v", name, start, length, err)
+		}
+		defer rc.Close()
+		b, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatalf("ReadAll(%s) failed: %v", name, err)
+		}
+		return b
+	}
+
+	t.Run("upload and download", func(t *testing.T) {
+		for _, size := range []int{0, 1, 100 << 10} {
+			data := make([]byte, size)
+			rand.Read(data)
+			name := prefix + "small"
+			upload(t, name, data)
+			if got := download(t, name, 0, 0); !bytes.Equal(got, data) {
+				t.Errorf("size %d: got %d bytes", size, len(got))
+			}
+			info, err := rs.Stat(ctx, name)
+			if err != nil || info.Size() != int64(size) {
+				t.Errorf("Stat(size %d) = %v, %v", size, info, err)
+			}
+		}
+	})
+
+	t.Run("large object", func(t *testing.T) {
+		// 超过分块大小，覆盖分块/可续传上传路径
+		data := make([]byte, 9<<20+123)
+		rand.Read(data)
+		name := prefix + "large"
+		if err := rs.Upload(ctx, name, io.MultiReader(bytes.NewReader(data)), -1); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		if got := download(t, name, 0, 0); !bytes.Equal(got, data) {
+			t.Errorf("got %d bytes, want %d", len(got), len(data))
+		}
+		if got := download(t, name, 4<<20-10, 20); !bytes.Equal(got, data[4<<20-10:4<<20+10]) {
+			t.Error("Range across block boundary mismatch")
+		}
+	})
+
+	t.Run("range", func(t *testing.T) {
+		name := prefix + "range"
+		upload(t, name, []byte("0123456789"))
+		tests := []struct {
+			start, length int64
+			want          string
+		}{
+			{0, 3, "012"},
+			{4, 2, "45"},
+			{7, 0, "789"},
+			{8, 100, "89"},
+			{20, 5, ""},
+		}
+		for _, tt := range tests {
+			if got := string(download(t, name, tt.start, tt.length)); got != tt.want {
+				t.Errorf("DownloadRange(%d, %d) = %q, want %q", tt.start, tt.length, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		if _, err := rs.Stat(ctx, prefix+"missing"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Stat: expected not exist, got %v", err)
+		}
+		if _, err := rs.Download(ctx, prefix+"missing"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Download: expected not exist, got %v", err)
+		}
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		upload(t, prefix+"old", []byte("old"))
+		upload(t, prefix+"target", []byte("target"))
+		if err := rs.Rename(ctx, prefix+"old", prefix+"target"); err != nil {
+			t.Fatalf("Rename failed: %v", err)
+		}
+		if got := string(download(t, prefix+"target", 0, 0)); got != "old" {
+			t.Errorf("Renamed content = %q", got)
+		}
+		if _, err := rs.Stat(ctx, prefix+"old"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected source removed, got %v", err)
+		}
+	})
+
+	t.Run("list and delete", func(t *testing.T) {
+		for _, name := range []string{"list/a", "list/b", "list/sub/c"} {
+			upload(t, prefix+name, []byte(name))
+		}
+		var names []string
+		err := remote.List(ctx, rs, prefix+"list/", func(name string, info os.FileInfo) error {
+			names = append(names, strings.TrimPrefix(name, prefix))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		sort.Strings(names)
+		if got := strings.Join(names, ","); got != "list/a,list/b,list/sub/c" {
+			t.Errorf("List = %s", got)
+		}
+
+		if err := rs.Delete(ctx, prefix+"list/a"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if _, err := rs.Stat(ctx, prefix+"list/a"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected not exist after delete, got %v", err)
+		}
+	})
+}
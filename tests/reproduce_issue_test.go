@@ -6,6 +6,7 @@ import (
 	"clearvault/internal/metadata"
 	"clearvault/internal/proxy"
 	"clearvault/internal/webdav"
+	"context"
 	"io"
 	"net/http/httptest"
 	"os"
@@ -64,7 +65,7 @@ func TestExportImportWithDifferentKeys(t *testing.T) {
 	// 4. Sender uploads a file
 	testFile := "/shared_doc.txt"
 	content := []byte("Secret Content Shared Between Users")
-	err := proxyA.UploadFile(testFile, bytes.NewReader(content), int64(len(content)))
+	err := proxyA.UploadFile(context.Background(), testFile, bytes.NewReader(content), int64(len(content)))
 	if err != nil {
 		t.Fatalf("Sender upload failed: %v", err)
 	}
@@ -88,7 +89,7 @@ func TestExportImportWithDifferentKeys(t *testing.T) {
 	// 7. Recipient tries to download file
 	// Note: We assume Recipient has access to the same remote storage (or files are public/shared on remote)
 	// Since we use the same mock server, they do access the same files.
-	rc, err := proxyB.DownloadFile(testFile)
+	rc, err := proxyB.DownloadFile(context.Background(), testFile)
 	if err != nil {
 		t.Fatalf("Recipient DownloadFile failed: %v", err)
 	}
@@ -169,7 +170,7 @@ func TestEncryptCommandUsage(t *testing.T) {
 	// Re-create proxy with remote storage
 	proxyWithRemote, _ := proxy.NewProxy(meta, remoteClient, cfg.Security.MasterKey)
 
-	rc, err := proxyWithRemote.DownloadFile("/" + testFileName)
+	rc, err := proxyWithRemote.DownloadFile(context.Background(), "/"+testFileName)
 	if err != nil {
 		t.Fatalf("DownloadFile failed: %v", err)
 	}